@@ -0,0 +1,189 @@
+// Package handlers provides the routes for the faucet service.
+package handlers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	v1Web "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/business/web/v1/mid"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/ardanlabs/blockchain/foundation/web"
+	"go.uber.org/zap"
+)
+
+// MuxConfig contains all the mandatory systems required by handlers.
+type MuxConfig struct {
+	Shutdown        chan os.Signal
+	Log             *zap.SugaredLogger
+	Client          *client.Client
+	PrivateKey      *ecdsa.PrivateKey
+	ChainID         uint16
+	Amount          uint64
+	Tip             uint64
+	AccountInterval time.Duration
+	IPRateLimit     float64
+	IPRateBurst     int
+	MaxBodySize     int64
+	CORSOrigins     []string
+}
+
+// APIMux constructs a http.Handler with all application routes defined.
+func APIMux(cfg MuxConfig) http.Handler {
+
+	// Construct the web.App which holds all routes as well as common Middleware.
+	app := web.NewApp(
+		cfg.Shutdown,
+		nil,
+		mid.Logger(cfg.Log),
+		mid.Errors(cfg.Log),
+		mid.Cors(cfg.CORSOrigins),
+		mid.MaxBodySize(cfg.MaxBodySize),
+		mid.Panics(),
+	)
+
+	h := Handlers{
+		log:             cfg.Log,
+		client:          cfg.Client,
+		privateKey:      cfg.PrivateKey,
+		accountID:       database.PublicKeyToAccountID(cfg.PrivateKey.PublicKey),
+		chainID:         cfg.ChainID,
+		amount:          cfg.Amount,
+		tip:             cfg.Tip,
+		accountInterval: cfg.AccountInterval,
+		served:          make(map[database.AccountID]time.Time),
+	}
+
+	app.Handle(http.MethodGet, "v1", "/faucet", h.Info)
+	app.Handle(http.MethodPost, "v1", "/faucet", h.Request, mid.RateLimit(cfg.IPRateLimit, cfg.IPRateBurst))
+
+	return app
+}
+
+// =============================================================================
+
+// Message returns the message an account signs to prove it owns the account
+// requesting funds.
+func Message(accountID database.AccountID) string {
+	return "faucet " + string(accountID)
+}
+
+// Handlers manages the set of faucet endpoints.
+type Handlers struct {
+	log             *zap.SugaredLogger
+	client          *client.Client
+	privateKey      *ecdsa.PrivateKey
+	accountID       database.AccountID
+	chainID         uint16
+	amount          uint64
+	tip             uint64
+	accountInterval time.Duration
+
+	// The transactions are submitted one at a time so each is signed with
+	// the next nonce of the faucet account.
+	mu     sync.Mutex
+	served map[database.AccountID]time.Time
+}
+
+// Info returns the account funding the requests and how much is sent.
+func (h *Handlers) Info(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	pb, err := h.client.PendingBalance(ctx, h.accountID)
+	if err != nil {
+		return fmt.Errorf("faucet balance: %w", err)
+	}
+
+	resp := info{
+		Account:         h.accountID,
+		Balance:         pb.Pending,
+		Amount:          h.amount,
+		AccountInterval: h.accountInterval.String(),
+		Message:         Message("{account}"),
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Request sends the amount to the account once the signature proves the
+// requester owns the account. An account is only funded once per interval.
+func (h *Handlers) Request(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req request
+	if err := web.Decode(r, &req); err != nil {
+		return v1Web.NewRequestError(fmt.Errorf("unable to decode payload: %w", err), http.StatusBadRequest)
+	}
+
+	accountID, err := database.ToAccountID(req.Account)
+	if err != nil {
+		return v1Web.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if _, err := signature.VerifyMessage(Message(accountID), req.Signature, string(accountID)); err != nil {
+		return v1Web.NewRequestError(fmt.Errorf("signature of %q: %w", Message(accountID), err), http.StatusForbidden)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	h.forget(now)
+
+	if last, exists := h.served[accountID]; exists {
+		wait := h.accountInterval - now.Sub(last)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return v1Web.NewRequestError(fmt.Errorf("account was funded recently, try again in %v", wait.Round(time.Second)), http.StatusTooManyRequests)
+	}
+
+	pb, err := h.client.PendingBalance(ctx, h.accountID)
+	if err != nil {
+		return fmt.Errorf("faucet balance: %w", err)
+	}
+	if pb.Pending < h.amount+h.tip {
+		return v1Web.NewRequestError(errors.New("faucet is out of funds"), http.StatusServiceUnavailable)
+	}
+
+	tx, err := database.NewTx(h.chainID, pb.PendingNonce+1, h.accountID, accountID, h.amount, h.tip, nil)
+	if err != nil {
+		return err
+	}
+
+	signedTx, err := tx.Sign(h.privateKey)
+	if err != nil {
+		return err
+	}
+
+	if err := h.client.SubmitTransaction(ctx, signedTx); err != nil {
+		return fmt.Errorf("submit: %w", err)
+	}
+
+	h.served[accountID] = now
+
+	h.log.Infow("faucet funded", "traceid", web.GetTraceID(ctx), "account", accountID, "value", h.amount, "tx", signedTx.TxHash())
+
+	resp := funded{
+		Account: accountID,
+		Value:   h.amount,
+		TxHash:  signedTx.TxHash(),
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// forget removes the accounts that can be funded again, so the map only
+// holds the accounts funded within the interval. The caller must hold the
+// lock.
+func (h *Handlers) forget(now time.Time) {
+	for accountID, last := range h.served {
+		if now.Sub(last) >= h.accountInterval {
+			delete(h.served, accountID)
+		}
+	}
+}
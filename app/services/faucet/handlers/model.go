@@ -0,0 +1,22 @@
+package handlers
+
+import "github.com/ardanlabs/blockchain/foundation/blockchain/database"
+
+type request struct {
+	Account   string `json:"account"`
+	Signature string `json:"signature"`
+}
+
+type info struct {
+	Account         database.AccountID `json:"account"`
+	Balance         uint64             `json:"balance"`
+	Amount          uint64             `json:"amount"`
+	AccountInterval string             `json:"account_interval"`
+	Message         string             `json:"message"`
+}
+
+type funded struct {
+	Account database.AccountID `json:"account"`
+	Value   uint64             `json:"value"`
+	TxHash  string             `json:"tx_hash"`
+}
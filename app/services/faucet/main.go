@@ -0,0 +1,202 @@
+// Package main implements a faucet for test networks. The faucet holds the
+// key of a funded account and sends a small amount to accounts that ask for
+// it, so new students can get test funds without asking an instructor. The
+// requester proves they own the account by signing a message with the
+// wallet's sign-message command.
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ardanlabs/blockchain/app/services/faucet/handlers"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/hash"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/ardanlabs/blockchain/foundation/keystore"
+	"github.com/ardanlabs/blockchain/foundation/logger"
+	"github.com/ardanlabs/conf/v3"
+	"go.uber.org/zap"
+)
+
+// build is the git version of this program. It is set using build flags in the makefile.
+var build = "develop"
+
+func main() {
+
+	// Construct the application logger.
+	log, err := logger.New("FAUCET")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer log.Sync()
+
+	// Perform the startup and shutdown sequence.
+	if err := run(log); err != nil {
+		log.Errorw("startup", "ERROR", err)
+		log.Sync()
+		os.Exit(1)
+	}
+}
+
+func run(log *zap.SugaredLogger) error {
+
+	// =========================================================================
+	// Configuration
+
+	cfg := struct {
+		conf.Version
+		Web struct {
+			ReadTimeout     time.Duration `conf:"default:5s"`
+			WriteTimeout    time.Duration `conf:"default:10s"`
+			IdleTimeout     time.Duration `conf:"default:120s"`
+			ShutdownTimeout time.Duration `conf:"default:20s"`
+			Host            string        `conf:"default:0.0.0.0:4080"`
+			MaxBodySize     int64         `conf:"default:4096"`
+			IPRateLimit     float64       `conf:"default:0.001"`
+			IPRateBurst     int           `conf:"default:5"`
+			CORSOrigins     []string      `conf:"default:*"`
+		}
+		Node struct {
+			PublicURL string `conf:"default:http://localhost:8080"`
+		}
+		Faucet struct {
+			KeyPath         string        `conf:"default:zblock/accounts/pavel.ecdsa"`
+			KeyPassword     string        `conf:"mask"`
+			Amount          uint64        `conf:"default:1000"`
+			Tip             uint64        `conf:"default:0"`
+			AccountInterval time.Duration `conf:"default:24h"`
+		}
+	}{
+		Version: conf.Version{
+			Build: build,
+			Desc:  "copyright information here",
+		},
+	}
+
+	const prefix = "FAUCET"
+	help, err := conf.Parse(prefix, &cfg)
+	if err != nil {
+		if errors.Is(err, conf.ErrHelpWanted) {
+			fmt.Println(help)
+			return nil
+		}
+		return fmt.Errorf("parsing config: %w", err)
+	}
+
+	// =========================================================================
+	// App Starting
+
+	log.Infow("starting service", "version", build)
+	defer log.Infow("shutdown complete")
+
+	out, err := conf.String(&cfg)
+	if err != nil {
+		return fmt.Errorf("generating config for output: %w", err)
+	}
+	log.Infow("startup", "config", out)
+
+	// =========================================================================
+	// Faucet Support
+
+	// Load the key of the account funding the requests. An encrypted key
+	// file needs the password.
+	loadKey := crypto.LoadECDSA
+	if cfg.Faucet.KeyPassword != "" {
+		loadKey = func(path string) (*ecdsa.PrivateKey, error) {
+			return keystore.Load(path, cfg.Faucet.KeyPassword)
+		}
+	}
+	privateKey, err := loadKey(cfg.Faucet.KeyPath)
+	if err != nil {
+		return fmt.Errorf("loading faucet key: %w", err)
+	}
+
+	cln := client.New(client.Config{URL: cfg.Node.PublicURL, Retries: 3})
+
+	// The transactions are signed for the chain the node is running, and
+	// their hashes use the hash function of the chain.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	gen, err := cln.Genesis(ctx)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("node genesis: %w", err)
+	}
+	if err := hash.Select(gen.Hash); err != nil {
+		return fmt.Errorf("genesis hash: %w", err)
+	}
+
+	log.Infow("startup", "status", "faucet account", "account", database.PublicKeyToAccountID(privateKey.PublicKey), "chain", gen.ChainID)
+
+	// =========================================================================
+	// Start Faucet Service
+
+	// Make a channel to listen for an interrupt or terminate signal from the OS.
+	// Use a buffered channel because the signal package requires it.
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
+
+	apiMux := handlers.APIMux(handlers.MuxConfig{
+		Shutdown:        shutdown,
+		Log:             log,
+		Client:          cln,
+		PrivateKey:      privateKey,
+		ChainID:         gen.ChainID,
+		Amount:          cfg.Faucet.Amount,
+		Tip:             cfg.Faucet.Tip,
+		AccountInterval: cfg.Faucet.AccountInterval,
+		IPRateLimit:     cfg.Web.IPRateLimit,
+		IPRateBurst:     cfg.Web.IPRateBurst,
+		MaxBodySize:     cfg.Web.MaxBodySize,
+		CORSOrigins:     cfg.Web.CORSOrigins,
+	})
+
+	api := http.Server{
+		Addr:         cfg.Web.Host,
+		Handler:      apiMux,
+		ReadTimeout:  cfg.Web.ReadTimeout,
+		WriteTimeout: cfg.Web.WriteTimeout,
+		IdleTimeout:  cfg.Web.IdleTimeout,
+		ErrorLog:     zap.NewStdLog(log.Desugar()),
+	}
+
+	// Make a channel to listen for errors coming from the listener. Use a
+	// buffered channel so the goroutine can exit if we don't collect this error.
+	serverErrors := make(chan error, 1)
+
+	go func() {
+		log.Infow("startup", "status", "faucet router started", "host", api.Addr)
+		serverErrors <- api.ListenAndServe()
+	}()
+
+	// =========================================================================
+	// Shutdown
+
+	select {
+	case err := <-serverErrors:
+		return fmt.Errorf("server error: %w", err)
+
+	case sig := <-shutdown:
+		log.Infow("shutdown", "status", "shutdown started", "signal", sig)
+		defer log.Infow("shutdown", "status", "shutdown complete", "signal", sig)
+
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
+		defer cancel()
+
+		if err := api.Shutdown(ctx); err != nil {
+			api.Close()
+			return fmt.Errorf("could not stop server gracefully: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -6,28 +6,47 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
 	"go.uber.org/zap"
 )
 
 // Handlers manages the set of check endpoints.
 type Handlers struct {
-	Build string
-	Log   *zap.SugaredLogger
+	Build    string
+	Revision string
+	Log      *zap.SugaredLogger
+	State    *state.State
 }
 
-// Readiness checks if the database is ready and if not will return a 500 status.
-// Do not respond by just returning an error because further up in the call
-// stack it will interpret that as a non-trusted error.
+// Readiness checks if the node is ready and if not will return a 500 status.
+// A node that is still syncing with its peers or has identified a persistent
+// chain split with a peer is not considered ready. Do not respond by just
+// returning an error because further up in the call stack it will interpret
+// that as a non-trusted error.
 func (h Handlers) Readiness(w http.ResponseWriter, r *http.Request) {
 	status := "ok"
 	statusCode := http.StatusOK
 
-	// TODO: NOT SURE WHAT TO DO RIGHT NOW
+	checks := h.State.Readiness()
+	if !checks.Ready() {
+		status = "not ready"
+		statusCode = http.StatusInternalServerError
+	}
+
+	splits := h.State.ChainSplits()
+	if len(splits) > 0 {
+		status = "chain split"
+		statusCode = http.StatusInternalServerError
+	}
 
 	data := struct {
-		Status string `json:"status"`
+		Status string             `json:"status"`
+		Checks state.Readiness    `json:"checks"`
+		Splits []state.ChainSplit `json:"splits,omitempty"`
 	}{
 		Status: status,
+		Checks: checks,
+		Splits: splits,
 	}
 
 	if err := response(w, statusCode, data); err != nil {
@@ -48,13 +67,15 @@ func (h Handlers) Liveness(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := struct {
-		Status string `json:"status,omitempty"`
-		Build  string `json:"build,omitempty"`
-		Host   string `json:"host,omitempty"`
+		Status   string `json:"status,omitempty"`
+		Build    string `json:"build,omitempty"`
+		Revision string `json:"revision,omitempty"`
+		Host     string `json:"host,omitempty"`
 	}{
-		Status: "up",
-		Build:  h.Build,
-		Host:   host,
+		Status:   "up",
+		Build:    h.Build,
+		Revision: h.Revision,
+		Host:     host,
 	}
 
 	statusCode := http.StatusOK
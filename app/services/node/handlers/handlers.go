@@ -7,18 +7,33 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/ardanlabs/blockchain/app/services/node/handlers/debug/checkgrp"
 	v1 "github.com/ardanlabs/blockchain/app/services/node/handlers/v1"
 	"github.com/ardanlabs/blockchain/business/web/v1/mid"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/metrics"
+	"github.com/ardanlabs/blockchain/foundation/tracing"
 	"github.com/ardanlabs/blockchain/foundation/web"
 	"go.uber.org/zap"
 )
 
 // MuxConfig contains all the mandatory systems required by handlers.
 type MuxConfig struct {
-	Shutdown chan os.Signal
-	Log      *zap.SugaredLogger
+	Shutdown    chan os.Signal
+	Log         *zap.SugaredLogger
+	Tracer      *tracing.Tracer
+	State       *state.State
+	AdminToken  string
+	MaxBodySize int64
+	TxRateLimit float64
+	TxRateBurst int
+	PollTimeout time.Duration
+	CORSOrigins []string
+	Build       string
+	Revision    string
 }
 
 // PublicMux constructs a http.Handler with all application routes defined.
@@ -27,58 +42,123 @@ func PublicMux(cfg MuxConfig) http.Handler {
 	// Construct the web.App which holds all routes as well as common Middleware.
 	app := web.NewApp(
 		cfg.Shutdown,
+		cfg.Tracer,
 		mid.Logger(cfg.Log),
 		mid.Errors(cfg.Log),
 		mid.Metrics(),
-		mid.Cors("*"),
+		mid.Cors(cfg.CORSOrigins),
+		mid.MaxBodySize(cfg.MaxBodySize),
 		mid.Panics(),
 	)
 
-	// Accept CORS 'OPTIONS' preflight requests if config has been provided.
-	// Don't forget to apply the CORS middleware to the routes that need it.
-	// Example Config: `conf:"default:https://MY_DOMAIN.COM"`
-	h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-		return nil
-	}
-	app.Handle(http.MethodOptions, "", "/*", h, mid.Cors("*"))
+	// Accept CORS 'OPTIONS' preflight requests from the configured origins.
+	app.Handle(http.MethodOptions, "", "/*", preflight)
 
 	// Load the v1 routes.
 	v1.PublicRoutes(app, v1.Config{
-		Log: cfg.Log,
+		Log:         cfg.Log,
+		State:       cfg.State,
+		TxRateLimit: cfg.TxRateLimit,
+		TxRateBurst: cfg.TxRateBurst,
+		PollTimeout: cfg.PollTimeout,
+		Build:       cfg.Build,
+		Revision:    cfg.Revision,
 	})
 
 	return app
 }
 
+// preflight answers the CORS 'OPTIONS' requests browsers send before calling
+// the API. The CORS middleware has already set the headers.
+func preflight(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
 // PrivateMux constructs a http.Handler with all application routes defined.
 func PrivateMux(cfg MuxConfig) http.Handler {
 
 	// Construct the web.App which holds all routes as well as common Middleware.
 	app := web.NewApp(
 		cfg.Shutdown,
+		cfg.Tracer,
 		mid.Logger(cfg.Log),
 		mid.Errors(cfg.Log),
 		mid.Metrics(),
-		mid.Cors("*"),
+		mid.Cors(cfg.CORSOrigins),
 		mid.Panics(),
 	)
 
-	// Accept CORS 'OPTIONS' preflight requests if config has been provided.
-	// Don't forget to apply the CORS middleware to the routes that need it.
-	// Example Config: `conf:"default:https://MY_DOMAIN.COM"`
-	h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-		return nil
-	}
-	app.Handle(http.MethodOptions, "", "/*", h, mid.Cors("*"))
+	// Accept CORS 'OPTIONS' preflight requests from the configured origins.
+	app.Handle(http.MethodOptions, "", "/*", preflight)
 
 	// Load the v1 routes.
 	v1.PrivateRoutes(app, v1.Config{
-		Log: cfg.Log,
+		Log:   cfg.Log,
+		State: cfg.State,
+	})
+
+	return app
+}
+
+// AdminMux constructs a http.Handler with all the node operator routes
+// defined. Every route requires the configured admin token.
+func AdminMux(cfg MuxConfig) http.Handler {
+
+	// Construct the web.App which holds all routes as well as common Middleware.
+	app := web.NewApp(
+		cfg.Shutdown,
+		cfg.Tracer,
+		mid.Logger(cfg.Log),
+		mid.Errors(cfg.Log),
+		mid.Authenticate(cfg.AdminToken),
+		mid.Metrics(),
+		mid.Panics(),
+	)
+
+	// Load the v1 routes.
+	v1.AdminRoutes(app, v1.Config{
+		Log:   cfg.Log,
+		State: cfg.State,
 	})
 
 	return app
 }
 
+// ChainMux represents the mux of a chain hosted by the node alongside the
+// chain served at the root of the API.
+type ChainMux struct {
+	Name    string
+	Handler http.Handler
+}
+
+// MountChains returns a handler serving the root mux and the mux of every
+// chain under its name, so /testnet/v1/genesis/list is the genesis of the
+// testnet chain. Without chains the root mux is returned as is.
+func MountChains(root http.Handler, chains []ChainMux) http.Handler {
+	if len(chains) == 0 {
+		return root
+	}
+
+	mux := http.NewServeMux()
+	for _, chain := range chains {
+		prefix := "/" + chain.Name
+		mux.Handle(prefix+"/", stripChain(prefix, chain.Handler))
+	}
+	mux.Handle("/", root)
+
+	return mux
+}
+
+// stripChain removes the name of the chain from the request before the mux
+// of the chain routes it. The router matches on the request URI, so the name
+// is removed from it as well as the URL.
+func stripChain(prefix string, handler http.Handler) http.Handler {
+	return http.StripPrefix(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.RequestURI = strings.TrimPrefix(r.RequestURI, prefix)
+		handler.ServeHTTP(w, r)
+	}))
+}
+
 // DebugStandardLibraryMux registers all the debug routes from the standard library
 // into a new mux bypassing the use of the DefaultServerMux. Using the
 // DefaultServerMux would be a security risk since a dependency could inject a
@@ -101,16 +181,52 @@ func DebugStandardLibraryMux() *http.ServeMux {
 // debug application routes for the service. This bypassing the use of the
 // DefaultServerMux. Using the DefaultServerMux would be a security risk since
 // a dependency could inject a handler into our service without us knowing it.
-func DebugMux(build string, log *zap.SugaredLogger) http.Handler {
+func DebugMux(build string, revision string, log *zap.SugaredLogger, st *state.State) http.Handler {
 	mux := DebugStandardLibraryMux()
 
 	// Register debug check endpoints.
 	cgh := checkgrp.Handlers{
-		Build: build,
-		Log:   log,
+		Build:    build,
+		Revision: revision,
+		Log:      log,
+		State:    st,
 	}
 	mux.HandleFunc("/debug/readiness", cgh.Readiness)
 	mux.HandleFunc("/debug/liveness", cgh.Liveness)
 
+	// Register the blockchain metrics in the Prometheus text format.
+	mux.Handle("/debug/metrics", metrics.Handler())
+
+	// Publish the state of the blockchain with the other expvar values.
+	publishState(st)
+
 	return mux
 }
+
+// publishState publishes the current state of the blockchain under the
+// blockchain key of the expvar endpoint. The values are read each time the
+// endpoint is called. The expvar package panics if a name is published
+// twice, so only the first state is published.
+func publishState(st *state.State) {
+	if expvar.Get("blockchain") != nil {
+		return
+	}
+
+	expvar.Publish("blockchain", expvar.Func(func() any {
+		latest := st.LatestBlock()
+
+		return struct {
+			LatestBlock uint64 `json:"latest_block"`
+			LatestHash  string `json:"latest_hash"`
+			Peers       int    `json:"peers"`
+			Accounts    int    `json:"accounts"`
+			Mempool     int    `json:"mempool"`
+		}{
+			LatestBlock: latest.Header.Number,
+			LatestHash:  latest.Hash(),
+			Peers:       len(st.KnownExternalPeers()),
+			Accounts:    st.QueryAccountCount(),
+			Mempool:     st.MempoolLength(),
+		}
+	}))
+}
@@ -0,0 +1,874 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/blockchain/app/services/node/handlers"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/mempool"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/testnode"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/ardanlabs/blockchain/foundation/hexutil"
+	"github.com/ardanlabs/blockchain/foundation/tracing"
+	"go.uber.org/zap"
+)
+
+// config returns the settings for nodes serving the application handlers.
+func config() testnode.Config {
+	return tracedConfig(nil)
+}
+
+// tracedConfig returns the settings for nodes traced by the tracer.
+func tracedConfig(tracer *tracing.Tracer) testnode.Config {
+	mux := func(build func(handlers.MuxConfig) http.Handler) testnode.Handler {
+		return func(st *state.State) http.Handler {
+			return build(handlers.MuxConfig{
+				Shutdown:    make(chan os.Signal, 1),
+				Log:         zap.NewNop().Sugar(),
+				Tracer:      tracer,
+				State:       st,
+				MaxBodySize: 1 << 20,
+				TxRateLimit: 1000,
+				TxRateBurst: 1000,
+				PollTimeout: time.Second,
+			})
+		}
+	}
+
+	return testnode.Config{
+		Public:  mux(handlers.PublicMux),
+		Private: mux(handlers.PrivateMux),
+		Tracer:  tracer,
+	}
+}
+
+func TestGossipAndSync(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	nodes := testnode.NewNetwork(t, 3, config())
+
+	// A transaction submitted to one node is shared with the others.
+	if _, err := nodes[0].Submit(0, nodes[0].AccountID(1), 100, 10, nil); err != nil {
+		t.Fatalf("Should be able to submit a transaction: %s", err)
+	}
+	for i, n := range nodes {
+		if err := n.WaitForMempool(ctx, 1); err != nil {
+			t.Fatalf("Should receive the transaction on node %d: %s", i, err)
+		}
+	}
+
+	// A block mined by one node is applied by the others.
+	block, err := nodes[0].Mine(ctx)
+	if err != nil {
+		t.Fatalf("Should be able to mine a block: %s", err)
+	}
+	for i, n := range nodes {
+		got, err := n.WaitForBlock(ctx, 1)
+		if err != nil {
+			t.Fatalf("Should receive the block on node %d: %s", i, err)
+		}
+		if got.Hash() != block.Hash() {
+			t.Fatalf("Should have the mined block on node %d, got %s, exp %s", i, got.Hash(), block.Hash())
+		}
+		if l := n.State.MempoolLength(); l != 0 {
+			t.Fatalf("Should have removed the mined transaction on node %d, got %d", i, l)
+		}
+	}
+
+	// A node joining later syncs the chain.
+	late := testnode.New(t, testnode.Config{
+		Public:  config().Public,
+		Private: config().Private,
+		Peers:   nodes[:1],
+	})
+	if got := late.State.LatestBlock().Hash(); got != block.Hash() {
+		t.Fatalf("Should sync the chain when joining, got %s, exp %s", got, block.Hash())
+	}
+}
+
+func TestReorg(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	nodes := testnode.NewNetwork(t, 2, config())
+	a, b := nodes[0], nodes[1]
+
+	// Split the network so each node builds its own fork.
+	a.Disconnect(b)
+
+	mine := func(n *testnode.Node, blocks int) {
+		t.Helper()
+
+		for i := 0; i < blocks; i++ {
+			if _, err := n.Submit(0, n.AccountID(1), uint64(i+1), 0, nil); err != nil {
+				t.Fatalf("Should be able to submit a transaction: %s", err)
+			}
+			if _, err := n.Mine(ctx); err != nil {
+				t.Fatalf("Should be able to mine a block: %s", err)
+			}
+		}
+	}
+
+	mine(a, 1)
+	mine(b, 2)
+
+	// A node receiving a block two or more ahead of its chain knows it's on
+	// the wrong side of a fork and resyncs with its peers.
+	a.Connect(b)
+	mine(b, 2)
+
+	got, err := a.WaitForBlock(ctx, 4)
+	if err != nil {
+		t.Fatalf("Should reorg to the longer chain: %s", err)
+	}
+	if exp := b.State.LatestBlock().Hash(); got.Hash() != exp {
+		t.Fatalf("Should have the longer chain, got %s, exp %s", got.Hash(), exp)
+	}
+}
+
+func TestChaos(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Every message the first node gossips is dropped, while the requests
+	// made to sync with a peer still go through.
+	cfg := config()
+	cfg.Chaos = state.Chaos{DropRate: 1}
+	a := testnode.New(t, cfg)
+
+	cfg = config()
+	cfg.Peers = []*testnode.Node{a}
+	b := testnode.New(t, cfg)
+
+	mine := func(n *testnode.Node, blocks int) {
+		t.Helper()
+
+		for i := 0; i < blocks; i++ {
+			if _, err := n.Submit(0, n.AccountID(1), uint64(i+1), 0, nil); err != nil {
+				t.Fatalf("Should be able to submit a transaction: %s", err)
+			}
+			if _, err := n.Mine(ctx); err != nil {
+				t.Fatalf("Should be able to mine a block: %s", err)
+			}
+		}
+	}
+
+	mine(a, 1)
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer waitCancel()
+	if _, err := b.WaitForBlock(waitCtx, 1); err == nil {
+		t.Fatalf("Should not receive a block that was dropped")
+	}
+
+	// The node that missed the block builds a longer fork the first node
+	// reorgs to, since blocks proposed to it aren't dropped.
+	mine(b, 4)
+
+	got, err := a.WaitForBlock(ctx, 4)
+	if err != nil {
+		t.Fatalf("Should reorg to the longer chain: %s", err)
+	}
+	if exp := b.State.LatestBlock().Hash(); got.Hash() != exp {
+		t.Fatalf("Should have the longer chain, got %s, exp %s", got.Hash(), exp)
+	}
+}
+
+func TestTxWindow(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	n := testnode.New(t, config())
+
+	sign := func(nonce uint64, notBefore uint64, notAfter uint64) database.SignedTx {
+		t.Helper()
+
+		tx, err := database.NewTx(n.Genesis.ChainID, nonce, n.AccountID(0), n.AccountID(1), 100, 0, nil)
+		if err != nil {
+			t.Fatalf("Should be able to construct the transaction: %s", err)
+		}
+		tx.NotBefore = notBefore
+		tx.NotAfter = notAfter
+
+		signedTx, err := tx.Sign(n.Accounts[0])
+		if err != nil {
+			t.Fatalf("Should be able to sign the transaction: %s", err)
+		}
+
+		return signedTx
+	}
+
+	// A pre-signed transaction waits in the mempool until its window opens.
+	windowed := sign(1, 2, 3)
+	if err := n.State.UpsertWalletTransaction(context.Background(), windowed); err != nil {
+		t.Fatalf("Should accept a transaction whose window hasn't opened: %s", err)
+	}
+	if _, err := n.Mine(ctx); !errors.Is(err, state.ErrNoTransactions) {
+		t.Fatalf("Should not mine the transaction before its window, got %v", err)
+	}
+
+	if _, err := n.Submit(1, n.AccountID(0), 100, 0, nil); err != nil {
+		t.Fatalf("Should be able to submit a transaction: %s", err)
+	}
+	block, err := n.Mine(ctx)
+	if err != nil {
+		t.Fatalf("Should be able to mine a block: %s", err)
+	}
+	if got := len(block.MerkleTree.Values()); got != 1 {
+		t.Fatalf("Should leave the transaction in the mempool, got %d transactions", got)
+	}
+
+	block, err = n.Mine(ctx)
+	if err != nil {
+		t.Fatalf("Should be able to mine the transaction inside its window: %s", err)
+	}
+	if values := block.MerkleTree.Values(); len(values) != 1 || values[0].TxHash() != windowed.TxHash() {
+		t.Fatalf("Should mine the transaction in block %d", block.Header.Number)
+	}
+
+	// A transaction whose window closed can never be mined.
+	if err := n.State.UpsertWalletTransaction(context.Background(), sign(2, 1, 2)); !errors.Is(err, database.ErrTxExpired) {
+		t.Fatalf("Should reject a transaction whose window closed, got %v", err)
+	}
+}
+
+func TestDoubleSpend(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	nodes := testnode.NewNetwork(t, 2, config())
+	a, b := nodes[0], nodes[1]
+
+	// Split the network so each node accepts a different version of the
+	// same transaction.
+	a.Disconnect(b)
+
+	sign := func(toID database.AccountID, tip uint64) database.SignedTx {
+		t.Helper()
+
+		tx, err := database.NewTx(a.Genesis.ChainID, 1, a.AccountID(0), toID, 100, tip, nil)
+		if err != nil {
+			t.Fatalf("Should be able to construct the transaction: %s", err)
+		}
+
+		signedTx, err := tx.Sign(a.Accounts[0])
+		if err != nil {
+			t.Fatalf("Should be able to sign the transaction: %s", err)
+		}
+
+		return signedTx
+	}
+
+	// The late version offers a higher tip since the gossip of the winning
+	// version can reach the node after the block and wait in its mempool.
+	lost, won, late := sign(a.AccountID(1), 0), sign(b.MinerID(), 0), sign(a.MinerID(), 1)
+	if err := a.State.UpsertWalletTransaction(context.Background(), lost); err != nil {
+		t.Fatalf("Should be able to submit a transaction: %s", err)
+	}
+	if err := b.State.UpsertWalletTransaction(context.Background(), won); err != nil {
+		t.Fatalf("Should be able to submit a transaction: %s", err)
+	}
+
+	// The version mined by one node wins on the other once they reconnect.
+	a.Connect(b)
+	if _, err := b.Mine(ctx); err != nil {
+		t.Fatalf("Should be able to mine a block: %s", err)
+	}
+	if _, err := a.WaitForBlock(ctx, 1); err != nil {
+		t.Fatalf("Should receive the block: %s", err)
+	}
+
+	// A version arriving after the nonce was mined is part of it too.
+	if err := a.State.UpsertWalletTransaction(context.Background(), late); err != nil {
+		t.Fatalf("Should be able to submit a transaction: %s", err)
+	}
+
+	report := a.State.DoubleSpendReport(10)
+	if len(report) != 1 {
+		t.Fatalf("Should report the double spend, got %d", len(report))
+	}
+
+	ds := report[0]
+	if ds.Status != state.DoubleSpendMined || ds.Winner != won.TxHash() || ds.BlockNumber != 1 {
+		t.Fatalf("Should report the mined version as the winner, got %+v", ds)
+	}
+
+	seen := make(map[string]bool)
+	for _, v := range ds.Versions {
+		seen[v.Hash] = true
+	}
+	for _, tx := range []database.SignedTx{lost, won, late} {
+		if !seen[tx.TxHash()] {
+			t.Fatalf("Should report version %s, got %+v", tx.TxHash(), ds.Versions)
+		}
+	}
+}
+
+func TestReplaceTx(t *testing.T) {
+	paths := []struct {
+		name   string
+		upsert func(n *testnode.Node, signedTx database.SignedTx) error
+	}{
+		{"wallet", func(n *testnode.Node, signedTx database.SignedTx) error {
+			return n.State.UpsertWalletTransaction(context.Background(), signedTx)
+		}},
+		{"node", func(n *testnode.Node, signedTx database.SignedTx) error {
+			return n.State.UpsertNodeTransaction(context.Background(), database.NewBlockTx(signedTx, n.Genesis.GasPrice, 1))
+		}},
+	}
+
+	for _, p := range paths {
+		t.Run(p.name, func(t *testing.T) {
+			n := testnode.New(t, config())
+
+			sign := func(value uint64, tip uint64) database.SignedTx {
+				t.Helper()
+
+				tx, err := database.NewTx(n.Genesis.ChainID, 1, n.AccountID(0), n.AccountID(1), value, tip, nil)
+				if err != nil {
+					t.Fatalf("Should be able to construct the transaction: %s", err)
+				}
+
+				signedTx, err := tx.Sign(n.Accounts[0])
+				if err != nil {
+					t.Fatalf("Should be able to sign the transaction: %s", err)
+				}
+
+				return signedTx
+			}
+
+			// pending checks the transaction is the one waiting for the nonce.
+			pending := func(signedTx database.SignedTx) {
+				t.Helper()
+
+				hashes := n.State.MempoolHashes()
+				if len(hashes) != 1 || hashes[0] != signedTx.TxHash() {
+					t.Fatalf("Should keep transaction %s pending, got %v", signedTx.TxHash(), hashes)
+				}
+			}
+
+			// With the same tip the pending transaction is kept, whichever
+			// hash is lower.
+			first, second := sign(100, 1), sign(200, 1)
+			if err := p.upsert(n, first); err != nil {
+				t.Fatalf("Should be able to submit the transaction: %s", err)
+			}
+			if err := p.upsert(n, second); !errors.Is(err, mempool.ErrReplaceUnderpriced) {
+				t.Fatalf("Should not replace the transaction with the same tip, got %v", err)
+			}
+			pending(first)
+
+			if err := p.upsert(n, sign(300, 0)); !errors.Is(err, mempool.ErrReplaceUnderpriced) {
+				t.Fatalf("Should not replace the transaction with a lower tip, got %v", err)
+			}
+			pending(first)
+
+			// A higher tip always replaces the pending transaction.
+			raised := sign(300, 2)
+			if err := p.upsert(n, raised); err != nil {
+				t.Fatalf("Should replace the transaction with a higher tip: %s", err)
+			}
+			pending(raised)
+		})
+	}
+}
+
+func TestBlockFees(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	n := testnode.New(t, config())
+
+	mine := func(tips ...uint64) database.Block {
+		t.Helper()
+
+		for _, tip := range tips {
+			if _, err := n.Submit(0, n.AccountID(1), 100, tip, nil); err != nil {
+				t.Fatalf("Should be able to submit a transaction: %s", err)
+			}
+		}
+
+		block, err := n.Mine(ctx)
+		if err != nil {
+			t.Fatalf("Should be able to mine a block: %s", err)
+		}
+
+		return block
+	}
+
+	block := mine(10, 1, 4)
+	mine(30, 20)
+
+	fees, err := n.State.QueryBlockFees(block.Header.Number)
+	if err != nil {
+		t.Fatalf("Should be able to query the fees of the block: %s", err)
+	}
+	if fees.TxCount != 3 || fees.TotalTips != 15 || fees.MinTip != 1 || fees.MedianTip != 4 || fees.MaxTip != 10 {
+		t.Fatalf("Should compute the tip statistics of the block, got %+v", fees)
+	}
+	if fees.BlockHash != block.Hash() {
+		t.Fatalf("Should record the hash of the block, got %s", fees.BlockHash)
+	}
+
+	// The summary median is the median of the block medians, 4 and 25.
+	sum := n.State.QueryFeeSummary(20)
+	if sum.Blocks != 2 || sum.TxCount != 5 || sum.TotalTips != 65 || sum.MinTip != 1 || sum.MedianTip != 14 || sum.MaxTip != 30 {
+		t.Fatalf("Should combine the fees of the recent blocks, got %+v", sum)
+	}
+
+	if _, err := n.State.QueryBlockFees(block.Header.Number + 2); !errors.Is(err, state.ErrFeesNotFound) {
+		t.Fatalf("Should not find fees for a block not mined yet, got %v", err)
+	}
+}
+
+func TestEmptyBlocks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	n := testnode.New(t, config())
+
+	// By default nothing is mined without transactions.
+	if _, err := n.Mine(ctx); !errors.Is(err, state.ErrNoTransactions) {
+		t.Fatalf("Should not mine an empty block by default, got %v", err)
+	}
+
+	n.State.SetEmptyBlockInterval(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	block, err := n.Mine(ctx)
+	if err != nil {
+		t.Fatalf("Should mine an empty block once one is due: %s", err)
+	}
+	if got := len(block.MerkleTree.Values()); got != 0 {
+		t.Fatalf("Should mine a block without transactions, got %d", got)
+	}
+
+	// The new block resets the wait for the next empty block.
+	n.State.SetEmptyBlockInterval(time.Hour)
+	if _, err := n.Mine(ctx); !errors.Is(err, state.ErrNoTransactions) {
+		t.Fatalf("Should not mine an empty block before the interval, got %v", err)
+	}
+}
+
+func TestAccountProof(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	n := testnode.New(t, config())
+
+	// The genesis block doesn't record an accounts root.
+	if _, err := n.State.QueryAccountProof(n.AccountID(1)); !errors.Is(err, database.ErrNoAccountsRoot) {
+		t.Fatalf("Should not prove an account before a block is mined, got %v", err)
+	}
+
+	if _, err := n.Submit(0, n.AccountID(1), 100, 0, nil); err != nil {
+		t.Fatalf("Should be able to submit a transaction: %s", err)
+	}
+	if _, err := n.Mine(ctx); err != nil {
+		t.Fatalf("Should be able to mine a block: %s", err)
+	}
+
+	// The next block records the root of the accounts the first block left.
+	account, err := n.State.QueryAccount(n.AccountID(1))
+	if err != nil {
+		t.Fatalf("Should be able to query the account: %s", err)
+	}
+
+	if _, err := n.Submit(0, n.AccountID(1), 100, 0, nil); err != nil {
+		t.Fatalf("Should be able to submit a transaction: %s", err)
+	}
+	block, err := n.Mine(ctx)
+	if err != nil {
+		t.Fatalf("Should be able to mine a block: %s", err)
+	}
+
+	ap, err := n.State.QueryAccountProof(n.AccountID(1))
+	if err != nil {
+		t.Fatalf("Should be able to prove the account: %s", err)
+	}
+	if err := ap.Verify(); err != nil {
+		t.Fatalf("Should verify the proof: %s", err)
+	}
+	if (database.Block{Header: ap.Header}).Hash() != block.Hash() {
+		t.Fatalf("Should prove the account against the latest block")
+	}
+	if ap.Account.Balance != account.Balance || ap.Account.Nonce != account.Nonce {
+		t.Fatalf("Should prove the account the block was applied to, got %+v, exp %+v", ap.Account, account)
+	}
+
+	ap.Account.Balance++
+	if err := ap.Verify(); err == nil {
+		t.Fatalf("Should not verify a proof for a changed balance")
+	}
+}
+
+func TestTxErrors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	n := testnode.New(t, config())
+	cln := client.New(client.Config{URL: n.Public.URL})
+
+	sign := func(chainID uint16, nonce uint64) database.SignedTx {
+		t.Helper()
+
+		tx, err := database.NewTx(chainID, nonce, n.AccountID(0), n.AccountID(1), 100, 0, nil)
+		if err != nil {
+			t.Fatalf("Should be able to construct the transaction: %s", err)
+		}
+
+		signedTx, err := tx.Sign(n.Accounts[0])
+		if err != nil {
+			t.Fatalf("Should be able to sign the transaction: %s", err)
+		}
+
+		return signedTx
+	}
+
+	tampered := sign(n.Genesis.ChainID, 1)
+	tampered.Value++
+
+	tests := []struct {
+		name   string
+		tx     database.SignedTx
+		code   string
+		status int
+	}{
+		{"chainid", sign(n.Genesis.ChainID+1, 1), client.CodeChainIDMismatch, http.StatusBadRequest},
+		{"signature", tampered, client.CodeInvalidSignature, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		err := cln.SubmitTransaction(ctx, tt.tx)
+
+		var apiErr *client.Error
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("%s: Should be rejected by the node, got %v", tt.name, err)
+		}
+		if apiErr.Code != tt.code || apiErr.StatusCode != tt.status {
+			t.Fatalf("%s: Should get the code and status, got %s:%d, exp %s:%d", tt.name, apiErr.Code, apiErr.StatusCode, tt.code, tt.status)
+		}
+	}
+
+	// The node reports a transaction that is already pending, which the
+	// client doesn't treat as a failure.
+	signedTx := sign(n.Genesis.ChainID, 1)
+	if err := cln.SubmitTransaction(ctx, signedTx); err != nil {
+		t.Fatalf("Should be able to submit the transaction: %s", err)
+	}
+	if err := cln.SubmitTransaction(ctx, signedTx); err != nil {
+		t.Fatalf("Should accept submitting the transaction again: %s", err)
+	}
+
+	results, err := cln.SubmitTransactions(ctx, []database.SignedTx{signedTx})
+	if err != nil {
+		t.Fatalf("Should be able to submit the batch: %s", err)
+	}
+	if results[0].Accepted() || results[0].Code != client.CodeDuplicateTx {
+		t.Fatalf("Should report the pending transaction, got %+v", results[0])
+	}
+}
+
+func TestMountChains(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	mainnet := testnode.New(t, config())
+
+	cfg := config()
+	cfg.Genesis = mainnet.Genesis
+	cfg.Genesis.ChainID = mainnet.Genesis.ChainID + 1
+	cfg.Genesis.Balances = nil
+	testnet := testnode.New(t, cfg)
+
+	srv := httptest.NewServer(handlers.MountChains(mainnet.Public.Config.Handler, []handlers.ChainMux{
+		{Name: "testnet", Handler: testnet.Public.Config.Handler},
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		url     string
+		chainID uint16
+	}{
+		{srv.URL, mainnet.Genesis.ChainID},
+		{srv.URL + "/testnet", testnet.Genesis.ChainID},
+	}
+
+	for _, tt := range tests {
+		gen, err := client.New(client.Config{URL: tt.url}).Genesis(ctx)
+		if err != nil {
+			t.Fatalf("%s: Should be able to query the genesis: %s", tt.url, err)
+		}
+		if gen.ChainID != tt.chainID {
+			t.Fatalf("%s: Should serve the chain, got %d, exp %d", tt.url, gen.ChainID, tt.chainID)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	type span struct {
+		Service      string
+		TraceID      string
+		SpanID       string
+		ParentSpanID string
+		Name         string
+	}
+
+	// The collector records the spans exported by the nodes.
+	var mu sync.Mutex
+	var spans []span
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResourceSpans []struct {
+				Resource struct {
+					Attributes []struct {
+						Value struct {
+							StringValue string `json:"stringValue"`
+						} `json:"value"`
+					} `json:"attributes"`
+				} `json:"resource"`
+				ScopeSpans []struct {
+					Spans []struct {
+						TraceID      string `json:"traceId"`
+						SpanID       string `json:"spanId"`
+						ParentSpanID string `json:"parentSpanId"`
+						Name         string `json:"name"`
+					} `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		for _, rs := range req.ResourceSpans {
+			service := rs.Resource.Attributes[0].Value.StringValue
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					spans = append(spans, span{service, s.TraceID, s.SpanID, s.ParentSpanID, s.Name})
+				}
+			}
+		}
+	}))
+	defer collector.Close()
+
+	newTracer := func(service string) *tracing.Tracer {
+		tracer := tracing.New(tracing.Config{
+			ServiceName:  service,
+			Endpoint:     collector.URL,
+			Probability:  1,
+			BatchTimeout: 10 * time.Millisecond,
+		})
+		t.Cleanup(func() { tracer.Shutdown(context.Background()) })
+		return tracer
+	}
+
+	a := testnode.New(t, tracedConfig(newTracer("a")))
+
+	cfg := tracedConfig(newTracer("b"))
+	cfg.Peers = []*testnode.Node{a}
+	b := testnode.New(t, cfg)
+
+	if _, err := a.Submit(0, a.AccountID(1), 100, 0, nil); err != nil {
+		t.Fatalf("Should be able to submit a transaction: %s", err)
+	}
+	if err := b.WaitForMempool(ctx, 1); err != nil {
+		t.Fatalf("Should receive the transaction: %s", err)
+	}
+
+	// find returns the span exported by the service with the name.
+	find := func(service string, name string) (span, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		for _, s := range spans {
+			if s.Service == service && s.Name == name {
+				return s, true
+			}
+		}
+		return span{}, false
+	}
+
+	var accepted, send, server, received span
+	for {
+		var ok [4]bool
+		accepted, ok[0] = find("a", "state.UpsertWalletTransaction")
+		send, ok[1] = find("a", "state.send")
+		server, ok[2] = find("b", "POST /v1/node/tx/submit")
+		received, ok[3] = find("b", "state.UpsertNodeTransaction")
+		if ok == [4]bool{true, true, true, true} {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatalf("Should export the spans of both nodes, found %v", ok)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// The transaction is gossiped in the trace it was accepted in.
+	for _, s := range []span{send, server, received} {
+		if s.TraceID != accepted.TraceID {
+			t.Fatalf("Should continue the trace in %s, got %s, exp %s", s.Name, s.TraceID, accepted.TraceID)
+		}
+	}
+	if server.ParentSpanID != send.SpanID {
+		t.Fatalf("Should continue the trace from the request to the peer, got %s, exp %s", server.ParentSpanID, send.SpanID)
+	}
+	if received.ParentSpanID != server.SpanID {
+		t.Fatalf("Should accept the transaction in the span of the request, got %s, exp %s", received.ParentSpanID, server.SpanID)
+	}
+}
+
+func TestRPC(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	n := testnode.New(t, config())
+
+	type response struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+
+	// call posts the body to the endpoint and decodes the responses into v.
+	call := func(body string, v any) {
+		t.Helper()
+
+		resp, err := http.Post(n.Public.URL+"/v1/rpc", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Should be able to call the endpoint: %s", err)
+		}
+		defer resp.Body.Close()
+
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("Should be able to decode the response: %s", err)
+		}
+	}
+
+	// result checks the call succeeded and decodes the result into v.
+	result := func(resp response, v any) {
+		t.Helper()
+
+		if resp.Error != nil {
+			t.Fatalf("Should be able to make call %d, got code %d", resp.ID, resp.Error.Code)
+		}
+		if err := json.Unmarshal(resp.Result, v); err != nil {
+			t.Fatalf("Should be able to decode the result of call %d: %s", resp.ID, err)
+		}
+	}
+
+	// A batch is answered call by call, with a failure not affecting the rest.
+	var batch []response
+	call(`[
+		{"jsonrpc": "2.0", "id": 1, "method": "eth_chainId"},
+		{"jsonrpc": "2.0", "id": 2, "method": "eth_getBalance", "params": ["`+string(n.AccountID(0))+`", "latest"]},
+		{"jsonrpc": "2.0", "id": 3, "method": "eth_mining"},
+		{"jsonrpc": "2.0", "id": 4, "method": "eth_getBalance", "params": ["not an account"]}
+	]`, &batch)
+
+	if len(batch) != 4 {
+		t.Fatalf("Should answer every call in the batch, got %d", len(batch))
+	}
+
+	var chainID, balance hexutil.Uint64
+	result(batch[0], &chainID)
+	result(batch[1], &balance)
+
+	account, err := n.State.QueryAccount(n.AccountID(0))
+	if err != nil {
+		t.Fatalf("Should be able to query the account: %s", err)
+	}
+	if uint16(chainID) != n.Genesis.ChainID || uint64(balance) != account.Balance {
+		t.Fatalf("Should get the chain id and balance, got %d:%d, exp %d:%d", chainID, balance, n.Genesis.ChainID, account.Balance)
+	}
+	if batch[2].Error == nil || batch[2].Error.Code != -32601 {
+		t.Fatalf("Should not find the method, got %+v", batch[2].Error)
+	}
+	if batch[3].Error == nil || batch[3].Error.Code != -32602 {
+		t.Fatalf("Should reject the parameter, got %+v", batch[3].Error)
+	}
+
+	// A raw transaction is the hex encoded JSON of the signed transaction.
+	tx, err := database.NewTx(n.Genesis.ChainID, 1, n.AccountID(0), n.AccountID(1), 100, 0, nil)
+	if err != nil {
+		t.Fatalf("Should be able to construct the transaction: %s", err)
+	}
+	signedTx, err := tx.Sign(n.Accounts[0])
+	if err != nil {
+		t.Fatalf("Should be able to sign the transaction: %s", err)
+	}
+	raw, err := json.Marshal(signedTx)
+	if err != nil {
+		t.Fatalf("Should be able to encode the transaction: %s", err)
+	}
+
+	var resp response
+	call(`{"jsonrpc": "2.0", "id": 5, "method": "eth_sendRawTransaction", "params": ["`+hexutil.Encode(raw)+`"]}`, &resp)
+
+	var hash string
+	result(resp, &hash)
+	if hash != signedTx.TxHash() {
+		t.Fatalf("Should return the transaction hash, got %s, exp %s", hash, signedTx.TxHash())
+	}
+
+	// getTx returns the block number the transaction was mined in.
+	getTx := func() *hexutil.Uint64 {
+		t.Helper()
+
+		var resp response
+		call(`{"jsonrpc": "2.0", "id": 6, "method": "eth_getTransactionByHash", "params": ["`+hash+`"]}`, &resp)
+
+		var tx struct {
+			Hash        string          `json:"hash"`
+			BlockNumber *hexutil.Uint64 `json:"blockNumber"`
+		}
+		result(resp, &tx)
+		if tx.Hash != hash {
+			t.Fatalf("Should find the transaction, got %q, exp %q", tx.Hash, hash)
+		}
+
+		return tx.BlockNumber
+	}
+
+	if number := getTx(); number != nil {
+		t.Fatalf("Should find the transaction pending, got block %d", *number)
+	}
+
+	if _, err := n.Mine(ctx); err != nil {
+		t.Fatalf("Should be able to mine a block: %s", err)
+	}
+
+	if number := getTx(); number == nil || *number != 1 {
+		t.Fatalf("Should find the transaction in block 1, got %v", number)
+	}
+
+	call(`{"jsonrpc": "2.0", "id": 7, "method": "eth_blockNumber"}`, &resp)
+	var blockNumber hexutil.Uint64
+	result(resp, &blockNumber)
+	if blockNumber != 1 {
+		t.Fatalf("Should get the latest block number, got %d, exp %d", blockNumber, 1)
+	}
+}
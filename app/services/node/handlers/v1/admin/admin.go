@@ -0,0 +1,224 @@
+// Package admin maintains the group of handlers for node operator access.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	v1 "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/web"
+	"go.uber.org/zap"
+)
+
+// Set of limits on the number of recent blocks searched for the winners of
+// double spends.
+const (
+	defaultSpendBlocks = 100
+	maxSpendBlocks     = 10_000
+)
+
+// Handlers manages the set of node operator endpoints.
+type Handlers struct {
+	Log   *zap.SugaredLogger
+	State *state.State
+}
+
+// StartMining allows the node to mine blocks again.
+func (h Handlers) StartMining(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	h.State.ResumeMining()
+	return respondStatus(ctx, w, "mining started")
+}
+
+// StopMining cancels any mining operation and stops the node from mining
+// new blocks.
+func (h Handlers) StopMining(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	h.State.PauseMining()
+	return respondStatus(ctx, w, "mining stopped")
+}
+
+// EmptyBlocks sets how long the node goes without a block before it mines an
+// empty one. An interval of zero stops empty blocks from being mined.
+func (h Handlers) EmptyBlocks(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req struct {
+		Interval string `json:"interval"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	var interval time.Duration
+	if req.Interval != "" {
+		var err error
+		if interval, err = time.ParseDuration(req.Interval); err != nil || interval < 0 {
+			return v1.NewRequestError(fmt.Errorf("invalid interval %q", req.Interval), http.StatusBadRequest)
+		}
+	}
+
+	h.State.SetEmptyBlockInterval(interval)
+
+	if interval == 0 {
+		return respondStatus(ctx, w, "empty blocks stopped")
+	}
+	return respondStatus(ctx, w, fmt.Sprintf("empty blocks after %s", interval))
+}
+
+// DropMempool removes all the uncommitted transactions.
+func (h Handlers) DropMempool(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	h.State.TruncateMempool()
+	return respondStatus(ctx, w, "mempool dropped")
+}
+
+// AddPeer adds a peer to the list of known peers.
+func (h Handlers) AddPeer(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var pr peer.Peer
+	if err := web.Decode(r, &pr); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if pr.Host == "" {
+		return v1.NewRequestError(errors.New("host is required"), http.StatusBadRequest)
+	}
+
+	if !h.State.AddKnownPeer(pr) {
+		return respondStatus(ctx, w, "peer already known")
+	}
+
+	h.State.Worker.SignalMempoolSync(pr)
+
+	return respondStatus(ctx, w, "peer added")
+}
+
+// RemovePeer removes a peer from the list of known peers.
+func (h Handlers) RemovePeer(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	h.State.RemoveKnownPeer(peer.New(web.Param(r, "host")))
+	return respondStatus(ctx, w, "peer removed")
+}
+
+// PeerBans returns the peers that are currently banned.
+func (h Handlers) PeerBans(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, h.State.PeerBans(), http.StatusOK)
+}
+
+// BanPeer bans a peer for the specified duration. The configured ban
+// duration is used when no duration is provided.
+func (h Handlers) BanPeer(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req struct {
+		Host     string `json:"host"`
+		Duration string `json:"duration"`
+		Reason   string `json:"reason"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if req.Host == "" {
+		return v1.NewRequestError(errors.New("host is required"), http.StatusBadRequest)
+	}
+
+	var duration time.Duration
+	if req.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(req.Duration); err != nil {
+			return v1.NewRequestError(err, http.StatusBadRequest)
+		}
+	}
+
+	if req.Reason == "" {
+		req.Reason = "banned by operator"
+	}
+
+	ban := h.State.BanPeer(req.Host, duration, req.Reason)
+
+	return web.Respond(ctx, w, ban, http.StatusOK)
+}
+
+// UnbanPeer lifts the ban on a peer.
+func (h Handlers) UnbanPeer(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if !h.State.UnbanPeer(web.Param(r, "host")) {
+		return respondStatus(ctx, w, "peer not banned")
+	}
+
+	return respondStatus(ctx, w, "peer unbanned")
+}
+
+// Resync resets the chain back to genesis and pulls the blocks from peers.
+func (h Handlers) Resync(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if err := h.State.Resync(); err != nil {
+		return err
+	}
+
+	return respondStatus(ctx, w, "resync started")
+}
+
+// DoubleSpends reports the transactions seen with the same account and nonce
+// but different hashes, with the competing versions and the one that won.
+// The blocks query parameter sets how many recent blocks are searched for
+// the winners.
+func (h Handlers) DoubleSpends(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	blocks := uint64(defaultSpendBlocks)
+	if s := r.URL.Query().Get("blocks"); s != "" {
+		var err error
+		if blocks, err = strconv.ParseUint(s, 10, 64); err != nil || blocks > maxSpendBlocks {
+			return v1.NewRequestError(fmt.Errorf("invalid blocks %q, must be between 0 and %d", s, maxSpendBlocks), http.StatusBadRequest)
+		}
+	}
+
+	return web.Respond(ctx, w, h.State.DoubleSpendReport(blocks), http.StatusOK)
+}
+
+// Metrics returns the internal metrics for the node.
+func (h Handlers) Metrics(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	latestBlock := h.State.LatestBlock()
+
+	// The expvar values already know how to represent themselves as JSON.
+	vars := make(map[string]json.RawMessage)
+	expvar.Do(func(kv expvar.KeyValue) {
+		vars[kv.Key] = json.RawMessage(kv.Value.String())
+	})
+
+	resp := struct {
+		LatestBlockNumber uint64                        `json:"latest_block_number"`
+		LatestBlockHash   string                        `json:"latest_block_hash"`
+		Mempool           int                           `json:"mempool"`
+		MiningAllowed     bool                          `json:"mining_allowed"`
+		EmptyBlocks       string                        `json:"empty_blocks"`
+		KnownPeers        []peer.Peer                   `json:"known_peers"`
+		PeerIdentities    map[string]database.AccountID `json:"peer_identities"`
+		ClockOffset       string                        `json:"clock_offset"`
+		Vars              map[string]json.RawMessage    `json:"vars"`
+	}{
+		LatestBlockNumber: latestBlock.Header.Number,
+		LatestBlockHash:   latestBlock.Hash(),
+		Mempool:           h.State.MempoolLength(),
+		MiningAllowed:     h.State.IsMiningAllowed(),
+		EmptyBlocks:       h.State.EmptyBlockInterval().String(),
+		KnownPeers:        h.State.KnownExternalPeers(),
+		PeerIdentities:    h.State.PeerIdentities(),
+		ClockOffset:       h.State.ClockOffset().String(),
+		Vars:              vars,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// =============================================================================
+
+// respondStatus responds with the status of the operation.
+func respondStatus(ctx context.Context, w http.ResponseWriter, status string) error {
+	resp := struct {
+		Status string `json:"status"`
+	}{
+		Status: status,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
@@ -0,0 +1,281 @@
+// Package grpcgrp maintains the gRPC service for the node. It serves the
+// same transaction submission and balance queries as the public REST API,
+// and streams blocks to clients following the chain.
+package grpcgrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/ardanlabs/blockchain/app/services/node/proto/nodev1"
+	"github.com/ardanlabs/blockchain/business/sys/validate"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxBlockRange is the largest number of blocks read from the database at
+// a time while catching a subscriber up with the chain.
+const maxBlockRange = 100
+
+// txCodes maps the errors returned for a rejected transaction to the gRPC
+// status code of the response. Errors without a code are invalid arguments.
+var txCodes = []struct {
+	err  error
+	code codes.Code
+}{
+	{database.ErrInsufficientFunds, codes.FailedPrecondition},
+	{database.ErrBadNonce, codes.FailedPrecondition},
+	{state.ErrDuplicateTx, codes.AlreadyExists},
+}
+
+// Service is the gRPC server with the node service registered.
+type Service struct {
+	*grpc.Server
+	shut chan struct{}
+}
+
+// New constructs a gRPC server with the node service registered.
+func New(log *zap.SugaredLogger, st *state.State) *Service {
+	shut := make(chan struct{})
+
+	srv := grpc.NewServer()
+	nodev1.RegisterNodeServer(srv, &Server{Log: log, State: st, shut: shut})
+
+	return &Service{Server: srv, shut: shut}
+}
+
+// Shutdown ends the block subscriptions, which would otherwise only end
+// when the client goes away, and gives the outstanding calls a deadline for
+// completion. The server is stopped if they don't complete in time.
+func (s *Service) Shutdown(timeout time.Duration) error {
+	close(s.shut)
+
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		s.Stop()
+		return errors.New("timeout waiting for calls to complete")
+	}
+}
+
+// =============================================================================
+
+// Server implements the node service over the node's state.
+type Server struct {
+	nodev1.UnimplementedNodeServer
+	Log   *zap.SugaredLogger
+	State *state.State
+	shut  chan struct{}
+}
+
+// SubmitTransaction adds a new transaction to the mempool, with the same
+// checks as a transaction submitted to the public REST API.
+func (s *Server) SubmitTransaction(ctx context.Context, req *nodev1.SubmitTransactionRequest) (*nodev1.SubmitTransactionResponse, error) {
+	signedTx, err := toSignedTx(req.GetTx())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Make sure the required fields are present before going any further.
+	if err := validate.Check(signedTx); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	s.Log.Infow("add tran", "api", "grpc", "sig:nonce", signedTx, "from", signedTx.FromID, "to", signedTx.ToID, "value", signedTx.Value, "tip", signedTx.Tip)
+
+	if err := s.State.UpsertWalletTransaction(ctx, signedTx); err != nil {
+		return nil, txError(err)
+	}
+
+	return &nodev1.SubmitTransactionResponse{Hash: signedTx.TxHash()}, nil
+}
+
+// GetBalance returns the balance and nonce of the account. An account the
+// database has never seen holds a zero balance.
+func (s *Server) GetBalance(ctx context.Context, req *nodev1.GetBalanceRequest) (*nodev1.GetBalanceResponse, error) {
+	accountID, err := database.ToAccountID(req.GetAccount())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	account, err := s.State.QueryAccount(accountID)
+	if err != nil {
+		account = database.Account{AccountID: accountID}
+	}
+
+	resp := nodev1.GetBalanceResponse{
+		Account: string(account.AccountID),
+		Balance: account.Balance,
+		Nonce:   account.Nonce,
+	}
+
+	return &resp, nil
+}
+
+// SubscribeBlocks streams the blocks after the block number provided, and
+// then every block added to the chain until the client goes away. Without a
+// block number, the stream starts with the next block. After a reorg the
+// stream continues from the new latest block.
+func (s *Server) SubscribeBlocks(req *nodev1.SubscribeBlocksRequest, stream nodev1.Node_SubscribeBlocksServer) error {
+	ctx := stream.Context()
+
+	after := req.GetFromNumber()
+	if after == 0 {
+		after = s.State.LatestBlock().Header.Number
+	}
+
+	// Subscribe before looking at the latest block so a block that arrives
+	// in between isn't missed.
+	id := uuid.NewString()
+	ch := s.State.Events().Subscribe(id)
+	defer s.State.Events().Unsubscribe(id)
+
+	s.Log.Infow("grpc stream open", "method", "SubscribeBlocks", "id", id, "after", after)
+	defer s.Log.Infow("grpc stream closed", "method", "SubscribeBlocks", "id", id)
+
+	for {
+		latest := s.State.LatestBlock().Header.Number
+		if latest < after {
+			after = latest
+		}
+
+		for after < latest {
+			to := latest
+			if to-after > maxBlockRange {
+				to = after + maxBlockRange
+			}
+
+			for _, blk := range s.State.QueryBlocksByNumber(after+1, to) {
+				if err := stream.Send(toBlock(blk)); err != nil {
+					return err
+				}
+				after = blk.Header.Number
+			}
+
+			// The blocks could not be read, the next event tries again.
+			if after < to {
+				break
+			}
+		}
+
+		select {
+		case _, wd := <-ch:
+			if !wd {
+				return status.Error(codes.Unavailable, "node is shutting down")
+			}
+
+		case <-s.shut:
+			return status.Error(codes.Unavailable, "node is shutting down")
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// =============================================================================
+
+// toSignedTx converts the transaction provided by the client into the
+// database representation.
+func toSignedTx(tx *nodev1.SignedTx) (database.SignedTx, error) {
+	if tx == nil {
+		return database.SignedTx{}, errors.New("transaction is missing")
+	}
+
+	if tx.GetChainId() > math.MaxUint16 {
+		return database.SignedTx{}, fmt.Errorf("invalid chain id %d", tx.GetChainId())
+	}
+
+	signedTx := database.SignedTx{
+		Tx: database.Tx{
+			ChainID:   uint16(tx.GetChainId()),
+			Nonce:     tx.GetNonce(),
+			FromID:    database.AccountID(tx.GetFrom()),
+			ToID:      database.AccountID(tx.GetTo()),
+			Value:     tx.GetValue(),
+			Tip:       tx.GetTip(),
+			Data:      tx.GetData(),
+			NotBefore: tx.GetNotBefore(),
+			NotAfter:  tx.GetNotAfter(),
+		},
+		V: new(big.Int).SetBytes(tx.GetV()),
+		R: new(big.Int).SetBytes(tx.GetR()),
+		S: new(big.Int).SetBytes(tx.GetS()),
+	}
+
+	return signedTx, nil
+}
+
+// toProtoTx converts a signed transaction into the representation sent to
+// clients.
+func toProtoTx(tx database.SignedTx) *nodev1.SignedTx {
+	return &nodev1.SignedTx{
+		ChainId:   uint32(tx.ChainID),
+		Nonce:     tx.Nonce,
+		From:      string(tx.FromID),
+		To:        string(tx.ToID),
+		Value:     tx.Value,
+		Tip:       tx.Tip,
+		Data:      tx.Data,
+		V:         tx.V.Bytes(),
+		R:         tx.R.Bytes(),
+		S:         tx.S.Bytes(),
+		NotBefore: tx.NotBefore,
+		NotAfter:  tx.NotAfter,
+	}
+}
+
+// toBlock converts a block into the representation sent to clients.
+func toBlock(blk database.Block) *nodev1.Block {
+	values := blk.MerkleTree.Values()
+
+	trans := make([]*nodev1.SignedTx, len(values))
+	for i, tx := range values {
+		trans[i] = toProtoTx(tx.SignedTx)
+	}
+
+	return &nodev1.Block{
+		Number:        blk.Header.Number,
+		Hash:          blk.Hash(),
+		PrevBlockHash: blk.Header.PrevBlockHash,
+		Timestamp:     blk.Header.TimeStamp,
+		Beneficiary:   string(blk.Header.BeneficiaryID),
+		Difficulty:    uint32(blk.Header.Difficulty),
+		MiningReward:  blk.Header.MiningReward,
+		StateRoot:     blk.Header.StateRoot,
+		TransRoot:     blk.Header.TransRoot,
+		Nonce:         blk.Header.Nonce,
+		Transactions:  trans,
+	}
+}
+
+// txError converts the error returned when a transaction is rejected into a
+// gRPC status with the code of the failure.
+func txError(err error) error {
+	for _, tc := range txCodes {
+		if errors.Is(err, tc.err) {
+			return status.Error(tc.code, err.Error())
+		}
+	}
+
+	return status.Error(codes.InvalidArgument, err.Error())
+}
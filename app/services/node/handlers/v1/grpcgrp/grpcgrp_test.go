@@ -0,0 +1,121 @@
+package grpcgrp_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/blockchain/app/services/node/handlers/v1/grpcgrp"
+	"github.com/ardanlabs/blockchain/app/services/node/proto/nodev1"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/testnode"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestNodeService(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	n := testnode.New(t, testnode.Config{})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Should be able to listen: %s", err)
+	}
+
+	svc := grpcgrp.New(zap.NewNop().Sugar(), n.State)
+	go svc.Serve(lis)
+	t.Cleanup(func() {
+		if err := svc.Shutdown(5 * time.Second); err != nil {
+			t.Errorf("Should be able to shut down the service: %s", err)
+		}
+	})
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Should be able to dial the service: %s", err)
+	}
+	defer conn.Close()
+
+	client := nodev1.NewNodeClient(conn)
+
+	bal, err := client.GetBalance(ctx, &nodev1.GetBalanceRequest{Account: string(n.AccountID(0))})
+	if err != nil {
+		t.Fatalf("Should be able to get the balance: %s", err)
+	}
+	if bal.Balance != 1_000_000 || bal.Nonce != 0 {
+		t.Fatalf("Should get the genesis balance, got %d:%d, exp %d:%d", bal.Balance, bal.Nonce, 1_000_000, 0)
+	}
+
+	// The chain starts at the genesis block, so the stream begins with the
+	// first block mined.
+	stream, err := client.SubscribeBlocks(ctx, &nodev1.SubscribeBlocksRequest{})
+	if err != nil {
+		t.Fatalf("Should be able to subscribe to blocks: %s", err)
+	}
+
+	tx, err := database.NewTx(n.Genesis.ChainID, 1, n.AccountID(0), n.AccountID(1), 100, 10, nil)
+	if err != nil {
+		t.Fatalf("Should be able to construct a transaction: %s", err)
+	}
+	signedTx, err := tx.Sign(n.Accounts[0])
+	if err != nil {
+		t.Fatalf("Should be able to sign the transaction: %s", err)
+	}
+
+	req := nodev1.SubmitTransactionRequest{
+		Tx: &nodev1.SignedTx{
+			ChainId: uint32(signedTx.ChainID),
+			Nonce:   signedTx.Nonce,
+			From:    string(signedTx.FromID),
+			To:      string(signedTx.ToID),
+			Value:   signedTx.Value,
+			Tip:     signedTx.Tip,
+			V:       signedTx.V.Bytes(),
+			R:       signedTx.R.Bytes(),
+			S:       signedTx.S.Bytes(),
+		},
+	}
+
+	resp, err := client.SubmitTransaction(ctx, &req)
+	if err != nil {
+		t.Fatalf("Should be able to submit the transaction: %s", err)
+	}
+	if resp.Hash != signedTx.TxHash() {
+		t.Fatalf("Should get the transaction hash, got %s, exp %s", resp.Hash, signedTx.TxHash())
+	}
+
+	_, err = client.SubmitTransaction(ctx, &req)
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("Should reject the duplicate transaction, got %v, exp %s", err, codes.AlreadyExists)
+	}
+
+	block, err := n.Mine(ctx)
+	if err != nil {
+		t.Fatalf("Should be able to mine a block: %s", err)
+	}
+
+	got, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Should receive the mined block: %s", err)
+	}
+	if got.Number != 1 || got.Hash != block.Hash() {
+		t.Fatalf("Should receive the mined block, got %d:%s, exp %d:%s", got.Number, got.Hash, 1, block.Hash())
+	}
+	if len(got.Transactions) != 1 || got.Transactions[0].From != string(n.AccountID(0)) {
+		t.Fatalf("Should receive the block with the transaction, got %v", got.Transactions)
+	}
+
+	bal, err = client.GetBalance(ctx, &nodev1.GetBalanceRequest{Account: string(n.AccountID(1))})
+	if err != nil {
+		t.Fatalf("Should be able to get the balance: %s", err)
+	}
+	if bal.Balance != 1_000_100 {
+		t.Fatalf("Should get the balance with the value received, got %d, exp %d", bal.Balance, 1_000_100)
+	}
+}
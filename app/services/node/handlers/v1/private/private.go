@@ -3,24 +3,267 @@ package private
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 
+	v1 "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
 	"github.com/ardanlabs/blockchain/foundation/web"
 	"go.uber.org/zap"
 )
 
+// maxNonceLen is the longest nonce a peer can ask to have signed with the
+// status of the node.
+const maxNonceLen = 64
+
 // Handlers manages the set of bar ledger endpoints.
 type Handlers struct {
-	Log *zap.SugaredLogger
+	Log   *zap.SugaredLogger
+	State *state.State
 }
 
-// Sample just provides a starting point for the class.
-func (h Handlers) Sample(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+// SubmitNodeTransaction adds new node transactions to the mempool.
+func (h Handlers) SubmitNodeTransaction(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	host := peer.FromRequest(r)
+
+	// Decode the post call into a block transaction.
+	var tx database.BlockTx
+	if err := decode(r, &tx); err != nil {
+		h.State.PenalizePeer(host, state.PenaltyMalformed, err.Error())
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	// Ask the state package to add this transaction to the mempool and perform
+	// any other business logic.
+	h.Log.Infow("add tran", "traceid", v.TraceID, "sig:nonce", tx, "from", tx.FromID, "to", tx.ToID, "value", tx.Value, "tip", tx.Tip)
+	if err := h.State.UpsertNodeTransaction(ctx, tx); err != nil {
+
+		// A transaction can be rejected because the mempool or the nonce of
+		// the account moved on, so only a transaction that could never be
+		// valid is held against the peer.
+		vErr := tx.Validate(h.State.Genesis().ChainID)
+		if vErr == nil {
+			vErr = tx.ValidateDataSize(h.State.Genesis().MaxTxDataBytes)
+		}
+		if vErr != nil {
+			h.State.PenalizePeer(host, state.PenaltyInvalidTx, vErr.Error())
+		}
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	resp := struct {
+		Status string `json:"status"`
+	}{
+		Status: "added",
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// ProposeBlock takes a block received from a peer, validates it and
+// if that passes, adds the block to the local blockchain.
+func (h Handlers) ProposeBlock(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	host := peer.FromRequest(r)
+
+	// Decode the post call into a database block.
+	var blockData database.BlockData
+	if err := decode(r, &blockData); err != nil {
+		h.State.PenalizePeer(host, state.PenaltyMalformed, err.Error())
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	// Convert the block data into a block.
+	block, err := database.ToBlock(blockData)
+	if err != nil {
+		h.State.PenalizePeer(host, state.PenaltyMalformed, err.Error())
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	// Ask the state package to validate the proposed block. If the block
+	// passes validation, it will be added to the blockchain database.
+	if err := h.State.ProcessProposedBlock(ctx, block); err != nil {
+		if errors.Is(err, database.ErrChainForked) {
+			h.Log.Infow("propose block", "traceid", v.TraceID, "status", "chain forked, resync started", "blk", block.Header.Number)
+			return v1.NewRequestError(errors.New("blockchain forked, start resync"), http.StatusNotAcceptable)
+		}
+
+		// Let the peer know which rule the block broke.
+		if state.IsPeerFault(err) {
+			h.State.PenalizePeer(host, state.PenaltyInvalidBlock, err.Error())
+		}
+		return v1.NewRequestError(fmt.Errorf("block not accepted: %w", err), http.StatusNotAcceptable)
+	}
+
 	resp := struct {
-		Status string
+		Status string `json:"status"`
 	}{
-		Status: "OK",
+		Status: "accepted",
 	}
 
 	return web.Respond(ctx, w, resp, http.StatusOK)
 }
+
+// SubmitPeer is called by a node to add itself to a peer's list of peers.
+func (h Handlers) SubmitPeer(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var pr peer.Peer
+	if err := decode(r, &pr); err != nil {
+		h.State.PenalizePeer(peer.FromRequest(r), state.PenaltyMalformed, err.Error())
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	// Add the peer to the list of known peers. When the peer is new, pull
+	// the transactions from its mempool we don't have.
+	if h.State.AddKnownPeer(pr) {
+		h.Log.Infow("adding peer", "traceid", v.TraceID, "host", pr.Host)
+		h.State.Worker.SignalMempoolSync(pr)
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Status returns the current status of the node signed with the nonce
+// provided by the peer asking.
+func (h Handlers) Status(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	nonce := r.URL.Query().Get("nonce")
+	if len(nonce) > maxNonceLen {
+		return v1.NewRequestError(fmt.Errorf("nonce is longer than %d characters", maxNonceLen), http.StatusBadRequest)
+	}
+
+	status, err := h.State.PeerStatus(nonce)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(ctx, w, status, http.StatusOK)
+}
+
+// BlocksByNumber returns all the blocks based on the specified to/from values.
+func (h Handlers) BlocksByNumber(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	fromStr := web.Param(r, "from")
+	if fromStr == "latest" || fromStr == "" {
+		fromStr = strconv.FormatUint(state.QueryLatest, 10)
+	}
+
+	toStr := web.Param(r, "to")
+	if toStr == "latest" || toStr == "" {
+		toStr = strconv.FormatUint(state.QueryLatest, 10)
+	}
+
+	from, err := strconv.ParseUint(fromStr, 10, 64)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+	to, err := strconv.ParseUint(toStr, 10, 64)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if from > to {
+		return v1.NewRequestError(errors.New("from is greater than to"), http.StatusBadRequest)
+	}
+
+	blocks := h.State.QueryBlocksByNumber(from, to)
+	if len(blocks) == 0 {
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}
+
+	// Peers that can read a stream of blocks ask for one in the Accept
+	// header. The blocks are encoded and sent as they're written, compressed
+	// when the peer accepts it, so a large range isn't held in memory as a
+	// single document. Everyone else gets the JSON array.
+	contentType := database.NegotiateBlockContent(r.Header.Get("Accept"))
+	if contentType == database.BlockContentJSON {
+		blockData := make([]database.BlockData, len(blocks))
+		for i, block := range blocks {
+			blockData[i] = database.NewBlockData(block)
+		}
+
+		return web.Respond(ctx, w, blockData, http.StatusOK)
+	}
+
+	return web.RespondStream(ctx, w, r, contentType, http.StatusOK, func(w io.Writer) error {
+		enc, err := database.NewBlockEncoder(w, contentType)
+		if err != nil {
+			return err
+		}
+
+		for _, block := range blocks {
+			if err := enc.Encode(database.NewBlockData(block)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Mempool returns the set of uncommitted transactions.
+func (h Handlers) Mempool(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	txs := h.State.Mempool()
+	return respond(ctx, w, r, txs, http.StatusOK)
+}
+
+// MempoolHashes returns the hashes of the uncommitted transactions. This is
+// used by peers to identify which transactions they are missing.
+func (h Handlers) MempoolHashes(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	hashes := h.State.MempoolHashes()
+	return respond(ctx, w, r, hashes, http.StatusOK)
+}
+
+// MempoolFetch returns the uncommitted transactions that match the set of
+// hashes provided in the request.
+func (h Handlers) MempoolFetch(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var hashes []string
+	if err := decode(r, &hashes); err != nil {
+		h.State.PenalizePeer(peer.FromRequest(r), state.PenaltyMalformed, err.Error())
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	txs := h.State.MempoolByHashes(hashes)
+	return respond(ctx, w, r, txs, http.StatusOK)
+}
+
+// =============================================================================
+
+// decode reads the body of the request into the value. Peers that read gob
+// send their messages as gob, older nodes send JSON.
+func decode(r *http.Request, val any) error {
+	if database.PeerContent(r.Header.Get("Content-Type")) == database.BlockContentGob {
+		return database.DecodePeerMessage(r.Body, database.BlockContentGob, val)
+	}
+
+	return web.Decode(r, val)
+}
+
+// respond sends the value to the peer as gob when it asks for gob in the
+// Accept header, otherwise as JSON.
+func respond(ctx context.Context, w http.ResponseWriter, r *http.Request, data any, statusCode int) error {
+	contentType := database.NegotiatePeerContent(r.Header.Get("Accept"))
+	if contentType == database.BlockContentJSON {
+		return web.Respond(ctx, w, data, statusCode)
+	}
+
+	return web.RespondStream(ctx, w, r, contentType, statusCode, func(w io.Writer) error {
+		return database.EncodePeerMessage(w, contentType, data)
+	})
+}
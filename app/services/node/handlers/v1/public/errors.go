@@ -0,0 +1,53 @@
+package public
+
+import (
+	"errors"
+	"net/http"
+
+	v1 "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+)
+
+// Set of codes returned with a rejected transaction. The codes are part of
+// the API, so wallets can react to the failure without matching the message.
+// A code is never changed once it's published.
+const (
+	codeInsufficientFunds = "insufficient_funds"
+	codeBadNonce          = "bad_nonce"
+	codeInvalidSignature  = "invalid_signature"
+	codeChainIDMismatch   = "chain_id_mismatch"
+	codeDuplicateTx       = "duplicate_tx"
+)
+
+// txErrors maps the errors returned for a rejected transaction to the code
+// and status of the response.
+var txErrors = []struct {
+	err    error
+	code   string
+	status int
+}{
+	{database.ErrInsufficientFunds, codeInsufficientFunds, http.StatusBadRequest},
+	{database.ErrBadNonce, codeBadNonce, http.StatusBadRequest},
+	{database.ErrInvalidSignature, codeInvalidSignature, http.StatusBadRequest},
+	{database.ErrChainIDMismatch, codeChainIDMismatch, http.StatusBadRequest},
+	{state.ErrDuplicateTx, codeDuplicateTx, http.StatusConflict},
+}
+
+// txErrorCode returns the code and status for the error returned when a
+// transaction is rejected. Errors without a code are a bad request.
+func txErrorCode(err error) (string, int) {
+	for _, te := range txErrors {
+		if errors.Is(err, te.err) {
+			return te.code, te.status
+		}
+	}
+	return "", http.StatusBadRequest
+}
+
+// txError wraps the error returned when a transaction is rejected with the
+// code and status of the failure.
+func txError(err error) error {
+	code, status := txErrorCode(err)
+	return v1.NewCodedRequestError(err, status, code)
+}
@@ -0,0 +1,51 @@
+package public
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"runtime"
+
+	"github.com/ardanlabs/blockchain/foundation/web"
+)
+
+// Liveness returns the build information of the node if the service is
+// alive. The node is alive while it syncs with its peers, so a liveness
+// probe doesn't restart a node that is catching up.
+func (h Handlers) Liveness(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unavailable"
+	}
+
+	resp := liveness{
+		Status:      "up",
+		Version:     h.Build,
+		Revision:    h.Revision,
+		GoVersion:   runtime.Version(),
+		Host:        host,
+		LatestBlock: h.State.LatestBlock().Header.Number,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Readiness reports if the node is ready to serve requests. The storage must
+// be readable, the genesis loaded and the initial sync with the peers
+// complete, otherwise a 503 is returned so the node is taken out of rotation.
+func (h Handlers) Readiness(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	checks := h.State.Readiness()
+
+	resp := readiness{
+		Status: "ok",
+		Checks: checks,
+	}
+
+	statusCode := http.StatusOK
+	if !checks.Ready() {
+		resp.Status = "not ready"
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	return web.Respond(ctx, w, resp, statusCode)
+}
@@ -0,0 +1,225 @@
+package public
+
+import (
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/merkle"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+)
+
+// maxBatchSize is the maximum number of transactions that can be submitted
+// in a single batch.
+const maxBatchSize = 500
+
+// Set of status values for a transaction submitted in a batch.
+const (
+	batchAccepted = "accepted"
+	batchRejected = "rejected"
+)
+
+type batchResult struct {
+	Index  int    `json:"index"`
+	Hash   string `json:"hash"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Code   string `json:"code,omitempty"`
+}
+
+type act struct {
+	Account  database.AccountID      `json:"account"`
+	Balance  uint64                  `json:"balance"`
+	Nonce    uint64                  `json:"nonce"`
+	Multisig *database.Multisig      `json:"multisig,omitempty"`
+	Rules    *database.SpendingRules `json:"rules,omitempty"`
+}
+
+type actInfo struct {
+	LatestBlock string `json:"latest_block"`
+	Uncommitted int    `json:"uncommitted"`
+	Total       int    `json:"total"`
+	Accounts    []act  `json:"accounts"`
+}
+
+type slot struct {
+	Account database.AccountID `json:"account"`
+	Key     uint64             `json:"key"`
+	Value   uint64             `json:"value"`
+}
+
+type name struct {
+	Name    string             `json:"name"`
+	Account database.AccountID `json:"account"`
+}
+
+type tokenBalance struct {
+	Symbol  string             `json:"symbol"`
+	Account database.AccountID `json:"account"`
+	Balance uint64             `json:"balance"`
+}
+
+type gasEstimate struct {
+	Low           uint64 `json:"low"`
+	Standard      uint64 `json:"standard"`
+	Fast          uint64 `json:"fast"`
+	GasPrice      uint64 `json:"gas_price"`
+	GasUnits      uint64 `json:"gas_units"`
+	MempoolDepth  int    `json:"mempool_depth"`
+	TransPerBlock uint16 `json:"trans_per_block"`
+	BlocksSampled int    `json:"blocks_sampled"`
+	TxsSampled    int    `json:"txs_sampled"`
+}
+
+type pendingAct struct {
+	Account      database.AccountID `json:"account"`
+	Balance      uint64             `json:"balance"`
+	Nonce        uint64             `json:"nonce"`
+	Debits       uint64             `json:"debits"`
+	Credits      uint64             `json:"credits"`
+	Pending      uint64             `json:"pending_balance"`
+	PendingNonce uint64             `json:"pending_nonce"`
+	PendingTxs   int                `json:"pending_txs"`
+}
+
+type tx struct {
+	Hash        string             `json:"hash"`
+	FromAccount database.AccountID `json:"from"`
+	To          database.AccountID `json:"to"`
+	ChainID     uint16             `json:"chain_id"`
+	Nonce       uint64             `json:"nonce"`
+	Value       uint64             `json:"value"`
+	Tip         uint64             `json:"tip"`
+	Data        []byte             `json:"data"`
+	Memo        *database.Memo     `json:"memo,omitempty"`
+	NotBefore   uint64             `json:"not_before,omitempty"`
+	NotAfter    uint64             `json:"not_after,omitempty"`
+	TimeStamp   uint64             `json:"timestamp"`
+	GasPrice    uint64             `json:"gas_price"`
+	GasUnits    uint64             `json:"gas_units"`
+	Sig         string             `json:"sig"`
+	Proof       []string           `json:"proof,omitempty"`
+	ProofOrder  []int64            `json:"proof_order,omitempty"`
+}
+
+type liveness struct {
+	Status      string `json:"status"`
+	Version     string `json:"version"`
+	Revision    string `json:"revision"`
+	GoVersion   string `json:"go_version"`
+	Host        string `json:"host"`
+	LatestBlock uint64 `json:"latest_block"`
+}
+
+type readiness struct {
+	Status string          `json:"status"`
+	Checks state.Readiness `json:"checks"`
+}
+
+type txLookup struct {
+	Tx      tx               `json:"tx"`
+	Receipt database.Receipt `json:"receipt"`
+}
+
+type block struct {
+	Hash          string                 `json:"hash"`
+	Number        uint64                 `json:"number"`
+	PrevBlockHash string                 `json:"prev_block_hash"`
+	TimeStamp     uint64                 `json:"timestamp"`
+	BeneficiaryID database.AccountID     `json:"beneficiary"`
+	Difficulty    uint16                 `json:"difficulty"`
+	MiningReward  uint64                 `json:"mining_reward"`
+	StateRoot     string                 `json:"state_root"`
+	TransRoot     string                 `json:"trans_root"`
+	Nonce         uint64                 `json:"nonce"`
+	Beneficiaries []database.Beneficiary `json:"beneficiaries,omitempty"`
+	AccountsRoot  string                 `json:"accounts_root,omitempty"`
+	Version       uint16                 `json:"version,omitempty"`
+	Transactions  []tx                   `json:"txs"`
+}
+
+type blockSummary struct {
+	Hash          string             `json:"hash"`
+	Number        uint64             `json:"number"`
+	TxCount       int                `json:"tx_count"`
+	BeneficiaryID database.AccountID `json:"beneficiary"`
+	TimeStamp     uint64             `json:"timestamp"`
+}
+
+// =============================================================================
+
+// toTx converts a block transaction into the public representation. A memo
+// in the data field is decoded so clients can display it.
+func toTx(tran database.BlockTx) tx {
+	var memo *database.Memo
+	if m, ok := database.ParseMemo(tran.Data); ok {
+		memo = &m
+	}
+
+	return tx{
+		Hash:        tran.TxHash(),
+		FromAccount: tran.FromID,
+		To:          tran.ToID,
+		ChainID:     tran.ChainID,
+		Nonce:       tran.Nonce,
+		Value:       tran.Value,
+		Tip:         tran.Tip,
+		Data:        tran.Data,
+		Memo:        memo,
+		NotBefore:   tran.NotBefore,
+		NotAfter:    tran.NotAfter,
+		TimeStamp:   tran.TimeStamp,
+		GasPrice:    tran.GasPrice,
+		GasUnits:    tran.GasUnits,
+		Sig:         tran.SignatureString(),
+	}
+}
+
+// toBlock converts a database block into the public representation which
+// includes the merkle proof for every transaction.
+func toBlock(blk database.Block) block {
+	values := blk.MerkleTree.Values()
+
+	trans := make([]tx, len(values))
+	for i, tran := range values {
+		t := toTx(tran)
+
+		rawProof, order, err := blk.MerkleTree.Proof(tran)
+		if err == nil {
+			proof := make([]string, len(rawProof))
+			for j, rp := range rawProof {
+				proof[j] = merkle.ToHex(rp)
+			}
+			t.Proof = proof
+			t.ProofOrder = order
+		}
+
+		trans[i] = t
+	}
+
+	return block{
+		Hash:          blk.Hash(),
+		Number:        blk.Header.Number,
+		PrevBlockHash: blk.Header.PrevBlockHash,
+		TimeStamp:     blk.Header.TimeStamp,
+		BeneficiaryID: blk.Header.BeneficiaryID,
+		Difficulty:    blk.Header.Difficulty,
+		MiningReward:  blk.Header.MiningReward,
+		StateRoot:     blk.Header.StateRoot,
+		TransRoot:     blk.Header.TransRoot,
+		Nonce:         blk.Header.Nonce,
+		Beneficiaries: blk.Header.Beneficiaries,
+		AccountsRoot:  blk.Header.AccountsRoot,
+		Version:       blk.Header.Version,
+		Transactions:  trans,
+	}
+}
+
+// toBlockSummary converts a database block into the compact public
+// representation used when listing a range of blocks.
+func toBlockSummary(blk database.Block) blockSummary {
+	return blockSummary{
+		Hash:          blk.Hash(),
+		Number:        blk.Header.Number,
+		TxCount:       len(blk.MerkleTree.Values()),
+		BeneficiaryID: blk.Header.BeneficiaryID,
+		TimeStamp:     blk.Header.TimeStamp,
+	}
+}
@@ -3,24 +3,757 @@ package public
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/ardanlabs/blockchain/business/sys/validate"
+	v1 "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
 	"github.com/ardanlabs/blockchain/foundation/web"
+	"github.com/ardanlabs/blockchain/foundation/websocket"
 	"go.uber.org/zap"
 )
 
+// Set of limits on the number of recent blocks combined in a fee summary.
+const (
+	defaultFeeBlocks = 20
+	maxFeeBlocks     = 1_000
+)
+
 // Handlers manages the set of bar ledger endpoints.
 type Handlers struct {
-	Log *zap.SugaredLogger
+	Log         *zap.SugaredLogger
+	State       *state.State
+	PollTimeout time.Duration
+	Build       string
+	Revision    string
 }
 
-// Sample just provides a starting point for the class.
-func (h Handlers) Sample(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+// SubmitWalletTransaction adds new transactions to the mempool.
+func (h Handlers) SubmitWalletTransaction(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	// Decode the JSON in the post call into a Signed transaction.
+	var signedTx database.SignedTx
+	if err := web.Decode(r, &signedTx); err != nil {
+		return v1.NewRequestError(fmt.Errorf("unable to decode transaction: %w", err), http.StatusBadRequest)
+	}
+
+	// Make sure the required fields are present before going any further.
+	if err := validate.Check(signedTx); err != nil {
+		return err
+	}
+
+	h.Log.Infow("add tran", "traceid", v.TraceID, "sig:nonce", signedTx, "from", signedTx.FromID, "to", signedTx.ToID, "value", signedTx.Value, "tip", signedTx.Tip)
+
+	// Ask the state package to add this transaction to the mempool. Only the
+	// checks are the transaction signature and the recipient account format.
+	// It's up to the wallet to make sure the account has a proper balance and
+	// nonce. Fees will be taken if this transaction is mined into a block.
+	if err := h.State.UpsertWalletTransaction(ctx, signedTx); err != nil {
+		return txError(err)
+	}
+
+	resp := struct {
+		Status string `json:"status"`
+	}{
+		Status: "transactions added to mempool",
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// CancelWalletTransaction replaces the pending transaction for the account
+// and nonce with the signed transaction provided. The replacement must send
+// no value to the account itself and offer a higher tip, so the only effect
+// of mining it is the account paying the fees.
+func (h Handlers) CancelWalletTransaction(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	accountID, err := database.ToAccountID(web.Param(r, "account"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	nonce, err := strconv.ParseUint(web.Param(r, "nonce"), 10, 64)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("invalid nonce: %w", err), http.StatusBadRequest)
+	}
+
+	var signedTx database.SignedTx
+	if err := web.Decode(r, &signedTx); err != nil {
+		return v1.NewRequestError(fmt.Errorf("unable to decode transaction: %w", err), http.StatusBadRequest)
+	}
+
+	if err := validate.Check(signedTx); err != nil {
+		return err
+	}
+
+	switch {
+	case signedTx.FromID != accountID || signedTx.Nonce != nonce:
+		return v1.NewRequestError(fmt.Errorf("replacement is for %s, exp %s:%d", signedTx, accountID, nonce), http.StatusBadRequest)
+	case signedTx.ToID != accountID || signedTx.Value != 0:
+		return v1.NewRequestError(errors.New("replacement must send no value to the account itself"), http.StatusBadRequest)
+	}
+
+	h.Log.Infow("cancel tran", "traceid", v.TraceID, "sig:nonce", signedTx, "tip", signedTx.Tip)
+
+	if err := h.State.UpsertWalletTransaction(ctx, signedTx); err != nil {
+		return txError(err)
+	}
+
 	resp := struct {
-		Status string
+		Status string `json:"status"`
+		Hash   string `json:"hash"`
+	}{
+		Status: "transaction replaced in mempool",
+		Hash:   signedTx.TxHash(),
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// SubmitWalletTransactions adds a batch of new transactions to the mempool.
+// Each transaction is validated independently and the result for every
+// transaction is returned in the same order as provided.
+func (h Handlers) SubmitWalletTransactions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	// Decode the JSON in the post call into a set of Signed transactions.
+	var signedTxs []database.SignedTx
+	if err := web.Decode(r, &signedTxs); err != nil {
+		return v1.NewRequestError(fmt.Errorf("unable to decode transactions: %w", err), http.StatusBadRequest)
+	}
+
+	if len(signedTxs) > maxBatchSize {
+		return v1.NewRequestError(fmt.Errorf("batch contains %d transactions, max is %d", len(signedTxs), maxBatchSize), http.StatusBadRequest)
+	}
+
+	h.Log.Infow("add tran batch", "traceid", v.TraceID, "count", len(signedTxs))
+
+	results := make([]batchResult, len(signedTxs))
+	for i, signedTx := range signedTxs {
+		results[i] = batchResult{
+			Index:  i,
+			Hash:   signedTx.TxHash(),
+			Status: batchAccepted,
+		}
+
+		if err := validate.Check(signedTx); err != nil {
+			results[i].Status = batchRejected
+			results[i].Error = err.Error()
+			continue
+		}
+
+		if err := h.State.UpsertWalletTransaction(ctx, signedTx); err != nil {
+			results[i].Status = batchRejected
+			results[i].Error = err.Error()
+			results[i].Code, _ = txErrorCode(err)
+		}
+	}
+
+	return web.Respond(ctx, w, results, http.StatusOK)
+}
+
+// Genesis returns the genesis information.
+func (h Handlers) Genesis(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	gen := h.State.Genesis()
+	return web.Respond(ctx, w, gen, http.StatusOK)
+}
+
+// maxAccountRows is the largest page of accounts that can be requested.
+const maxAccountRows = 1000
+
+// Accounts returns the current balances for all users ordered by balance,
+// largest first. The page and rows query parameters select a page of the
+// accounts, otherwise all the accounts are returned. The min_balance,
+// min_nonce and max_nonce query parameters only return the accounts in
+// those ranges.
+func (h Handlers) Accounts(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountStr := web.Param(r, "account")
+
+	var accounts []database.Account
+	var total int
+	switch accountStr {
+	case "":
+		offset, limit, err := accountsPage(r)
+		if err != nil {
+			return v1.NewRequestError(err, http.StatusBadRequest)
+		}
+		filter, err := accountsFilter(r)
+		if err != nil {
+			return v1.NewRequestError(err, http.StatusBadRequest)
+		}
+		accounts, total = h.State.QueryAccountsByBalance(filter, offset, limit)
+
+	default:
+		accountID, err := database.ToAccountID(accountStr)
+		if err != nil {
+			return v1.NewRequestError(err, http.StatusBadRequest)
+		}
+
+		// An account the database has never seen holds a zero balance.
+		account, err := h.State.QueryAccount(accountID)
+		if err != nil {
+			account = database.Account{AccountID: accountID}
+		}
+		accounts = []database.Account{account}
+		total = 1
+	}
+
+	resp := make([]act, len(accounts))
+	for i, info := range accounts {
+		resp[i] = act{
+			Account:  info.AccountID,
+			Balance:  info.Balance,
+			Nonce:    info.Nonce,
+			Multisig: info.Multisig,
+			Rules:    info.Rules,
+		}
+	}
+
+	ai := actInfo{
+		LatestBlock: h.State.LatestBlock().Hash(),
+		Uncommitted: h.State.MempoolLength(),
+		Total:       total,
+		Accounts:    resp,
+	}
+
+	return web.Respond(ctx, w, ai, http.StatusOK)
+}
+
+// accountsPage converts the page and rows query parameters into the offset
+// and limit for the accounts. Without rows, all the accounts are returned.
+func accountsPage(r *http.Request) (int, int, error) {
+	rowsStr := r.URL.Query().Get("rows")
+	if rowsStr == "" {
+		return 0, 0, nil
+	}
+
+	rows, err := strconv.Atoi(rowsStr)
+	if err != nil || rows < 1 || rows > maxAccountRows {
+		return 0, 0, fmt.Errorf("invalid rows %q, must be between 1 and %d", rowsStr, maxAccountRows)
+	}
+
+	page := 1
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		page, err = strconv.Atoi(pageStr)
+		if err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("invalid page %q", pageStr)
+		}
+	}
+
+	return (page - 1) * rows, rows, nil
+}
+
+// accountsFilter converts the min_balance, min_nonce and max_nonce query
+// parameters into the filter for the accounts.
+func accountsFilter(r *http.Request) (database.AccountFilter, error) {
+	var filter database.AccountFilter
+
+	params := []struct {
+		name  string
+		value *uint64
 	}{
-		Status: "OK",
+		{"min_balance", &filter.MinBalance},
+		{"min_nonce", &filter.MinNonce},
+		{"max_nonce", &filter.MaxNonce},
+	}
+
+	for _, p := range params {
+		str := r.URL.Query().Get(p.name)
+		if str == "" {
+			continue
+		}
+
+		v, err := strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			return database.AccountFilter{}, fmt.Errorf("invalid %s %q", p.name, str)
+		}
+		*p.value = v
+	}
+
+	if filter.MaxNonce > 0 && filter.MinNonce > filter.MaxNonce {
+		return database.AccountFilter{}, fmt.Errorf("min_nonce %d is greater than max_nonce %d", filter.MinNonce, filter.MaxNonce)
+	}
+
+	return filter, nil
+}
+
+// PendingBalance returns the balance for the specified account once the
+// transactions in the mempool are applied.
+func (h Handlers) PendingBalance(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountID, err := database.ToAccountID(web.Param(r, "account"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	pb := h.State.QueryPendingBalance(accountID)
+
+	resp := pendingAct{
+		Account:      pb.AccountID,
+		Balance:      pb.Balance,
+		Nonce:        pb.Nonce,
+		Debits:       pb.Debits,
+		Credits:      pb.Credits,
+		Pending:      pb.Pending,
+		PendingNonce: pb.PendingNonce,
+		PendingTxs:   pb.PendingTxs,
 	}
 
 	return web.Respond(ctx, w, resp, http.StatusOK)
 }
+
+// AccountProof returns the specified account as of the latest block along
+// with the merkle proof tying it to the header of the block. A light client
+// checks the proof and compares the header hash with other peers instead of
+// trusting this node.
+func (h Handlers) AccountProof(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountID, err := database.ToAccountID(web.Param(r, "account"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	proof, err := h.State.QueryAccountProof(accountID)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	return web.Respond(ctx, w, proof, http.StatusOK)
+}
+
+// Storage returns the value stored under the key in the contract storage
+// of the specified account.
+func (h Handlers) Storage(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountID, err := database.ToAccountID(web.Param(r, "account"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	key, err := strconv.ParseUint(web.Param(r, "key"), 10, 64)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("invalid key: %w", err), http.StatusBadRequest)
+	}
+
+	resp := slot{
+		Account: accountID,
+		Key:     key,
+		Value:   h.State.QueryStorage(accountID, key),
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Name returns the account the specified name is registered to.
+func (h Handlers) Name(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	nameStr := web.Param(r, "name")
+
+	accountID, err := h.State.QueryName(nameStr)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	resp := name{
+		Name:    nameStr,
+		Account: accountID,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Token returns the supply and balances for the specified token.
+func (h Handlers) Token(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	token, err := h.State.QueryToken(web.Param(r, "symbol"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	return web.Respond(ctx, w, token, http.StatusOK)
+}
+
+// TokenBalance returns the balance of the specified token held by the
+// specified account.
+func (h Handlers) TokenBalance(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountID, err := database.ToAccountID(web.Param(r, "account"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	token, err := h.State.QueryToken(web.Param(r, "symbol"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	resp := tokenBalance{
+		Symbol:  token.Symbol,
+		Account: accountID,
+		Balance: token.Balances[accountID],
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Policy returns the admins and the accounts they allowed or denied on a
+// permissioned chain.
+func (h Handlers) Policy(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, h.State.QueryPolicy(), http.StatusOK)
+}
+
+// Mempool returns the set of uncommitted transactions.
+func (h Handlers) Mempool(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	acct := web.Param(r, "account")
+	mempool := h.State.Mempool()
+
+	trans := []tx{}
+	for _, tran := range mempool {
+		if acct != "" && ((acct != string(tran.FromID)) && (acct != string(tran.ToID))) {
+			continue
+		}
+
+		trans = append(trans, toTx(tran))
+	}
+
+	return web.Respond(ctx, w, trans, http.StatusOK)
+}
+
+// GasEstimate returns the tips to offer for a transaction to be included
+// based on the depth of the mempool and the tips paid in recent blocks.
+func (h Handlers) GasEstimate(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	est := h.State.EstimateTip()
+
+	resp := gasEstimate{
+		Low:           est.Low,
+		Standard:      est.Standard,
+		Fast:          est.Fast,
+		GasPrice:      est.GasPrice,
+		GasUnits:      est.GasUnits,
+		MempoolDepth:  est.MempoolDepth,
+		TransPerBlock: est.TransPerBlock,
+		BlocksSampled: est.BlocksSampled,
+		TxsSampled:    est.TxsSampled,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Receipt returns the receipt for the specified transaction hash.
+func (h Handlers) Receipt(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	receipt, err := h.State.QueryReceipt(web.Param(r, "hash"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	return web.Respond(ctx, w, receipt, http.StatusOK)
+}
+
+// Tx returns the transaction recorded in the chain with the specified hash
+// along with its receipt.
+func (h Handlers) Tx(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	tran, receipt, err := h.State.QueryTx(web.Param(r, "hash"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	resp := txLookup{
+		Tx:      toTx(tran),
+		Receipt: receipt,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Eviction returns why the transaction with the specified hash was removed
+// from the mempool without being mined.
+func (h Handlers) Eviction(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ev, err := h.State.QueryEviction(web.Param(r, "hash"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	return web.Respond(ctx, w, ev, http.StatusOK)
+}
+
+// BlocksByAccount returns all the blocks and their details.
+func (h Handlers) BlocksByAccount(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var accountID database.AccountID
+	if accountStr := web.Param(r, "account"); accountStr != "" {
+		var err error
+		accountID, err = database.ToAccountID(accountStr)
+		if err != nil {
+			return v1.NewRequestError(err, http.StatusBadRequest)
+		}
+	}
+
+	dbBlocks, err := h.State.QueryBlocksByAccount(accountID)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+	if len(dbBlocks) == 0 {
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}
+
+	blocks := make([]block, len(dbBlocks))
+	for i, blk := range dbBlocks {
+		blocks[i] = toBlock(blk)
+	}
+
+	return web.Respond(ctx, w, blocks, http.StatusOK)
+}
+
+// maxBlockRange is the largest number of blocks that can be listed by a
+// single range query.
+const maxBlockRange = 100
+
+// BlocksByRange returns a summary of the blocks in the specified from/to
+// range. Either value can be latest to specify the latest block.
+func (h Handlers) BlocksByRange(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	latest := h.State.LatestBlock().Header.Number
+
+	from, err := blockNumber(web.Param(r, "from"), latest)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+	to, err := blockNumber(web.Param(r, "to"), latest)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if from > to {
+		return v1.NewRequestError(errors.New("from is greater than to"), http.StatusBadRequest)
+	}
+	if to-from >= maxBlockRange {
+		return v1.NewRequestError(fmt.Errorf("range of %d blocks is larger than the max of %d", to-from+1, maxBlockRange), http.StatusBadRequest)
+	}
+
+	dbBlocks := h.State.QueryBlocksByNumber(from, to)
+	if len(dbBlocks) == 0 {
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}
+
+	blocks := make([]blockSummary, len(dbBlocks))
+	for i, blk := range dbBlocks {
+		blocks[i] = toBlockSummary(blk)
+	}
+
+	return web.Respond(ctx, w, blocks, http.StatusOK)
+}
+
+// BlocksSubscribe long polls for the blocks after the specified block number,
+// so clients without web socket support can follow the chain. The response
+// is sent as soon as there are new blocks, or with no content when none
+// arrive before the timeout. Without an after value, the next block is
+// waited for.
+func (h Handlers) BlocksSubscribe(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	query := r.URL.Query()
+
+	after := h.State.LatestBlock().Header.Number
+	if s := query.Get("after"); s != "" {
+		if after, err = strconv.ParseUint(s, 10, 64); err != nil {
+			return v1.NewRequestError(fmt.Errorf("invalid block number %q", s), http.StatusBadRequest)
+		}
+	}
+
+	// The wait can be shortened by the client but never beyond what the
+	// server allows for writing the response.
+	timeout := h.PollTimeout
+	if s := query.Get("timeout"); s != "" {
+		secs, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return v1.NewRequestError(fmt.Errorf("invalid timeout %q", s), http.StatusBadRequest)
+		}
+		if d := time.Duration(secs) * time.Second; d < timeout {
+			timeout = d
+		}
+	}
+
+	// Subscribe before looking at the latest block so a block that arrives
+	// in between isn't missed.
+	ch := h.State.Events().Subscribe(v.TraceID)
+	defer h.State.Events().Unsubscribe(v.TraceID)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		if latest := h.State.LatestBlock().Header.Number; latest > after {
+			to := latest
+			if to-after > maxBlockRange {
+				to = after + maxBlockRange
+			}
+
+			dbBlocks := h.State.QueryBlocksByNumber(after+1, to)
+			blocks := make([]blockSummary, len(dbBlocks))
+			for i, blk := range dbBlocks {
+				blocks[i] = toBlockSummary(blk)
+			}
+
+			return web.Respond(ctx, w, blocks, http.StatusOK)
+		}
+
+		select {
+		case evt, wd := <-ch:
+			if !wd {
+				return web.Respond(ctx, w, nil, http.StatusNoContent)
+			}
+
+			// Only a change to the chain can produce new blocks.
+			switch evt.Type {
+			case state.EventBlockMined, state.EventBlockReceived, state.EventReorg:
+			default:
+				continue
+			}
+
+		case <-timer.C:
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// StateDiff returns the accounts whose balance or nonce changed in the
+// specified block, with their values before and after the block.
+func (h Handlers) StateDiff(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	num, err := blockNumber(web.Param(r, "number"), h.State.LatestBlock().Header.Number)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	diff, err := h.State.QueryStateDiff(num)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	return web.Respond(ctx, w, diff, http.StatusOK)
+}
+
+// BlockFees returns the total tips and gas paid in the specified block along
+// with the min, median and max tip offered by its transactions.
+func (h Handlers) BlockFees(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	num, err := blockNumber(web.Param(r, "number"), h.State.LatestBlock().Header.Number)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	fees, err := h.State.QueryBlockFees(num)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusNotFound)
+	}
+
+	return web.Respond(ctx, w, fees, http.StatusOK)
+}
+
+// FeeSummary returns the fee statistics combined over the most recent
+// blocks, 20 unless the blocks parameter says otherwise.
+func (h Handlers) FeeSummary(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	blocks := uint64(defaultFeeBlocks)
+	if s := r.URL.Query().Get("blocks"); s != "" {
+		var err error
+		if blocks, err = strconv.ParseUint(s, 10, 64); err != nil || blocks == 0 || blocks > maxFeeBlocks {
+			return v1.NewRequestError(fmt.Errorf("invalid blocks %q, must be between 1 and %d", s, maxFeeBlocks), http.StatusBadRequest)
+		}
+	}
+
+	return web.Respond(ctx, w, h.State.QueryFeeSummary(blocks), http.StatusOK)
+}
+
+// blockNumber parses the block number from the url, where latest is the
+// number of the latest block.
+func blockNumber(s string, latest uint64) (uint64, error) {
+	if s == "latest" {
+		return latest, nil
+	}
+
+	number, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid block number %q", s)
+	}
+
+	return number, nil
+}
+
+// Events handles a web socket to provide events to a client.
+func (h Handlers) Events(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	// Take ownership of the connection to push events to the client.
+	c, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+	defer c.Close()
+
+	// The connection has been hijacked so record the status for logging.
+	web.SetStatusCode(ctx, http.StatusSwitchingProtocols)
+
+	h.Log.Infow("websocket open", "path", "/v1/events", "traceid", v.TraceID)
+	defer h.Log.Infow("websocket closed", "path", "/v1/events", "traceid", v.TraceID)
+
+	// Subscribe to the state events using the trace id as the subscriber id.
+	ch := h.State.Events().Subscribe(v.TraceID)
+	defer h.State.Events().Unsubscribe(v.TraceID)
+
+	// The client is not expected to send data, but reading is required to
+	// process control messages and identify when the client goes away.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case evt, wd := <-ch:
+			if !wd {
+				return nil
+			}
+
+			data, err := json.Marshal(evt)
+			if err != nil {
+				return err
+			}
+
+			if err := c.WriteText(data); err != nil {
+				return nil
+			}
+
+		case <-ticker.C:
+			if err := c.WritePing(); err != nil {
+				return nil
+			}
+
+		case <-closed:
+			return nil
+		}
+	}
+}
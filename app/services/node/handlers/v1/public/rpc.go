@@ -0,0 +1,275 @@
+package public
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ardanlabs/blockchain/business/sys/validate"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/hexutil"
+	"github.com/ardanlabs/blockchain/foundation/web"
+)
+
+// Set of error codes defined by the JSON-RPC 2.0 specification.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+type rpcRequest struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcTx struct {
+	Hash             string          `json:"hash"`
+	Nonce            hexutil.Uint64  `json:"nonce"`
+	From             string          `json:"from"`
+	To               string          `json:"to"`
+	Value            hexutil.Uint64  `json:"value"`
+	Gas              hexutil.Uint64  `json:"gas"`
+	GasPrice         hexutil.Uint64  `json:"gasPrice"`
+	Input            hexutil.Bytes   `json:"input"`
+	ChainID          hexutil.Uint64  `json:"chainId"`
+	BlockHash        *string         `json:"blockHash"`
+	BlockNumber      *hexutil.Uint64 `json:"blockNumber"`
+	TransactionIndex *hexutil.Uint64 `json:"transactionIndex"`
+	V                *hexutil.Big    `json:"v"`
+	R                *hexutil.Big    `json:"r"`
+	S                *hexutil.Big    `json:"s"`
+}
+
+// RPC provides a subset of the Ethereum JSON-RPC API so tools written for
+// Ethereum can read from and submit transactions to the node. A single call
+// or a batch of calls can be made in one request.
+func (h Handlers) RPC(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	if body = bytes.TrimSpace(body); len(body) > 0 && body[0] == '[' {
+		var reqs []json.RawMessage
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			return web.Respond(ctx, w, rpcFailure(nil, rpcParseError, err), http.StatusOK)
+		}
+
+		resps := make([]rpcResponse, len(reqs))
+		for i, req := range reqs {
+			resps[i] = h.rpcCall(ctx, req)
+		}
+
+		return web.Respond(ctx, w, resps, http.StatusOK)
+	}
+
+	return web.Respond(ctx, w, h.rpcCall(ctx, body), http.StatusOK)
+}
+
+// rpcCall decodes and executes a single call.
+func (h Handlers) rpcCall(ctx context.Context, body []byte) rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return rpcFailure(nil, rpcParseError, err)
+	}
+
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return rpcFailure(req.ID, rpcInvalidRequest, errors.New("invalid request"))
+	}
+
+	var result any
+	var err error
+
+	switch req.Method {
+	case "eth_chainId":
+		result = hexutil.Uint64(h.State.Genesis().ChainID)
+
+	case "eth_blockNumber":
+		result = hexutil.Uint64(h.State.LatestBlock().Header.Number)
+
+	case "eth_getBalance":
+		result, err = h.rpcGetBalance(req.Params)
+
+	case "eth_getTransactionByHash":
+		result, err = h.rpcGetTransactionByHash(req.Params)
+
+	case "eth_sendRawTransaction":
+		result, err = h.rpcSendRawTransaction(ctx, req.Params)
+
+	default:
+		return rpcFailure(req.ID, rpcMethodNotFound, fmt.Errorf("method %s not supported", req.Method))
+	}
+
+	if err != nil {
+		code := rpcServerError
+		var pe *paramError
+		if errors.As(err, &pe) {
+			code = rpcInvalidParams
+		}
+		return rpcFailure(req.ID, code, err)
+	}
+
+	// A null result must still be present in the response.
+	data, err := json.Marshal(result)
+	if err != nil {
+		return rpcFailure(req.ID, rpcServerError, err)
+	}
+
+	return rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: data}
+}
+
+// rpcGetBalance returns the balance of the account in the first parameter.
+// The block parameter is accepted but only the latest state is available.
+func (h Handlers) rpcGetBalance(params []json.RawMessage) (any, error) {
+	var accountStr string
+	if err := rpcParam(params, 0, &accountStr); err != nil {
+		return nil, err
+	}
+
+	accountID, err := database.ToAccountID(accountStr)
+	if err != nil {
+		return nil, &paramError{err}
+	}
+
+	// An account the database has never seen holds a zero balance.
+	account, err := h.State.QueryAccount(accountID)
+	if err != nil {
+		return hexutil.Uint64(0), nil
+	}
+
+	return hexutil.Uint64(account.Balance), nil
+}
+
+// rpcGetTransactionByHash returns the transaction with the hash in the first
+// parameter, looking in the chain and then the mempool. A transaction that
+// can't be found returns null.
+func (h Handlers) rpcGetTransactionByHash(params []json.RawMessage) (any, error) {
+	var hash string
+	if err := rpcParam(params, 0, &hash); err != nil {
+		return nil, err
+	}
+
+	tran, receipt, err := h.State.QueryTx(hash)
+	switch {
+	case err == nil:
+		tx := toRPCTx(tran)
+		blockHash := receipt.BlockHash
+		blockNumber := hexutil.Uint64(receipt.BlockNumber)
+		index := hexutil.Uint64(receipt.Index)
+		tx.BlockHash = &blockHash
+		tx.BlockNumber = &blockNumber
+		tx.TransactionIndex = &index
+
+		return tx, nil
+
+	case errors.Is(err, state.ErrTxPending):
+		txs := h.State.MempoolByHashes([]string{hash})
+		if len(txs) == 0 {
+			return nil, nil
+		}
+
+		return toRPCTx(txs[0]), nil
+	}
+
+	return nil, nil
+}
+
+// rpcSendRawTransaction submits the transaction in the first parameter. The
+// raw transaction is the hex encoded JSON of a signed transaction rather than
+// the RLP encoding used by Ethereum.
+func (h Handlers) rpcSendRawTransaction(ctx context.Context, params []json.RawMessage) (any, error) {
+	var raw hexutil.Bytes
+	if err := rpcParam(params, 0, &raw); err != nil {
+		return nil, err
+	}
+
+	var signedTx database.SignedTx
+	if err := json.Unmarshal(raw, &signedTx); err != nil {
+		return nil, &paramError{fmt.Errorf("unable to decode transaction: %w", err)}
+	}
+
+	if err := validate.Check(signedTx); err != nil {
+		return nil, &paramError{err}
+	}
+
+	h.Log.Infow("add tran rpc", "sig:nonce", signedTx, "from", signedTx.FromID, "to", signedTx.ToID, "value", signedTx.Value, "tip", signedTx.Tip)
+
+	if err := h.State.UpsertWalletTransaction(ctx, signedTx); err != nil {
+		return nil, err
+	}
+
+	return signedTx.TxHash(), nil
+}
+
+// =============================================================================
+
+// paramError indicates the parameters for a call are invalid.
+type paramError struct {
+	err error
+}
+
+func (pe *paramError) Error() string {
+	return pe.err.Error()
+}
+
+// rpcParam decodes the parameter at the specified position.
+func rpcParam(params []json.RawMessage, i int, v any) error {
+	if i >= len(params) {
+		return &paramError{fmt.Errorf("missing parameter %d", i)}
+	}
+
+	if err := json.Unmarshal(params[i], v); err != nil {
+		return &paramError{fmt.Errorf("parameter %d: %w", i, err)}
+	}
+
+	return nil
+}
+
+// rpcFailure constructs the response for a failed call.
+func rpcFailure(id json.RawMessage, code int, err error) rpcResponse {
+	return rpcResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   &rpcError{Code: code, Message: err.Error()},
+	}
+}
+
+// toRPCTx converts a block transaction into the form used by Ethereum.
+func toRPCTx(tx database.BlockTx) rpcTx {
+	return rpcTx{
+		Hash:     tx.TxHash(),
+		Nonce:    hexutil.Uint64(tx.Nonce),
+		From:     string(tx.FromID),
+		To:       string(tx.ToID),
+		Value:    hexutil.Uint64(tx.Value),
+		Gas:      hexutil.Uint64(tx.GasUnits),
+		GasPrice: hexutil.Uint64(tx.GasPrice),
+		Input:    tx.Data,
+		ChainID:  hexutil.Uint64(tx.ChainID),
+		V:        (*hexutil.Big)(tx.V),
+		R:        (*hexutil.Big)(tx.R),
+		S:        (*hexutil.Big)(tx.S),
+	}
+}
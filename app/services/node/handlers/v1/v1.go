@@ -4,34 +4,122 @@ package v1
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/ardanlabs/blockchain/app/services/node/handlers/v1/admin"
 	"github.com/ardanlabs/blockchain/app/services/node/handlers/v1/private"
 	"github.com/ardanlabs/blockchain/app/services/node/handlers/v1/public"
+	"github.com/ardanlabs/blockchain/business/web/v1/mid"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
 	"github.com/ardanlabs/blockchain/foundation/web"
 	"go.uber.org/zap"
 )
 
+// version is the path prefix of the routes and the version negotiated for
+// their responses. Breaking changes ship under a new version with its own
+// set of routes.
 const version = "v1"
 
 // Config contains all the mandatory systems required by handlers.
 type Config struct {
-	Log *zap.SugaredLogger
+	Log         *zap.SugaredLogger
+	State       *state.State
+	TxRateLimit float64
+	TxRateBurst int
+	PollTimeout time.Duration
+	Build       string
+	Revision    string
 }
 
 // PublicRoutes binds all the version 1 public routes.
 func PublicRoutes(app *web.App, cfg Config) {
 	pbl := public.Handlers{
-		Log: cfg.Log,
+		Log:         cfg.Log,
+		State:       cfg.State,
+		PollTimeout: cfg.PollTimeout,
+		Build:       cfg.Build,
+		Revision:    cfg.Revision,
 	}
 
-	app.Handle(http.MethodGet, version, "/sample", pbl.Sample)
+	grp := app.Group(version, mid.Version(version))
+
+	grp.Handle(http.MethodGet, "/liveness", pbl.Liveness)
+	grp.Handle(http.MethodGet, "/readiness", pbl.Readiness)
+	grp.Handle(http.MethodGet, "/events", pbl.Events)
+	grp.Handle(http.MethodGet, "/genesis/list", pbl.Genesis)
+	grp.Handle(http.MethodGet, "/accounts/list", pbl.Accounts)
+	grp.Handle(http.MethodGet, "/accounts/list/:account", pbl.Accounts)
+	grp.Handle(http.MethodGet, "/accounts/pending/:account", pbl.PendingBalance)
+	grp.Handle(http.MethodGet, "/accounts/proof/:account", pbl.AccountProof)
+	grp.Handle(http.MethodGet, "/accounts/storage/:account/:key", pbl.Storage)
+	grp.Handle(http.MethodGet, "/names/:name", pbl.Name)
+	grp.Handle(http.MethodGet, "/tokens/:symbol", pbl.Token)
+	grp.Handle(http.MethodGet, "/tokens/:symbol/balances/:account", pbl.TokenBalance)
+	grp.Handle(http.MethodGet, "/policy", pbl.Policy)
+	grp.Handle(http.MethodGet, "/blocks/list", pbl.BlocksByAccount)
+	grp.Handle(http.MethodGet, "/blocks/list/:account", pbl.BlocksByAccount)
+	grp.Handle(http.MethodGet, "/blocks/list/:from/:to", pbl.BlocksByRange)
+	grp.Handle(http.MethodGet, "/blocks/subscribe", pbl.BlocksSubscribe)
+	grp.Handle(http.MethodGet, "/blocks/fees", pbl.FeeSummary)
+	grp.Handle(http.MethodGet, "/blocks/:number/statediff", pbl.StateDiff)
+	grp.Handle(http.MethodGet, "/blocks/:number/fees", pbl.BlockFees)
+	grp.Handle(http.MethodGet, "/tx/uncommitted/list", pbl.Mempool)
+	grp.Handle(http.MethodGet, "/tx/uncommitted/list/:account", pbl.Mempool)
+	grp.Handle(http.MethodGet, "/tx/gas-estimate", pbl.GasEstimate)
+	// The routes that submit transactions share a per client rate limit.
+	limit := mid.RateLimit(cfg.TxRateLimit, cfg.TxRateBurst)
+	grp.Handle(http.MethodPost, "/tx/submit", pbl.SubmitWalletTransaction, limit)
+	grp.Handle(http.MethodPost, "/tx/batch", pbl.SubmitWalletTransactions, limit)
+	grp.Handle(http.MethodPost, "/rpc", pbl.RPC, limit)
+	grp.Handle(http.MethodDelete, "/tx/:account/:nonce", pbl.CancelWalletTransaction, limit)
+	grp.Handle(http.MethodGet, "/tx/:hash", pbl.Tx)
+	grp.Handle(http.MethodGet, "/tx/:hash/receipt", pbl.Receipt)
+	grp.Handle(http.MethodGet, "/tx/:hash/eviction", pbl.Eviction)
 }
 
 // PrivateRoutes binds all the version 1 private routes.
 func PrivateRoutes(app *web.App, cfg Config) {
 	prv := private.Handlers{
-		Log: cfg.Log,
+		Log:   cfg.Log,
+		State: cfg.State,
 	}
 
-	app.Handle(http.MethodGet, version, "/node/sample", prv.Sample)
+	// Peers that are banned for sending invalid data are turned away. Peers
+	// can ask for a range of blocks as a stream, and for blocks and
+	// transactions as gob, in place of JSON.
+	blockTypes := []string{database.BlockContentStream, database.BlockContentGob}
+	grp := app.Group(version, mid.Version(version, blockTypes...), mid.PeerBan(cfg.State.IsPeerBanned))
+
+	grp.Handle(http.MethodPost, "/node/peers", prv.SubmitPeer)
+	grp.Handle(http.MethodGet, "/node/status", prv.Status)
+	grp.Handle(http.MethodGet, "/node/block/list/:from/:to", prv.BlocksByNumber)
+	grp.Handle(http.MethodPost, "/node/block/propose", prv.ProposeBlock)
+	grp.Handle(http.MethodPost, "/node/tx/submit", prv.SubmitNodeTransaction)
+	grp.Handle(http.MethodGet, "/node/tx/list", prv.Mempool)
+	grp.Handle(http.MethodGet, "/node/tx/hashes", prv.MempoolHashes)
+	grp.Handle(http.MethodPost, "/node/tx/fetch", prv.MempoolFetch)
+}
+
+// AdminRoutes binds all the version 1 admin routes.
+func AdminRoutes(app *web.App, cfg Config) {
+	adm := admin.Handlers{
+		Log:   cfg.Log,
+		State: cfg.State,
+	}
+
+	grp := app.Group(version, mid.Version(version))
+
+	grp.Handle(http.MethodPost, "/admin/mining/start", adm.StartMining)
+	grp.Handle(http.MethodPost, "/admin/mining/stop", adm.StopMining)
+	grp.Handle(http.MethodPost, "/admin/mining/empty", adm.EmptyBlocks)
+	grp.Handle(http.MethodDelete, "/admin/mempool", adm.DropMempool)
+	grp.Handle(http.MethodPost, "/admin/peers", adm.AddPeer)
+	grp.Handle(http.MethodDelete, "/admin/peers/:host", adm.RemovePeer)
+	grp.Handle(http.MethodGet, "/admin/peers/bans", adm.PeerBans)
+	grp.Handle(http.MethodPost, "/admin/peers/bans", adm.BanPeer)
+	grp.Handle(http.MethodDelete, "/admin/peers/bans/:host", adm.UnbanPeer)
+	grp.Handle(http.MethodPost, "/admin/resync", adm.Resync)
+	grp.Handle(http.MethodGet, "/admin/doublespends", adm.DoubleSpends)
+	grp.Handle(http.MethodGet, "/admin/metrics", adm.Metrics)
 }
@@ -2,17 +2,33 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/ardanlabs/blockchain/app/services/node/handlers"
+	"github.com/ardanlabs/blockchain/app/services/node/handlers/v1/grpcgrp"
+	"github.com/ardanlabs/blockchain/business/watch"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/hash"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/worker"
 	"github.com/ardanlabs/blockchain/foundation/logger"
+	"github.com/ardanlabs/blockchain/foundation/tracing"
 	"github.com/ardanlabs/conf/v3"
 	"go.uber.org/zap"
 )
@@ -49,13 +65,65 @@ func run(log *zap.SugaredLogger) error {
 	cfg := struct {
 		conf.Version
 		Web struct {
-			ReadTimeout     time.Duration `conf:"default:5s"`
-			WriteTimeout    time.Duration `conf:"default:10s"`
-			IdleTimeout     time.Duration `conf:"default:120s"`
-			ShutdownTimeout time.Duration `conf:"default:20s"`
-			DebugHost       string        `conf:"default:0.0.0.0:7080"`
-			PublicHost      string        `conf:"default:0.0.0.0:8080"`
-			PrivateHost     string        `conf:"default:0.0.0.0:9080"`
+			ReadTimeout      time.Duration `conf:"default:5s"`
+			WriteTimeout     time.Duration `conf:"default:10s"`
+			PollTimeout      time.Duration `conf:"default:8s"`
+			IdleTimeout      time.Duration `conf:"default:120s"`
+			ShutdownTimeout  time.Duration `conf:"default:20s"`
+			DebugHost        string        `conf:"default:0.0.0.0:7080"`
+			PublicHost       string        `conf:"default:0.0.0.0:8080"`
+			PrivateHost      string        `conf:"default:0.0.0.0:9080"`
+			AdminHost        string        `conf:"default:0.0.0.0:6080"`
+			GRPCHost         string        `conf:"default:0.0.0.0:5080"`
+			AdminToken       string        `conf:"mask"`
+			PrivateTLSRootCA string
+			PrivateTLSCert   string
+			PrivateTLSKey    string
+			MaxBodySize      int64    `conf:"default:1048576"`
+			TxRateLimit      float64  `conf:"default:50"`
+			TxRateBurst      int      `conf:"default:100"`
+			CORSOrigins      []string `conf:"default:*"`
+		}
+		State struct {
+			Beneficiary     string `conf:"default:miner1"`
+			BeneficiaryPool []string
+			KeysFolder      string        `conf:"default:zblock/accounts/"`
+			SignerTimeout   time.Duration `conf:"default:10s"`
+			GenesisPath     string        `conf:"default:zblock/genesis.json"`
+			DBPath          string        `conf:"default:zblock/miner1/"`
+			Storage         string        `conf:"default:disk"`
+			SelectStrategy  string        `conf:"default:Tip"`
+			OriginPeers     []string      `conf:"default:0.0.0.0:9080"`
+			PeerIdentities  []string      `conf:"default:0.0.0.0:9080=0xFef311483Cc040e1A89fb9bb469eeB8A70935EF8"`
+			SplitThreshold  uint64        `conf:"default:3"`
+			NonceWindow     uint64        `conf:"default:64"`
+			TxTTL           time.Duration `conf:"default:30m"`
+			TxTTLBlocks     uint64        `conf:"default:0"`
+			MiningWorkers   int           `conf:"default:0"`
+			EmptyBlocks     time.Duration `conf:"default:0s"`
+			BanThreshold    int           `conf:"default:100"`
+			BanDuration     time.Duration `conf:"default:1h"`
+			MaxBlockDrift   time.Duration `conf:"default:2h"`
+			ShutdownTimeout time.Duration `conf:"default:30s"`
+			SignerURL       string
+			SnapshotPath    string
+			SnapshotSigner  string
+			Chains          []string
+		}
+		Watch struct {
+			Addresses  []string
+			WebhookURL string
+		}
+		Chaos struct {
+			Latency     time.Duration `conf:"default:0s"`
+			Jitter      time.Duration `conf:"default:0s"`
+			DropRate    float64       `conf:"default:0"`
+			BlockDelays []string
+		}
+		Tracing struct {
+			ReporterURI string
+			ServiceName string  `conf:"default:node"`
+			Probability float64 `conf:"default:0.05"`
 		}
 	}{
 		Version: conf.Version{
@@ -86,7 +154,8 @@ func run(log *zap.SugaredLogger) error {
 	fmt.Println(` /_/   \_\_| \_\____/_/   \_\_| \_|  |____/|_____\___/ \____|_|\_\____|_| |_/_/   \_\___|_| \_| `)
 	fmt.Print("\n")
 
-	log.Infow("starting service", "version", build)
+	revision := vcsRevision()
+	log.Infow("starting service", "version", build, "revision", revision)
 	defer log.Infow("shutdown complete")
 
 	// Display the current configuration to the logs.
@@ -96,14 +165,328 @@ func run(log *zap.SugaredLogger) error {
 	}
 	log.Infow("startup", "config", out)
 
+	// =========================================================================
+	// Start Tracing Support
+
+	// Traces are exported to an OpenTelemetry collector over OTLP/HTTP, so
+	// they are only recorded when a collector has been configured.
+	var tracer *tracing.Tracer
+	switch cfg.Tracing.ReporterURI {
+	case "":
+		log.Infow("startup", "status", "tracing disabled, no reporter uri configured")
+
+	default:
+		if cfg.Tracing.Probability < 0 || cfg.Tracing.Probability > 1 {
+			return fmt.Errorf("tracing probability %v: must be between 0 and 1", cfg.Tracing.Probability)
+		}
+
+		log.Infow("startup", "status", "initializing tracing support", "reporter", cfg.Tracing.ReporterURI, "probability", cfg.Tracing.Probability)
+
+		tracer = tracing.New(tracing.Config{
+			ServiceName: cfg.Tracing.ServiceName,
+			Endpoint:    cfg.Tracing.ReporterURI,
+			Probability: cfg.Tracing.Probability,
+			Log:         log.Infow,
+		})
+
+		// Export the last spans once the blockchain has shut down.
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
+			defer cancel()
+
+			if err := tracer.Shutdown(ctx); err != nil {
+				log.Errorw("shutdown", "status", "could not export the last spans", "ERROR", err)
+			}
+		}()
+	}
+
 	// =========================================================================
 	// Blockchain Support
-	gen, err := genesis.Load()
+
+	// Need a signer for the configured beneficiary so the account can get
+	// credited with fees and tips. When a signing service is configured the
+	// private key doesn't need to live on this host.
+	var signer database.Signer
+	switch cfg.State.SignerURL {
+	case "":
+		path := filepath.Join(cfg.State.KeysFolder, cfg.State.Beneficiary+".ecdsa")
+		privateKey, err := crypto.LoadECDSA(path)
+		if err != nil {
+			return fmt.Errorf("unable to load private key for node: %w", err)
+		}
+		signer = database.NewECDSASigner(privateKey)
+
+	default:
+		remote, err := database.NewRemoteSigner(cfg.State.SignerURL, cfg.State.SignerTimeout)
+		if err != nil {
+			return fmt.Errorf("unable to connect to signer for node: %w", err)
+		}
+		signer = remote
+	}
+	log.Infow("startup", "beneficiary", signer.AccountID())
+
+	// A peer set is a collection of known nodes in the network so transactions
+	// and blocks can be shared.
+	peerSet := peer.NewPeerSet()
+	for _, host := range cfg.State.OriginPeers {
+		peerSet.Add(peer.New(host))
+	}
+	peerSet.Add(peer.New(cfg.Web.PrivateHost))
+
+	// The accounts the origin peers must sign their status with, so no other
+	// node can pose as the origin and feed this node a fake chain.
+	identities, err := parsePeerIdentities(cfg.State.PeerIdentities)
+	if err != nil {
+		return err
+	}
+
+	// The private API can be secured with mutual TLS so the peers can talk
+	// across untrusted networks. Every peer must then present a certificate
+	// signed by the same certificate authority.
+	var peerTLS *tls.Config
+	if cfg.Web.PrivateTLSCert != "" {
+		peerTLS, err = peer.TLSConfig(cfg.Web.PrivateTLSRootCA, cfg.Web.PrivateTLSCert, cfg.Web.PrivateTLSKey)
+		if err != nil {
+			return fmt.Errorf("private api tls: %w", err)
+		}
+		log.Infow("startup", "status", "private api using mutual tls", "ca", cfg.Web.PrivateTLSRootCA)
+	}
+
+	// Construct the storage for the configured engine.
+	store, err := storage.New(cfg.State.Storage, cfg.State.DBPath)
+	if err != nil {
+		return err
+	}
+
+	// Load the genesis file for blockchain settings and origin balances.
+	gen, err := genesis.Load(cfg.State.GenesisPath)
 	if err != nil {
 		return fmt.Errorf("genesis block load: %w", err)
 	}
 	log.Infow("startup", "genesis", gen)
 
+	// Every hash the node produces must use the hash function of the chain.
+	if err := hash.Select(gen.Hash); err != nil {
+		return fmt.Errorf("genesis hash: %w", err)
+	}
+
+	// The other chains hosted by this node, such as a testnet running next to
+	// the main chain. Their APIs are served under the chain name on the same
+	// hosts.
+	chainCfgs, err := parseChains(cfg.State.Chains, cfg.State.DBPath)
+	if err != nil {
+		return err
+	}
+
+	// A snapshot lets the node start from a checkpoint instead of replaying
+	// the chain from genesis. It must be signed by an account the operator
+	// trusts.
+	var snapshot *database.Snapshot
+	if cfg.State.SnapshotPath != "" {
+		ss, err := database.LoadSnapshot(cfg.State.SnapshotPath)
+		if err != nil {
+			return fmt.Errorf("snapshot load: %w", err)
+		}
+
+		signer, err := database.ToAccountID(cfg.State.SnapshotSigner)
+		if err != nil {
+			return fmt.Errorf("snapshot signer %q: %w", cfg.State.SnapshotSigner, err)
+		}
+
+		if err := ss.Verify(signer); err != nil {
+			return fmt.Errorf("snapshot verify: %w", err)
+		}
+
+		snapshot = &ss.Snapshot
+		log.Infow("startup", "snapshot", cfg.State.SnapshotPath, "block", ss.Block.Header.Number)
+	}
+
+	// The accounts splitting the mining reward and fees of the blocks this
+	// node mines, such as the members of a mining pool.
+	beneficiaries, err := parseBeneficiaries(cfg.State.BeneficiaryPool)
+	if err != nil {
+		return err
+	}
+	if len(beneficiaries) > 0 {
+		log.Infow("startup", "beneficiaries", beneficiaries)
+	}
+
+	// The set of addresses the node operator wants to be notified about when
+	// they appear in an applied block.
+	watchList := make([]database.AccountID, len(cfg.Watch.Addresses))
+	for i, address := range cfg.Watch.Addresses {
+		accountID, err := database.ToAccountID(address)
+		if err != nil {
+			return fmt.Errorf("watch address %q: %w", address, err)
+		}
+		watchList[i] = accountID
+	}
+
+	// Faults injected into the messages sent to peers so a test network
+	// forks and recovers on its own. Never enable this on a real network.
+	chaos, err := parseChaos(cfg.Chaos.Latency, cfg.Chaos.Jitter, cfg.Chaos.DropRate, cfg.Chaos.BlockDelays)
+	if err != nil {
+		return err
+	}
+	if chaos.Enabled() {
+		log.Infow("startup", "status", "WARNING: chaos enabled, peer messages are delayed and dropped", "chaos", chaos)
+	}
+
+	// The goroutines mining a block are taken from a pool shared by every
+	// chain hosted by this node, so the chains take turns on the CPUs.
+	miningPool := database.NewWorkerPool(cfg.State.MiningWorkers)
+
+	// The state value represents the blockchain node and manages the blockchain
+	// database and provides an API for application support.
+	stateCfg := state.Config{
+		BeneficiaryID:  signer.AccountID(),
+		Signer:         signer,
+		PeerIdentities: identities,
+		Beneficiaries:  beneficiaries,
+		Host:           cfg.Web.PrivateHost,
+		Storage:        store,
+		Genesis:        gen,
+		Snapshot:       snapshot,
+		SelectStrategy: cfg.State.SelectStrategy,
+		KnownPeers:     peerSet,
+		PeerTLS:        peerTLS,
+		WatchList:      watchList,
+		SplitThreshold: cfg.State.SplitThreshold,
+		NonceWindow:    cfg.State.NonceWindow,
+		TxTTL:          cfg.State.TxTTL,
+		TxTTLBlocks:    cfg.State.TxTTLBlocks,
+		MiningWorkers:  cfg.State.MiningWorkers,
+		MiningPool:     miningPool,
+		EmptyBlocks:    cfg.State.EmptyBlocks,
+		BanThreshold:   cfg.State.BanThreshold,
+		BanDuration:    cfg.State.BanDuration,
+		MaxBlockDrift:  cfg.State.MaxBlockDrift,
+		Chaos:          chaos,
+		Tracer:         tracer,
+		Log:            logger.Func(log),
+	}
+
+	st, err := state.New(stateCfg)
+	if err != nil {
+		return err
+	}
+
+	// Stop the worker and flush the latest block to storage once the API
+	// services are no longer accepting requests.
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.State.ShutdownTimeout)
+		defer cancel()
+
+		log.Infow("shutdown", "status", "shutdown blockchain started")
+		if err := st.Shutdown(ctx); err != nil {
+			log.Errorw("shutdown", "status", "could not stop blockchain gracefully", "ERROR", err)
+		}
+	}()
+
+	// The worker package implements the different workflows such as mining,
+	// transaction peer sharing, and peer updates. The worker will register
+	// itself with the state.
+	// The blockchain packages accept a logger function so the application
+	// decides where the output goes.
+	worker.Run(st, logger.Func(log))
+
+	// The watcher records metrics and calls the optional webhook when watched
+	// addresses appear in an applied block.
+	watch.Run(watch.Config{
+		Log:        log,
+		State:      st,
+		WebhookURL: cfg.Watch.WebhookURL,
+	})
+
+	// =========================================================================
+	// Start Hosted Chains
+
+	// Every other chain runs its own state and worker with the signer, peers
+	// and settings of the chain above. Only the chain above is watched and
+	// reported by the debug service.
+	chainIDs := map[uint16]string{gen.ChainID: "root"}
+	chains := make([]hostedChain, len(chainCfgs))
+	for i, cc := range chainCfgs {
+		chainLog := log.With("chain", cc.name)
+
+		chainGen, err := genesis.Load(cc.genesisPath)
+		if err != nil {
+			return fmt.Errorf("chain %s: genesis block load: %w", cc.name, err)
+		}
+		chainLog.Infow("startup", "genesis", chainGen)
+
+		if other, exists := chainIDs[chainGen.ChainID]; exists {
+			return fmt.Errorf("chain %s: chain id %d is already used by the %s chain", cc.name, chainGen.ChainID, other)
+		}
+		chainIDs[chainGen.ChainID] = cc.name
+
+		// The hash function is selected for the whole process, so every
+		// chain must use the same one.
+		hasher, err := hash.New(chainGen.Hash)
+		if err != nil {
+			return fmt.Errorf("chain %s: genesis hash: %w", cc.name, err)
+		}
+		if hasher.Name() != hash.Active().Name() {
+			return fmt.Errorf("chain %s: hash %s, every chain hosted by the node must use %s", cc.name, hasher.Name(), hash.Active().Name())
+		}
+
+		chainStorage, err := storage.New(cfg.State.Storage, cc.dbPath)
+		if err != nil {
+			return fmt.Errorf("chain %s: %w", cc.name, err)
+		}
+
+		// The peers hosting the chain are found the same way, every node
+		// is expected to serve the chain under the same name.
+		chainPeers := peer.NewPeerSet()
+		for _, host := range cfg.State.OriginPeers {
+			chainPeers.Add(peer.New(host))
+		}
+		chainPeers.Add(peer.New(cfg.Web.PrivateHost))
+
+		chainCfg := stateCfg
+		chainCfg.ChainName = cc.name
+		chainCfg.Storage = chainStorage
+		chainCfg.Genesis = chainGen
+		chainCfg.Snapshot = nil
+		chainCfg.KnownPeers = chainPeers
+		chainCfg.WatchList = nil
+		chainCfg.Log = logger.Func(chainLog)
+
+		chainSt, err := state.New(chainCfg)
+		if err != nil {
+			return fmt.Errorf("chain %s: %w", cc.name, err)
+		}
+
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.State.ShutdownTimeout)
+			defer cancel()
+
+			chainLog.Infow("shutdown", "status", "shutdown blockchain started")
+			if err := chainSt.Shutdown(ctx); err != nil {
+				chainLog.Errorw("shutdown", "status", "could not stop blockchain gracefully", "ERROR", err)
+			}
+		}()
+
+		worker.Run(chainSt, logger.Func(chainLog))
+
+		chains[i] = hostedChain{name: cc.name, state: chainSt, log: chainLog}
+		log.Infow("startup", "status", "chain started", "chain", cc.name, "chainid", chainGen.ChainID, "path", "/"+cc.name)
+	}
+
+	// mount serves the mux of every chain on the same server, the hosted
+	// chains under their name.
+	mount := func(build func(handlers.MuxConfig) http.Handler, muxCfg handlers.MuxConfig) http.Handler {
+		chainMuxes := make([]handlers.ChainMux, len(chains))
+		for i, chain := range chains {
+			chainCfg := muxCfg
+			chainCfg.Log = chain.log
+			chainCfg.State = chain.state
+			chainMuxes[i] = handlers.ChainMux{Name: chain.name, Handler: build(chainCfg)}
+		}
+
+		return handlers.MountChains(build(muxCfg), chainMuxes)
+	}
+
 	// =========================================================================
 	// Start Debug Service
 
@@ -113,7 +496,7 @@ func run(log *zap.SugaredLogger) error {
 	// related endpoints. This includes the standard library endpoints.
 
 	// Construct the mux for the debug calls.
-	debugMux := handlers.DebugMux(build, log)
+	debugMux := handlers.DebugMux(build, revision, log, st)
 
 	// Start the service listening for debug requests.
 	// Not concerned with shutting this down with load shedding.
@@ -140,10 +523,25 @@ func run(log *zap.SugaredLogger) error {
 
 	log.Infow("startup", "status", "initializing V1 public API support")
 
+	// A long poll must respond before the server gives up writing to the
+	// client.
+	if cfg.Web.PollTimeout <= 0 || cfg.Web.PollTimeout >= cfg.Web.WriteTimeout {
+		return fmt.Errorf("poll timeout %v must be positive and less than the write timeout %v", cfg.Web.PollTimeout, cfg.Web.WriteTimeout)
+	}
+
 	// Construct the mux for the public API calls.
-	publicMux := handlers.PublicMux(handlers.MuxConfig{
-		Shutdown: shutdown,
-		Log:      log,
+	publicMux := mount(handlers.PublicMux, handlers.MuxConfig{
+		Shutdown:    shutdown,
+		Log:         log,
+		Tracer:      tracer,
+		State:       st,
+		MaxBodySize: cfg.Web.MaxBodySize,
+		TxRateLimit: cfg.Web.TxRateLimit,
+		TxRateBurst: cfg.Web.TxRateBurst,
+		PollTimeout: cfg.Web.PollTimeout,
+		CORSOrigins: cfg.Web.CORSOrigins,
+		Build:       build,
+		Revision:    revision,
 	})
 
 	// Construct a server to service the requests against the mux.
@@ -168,15 +566,19 @@ func run(log *zap.SugaredLogger) error {
 	log.Infow("startup", "status", "initializing V1 private API support")
 
 	// Construct the mux for the private API calls.
-	privateMux := handlers.PrivateMux(handlers.MuxConfig{
-		Shutdown: shutdown,
-		Log:      log,
+	privateMux := mount(handlers.PrivateMux, handlers.MuxConfig{
+		Shutdown:    shutdown,
+		Log:         log,
+		Tracer:      tracer,
+		State:       st,
+		CORSOrigins: cfg.Web.CORSOrigins,
 	})
 
 	// Construct a server to service the requests against the mux.
 	private := http.Server{
 		Addr:         cfg.Web.PrivateHost,
 		Handler:      privateMux,
+		TLSConfig:    peerTLS,
 		ReadTimeout:  cfg.Web.ReadTimeout,
 		WriteTimeout: cfg.Web.WriteTimeout,
 		IdleTimeout:  cfg.Web.IdleTimeout,
@@ -185,10 +587,71 @@ func run(log *zap.SugaredLogger) error {
 
 	// Start the service listening for api requests.
 	go func() {
-		log.Infow("startup", "status", "private api router started", "host", private.Addr)
+		log.Infow("startup", "status", "private api router started", "host", private.Addr, "tls", peerTLS != nil)
+		if peerTLS != nil {
+			serverErrors <- private.ListenAndServeTLS("", "")
+			return
+		}
 		serverErrors <- private.ListenAndServe()
 	}()
 
+	// =========================================================================
+	// Start Admin Service
+
+	// The admin API gives runtime control of the node so it's only started
+	// when a token has been configured to protect it.
+	var admin *http.Server
+	switch cfg.Web.AdminToken {
+	case "":
+		log.Infow("startup", "status", "admin api disabled, no admin token configured")
+
+	default:
+		log.Infow("startup", "status", "initializing V1 admin API support")
+
+		// Construct the mux for the admin API calls.
+		adminMux := mount(handlers.AdminMux, handlers.MuxConfig{
+			Shutdown:   shutdown,
+			Log:        log,
+			Tracer:     tracer,
+			State:      st,
+			AdminToken: cfg.Web.AdminToken,
+		})
+
+		// Construct a server to service the requests against the mux.
+		admin = &http.Server{
+			Addr:         cfg.Web.AdminHost,
+			Handler:      adminMux,
+			ReadTimeout:  cfg.Web.ReadTimeout,
+			WriteTimeout: cfg.Web.WriteTimeout,
+			IdleTimeout:  cfg.Web.IdleTimeout,
+			ErrorLog:     zap.NewStdLog(log.Desugar()),
+		}
+
+		// Start the service listening for api requests.
+		go func() {
+			log.Infow("startup", "status", "admin api router started", "host", admin.Addr)
+			serverErrors <- admin.ListenAndServe()
+		}()
+	}
+
+	// =========================================================================
+	// Start gRPC Service
+
+	log.Infow("startup", "status", "initializing gRPC API support")
+
+	grpcListener, err := net.Listen("tcp", cfg.Web.GRPCHost)
+	if err != nil {
+		return fmt.Errorf("grpc listen: %w", err)
+	}
+
+	grpcService := grpcgrp.New(log, st)
+
+	// Start the service listening for api requests.
+	go func() {
+		log.Infow("startup", "status", "grpc api started", "host", cfg.Web.GRPCHost)
+		serverErrors <- grpcService.Serve(grpcListener)
+	}()
+
 	// =========================================================================
 	// Shutdown
 
@@ -201,28 +664,255 @@ func run(log *zap.SugaredLogger) error {
 		log.Infow("shutdown", "status", "shutdown started", "signal", sig)
 		defer log.Infow("shutdown", "status", "shutdown complete", "signal", sig)
 
-		// Give outstanding requests a deadline for completion.
-		ctx, cancelPub := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
-		defer cancelPub()
-
-		// Asking listener to shut down and shed load.
-		log.Infow("shutdown", "status", "shutdown private API started")
-		if err := private.Shutdown(ctx); err != nil {
-			private.Close()
-			return fmt.Errorf("could not stop private service gracefully: %w", err)
+		// A second signal means the operator doesn't want to wait.
+		go func() {
+			sig := <-shutdown
+			log.Errorw("shutdown", "status", "forced shutdown", "signal", sig)
+			log.Sync()
+			os.Exit(1)
+		}()
+
+		// Asking the listeners to shut down and shed load. Every service is
+		// given the chance to drain its outstanding requests, even when a
+		// previous one could not be stopped gracefully.
+		servers := []struct {
+			name string
+			srv  *http.Server
+		}{
+			{"private", &private},
+			{"public", &public},
+			{"admin", admin},
 		}
 
-		// Give outstanding requests a deadline for completion.
-		ctx, cancelPri := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
-		defer cancelPri()
+		var shutdownErr error
+		for _, s := range servers {
+			if s.srv == nil {
+				continue
+			}
+
+			log.Infow("shutdown", "status", "shutdown "+s.name+" API started")
+			if err := shutdownServer(s.srv, cfg.Web.ShutdownTimeout); err != nil {
+				log.Errorw("shutdown", "status", "could not stop "+s.name+" service gracefully", "ERROR", err)
+				if shutdownErr == nil {
+					shutdownErr = fmt.Errorf("could not stop %s service gracefully: %w", s.name, err)
+				}
+			}
+		}
 
-		// Asking listener to shut down and shed load.
-		log.Infow("shutdown", "status", "shutdown public API started")
-		if err := public.Shutdown(ctx); err != nil {
-			public.Close()
-			return fmt.Errorf("could not stop public service gracefully: %w", err)
+		log.Infow("shutdown", "status", "shutdown gRPC API started")
+		if err := grpcService.Shutdown(cfg.Web.ShutdownTimeout); err != nil {
+			log.Errorw("shutdown", "status", "could not stop gRPC service gracefully", "ERROR", err)
+			if shutdownErr == nil {
+				shutdownErr = fmt.Errorf("could not stop gRPC service gracefully: %w", err)
+			}
 		}
+
+		return shutdownErr
+	}
+}
+
+// shutdownServer gives the outstanding requests for the server a deadline for
+// completion. The server is closed if they don't complete in time.
+func shutdownServer(srv *http.Server, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		srv.Close()
+		return err
 	}
 
 	return nil
 }
+
+// hostedChain represents a chain hosted by the node next to the chain served
+// at the root of the APIs.
+type hostedChain struct {
+	name  string
+	state *state.State
+	log   *zap.SugaredLogger
+}
+
+// chainConfig represents the settings for a hosted chain.
+type chainConfig struct {
+	name        string
+	genesisPath string
+	dbPath      string
+}
+
+// parseChains parses the chains hosted by the node, each provided as
+// name:genesis-path:db-path. The name is the path the APIs of the chain are
+// served under, so it's limited to lowercase letters, digits and dashes and
+// can't be an API version. Every chain needs its own data directory.
+func parseChains(entries []string, dbPath string) ([]chainConfig, error) {
+	names := make(map[string]bool)
+	dbPaths := map[string]bool{filepath.Clean(dbPath): true}
+
+	var chains []chainConfig
+	for _, entry := range entries {
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("chain %q: expected name:genesis-path:db-path", entry)
+		}
+		cc := chainConfig{name: parts[0], genesisPath: parts[1], dbPath: parts[2]}
+
+		if !validChainName(cc.name) {
+			return nil, fmt.Errorf("chain %q: name must be lowercase letters, digits and dashes, and not an api version", entry)
+		}
+		if names[cc.name] {
+			return nil, fmt.Errorf("chain %q: name %s is already used", entry, cc.name)
+		}
+		names[cc.name] = true
+
+		if dbPaths[filepath.Clean(cc.dbPath)] {
+			return nil, fmt.Errorf("chain %q: db path %s is already used", entry, cc.dbPath)
+		}
+		dbPaths[filepath.Clean(cc.dbPath)] = true
+
+		chains = append(chains, cc)
+	}
+
+	return chains, nil
+}
+
+// validChainName reports if the name can be used as the path of a chain.
+func validChainName(name string) bool {
+	if name == "" || name[0] == '-' || name == "debug" {
+		return false
+	}
+	if name[0] == 'v' && len(name) > 1 && strings.Trim(name[1:], "0123456789") == "" {
+		return false
+	}
+
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseBeneficiaries parses the accounts splitting the mining reward, each
+// provided as address:weight.
+func parseBeneficiaries(pool []string) ([]database.Beneficiary, error) {
+	beneficiaries := make([]database.Beneficiary, len(pool))
+	for i, entry := range pool {
+		address, weightStr, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("beneficiary %q: expected address:weight", entry)
+		}
+
+		accountID, err := database.ToAccountID(address)
+		if err != nil {
+			return nil, fmt.Errorf("beneficiary %q: %w", entry, err)
+		}
+
+		weight, err := strconv.ParseUint(weightStr, 10, 16)
+		if err != nil || weight == 0 {
+			return nil, fmt.Errorf("beneficiary %q: weight must be between 1 and 65535", entry)
+		}
+
+		beneficiaries[i] = database.Beneficiary{AccountID: accountID, Weight: uint16(weight)}
+	}
+
+	return beneficiaries, nil
+}
+
+// parsePeerIdentities parses the accounts bound to peer hosts, each provided
+// as host=address. An empty entry is skipped so the default can be cleared.
+func parsePeerIdentities(entries []string) (map[string]database.AccountID, error) {
+	identities := make(map[string]database.AccountID, len(entries))
+	for _, entry := range entries {
+		if entry == "" {
+			continue
+		}
+
+		host, address, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("peer identity %q: expected host=address", entry)
+		}
+
+		accountID, err := database.ToAccountID(address)
+		if err != nil {
+			return nil, fmt.Errorf("peer identity %q: %w", entry, err)
+		}
+
+		identities[host] = accountID
+	}
+
+	return identities, nil
+}
+
+// parseChaos validates the faults to inject into the messages sent to peers.
+// The block delays are provided as host=duration for each peer.
+func parseChaos(latency time.Duration, jitter time.Duration, dropRate float64, blockDelays []string) (state.Chaos, error) {
+	if latency < 0 || jitter < 0 {
+		return state.Chaos{}, errors.New("chaos latency and jitter can't be negative")
+	}
+	if dropRate < 0 || dropRate > 1 {
+		return state.Chaos{}, fmt.Errorf("chaos drop rate %v: must be between 0 and 1", dropRate)
+	}
+
+	chaos := state.Chaos{
+		Latency:  latency,
+		Jitter:   jitter,
+		DropRate: dropRate,
+	}
+
+	for _, entry := range blockDelays {
+		if entry == "" {
+			continue
+		}
+
+		host, value, found := strings.Cut(entry, "=")
+		if !found {
+			return state.Chaos{}, fmt.Errorf("chaos block delay %q: expected host=duration", entry)
+		}
+
+		delay, err := time.ParseDuration(value)
+		if err != nil || delay < 0 {
+			return state.Chaos{}, fmt.Errorf("chaos block delay %q: invalid duration", entry)
+		}
+
+		if chaos.BlockDelays == nil {
+			chaos.BlockDelays = make(map[string]time.Duration)
+		}
+		chaos.BlockDelays[host] = delay
+	}
+
+	return chaos, nil
+}
+
+// vcsRevision returns the git commit the binary was built from, marked as
+// dirty when the tree had uncommitted changes. Binaries built with go run
+// don't carry the information.
+func vcsRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+
+	switch {
+	case revision == "":
+		return "unknown"
+	case modified == "true":
+		return revision + "-dirty"
+	}
+
+	return revision
+}
@@ -0,0 +1,288 @@
+var currentTab = "blocks";
+var blocks = [];
+
+// Things to run when the viewer is opened.
+window.onload = function () {
+    document.getElementById("node").innerText = nodeURL;
+
+    wireEvents();
+    showTab("blocks");
+    connectEvents();
+}
+
+// =============================================================================
+
+// Wiring all of the page events here.
+function wireEvents() {
+    document.getElementById("blocksbutton").addEventListener('click', function () { showTab("blocks"); }, false);
+    document.getElementById("mempbutton").addEventListener('click', function () { showTab("memp"); }, false);
+    document.getElementById("accountsbutton").addEventListener('click', function () { showTab("accounts"); }, false);
+    document.getElementById("refreshbutton").addEventListener('click', load, false);
+    document.getElementById("account").addEventListener('change', load, false);
+    document.getElementById("backbutton").addEventListener('click', function () { showTab(currentTab); }, false);
+}
+
+// showTab displays the specified tab and loads its data.
+function showTab(name) {
+    currentTab = name;
+
+    const tabs = ["blocks", "memp", "accounts", "details"];
+    for (const tab of tabs) {
+        document.getElementById(tab).style.display = (tab == name) ? "block" : "none";
+    }
+
+    const buttons = {blocks: "blocksbutton", memp: "mempbutton", accounts: "accountsbutton"};
+    for (const tab in buttons) {
+        document.getElementById(buttons[tab]).classList.toggle("active", tab == name);
+    }
+
+    load();
+}
+
+// load refreshes the status line and the data for the current tab.
+function load() {
+    clearErrors();
+    loadStatus();
+
+    switch (currentTab) {
+    case "blocks":
+        loadBlocks();
+        break;
+    case "memp":
+        loadMempool();
+        break;
+    case "accounts":
+        loadAccounts();
+        break;
+    }
+}
+
+// =============================================================================
+
+// account returns the account filter provided by the user.
+function account() {
+    return document.getElementById("account").value.trim();
+}
+
+// get performs a GET call against the node's public API. A 204 response
+// is returned as an empty list.
+async function get(path) {
+    const resp = await fetch(nodeURL + path);
+    if (resp.status == 204) {
+        return [];
+    }
+
+    const data = await resp.json();
+    if (!resp.ok) {
+        throw new Error(data.error || resp.statusText);
+    }
+
+    return data;
+}
+
+async function loadStatus() {
+    try {
+        const info = await get("/v1/accounts/list");
+        document.getElementById("latest").innerText = short(info.latest_block);
+        document.getElementById("uncommitted").innerText = info.uncommitted;
+    } catch (err) {
+        showError(err);
+    }
+}
+
+async function loadBlocks() {
+    let path = "/v1/blocks/list";
+    if (account() != "") {
+        path += "/" + account();
+    }
+
+    try {
+        blocks = await get(path);
+        blocks.sort(function (a, b) { return b.number - a.number; });
+    } catch (err) {
+        showError(err);
+        return;
+    }
+
+    const rows = blocks.map(function (blk) {
+        return {
+            cells: [blk.number, short(blk.hash), formatTime(blk.timestamp), short(blk.beneficiary), blk.difficulty, blk.txs ? blk.txs.length : 0],
+            click: function () { showBlock(blk); },
+        };
+    });
+
+    fillTable("blocktable", ["Number", "Hash", "Time", "Beneficiary", "Difficulty", "Txs"], rows);
+}
+
+async function loadMempool() {
+    let path = "/v1/tx/uncommitted/list";
+    if (account() != "") {
+        path += "/" + account();
+    }
+
+    let txs;
+    try {
+        txs = await get(path);
+    } catch (err) {
+        showError(err);
+        return;
+    }
+
+    fillTable("memptable", txHeaders(), txs.map(txRow));
+}
+
+async function loadAccounts() {
+    let path = "/v1/accounts/list";
+    if (account() != "") {
+        path += "/" + account();
+    }
+
+    let info;
+    try {
+        info = await get(path);
+    } catch (err) {
+        showError(err);
+        return;
+    }
+
+    info.accounts.sort(function (a, b) { return b.balance - a.balance; });
+
+    const rows = info.accounts.map(function (act) {
+        return {cells: [act.account, act.balance, act.nonce]};
+    });
+
+    fillTable("accounttable", ["Account", "Balance", "Nonce"], rows);
+}
+
+// =============================================================================
+
+// showBlock displays the header and transactions for the specified block.
+function showBlock(blk) {
+    const header = Object.assign({}, blk);
+    delete header.txs;
+
+    showDetails(JSON.stringify(header, null, 2), blk.txs || []);
+}
+
+// showTx displays all the information for the specified transaction,
+// including the merkle proof.
+function showTx(tx) {
+    showDetails(JSON.stringify(tx, null, 2), []);
+}
+
+function showDetails(text, txs) {
+    for (const tab of ["blocks", "memp", "accounts"]) {
+        document.getElementById(tab).style.display = "none";
+    }
+    document.getElementById("details").style.display = "block";
+
+    document.getElementById("detailtext").innerText = text;
+    fillTable("txtable", txs.length > 0 ? txHeaders() : [], txs.map(txRow));
+}
+
+function txHeaders() {
+    return ["Hash", "From", "To", "Nonce", "Value", "Tip", "Memo", "Time"];
+}
+
+function txRow(tx) {
+    return {
+        cells: [short(tx.hash), short(tx.from), short(tx.to), tx.nonce, tx.value, tx.tip, memoText(tx.memo), formatTime(tx.timestamp)],
+        click: function () { showTx(tx); },
+    };
+}
+
+// memoText returns the memo of a transaction as a single line.
+function memoText(memo) {
+    if (!memo) {
+        return "";
+    }
+
+    if (memo.reference && memo.text) {
+        return "[" + memo.reference + "] " + memo.text;
+    }
+
+    return memo.reference ? "[" + memo.reference + "]" : memo.text;
+}
+
+// fillTable replaces the contents of the table with the specified headers
+// and rows. A row with a click function can be selected.
+function fillTable(id, headers, rows) {
+    const table = document.getElementById(id);
+    table.replaceChildren();
+
+    if (headers.length > 0) {
+        const tr = table.insertRow();
+        for (const h of headers) {
+            const th = document.createElement("th");
+            th.innerText = h;
+            tr.appendChild(th);
+        }
+    }
+
+    for (const row of rows) {
+        const tr = table.insertRow();
+        for (const cell of row.cells) {
+            tr.insertCell().innerText = cell;
+        }
+        if (row.click) {
+            tr.className = "link";
+            tr.addEventListener('click', row.click, false);
+        }
+    }
+}
+
+// =============================================================================
+
+// connectEvents opens the node's event stream so the viewer refreshes when
+// transactions are accepted and blocks are added.
+function connectEvents() {
+    const ws = new WebSocket(nodeURL.replace(/^http/, "ws") + "/v1/events");
+
+    ws.onopen = function () {
+        document.getElementById("live").innerText = "on";
+    };
+
+    ws.onmessage = function (msg) {
+        const evt = JSON.parse(msg.data);
+        switch (evt.type) {
+        case "tx_accepted":
+        case "block_mined":
+        case "block_received":
+        case "reorg":
+            if (document.getElementById("details").style.display != "block") {
+                load();
+            }
+            break;
+        }
+    };
+
+    // Try again later if the node goes away.
+    ws.onclose = function () {
+        document.getElementById("live").innerText = "off";
+        setTimeout(connectEvents, 5000);
+    };
+}
+
+// =============================================================================
+
+function short(value) {
+    if (!value || value.length <= 18) {
+        return value;
+    }
+    return value.substring(0, 10) + "..." + value.substring(value.length - 6);
+}
+
+function formatTime(ms) {
+    if (!ms) {
+        return "";
+    }
+    return new Date(ms).toLocaleString();
+}
+
+function clearErrors() {
+    document.getElementById("errors").innerText = "";
+}
+
+function showError(err) {
+    document.getElementById("errors").innerText = err.message || err;
+}
@@ -0,0 +1,233 @@
+// This program audits a blockchain database with no node running. Every block
+// is checked against the rules a node applies: the hash links each block to
+// its parent, the hash solves the difficulty, the merkle root matches the
+// transactions, every transaction is signed by its sender and the state root
+// in each header matches the state produced by replaying the chain. The
+// final state root is reported and can be compared with a root the student
+// submitted. Unlike a node, the audit doesn't stop at the first problem, so a
+// grader sees everything wrong with a chain. The report is written as JSON
+// and the program exits with a non-zero status when the chain is invalid.
+//
+//	go run app/tooling/audit/main.go -db-path zblock/miner1/
+//	go run app/tooling/audit/main.go -db-path submissions/alice/ -genesis submissions/alice/genesis.json -state-root 0x... -file alice.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/hash"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/memory"
+)
+
+// Set of checks performed by the audit in addition to the block rules of the
+// database package.
+const (
+	checkDecode            = "decode"
+	checkGenesisDifficulty = "genesis_difficulty"
+	checkDrift             = "timestamp_drift"
+	checkLimits            = "limits"
+	checkSignature         = "signature"
+	checkFinalStateRoot    = "final_state_root"
+)
+
+// report is the machine readable result of the audit.
+type report struct {
+	DBPath            string           `json:"db_path"`
+	Genesis           string           `json:"genesis"`
+	ChainID           uint16           `json:"chain_id"`
+	Valid             bool             `json:"valid"`
+	Blocks            int              `json:"blocks"`
+	Txs               int              `json:"txs"`
+	LatestBlockHash   string           `json:"latest_block_hash"`
+	FinalStateRoot    string           `json:"final_state_root"`
+	ExpectedStateRoot string           `json:"expected_state_root,omitempty"`
+	FirstInvalidBlock uint64           `json:"first_invalid_block,omitempty"`
+	Checks            map[string]tally `json:"checks"`
+	Failures          []failure        `json:"failures"`
+}
+
+// tally counts the outcome of a check across the chain.
+type tally struct {
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+}
+
+// failure describes a check a block or transaction failed.
+type failure struct {
+	Block  uint64 `json:"block"`
+	Check  string `json:"check"`
+	TxHash string `json:"tx_hash,omitempty"`
+	Error  string `json:"error"`
+}
+
+// record adds the outcome of the check to the report.
+func (r *report) record(block uint64, check string, txHash string, err error) {
+	t := r.Checks[check]
+	defer func() { r.Checks[check] = t }()
+
+	if err == nil {
+		t.Passed++
+		return
+	}
+
+	t.Failed++
+	r.Failures = append(r.Failures, failure{
+		Block:  block,
+		Check:  check,
+		TxHash: txHash,
+		Error:  err.Error(),
+	})
+
+	if r.Valid || block < r.FirstInvalidBlock {
+		r.FirstInvalidBlock = block
+	}
+	r.Valid = false
+}
+
+// =============================================================================
+
+func main() {
+	valid, err := run()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !valid {
+		os.Exit(1)
+	}
+}
+
+func run() (bool, error) {
+	dbPath := flag.String("db-path", "zblock/miner1/", "database directory of the chain to audit")
+	engine := flag.String("storage", "disk", "storage engine of the database, disk or kv")
+	genesisPath := flag.String("genesis", "zblock/genesis.json", "genesis file the chain was built with")
+	stateRoot := flag.String("state-root", "", "state root the chain is expected to end with, not checked when empty")
+	file := flag.String("file", "", "file to write the report to, stdout when empty")
+	flag.Parse()
+
+	if _, err := os.Stat(*dbPath); err != nil {
+		return false, err
+	}
+
+	gen, err := genesis.Load(*genesisPath)
+	if err != nil {
+		return false, err
+	}
+
+	if err := hash.Select(gen.Hash); err != nil {
+		return false, err
+	}
+
+	stored, err := storage.New(*engine, *dbPath)
+	if err != nil {
+		return false, err
+	}
+	defer stored.Close()
+
+	rpt, err := audit(gen, stored, *stateRoot)
+	if err != nil {
+		return false, err
+	}
+	rpt.DBPath = *dbPath
+	rpt.Genesis = *genesisPath
+
+	var w io.Writer = os.Stdout
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return false, err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rpt); err != nil {
+		return false, err
+	}
+
+	return rpt.Valid, nil
+}
+
+// audit checks every block in storage and replays the chain from genesis
+// into an in-memory database to check the state roots.
+func audit(gen genesis.Genesis, stored database.Storage, expectedRoot string) (report, error) {
+	rpt := report{
+		ChainID:           gen.ChainID,
+		Valid:             true,
+		ExpectedStateRoot: expectedRoot,
+		Checks:            make(map[string]tally),
+		Failures:          []failure{},
+	}
+
+	db, err := database.New(gen, memory.New(), func(...any) {})
+	if err != nil {
+		return report{}, err
+	}
+
+	var prev database.Block
+
+	iter := stored.ForEach()
+	for blockData, err := iter.Next(); !iter.Done(); blockData, err = iter.Next() {
+		if err != nil {
+			return report{}, err
+		}
+
+		// A block that can't be decoded can't be checked or replayed, so
+		// the rest of the chain can't be audited.
+		block, err := database.ToBlock(blockData)
+		rpt.record(blockData.Header.Number, checkDecode, "", err)
+		if err != nil {
+			break
+		}
+
+		for _, result := range block.Check(prev, db.StateRoots()) {
+			rpt.record(block.Header.Number, result.Rule, "", result.Err)
+		}
+
+		var errDifficulty error
+		if block.Header.Difficulty < gen.Difficulty {
+			errDifficulty = fmt.Errorf("block difficulty %d is less than the genesis difficulty %d", block.Header.Difficulty, gen.Difficulty)
+		}
+		rpt.record(block.Header.Number, checkGenesisDifficulty, "", errDifficulty)
+
+		// The block rules check the timestamp against the chain, only how far
+		// it is into the future is left to check.
+		rpt.record(block.Header.Number, checkDrift, "", block.ValidateTimestamp(database.Block{}, 0, 0))
+		rpt.record(block.Header.Number, checkLimits, "", block.ValidateLimits(gen.TransPerBlock, gen.MaxTxDataBytes))
+
+		for _, tx := range block.MerkleTree.Values() {
+			rpt.record(block.Header.Number, checkSignature, tx.TxHash(), tx.Validate(gen.ChainID))
+			rpt.Txs++
+		}
+
+		db.ApplyBlock(block)
+
+		prev = block
+		rpt.Blocks++
+	}
+
+	rpt.FinalStateRoot = db.HashState()
+	if rpt.Blocks > 0 {
+		rpt.LatestBlockHash = prev.Hash()
+	}
+
+	if expectedRoot != "" {
+		var err error
+		if rpt.FinalStateRoot != expectedRoot {
+			err = fmt.Errorf("final state root %s, expected %s", rpt.FinalStateRoot, expectedRoot)
+		}
+		rpt.record(prev.Header.Number, checkFinalStateRoot, "", err)
+	}
+
+	return rpt, nil
+}
@@ -0,0 +1,208 @@
+// This program mints the certificates used to secure the private peer API
+// with mutual TLS. The ca command creates the certificate authority every
+// node trusts. The node command creates a certificate for a node signed by
+// the authority, which the node presents both as a server to the peers that
+// call it and as a client to the peers it calls.
+//
+//	go run app/tooling/certs/main.go ca -dir zblock/certs/
+//	go run app/tooling/certs/main.go node -dir zblock/certs/ -name miner1 -hosts 0.0.0.0,127.0.0.1,localhost
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Names of the files holding the certificate authority in the directory.
+const (
+	caCertFile = "ca.crt"
+	caKeyFile  = "ca.key"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("usage: certs ca|node [flags]")
+		os.Exit(1)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "ca":
+		err = createCA(os.Args[2:])
+	case "node":
+		err = createNode(os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown command %q", os.Args[1])
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// =============================================================================
+
+// createCA writes a self signed certificate authority to the directory.
+func createCA(args []string) error {
+	fs := flag.NewFlagSet("ca", flag.ExitOnError)
+	dir := fs.String("dir", "zblock/certs/", "directory to write the certificate authority to")
+	name := fs.String("name", "Ardan Blockchain CA", "common name of the certificate authority")
+	validFor := fs.Duration("valid-for", 10*365*24*time.Hour, "how long the certificate authority is valid")
+	fs.Parse(args)
+
+	if _, err := os.Stat(filepath.Join(*dir, caKeyFile)); err == nil {
+		return fmt.Errorf("certificate authority already exists in %s", *dir)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	tmpl := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: *name},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(*validFor),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return err
+	}
+
+	if err := writePair(*dir, caCertFile, caKeyFile, der, key); err != nil {
+		return err
+	}
+
+	fmt.Printf("certificate authority written to %s\n", *dir)
+
+	return nil
+}
+
+// createNode writes a certificate for a node signed by the certificate
+// authority in the directory.
+func createNode(args []string) error {
+	fs := flag.NewFlagSet("node", flag.ExitOnError)
+	dir := fs.String("dir", "zblock/certs/", "directory holding the certificate authority")
+	name := fs.String("name", "", "name of the node, used for the file names and common name")
+	hosts := fs.String("hosts", "0.0.0.0,127.0.0.1,localhost", "comma separated IP addresses and DNS names the node is reached at")
+	validFor := fs.Duration("valid-for", 365*24*time.Hour, "how long the certificate is valid")
+	fs.Parse(args)
+
+	if *name == "" {
+		return errors.New("name is required")
+	}
+
+	ca, err := tls.LoadX509KeyPair(filepath.Join(*dir, caCertFile), filepath.Join(*dir, caKeyFile))
+	if err != nil {
+		return fmt.Errorf("loading certificate authority: %w", err)
+	}
+
+	caCert, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	serial, err := serialNumber()
+	if err != nil {
+		return err
+	}
+
+	// The node presents the same certificate when it accepts and makes peer
+	// requests, so it's valid for both server and client authentication.
+	now := time.Now()
+	tmpl := x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: *name},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(*validFor),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	for _, host := range strings.Split(*hosts, ",") {
+		host = strings.TrimSpace(host)
+		switch ip := net.ParseIP(host); {
+		case host == "":
+		case ip != nil:
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		default:
+			tmpl.DNSNames = append(tmpl.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, caCert, &key.PublicKey, ca.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	if err := writePair(*dir, *name+".crt", *name+".key", der, key); err != nil {
+		return err
+	}
+
+	fmt.Printf("certificate for %s written to %s\n", *name, *dir)
+
+	return nil
+}
+
+// =============================================================================
+
+// serialNumber returns a random serial number for a certificate.
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// writePair writes the certificate and private key as PEM files. The key is
+// only readable by the owner.
+func writePair(dir string, certFile string, keyFile string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, certFile), certPEM, 0644); err != nil {
+		return err
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(filepath.Join(dir, keyFile), keyPEM, 0600); err != nil {
+		return err
+	}
+
+	return nil
+}
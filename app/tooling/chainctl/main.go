@@ -0,0 +1,553 @@
+// This program moves a blockchain between machines. The export command writes
+// the genesis file and every block in a node's database into a single gzip'd
+// archive. The import command restores the archive into a new database
+// directory, validating the hash of every block and then replaying the chain
+// so the blocks and state roots are checked the same way a node checks them
+// on startup. The snapshot command writes the state at a block signed by the
+// node's key, which a new node can start from instead of replaying the chain.
+// The reindex command rebuilds the transaction index of a database directory
+// from its blocks, for directories written before the index existed. The
+// migrate command rewrites the blocks of a database directory into a new
+// directory in the current block format, mining each upgraded block again.
+//
+//	go run app/tooling/chainctl/main.go export -db-path zblock/miner1/ -file chain.tar.gz
+//	go run app/tooling/chainctl/main.go import -file chain.tar.gz -db-path zblock/student/
+//	go run app/tooling/chainctl/main.go import -file chain.tar.gz -db-path zblock/student-kv/ -storage kv
+//	go run app/tooling/chainctl/main.go snapshot -db-path zblock/miner1/ -key zblock/accounts/miner1.ecdsa -file snapshot.json
+//	go run app/tooling/chainctl/main.go reindex -db-path zblock/miner1/
+//	go run app/tooling/chainctl/main.go migrate -db-path zblock/miner1/ -out zblock/miner1-v1/
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/hash"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/memory"
+)
+
+// Names of the entries stored in the archive.
+const (
+	genesisEntry = "genesis.json"
+	blocksDir    = "blocks/"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("usage: chainctl export|import|snapshot|reindex|migrate [flags]")
+		os.Exit(1)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = export(os.Args[2:])
+	case "import":
+		err = importChain(os.Args[2:])
+	case "snapshot":
+		err = snapshot(os.Args[2:])
+	case "reindex":
+		err = reindex(os.Args[2:])
+	case "migrate":
+		err = migrate(os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown command %q", os.Args[1])
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// =============================================================================
+
+// export writes the genesis file and blocks for a database into an archive.
+func export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dbPath := fs.String("db-path", "zblock/miner1/", "database directory of the node to export")
+	engine := fs.String("storage", "disk", "storage engine of the database, disk or kv")
+	genesisPath := fs.String("genesis", "zblock/genesis.json", "genesis file the chain was built with")
+	file := fs.String("file", "chain.tar.gz", "archive to write")
+	fs.Parse(args)
+
+	if _, err := os.Stat(*dbPath); err != nil {
+		return err
+	}
+
+	gen, err := genesis.Load(*genesisPath)
+	if err != nil {
+		return err
+	}
+
+	if err := hash.Select(gen.Hash); err != nil {
+		return err
+	}
+
+	storage, err := storage.New(*engine, *dbPath)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	f, err := os.Create(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	if err := writeEntry(tw, genesisEntry, gen); err != nil {
+		return err
+	}
+
+	var blocks int
+	iter := storage.ForEach()
+	for blockData, err := iter.Next(); !iter.Done(); blockData, err = iter.Next() {
+		if err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf("%s%d.json", blocksDir, blockData.Header.Number)
+		if err := writeEntry(tw, name, blockData); err != nil {
+			return err
+		}
+		blocks++
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d blocks to %s\n", blocks, *file)
+
+	return nil
+}
+
+// writeEntry adds the value as a JSON file to the archive.
+func writeEntry(tw *tar.Writer, name string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	hdr := tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+
+	if err := tw.WriteHeader(&hdr); err != nil {
+		return err
+	}
+
+	_, err = tw.Write(data)
+	return err
+}
+
+// =============================================================================
+
+// importChain restores the archive into a new database directory.
+func importChain(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	file := fs.String("file", "chain.tar.gz", "archive to import")
+	dbPath := fs.String("db-path", "", "new database directory to restore the blocks into")
+	engine := fs.String("storage", "disk", "storage engine to restore the blocks into, disk or kv")
+	genesisPath := fs.String("genesis", "", "where to write the genesis file, defaults to genesis.json in the database directory")
+	fs.Parse(args)
+
+	if *dbPath == "" {
+		return errors.New("a database directory is required")
+	}
+	if *genesisPath == "" {
+		*genesisPath = filepath.Join(*dbPath, genesisEntry)
+	}
+
+	// Refuse to mix the imported blocks with an existing chain.
+	if entries, err := os.ReadDir(*dbPath); err == nil && len(entries) > 0 {
+		return fmt.Errorf("database directory %s is not empty", *dbPath)
+	}
+
+	gen, blocks, err := readArchive(*file)
+	if err != nil {
+		return err
+	}
+
+	storage, err := storage.New(*engine, *dbPath)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	if err := restore(storage, gen, blocks); err != nil {
+		os.RemoveAll(*dbPath)
+		return err
+	}
+
+	if err := genesis.Save(*genesisPath, gen); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d blocks into %s\n", len(blocks), *dbPath)
+	fmt.Printf("Start the node with --state-storage=%s --state-db-path=%s --state-genesis-path=%s\n", *engine, *dbPath, *genesisPath)
+
+	return nil
+}
+
+// readArchive reads the genesis information and blocks from the archive. The
+// blocks are returned in order and must form a complete chain from block 1.
+func readArchive(file string) (genesis.Genesis, []database.BlockData, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return genesis.Genesis{}, nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return genesis.Genesis{}, nil, err
+	}
+	defer gz.Close()
+
+	var gen genesis.Genesis
+	var foundGenesis bool
+	blocks := make(map[uint64]database.BlockData)
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return genesis.Genesis{}, nil, err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		switch {
+		case hdr.Name == genesisEntry:
+			if err := json.NewDecoder(tr).Decode(&gen); err != nil {
+				return genesis.Genesis{}, nil, fmt.Errorf("decoding genesis: %w", err)
+			}
+			foundGenesis = true
+
+		case strings.HasPrefix(hdr.Name, blocksDir):
+			var blockData database.BlockData
+			if err := json.NewDecoder(tr).Decode(&blockData); err != nil {
+				return genesis.Genesis{}, nil, fmt.Errorf("decoding %s: %w", hdr.Name, err)
+			}
+
+			num, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(hdr.Name, blocksDir), ".json"), 10, 64)
+			if err != nil || num != blockData.Header.Number {
+				return genesis.Genesis{}, nil, fmt.Errorf("%s holds block %d", hdr.Name, blockData.Header.Number)
+			}
+			blocks[num] = blockData
+		}
+	}
+
+	if !foundGenesis {
+		return genesis.Genesis{}, nil, errors.New("archive is missing the genesis file")
+	}
+
+	chain := make([]database.BlockData, len(blocks))
+	for i := range chain {
+		blockData, exists := blocks[uint64(i+1)]
+		if !exists {
+			return genesis.Genesis{}, nil, fmt.Errorf("archive is missing block %d", i+1)
+		}
+		chain[i] = blockData
+	}
+
+	return gen, chain, nil
+}
+
+// restore checks the hash of each block and writes it to storage. Once all
+// the blocks are written, the database is constructed which replays the
+// chain and validates every block against the state it builds.
+func restore(storage database.Storage, gen genesis.Genesis, blocks []database.BlockData) error {
+	for _, blockData := range blocks {
+		block, err := database.ToBlock(blockData)
+		if err != nil {
+			return fmt.Errorf("block %d: %w", blockData.Header.Number, err)
+		}
+
+		if hash := block.Hash(); hash != blockData.Hash {
+			return fmt.Errorf("block %d: hash mismatch, got %s, exp %s", blockData.Header.Number, blockData.Hash, hash)
+		}
+
+		if err := storage.Write(blockData); err != nil {
+			return err
+		}
+	}
+
+	noLog := func(v ...any) {}
+
+	db, err := database.New(gen, storage, noLog)
+	if err != nil {
+		return fmt.Errorf("validating chain: %w", err)
+	}
+	defer db.Close()
+
+	return nil
+}
+
+// =============================================================================
+
+// snapshot writes the signed state of the chain at a block.
+func snapshot(args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	dbPath := fs.String("db-path", "zblock/miner1/", "database directory of the node to snapshot")
+	engine := fs.String("storage", "disk", "storage engine of the database, disk or kv")
+	genesisPath := fs.String("genesis", "zblock/genesis.json", "genesis file the chain was built with")
+	keyPath := fs.String("key", "zblock/accounts/miner1.ecdsa", "private key to sign the snapshot with")
+	num := fs.Uint64("block", 0, "block to snapshot, defaults to the latest block")
+	file := fs.String("file", "snapshot.json", "snapshot file to write")
+	fs.Parse(args)
+
+	gen, err := genesis.Load(*genesisPath)
+	if err != nil {
+		return err
+	}
+
+	if err := hash.Select(gen.Hash); err != nil {
+		return err
+	}
+
+	privateKey, err := crypto.LoadECDSA(*keyPath)
+	if err != nil {
+		return err
+	}
+
+	storage, err := storage.New(*engine, *dbPath)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	if *num == 0 {
+		iter := storage.ForEach()
+		for blockData, err := iter.Next(); !iter.Done(); blockData, err = iter.Next() {
+			if err != nil {
+				return err
+			}
+			*num = blockData.Header.Number
+		}
+	}
+
+	noLog := func(v ...any) {}
+
+	snap, err := database.NewSnapshot(gen, storage, *num, noLog)
+	if err != nil {
+		return err
+	}
+
+	signed, err := snap.Sign(privateKey)
+	if err != nil {
+		return err
+	}
+
+	if err := signed.Save(*file); err != nil {
+		return err
+	}
+
+	signer := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	fmt.Printf("Wrote snapshot of block %d with %d accounts to %s\n", *num, len(snap.Accounts), *file)
+	fmt.Printf("Start a node with --state-snapshot-path=%s --state-snapshot-signer=%s\n", *file, signer)
+
+	return nil
+}
+
+// =============================================================================
+
+// reindex rebuilds the transaction index of a database from its blocks.
+func reindex(args []string) error {
+	fs := flag.NewFlagSet("reindex", flag.ExitOnError)
+	dbPath := fs.String("db-path", "zblock/miner1/", "database directory of the node to reindex")
+	engine := fs.String("storage", "disk", "storage engine of the database, disk or kv")
+	fs.Parse(args)
+
+	if _, err := os.Stat(*dbPath); err != nil {
+		return err
+	}
+
+	storage, err := storage.New(*engine, *dbPath)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	indexer, ok := storage.(database.TxIndexer)
+	if !ok {
+		return database.ErrTxIndexNotSupported
+	}
+
+	if err := indexer.ResetTxIndex(); err != nil {
+		return err
+	}
+
+	var blocks, txs int
+	iter := storage.ForEach()
+	for blockData, err := iter.Next(); !iter.Done(); blockData, err = iter.Next() {
+		if err != nil {
+			return err
+		}
+
+		if err := indexer.IndexTxs(blockData); err != nil {
+			return err
+		}
+		blocks++
+		txs += len(blockData.Trans)
+	}
+
+	fmt.Printf("Indexed %d transactions in %d blocks of %s\n", txs, blocks, *dbPath)
+
+	return nil
+}
+
+// =============================================================================
+
+// migrate rewrites the blocks of a database into a new database directory in
+// the current block format. Changing the format of a block changes its hash,
+// so from the first block upgraded every block is mined again on top of the
+// upgraded parent. The transactions are kept, so the chain arrives at the same
+// state. Every node of a network must move to the migrated chain together.
+func migrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dbPath := fs.String("db-path", "zblock/miner1/", "database directory of the node to migrate")
+	out := fs.String("out", "", "new database directory to write the migrated blocks into")
+	engine := fs.String("storage", "disk", "storage engine of the databases, disk or kv")
+	genesisPath := fs.String("genesis", "zblock/genesis.json", "genesis file the chain was built with")
+	workers := fs.Int("workers", 0, "goroutines mining the upgraded blocks, defaults to the number of CPUs")
+	fs.Parse(args)
+
+	if *out == "" {
+		return errors.New("a new database directory is required")
+	}
+	if _, err := os.Stat(*dbPath); err != nil {
+		return err
+	}
+
+	// Refuse to mix the migrated blocks with an existing chain.
+	if entries, err := os.ReadDir(*out); err == nil && len(entries) > 0 {
+		return fmt.Errorf("database directory %s is not empty", *out)
+	}
+
+	gen, err := genesis.Load(*genesisPath)
+	if err != nil {
+		return err
+	}
+
+	if err := hash.Select(gen.Hash); err != nil {
+		return err
+	}
+
+	oldStorage, err := storage.New(*engine, *dbPath)
+	if err != nil {
+		return err
+	}
+	defer oldStorage.Close()
+
+	newStorage, err := storage.New(*engine, *out)
+	if err != nil {
+		return err
+	}
+	defer newStorage.Close()
+
+	if err := upgradeChain(gen, oldStorage, newStorage, *workers); err != nil {
+		os.RemoveAll(*out)
+		return err
+	}
+
+	fmt.Printf("Start the node with --state-storage=%s --state-db-path=%s\n", *engine, *out)
+
+	return nil
+}
+
+// upgradeChain replays the old chain in memory to know the state every block
+// is applied to, writing each block in the current format to the new storage.
+// Once all the blocks are written, the database is constructed which replays
+// the migrated chain and validates every block against the state it builds.
+func upgradeChain(gen genesis.Genesis, oldStorage database.Storage, newStorage database.Storage, workers int) error {
+	noLog := func(v ...any) {}
+
+	db, err := database.New(gen, memory.New(), noLog)
+	if err != nil {
+		return err
+	}
+
+	var prevOld, prevNew database.Block
+	var blocks, upgraded int
+
+	iter := oldStorage.ForEach()
+	for blockData, err := iter.Next(); !iter.Done(); blockData, err = iter.Next() {
+		if err != nil {
+			return err
+		}
+
+		block, err := database.ToBlock(blockData)
+		if err != nil {
+			return fmt.Errorf("block %d: %w", blockData.Header.Number, err)
+		}
+
+		// The old chain must be intact before it's rewritten.
+		if hash := block.Hash(); hash != blockData.Hash {
+			return fmt.Errorf("block %d: hash mismatch, got %s, exp %s", blockData.Header.Number, blockData.Hash, hash)
+		}
+		roots := db.StateRoots()
+		if block.Header.StateRoot != roots.State {
+			return fmt.Errorf("block %d: %w, current %s, expected %s", block.Header.Number, database.ErrStateRoot, roots.State, block.Header.StateRoot)
+		}
+
+		// A block already in the current format is kept as long as its
+		// parent wasn't upgraded.
+		newBlock := block
+		if block.Header.Version != database.CurrentBlockVersion || prevNew.Hash() != prevOld.Hash() {
+			if newBlock, err = block.Upgrade(context.Background(), prevNew, roots, workers, noLog); err != nil {
+				return fmt.Errorf("block %d: %w", block.Header.Number, err)
+			}
+			upgraded++
+		}
+
+		db.ApplyBlock(newBlock)
+
+		if err := newStorage.Write(database.NewBlockData(newBlock)); err != nil {
+			return err
+		}
+
+		prevOld, prevNew = block, newBlock
+		blocks++
+	}
+
+	if _, err := database.New(gen, newStorage, noLog); err != nil {
+		return fmt.Errorf("validating chain: %w", err)
+	}
+
+	fmt.Printf("Migrated %d blocks, upgraded %d to version %d\n", blocks, upgraded, database.CurrentBlockVersion)
+
+	return nil
+}
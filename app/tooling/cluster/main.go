@@ -0,0 +1,265 @@
+// This program bootstraps a local network of nodes. It generates a key for
+// every node, writes a shared genesis file, creates a data directory for each
+// node and then launches the nodes on distinct ports wired to each other as
+// peers. Stopping this program stops every node.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+)
+
+var (
+	nodes       int
+	dir         string
+	genesisPath string
+	difficulty  uint
+	balance     uint64
+	nodeBin     string
+	reset       bool
+)
+
+func init() {
+	flag.IntVar(&nodes, "nodes", 3, "number of nodes to launch")
+	flag.StringVar(&dir, "dir", "zblock/cluster/", "directory for the keys, genesis file and node data")
+	flag.StringVar(&genesisPath, "genesis", "zblock/genesis.json", "genesis file used as the template for the cluster")
+	flag.UintVar(&difficulty, "difficulty", 0, "override the genesis difficulty, 0 keeps the template value")
+	flag.Uint64Var(&balance, "balance", 0, "starting balance for each node account")
+	flag.StringVar(&nodeBin, "node", "", "node binary to launch, built from app/services/node when empty")
+	flag.BoolVar(&reset, "reset", false, "remove any existing cluster data before starting")
+}
+
+// node represents the configuration for a single node in the cluster.
+type node struct {
+	name        string
+	publicHost  string
+	privateHost string
+	debugHost   string
+}
+
+func main() {
+	flag.Parse()
+
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	if nodes < 1 || nodes > 9 {
+		return errors.New("the number of nodes must be between 1 and 9")
+	}
+
+	if reset {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+
+	keysFolder := filepath.Join(dir, "accounts")
+	if err := os.MkdirAll(keysFolder, 0755); err != nil {
+		return err
+	}
+
+	// Each node listens on its own set of ports. The first node uses the
+	// same ports as a node started with the defaults.
+	cluster := make([]node, nodes)
+	for i := range cluster {
+		cluster[i] = node{
+			name:        fmt.Sprintf("node%d", i+1),
+			publicHost:  fmt.Sprintf("0.0.0.0:%d", 8080+i*100),
+			privateHost: fmt.Sprintf("0.0.0.0:%d", 9080+i*100),
+			debugHost:   fmt.Sprintf("0.0.0.0:%d", 7080+i*100),
+		}
+	}
+
+	accounts, err := generateKeys(keysFolder, cluster)
+	if err != nil {
+		return err
+	}
+
+	clusterGenesis := filepath.Join(dir, "genesis.json")
+	if err := writeGenesis(clusterGenesis, accounts); err != nil {
+		return err
+	}
+
+	bin, err := buildNode()
+	if err != nil {
+		return err
+	}
+
+	// Every node is given the private host of every other node so they are
+	// wired to each other on startup, along with the account each of them
+	// signs its status with.
+	var cmds []*exec.Cmd
+	defer func() {
+		stopNodes(cmds)
+		log.Printf("cluster stopped")
+	}()
+
+	for _, n := range cluster {
+		var peers, identities []string
+		for i, other := range cluster {
+			if other.name != n.name {
+				peers = append(peers, other.privateHost)
+				identities = append(identities, fmt.Sprintf("%s=%s", other.privateHost, accounts[i]))
+			}
+		}
+
+		cmd, err := startNode(bin, n, keysFolder, clusterGenesis, peers, identities)
+		if err != nil {
+			return err
+		}
+		cmds = append(cmds, cmd)
+
+		log.Printf("started %s: public %s private %s debug %s log %s", n.name, n.publicHost, n.privateHost, n.debugHost, logPath(n))
+	}
+
+	// Wait for the operator to stop the cluster.
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
+
+	sig := <-shutdown
+	log.Printf("received %s, stopping the cluster", sig)
+
+	return nil
+}
+
+// generateKeys makes sure there is a key for every node and returns the
+// account for each node. Existing keys are reused so a cluster can be
+// restarted with the same chain.
+func generateKeys(keysFolder string, cluster []node) ([]database.AccountID, error) {
+	accounts := make([]database.AccountID, len(cluster))
+
+	for i, n := range cluster {
+		path := filepath.Join(keysFolder, n.name+".ecdsa")
+
+		privateKey, err := crypto.LoadECDSA(path)
+		if err != nil {
+			if privateKey, err = crypto.GenerateKey(); err != nil {
+				return nil, err
+			}
+			if err := crypto.SaveECDSA(path, privateKey); err != nil {
+				return nil, err
+			}
+		}
+
+		accounts[i] = database.PublicKeyToAccountID(privateKey.PublicKey)
+	}
+
+	return accounts, nil
+}
+
+// writeGenesis writes the genesis file shared by the cluster using the
+// template. An existing genesis file is left alone since the nodes may
+// already have blocks built on it.
+func writeGenesis(path string, accounts []database.AccountID) error {
+	if _, err := os.Stat(path); err == nil {
+		log.Printf("using existing genesis file %s", path)
+		return nil
+	}
+
+	gen, err := genesis.Load(genesisPath)
+	if err != nil {
+		return err
+	}
+
+	if difficulty > 0 {
+		gen.Difficulty = uint16(difficulty)
+	}
+
+	if balance > 0 {
+		if gen.Balances == nil {
+			gen.Balances = make(map[string]uint64)
+		}
+		for _, accountID := range accounts {
+			gen.Balances[string(accountID)] = balance
+		}
+	}
+
+	return genesis.Save(path, gen)
+}
+
+// buildNode returns the node binary to launch, building it if one wasn't
+// provided.
+func buildNode() (string, error) {
+	if nodeBin != "" {
+		return nodeBin, nil
+	}
+
+	bin, err := filepath.Abs(filepath.Join(dir, "node"))
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("building node: %s", bin)
+
+	cmd := exec.Command("go", "build", "-o", bin, "./app/services/node")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("building node: %w", err)
+	}
+
+	return bin, nil
+}
+
+// startNode launches the node with its output written to a log file.
+func startNode(bin string, n node, keysFolder string, genesisPath string, peers []string, identities []string) (*exec.Cmd, error) {
+	f, err := os.Create(logPath(n))
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"--web-public-host", n.publicHost,
+		"--web-private-host", n.privateHost,
+		"--web-debug-host", n.debugHost,
+		"--state-beneficiary", n.name,
+		"--state-keys-folder", keysFolder,
+		"--state-genesis-path", genesisPath,
+		"--state-db-path", filepath.Join(dir, n.name) + "/",
+	}
+	if len(peers) > 0 {
+		args = append(args, "--state-origin-peers", strings.Join(peers, ";"))
+		args = append(args, "--state-peer-identities", strings.Join(identities, ";"))
+	}
+
+	cmd := exec.Command(bin, args...)
+	cmd.Stdout = f
+	cmd.Stderr = f
+
+	if err := cmd.Start(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("starting %s: %w", n.name, err)
+	}
+
+	return cmd, nil
+}
+
+// stopNodes asks every node to shut down and waits for them to exit.
+func stopNodes(cmds []*exec.Cmd) {
+	for _, cmd := range cmds {
+		cmd.Process.Signal(syscall.SIGINT)
+	}
+
+	for _, cmd := range cmds {
+		cmd.Wait()
+	}
+}
+
+func logPath(n node) string {
+	return filepath.Join(dir, n.name+".log")
+}
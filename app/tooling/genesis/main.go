@@ -0,0 +1,207 @@
+// This program bootstraps a test network for a class. The new command
+// creates a set of funded accounts, writes a genesis file funding them, an
+// encrypted key file for each account and a manifest listing the accounts
+// with the password that unlocks each key file. Hand each student their key
+// file and password, start the nodes with the genesis file and the students
+// can use the wallet with their account straight away.
+//
+//	go run app/tooling/genesis/main.go new --accounts 50 --balance 1000
+//	go run app/tooling/genesis/main.go new -dir zblock/classroom/ -accounts 30 -password secret -chain-id 7
+//	go run app/tooling/genesis/main.go new -dir zblock/keccak/ -accounts 5 -hash keccak256
+//
+// The wallet reads an encrypted key file when the account has no plain key.
+//
+//	go run app/wallet/cli/main.go balance -p zblock/classroom/keys/ -a student01 --password <password>
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/hash"
+	"github.com/ardanlabs/blockchain/foundation/keystore"
+)
+
+// Names of the files and directories written to the network directory.
+const (
+	genesisFile  = "genesis.json"
+	manifestFile = "manifest.json"
+	keysDir      = "keys"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("usage: genesis new [flags]")
+		os.Exit(1)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "new":
+		err = newNetwork(os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown command %q", os.Args[1])
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// =============================================================================
+
+// manifest lists the accounts funded by the genesis file.
+type manifest struct {
+	Genesis  string    `json:"genesis"`
+	ChainID  uint16    `json:"chain_id"`
+	Accounts []account `json:"accounts"`
+}
+
+// account represents an account funded by the genesis file.
+type account struct {
+	Name      string             `json:"name"`
+	AccountID database.AccountID `json:"account_id"`
+	Balance   uint64             `json:"balance"`
+	KeyFile   string             `json:"key_file"`
+	Password  string             `json:"password"`
+}
+
+// newNetwork writes the genesis file, key files and manifest for a new test
+// network to the directory.
+func newNetwork(args []string) error {
+	fs := flag.NewFlagSet("new", flag.ExitOnError)
+	dir := fs.String("dir", "zblock/classroom/", "directory to write the network to")
+	accounts := fs.Int("accounts", 10, "number of funded accounts to create")
+	balance := fs.Uint64("balance", 1_000_000, "starting balance for each account")
+	prefix := fs.String("prefix", "student", "prefix of the account names, followed by the account number")
+	password := fs.String("password", "", "password for every key file, a random password per account when empty")
+	template := fs.String("genesis", "zblock/genesis.json", "genesis file used as the template for the chain settings")
+	chainID := fs.Uint("chain-id", 0, "chain id of the network, 0 keeps the chain id of the template")
+	difficulty := fs.Uint("difficulty", 0, "mining difficulty of the network, 0 keeps the difficulty of the template")
+	hashName := fs.String("hash", "", "hash function of the network, sha256, keccak256 or sha3-256, empty keeps the hash function of the template")
+	fs.Parse(args)
+
+	if *accounts < 1 {
+		return errors.New("at least 1 account is required")
+	}
+
+	genesisPath := filepath.Join(*dir, genesisFile)
+	if _, err := os.Stat(genesisPath); err == nil {
+		return fmt.Errorf("a network already exists in %s", *dir)
+	}
+
+	// The template only provides the chain settings, the network is funded
+	// with the new accounts alone.
+	gen, err := genesis.Load(*template)
+	if err != nil {
+		return err
+	}
+	gen.Date = time.Now().UTC().Truncate(time.Second)
+	gen.Balances = make(map[string]uint64, *accounts)
+	if *chainID != 0 {
+		gen.ChainID = uint16(*chainID)
+	}
+	if *difficulty != 0 {
+		gen.Difficulty = uint16(*difficulty)
+	}
+	if *hashName != "" {
+		if _, err := hash.New(*hashName); err != nil {
+			return err
+		}
+		gen.Hash = *hashName
+	}
+
+	keysPath := filepath.Join(*dir, keysDir)
+	if err := os.MkdirAll(keysPath, 0755); err != nil {
+		return err
+	}
+
+	m := manifest{
+		Genesis: genesisPath,
+		ChainID: gen.ChainID,
+	}
+
+	width := len(strconv.Itoa(*accounts))
+	for i := 1; i <= *accounts; i++ {
+		privateKey, err := crypto.GenerateKey()
+		if err != nil {
+			return err
+		}
+
+		pass := *password
+		if pass == "" {
+			if pass, err = randomPassword(); err != nil {
+				return err
+			}
+		}
+
+		name := fmt.Sprintf("%s%0*d", *prefix, width, i)
+		path := filepath.Join(keysPath, name+keystore.Extension)
+		if err := keystore.Save(path, privateKey, pass); err != nil {
+			return err
+		}
+
+		accountID := database.PublicKeyToAccountID(privateKey.PublicKey)
+		gen.Balances[string(accountID)] = *balance
+
+		m.Accounts = append(m.Accounts, account{
+			Name:      name,
+			AccountID: accountID,
+			Balance:   *balance,
+			KeyFile:   path,
+			Password:  pass,
+		})
+	}
+
+	if err := genesis.Save(genesisPath, gen); err != nil {
+		return err
+	}
+
+	// The manifest holds every password so it's only readable by the owner.
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(*dir, manifestFile), data, 0600); err != nil {
+		return err
+	}
+
+	printManifest(m)
+
+	fmt.Printf("\nCreated %d accounts in %s\n", len(m.Accounts), *dir)
+	fmt.Printf("Start the nodes with a new database and --state-genesis-path=%s\n", genesisPath)
+	fmt.Printf("Use an account with the wallet: -p %s -a %s --password <password>\n", keysPath, m.Accounts[0].Name)
+
+	return nil
+}
+
+// printManifest prints the accounts in the manifest.
+func printManifest(m manifest) {
+	fmt.Printf("Chain ID: %d\n\n", m.ChainID)
+	fmt.Printf("%-12s %-42s %12s  %s\n", "NAME", "ACCOUNT", "BALANCE", "PASSWORD")
+	for _, a := range m.Accounts {
+		fmt.Printf("%-12s %-42s %12d  %s\n", a.Name, a.AccountID, a.Balance, a.Password)
+	}
+}
+
+// randomPassword returns a random password for a key file.
+func randomPassword() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
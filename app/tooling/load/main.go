@@ -0,0 +1,480 @@
+// This program generates transaction load against one or more nodes to
+// benchmark throughput. The setup command creates a set of test accounts and
+// a genesis file that funds them. Start the nodes with that genesis file and
+// then use the run command to submit transactions between the accounts at a
+// configured rate. When the run completes, a report of the acceptance latency,
+// block production and a reconciliation of the final balances is displayed.
+//
+//	go run app/tooling/load/main.go setup -accounts 20
+//	go run app/tooling/load/main.go run -nodes http://localhost:8080 -rate 50 -duration 30s
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/hash"
+	"github.com/ardanlabs/blockchain/foundation/client"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("usage: load setup|run [flags]")
+		os.Exit(1)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "setup":
+		err = setup(os.Args[2:])
+	case "run":
+		err = run(os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown command %q", os.Args[1])
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// =============================================================================
+
+// setup generates the test account keys and a genesis file funding them.
+func setup(args []string) error {
+	fs := flag.NewFlagSet("setup", flag.ExitOnError)
+	dir := fs.String("dir", "zblock/load/", "directory for the test account keys and genesis file")
+	accounts := fs.Int("accounts", 10, "number of test accounts to create")
+	balance := fs.Uint64("balance", 1_000_000, "starting balance for each test account")
+	template := fs.String("genesis", "zblock/genesis.json", "genesis file used as the template")
+	fs.Parse(args)
+
+	if *accounts < 2 {
+		return errors.New("at least 2 accounts are required")
+	}
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return err
+	}
+
+	gen, err := genesis.Load(*template)
+	if err != nil {
+		return err
+	}
+	if gen.Balances == nil {
+		gen.Balances = make(map[string]uint64)
+	}
+
+	for i := 0; i < *accounts; i++ {
+		privateKey, err := crypto.GenerateKey()
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(*dir, fmt.Sprintf("load%03d.ecdsa", i))
+		if err := crypto.SaveECDSA(path, privateKey); err != nil {
+			return err
+		}
+
+		gen.Balances[string(database.PublicKeyToAccountID(privateKey.PublicKey))] = *balance
+	}
+
+	path := filepath.Join(*dir, "genesis.json")
+	if err := genesis.Save(path, gen); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created %d accounts in %s\n", *accounts, *dir)
+	fmt.Printf("Start the nodes with a new database and --state-genesis-path=%s\n", path)
+
+	return nil
+}
+
+// =============================================================================
+
+// account represents a test account and the nonce for its next transaction.
+type account struct {
+	id         database.AccountID
+	privateKey *ecdsa.PrivateKey
+	nonce      uint64
+}
+
+// result represents the outcome of submitting a single transaction.
+type result struct {
+	tx       database.SignedTx
+	latency  time.Duration
+	accepted bool
+}
+
+// run submits transactions between the test accounts and reports the results.
+func run(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	dir := fs.String("dir", "zblock/load/", "directory containing the test account keys")
+	nodes := fs.String("nodes", "http://localhost:8080", "comma separated list of node public API urls")
+	rate := fs.Int("rate", 20, "transactions per second to submit")
+	duration := fs.Duration("duration", 30*time.Second, "how long to submit transactions")
+	workers := fs.Int("workers", 4, "number of concurrent submitters")
+	value := fs.Uint64("value", 1, "value of each transaction")
+	tip := fs.Uint64("tip", 1, "tip of each transaction")
+	settle := fs.Duration("settle", 2*time.Minute, "max time to wait for the mempool to drain before reporting")
+	fs.Parse(args)
+
+	if *rate < 1 || *workers < 1 {
+		return errors.New("rate and workers must be at least 1")
+	}
+
+	var clients []*client.Client
+	for _, url := range strings.Split(*nodes, ",") {
+		clients = append(clients, client.New(client.Config{URL: strings.TrimSpace(url)}))
+	}
+	cln := clients[0]
+
+	ctx := context.Background()
+
+	gen, err := cln.Genesis(ctx)
+	if err != nil {
+		return fmt.Errorf("genesis: %w", err)
+	}
+
+	// The transactions are tracked by hash so use the hash function of the
+	// chain.
+	if err := hash.Select(gen.Hash); err != nil {
+		return fmt.Errorf("genesis: %w", err)
+	}
+
+	accounts, err := loadAccounts(ctx, cln, *dir)
+	if err != nil {
+		return err
+	}
+
+	if *workers > len(accounts) {
+		*workers = len(accounts)
+	}
+
+	startBalances, err := balances(ctx, cln, accounts)
+	if err != nil {
+		return err
+	}
+
+	startBlock := latestBlockNumber(ctx, cln)
+
+	// The ticker hands out permission to submit a transaction at the
+	// configured rate.
+	tokens := make(chan struct{})
+	go func() {
+		defer close(tokens)
+
+		ticker := time.NewTicker(time.Second / time.Duration(*rate))
+		defer ticker.Stop()
+
+		deadline := time.After(*duration)
+		for {
+			select {
+			case <-ticker.C:
+				select {
+				case tokens <- struct{}{}:
+				default:
+				}
+			case <-deadline:
+				return
+			}
+		}
+	}()
+
+	fmt.Printf("Submitting %d tx/s for %s from %d accounts to %d nodes\n", *rate, *duration, len(accounts), len(clients))
+	start := time.Now()
+
+	// Each worker owns a subset of the accounts so nonces for an account
+	// are always used in order.
+	var mu sync.Mutex
+	var results []result
+
+	var wg sync.WaitGroup
+	wg.Add(*workers)
+	for w := 0; w < *workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+
+			var owned []*account
+			for i := w; i < len(accounts); i += *workers {
+				owned = append(owned, accounts[i])
+			}
+
+			rnd := rand.New(rand.NewSource(time.Now().UnixNano() + int64(w)))
+
+			for i := 0; ; i++ {
+				if _, ok := <-tokens; !ok {
+					return
+				}
+
+				from := owned[i%len(owned)]
+				to := accounts[rnd.Intn(len(accounts))]
+				for to.id == from.id {
+					to = accounts[rnd.Intn(len(accounts))]
+				}
+
+				res := submit(ctx, clients[rnd.Intn(len(clients))], gen.ChainID, from, to.id, *value, *tip)
+
+				mu.Lock()
+				results = append(results, res)
+				mu.Unlock()
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	submitDuration := time.Since(start)
+
+	// Give the nodes time to mine the transactions that were accepted.
+	fmt.Println("Waiting for the mempool to drain")
+	settleDeadline := time.Now().Add(*settle)
+	for time.Now().Before(settleDeadline) {
+		info, err := cln.Accounts(ctx)
+		if err == nil && info.Uncommitted == 0 {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+
+	elapsed := time.Since(start)
+	endBlock := latestBlockNumber(ctx, cln)
+
+	report(results, submitDuration)
+
+	blocks := endBlock - startBlock
+	fmt.Println()
+	fmt.Printf("Blocks:     %d (from %d to %d)\n", blocks, startBlock, endBlock)
+	fmt.Printf("Blocks/sec: %.3f\n", float64(blocks)/elapsed.Seconds())
+
+	return reconcile(ctx, cln, accounts, startBalances, results)
+}
+
+// submit signs and submits a transaction, recording how long the node took
+// to accept it.
+func submit(ctx context.Context, cln *client.Client, chainID uint16, from *account, toID database.AccountID, value uint64, tip uint64) result {
+	tx, err := database.NewTx(chainID, from.nonce, from.id, toID, value, tip, nil)
+	if err != nil {
+		return result{}
+	}
+
+	signedTx, err := tx.Sign(from.privateKey)
+	if err != nil {
+		return result{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err = cln.SubmitTransaction(ctx, signedTx)
+	res := result{
+		tx:       signedTx,
+		latency:  time.Since(start),
+		accepted: err == nil,
+	}
+
+	// Only move to the next nonce once the node has the transaction.
+	if err == nil {
+		from.nonce++
+	}
+
+	return res
+}
+
+// report displays the acceptance counts and latencies.
+func report(results []result, duration time.Duration) {
+	var latencies []time.Duration
+	var accepted int
+	for _, res := range results {
+		if res.accepted {
+			accepted++
+		}
+		latencies = append(latencies, res.latency)
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	fmt.Println()
+	fmt.Printf("Submitted:  %d in %s (%.1f tx/s)\n", len(results), duration.Round(time.Millisecond), float64(len(results))/duration.Seconds())
+	fmt.Printf("Accepted:   %d\n", accepted)
+	fmt.Printf("Rejected:   %d\n", len(results)-accepted)
+
+	if len(latencies) == 0 {
+		return
+	}
+
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+
+	fmt.Printf("Latency:    mean %s p50 %s p95 %s p99 %s max %s\n",
+		(total / time.Duration(len(latencies))).Round(time.Microsecond),
+		percentile(latencies, 50).Round(time.Microsecond),
+		percentile(latencies, 95).Round(time.Microsecond),
+		percentile(latencies, 99).Round(time.Microsecond),
+		latencies[len(latencies)-1].Round(time.Microsecond),
+	)
+}
+
+// reconcile compares the final balance of every test account with the
+// balance expected from the receipts of the accepted transactions.
+func reconcile(ctx context.Context, cln *client.Client, accounts []*account, startBalances map[database.AccountID]uint64, results []result) error {
+	expected := make(map[database.AccountID]int64, len(startBalances))
+	for id, balance := range startBalances {
+		expected[id] = int64(balance)
+	}
+
+	var mined, failed, pending int
+	for _, res := range results {
+		if !res.accepted {
+			continue
+		}
+
+		receipt, err := cln.Receipt(ctx, res.tx.TxHash())
+		if err != nil {
+			pending++
+			continue
+		}
+
+		mined++
+		expected[res.tx.FromID] -= int64(receipt.GasFee + receipt.Tip)
+
+		switch receipt.Status {
+		case database.ReceiptStatusSuccess:
+			expected[res.tx.FromID] -= int64(res.tx.Value)
+			expected[res.tx.ToID] += int64(res.tx.Value)
+		default:
+			failed++
+		}
+	}
+
+	final, err := balances(ctx, cln, accounts)
+	if err != nil {
+		return err
+	}
+
+	var mismatches int
+	for _, acct := range accounts {
+		if int64(final[acct.id]) != expected[acct.id] {
+			mismatches++
+			fmt.Printf("MISMATCH:   %s expected %d got %d\n", acct.id, expected[acct.id], final[acct.id])
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("Mined:      %d (%d failed)\n", mined, failed)
+	fmt.Printf("Pending:    %d\n", pending)
+
+	if pending > 0 {
+		fmt.Println("Reconcile:  skipped, transactions are still pending")
+		return nil
+	}
+
+	if mismatches > 0 {
+		return fmt.Errorf("reconcile: %d accounts don't match their expected balance", mismatches)
+	}
+
+	fmt.Printf("Reconcile:  %d accounts match their expected balance\n", len(accounts))
+
+	return nil
+}
+
+// =============================================================================
+
+// loadAccounts loads the test account keys and the next nonce for each.
+func loadAccounts(ctx context.Context, cln *client.Client, dir string) ([]*account, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.ecdsa"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) < 2 {
+		return nil, fmt.Errorf("at least 2 account keys are required in %s, run the setup command", dir)
+	}
+
+	accounts := make([]*account, len(paths))
+	for i, path := range paths {
+		privateKey, err := crypto.LoadECDSA(path)
+		if err != nil {
+			return nil, err
+		}
+
+		accountID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+		var nonce uint64
+		if acct, err := cln.Account(ctx, accountID); err == nil {
+			nonce = acct.Nonce
+		}
+
+		accounts[i] = &account{
+			id:         accountID,
+			privateKey: privateKey,
+			nonce:      nonce + 1,
+		}
+	}
+
+	return accounts, nil
+}
+
+// balances returns the current balance for each of the test accounts.
+func balances(ctx context.Context, cln *client.Client, accounts []*account) (map[database.AccountID]uint64, error) {
+	info, err := cln.Accounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make(map[database.AccountID]uint64, len(info.Accounts))
+	for _, acct := range info.Accounts {
+		all[acct.AccountID] = acct.Balance
+	}
+
+	m := make(map[database.AccountID]uint64, len(accounts))
+	for _, acct := range accounts {
+		m[acct.id] = all[acct.id]
+	}
+
+	return m, nil
+}
+
+// latestBlockNumber returns the number of the latest block on the node.
+func latestBlockNumber(ctx context.Context, cln *client.Client) uint64 {
+	blocks, err := cln.Blocks(ctx, "")
+	if err != nil {
+		return 0
+	}
+
+	var latest uint64
+	for _, block := range blocks {
+		if block.Number > latest {
+			latest = block.Number
+		}
+	}
+
+	return latest
+}
+
+// percentile returns the value at the specified percentile of the sorted
+// durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	idx := (len(sorted)*p + 99) / 100
+	if idx > 0 {
+		idx--
+	}
+
+	return sorted[idx]
+}
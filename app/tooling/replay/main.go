@@ -0,0 +1,172 @@
+// This program re-executes every block of a node's database from genesis
+// against a fresh in-memory database. The state root after each block is
+// printed and diffed against the state root recorded in the header of the
+// next block, so the first block whose transactions produce a different
+// state than the miner produced is reported. The receipts for that block
+// are diffed against the stored receipts to show which transaction applied
+// differently. Use it when nodes disagree on the state to find the block
+// that introduced the consensus bug.
+//
+//	go run app/tooling/replay/main.go -db-path zblock/miner1/
+//	go run app/tooling/replay/main.go -db-path zblock/miner1/ -storage kv -continue
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/hash"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/memory"
+)
+
+// errDiverged is returned when a replayed state root doesn't match the root
+// recorded in the chain.
+var errDiverged = errors.New("state diverged from the chain")
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	dbPath := flag.String("db-path", "zblock/miner1/", "database directory of the node to replay")
+	engine := flag.String("storage", "disk", "storage engine of the database, disk or kv")
+	genesisPath := flag.String("genesis", "zblock/genesis.json", "genesis file the chain was built with")
+	keepGoing := flag.Bool("continue", false, "keep replaying after the first block that diverges")
+	flag.Parse()
+
+	if _, err := os.Stat(*dbPath); err != nil {
+		return err
+	}
+
+	gen, err := genesis.Load(*genesisPath)
+	if err != nil {
+		return err
+	}
+
+	if err := hash.Select(gen.Hash); err != nil {
+		return err
+	}
+
+	stored, err := storage.New(*engine, *dbPath)
+	if err != nil {
+		return err
+	}
+	defer stored.Close()
+
+	// The replay starts from a database holding only the genesis balances.
+	db, err := database.New(gen, memory.New(), func(...any) {})
+	if err != nil {
+		return err
+	}
+
+	// The header of each block records the state root before the block was
+	// applied, which is the root the block before it produced. So the root
+	// after a block is checked once the next block is read.
+	var prev database.Block
+	var prevReceipts []database.Receipt
+	var blocks, diverged int
+
+	iter := stored.ForEach()
+	for blockData, err := iter.Next(); !iter.Done(); blockData, err = iter.Next() {
+		if err != nil {
+			return err
+		}
+
+		block, err := database.ToBlock(blockData)
+		if err != nil {
+			return fmt.Errorf("block %d: %w", blockData.Header.Number, err)
+		}
+
+		if block.Header.Number != prev.Header.Number+1 {
+			return fmt.Errorf("block %d: expected block %d", block.Header.Number, prev.Header.Number+1)
+		}
+
+		root := db.HashState()
+		if root == block.Header.StateRoot {
+			printRoot(prev, root, "ok")
+		} else {
+			diverged++
+			printRoot(prev, root, fmt.Sprintf("DIVERGED, block %d header records %s", block.Header.Number, block.Header.StateRoot))
+			diffReceipts(stored, prev, prevReceipts)
+
+			if !*keepGoing {
+				return errDiverged
+			}
+		}
+
+		prevReceipts = db.ApplyBlock(block)
+		prev = block
+		blocks++
+	}
+
+	if blocks > 0 {
+		printRoot(prev, db.HashState(), "unchecked, no later block records it")
+	}
+
+	fmt.Printf("Replayed %d blocks of %s, %d diverged\n", blocks, *dbPath, diverged)
+
+	if diverged > 0 {
+		return errDiverged
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// printRoot prints the state root produced by the block. Block 0 is the state
+// root of the genesis balances.
+func printRoot(block database.Block, root string, result string) {
+	var txs int
+	if block.MerkleTree != nil {
+		txs = len(block.MerkleTree.Values())
+	}
+
+	fmt.Printf("block %-6d txs %-4d root %s %s\n", block.Header.Number, txs, root, result)
+}
+
+// diffReceipts prints the replayed receipts of the block that differ from the
+// receipts the node stored when it applied the block.
+func diffReceipts(stored database.Storage, block database.Block, receipts []database.Receipt) {
+	if block.Header.Number == 0 {
+		fmt.Println("  the genesis balances don't match the chain, check the genesis file")
+		return
+	}
+
+	storedReceipts, err := stored.GetReceipts(block.Header.Number)
+	if err != nil {
+		fmt.Printf("  no stored receipts to compare: %s\n", err)
+		return
+	}
+
+	if len(storedReceipts) != len(receipts) {
+		fmt.Printf("  stored %d receipts, replay produced %d\n", len(storedReceipts), len(receipts))
+		return
+	}
+
+	var differ bool
+	for i, got := range receipts {
+		want := storedReceipts[i]
+		if got.TxHash == want.TxHash && got.Status == want.Status && got.Error == want.Error &&
+			got.GasFee == want.GasFee && got.Tip == want.Tip && got.MinerFee == want.MinerFee {
+			continue
+		}
+
+		differ = true
+		fmt.Printf("  tx %d %s\n", i, got.TxHash)
+		fmt.Printf("    stored: status %s error %q gas fee %d tip %d miner fee %d\n", want.Status, want.Error, want.GasFee, want.Tip, want.MinerFee)
+		fmt.Printf("    replay: status %s error %q gas fee %d tip %d miner fee %d\n", got.Status, got.Error, got.GasFee, got.Tip, got.MinerFee)
+	}
+
+	if !differ {
+		fmt.Println("  the receipts match, the difference is in the mining reward, pruning or data the receipts don't record")
+	}
+}
@@ -2,23 +2,36 @@ package main
 
 import (
 	"fmt"
-	"github.com/ethereum/go-ethereum/crypto"
-	_ "github.com/ethereum/go-ethereum/crypto"
-	"log"
+	"os"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/logger"
+	"go.uber.org/zap"
 )
 
 func main() {
-	if err := run(); err != nil {
-		log.Fatalln(err)
+	log, err := logger.New("SCRATCH")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer log.Sync()
+
+	if err := run(log); err != nil {
+		log.Errorw("scratch", "ERROR", err)
+		log.Sync()
+		os.Exit(1)
 	}
 }
 
-func run() error {
+func run(log *zap.SugaredLogger) error {
 	pr, err := crypto.GenerateKey()
 	if err != nil {
 		return err
 	}
+
 	address := crypto.PubkeyToAddress(pr.PublicKey)
-	fmt.Println(address)
+	log.Infow("generated key", "address", address)
+
 	return nil
 }
@@ -469,34 +469,33 @@ function createTransaction() {
     const amountStr = document.getElementById("sendamount").value.replace(/\$|,/g, '');
     const tipStr = document.getElementById("sendtip").value.replace(/\$|,/g, '');
 
-     // Construct a transaction with all the information.
+     // Construct a transaction with all the information. The fields must be
+     // in sorted order to match the canonical encoding the node verifies.
     const tx = {
         chain_id: chainID,
-        nonce: nonce,
+        data: null,
         from: document.getElementById("from").options[document.getElementById("from").selectedIndex].getAttribute('p'),
+        nonce: nonce,
+        tip: Number(tipStr),
         to: document.getElementById("to").value,
         value: Number(amountStr),
-        tip: Number(tipStr),
-        data: null,
     };
 
     // Marshal the transaction to a string and convert the string to bytes.
     const marshal = JSON.stringify(tx);
     const marshalBytes = ethers.utils.toUtf8Bytes(marshal);
 
-    // Hash the transaction data into a 32 byte array. This will provide
-	// a data length consistency with all transactions.
-    const txHash = ethers.utils.keccak256(marshalBytes);
-    const bytes = ethers.utils.arrayify(txHash);
+    // Apply the same stamp the node uses when verifying signatures and hash
+    // the result into the 32 byte digest that gets signed.
+    const stamp = ethers.utils.toUtf8Bytes("\x19Taha Signed Message:\n" + marshalBytes.length);
+    const digest = ethers.utils.keccak256(ethers.utils.concat([stamp, marshalBytes]));
 
-    // Now sign the data. The underlying code will apply the Ardan stamp and
-    // ID to the signature thanks to changes made to the ether.js api.
+    // Now sign the digest. The underlying code will apply the Taha ID to the
+    // 65 byte signature thanks to changes made to the ether.js api.
     const wallet = new ethers.Wallet(document.getElementById("from").value);
-    signature = wallet.signMessage(bytes);
+    const sig = ethers.utils.joinSignature(wallet._signingKey().signDigest(digest));
 
-    // Since everything is built on promises, wait for the signature to
-    // be calculated and then send the transaction to the node.
-    signature.then((sig) => sendTran(tx, sig));
+    sendTran(tx, sig);
 }
 
 // sendTran submits the signed transaction to the node for inclusion.
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/spf13/cobra"
+)
+
+var balanceCmd = &cobra.Command{
+	Use:   "balance",
+	Short: "Print your balance",
+	Run:   balanceRun,
+}
+
+var (
+	prove      bool
+	proofPeers []string
+)
+
+func init() {
+	rootCmd.AddCommand(balanceCmd)
+	balanceCmd.Flags().BoolVar(&prove, "prove", false, "Verify the balance with a merkle proof instead of trusting the node.")
+	balanceCmd.Flags().StringSliceVar(&proofPeers, "peers", nil, "Urls of other nodes the block header of the proof is checked against.")
+}
+
+func balanceRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	accountID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	if prove {
+		proveBalance(ctx, cln, accountID)
+		return
+	}
+
+	pb, err := cln.PendingBalance(ctx, accountID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Account:", pb.AccountID)
+	fmt.Println("Balance:", pb.Balance)
+	fmt.Println("Nonce:  ", pb.Nonce)
+
+	if pb.PendingTxs > 0 {
+		fmt.Println("Pending:", pb.Pending, "after", pb.PendingTxs, "pending transactions")
+	}
+}
+
+// proveBalance prints the balance and nonce of the account once the merkle
+// proof provided by the node checks out against the block header, and the
+// header is the one the other peers have for the block.
+func proveBalance(ctx context.Context, cln *client.Client, accountID database.AccountID) {
+
+	// The merkle proof is checked with the hash function of the chain.
+	if _, err := chainGenesis(ctx, cln); err != nil {
+		log.Fatal(err)
+	}
+
+	ap, err := cln.AccountProof(ctx, accountID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if ap.Account.AccountID != accountID {
+		log.Fatalf("INVALID: proof is for account %s", ap.Account.AccountID)
+	}
+
+	if err := ap.Verify(); err != nil {
+		log.Fatal("INVALID: ", err)
+	}
+
+	hash := database.Block{Header: ap.Header}.Hash()
+	num := strconv.FormatUint(ap.Header.Number, 10)
+
+	for _, peer := range proofPeers {
+		blocks, err := client.New(client.Config{URL: peer, Retries: 3}).BlocksByRange(ctx, num, num)
+		if err != nil {
+			log.Fatalf("peer %s: %s", peer, err)
+		}
+
+		if len(blocks) != 1 || blocks[0].Hash != hash {
+			log.Fatalf("INVALID: peer %s doesn't have block %s with hash %s", peer, num, hash)
+		}
+	}
+
+	fmt.Println("Account:", ap.Account.AccountID)
+	fmt.Println("Balance:", ap.Account.Balance)
+	fmt.Println("Nonce:  ", ap.Account.Nonce)
+	fmt.Println("Block:  ", ap.Header.Number, hash)
+
+	if len(proofPeers) == 0 {
+		fmt.Println("Header not checked against other peers, use --peers to check it.")
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/spf13/cobra"
+)
+
+var cancelCmd = &cobra.Command{
+	Use:   "cancel",
+	Short: "Cancel a pending transaction",
+	Run:   cancelRun,
+}
+
+func init() {
+	rootCmd.AddCommand(cancelCmd)
+	cancelCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Nonce of the pending transaction.")
+	cancelCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send, defaults to one more than the pending transaction.")
+}
+
+func cancelRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	accountID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	// The replacement must offer a higher tip than the pending transaction.
+	if tip == 0 {
+		txs, err := cln.Mempool(ctx, accountID)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, tx := range txs {
+			if tx.FromID == accountID && tx.Nonce == nonce {
+				tip = tx.Tip + 1
+				break
+			}
+		}
+
+		if tip == 0 {
+			log.Fatalf("no pending transaction for nonce %d", nonce)
+		}
+	}
+
+	gen, err := chainGenesis(ctx, cln)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Replace the pending transaction with one that sends nothing to this
+	// account so the only cost is the fees.
+	tx, err := database.NewTx(gen.ChainID, nonce, accountID, accountID, 0, tip, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := cln.CancelTransaction(ctx, signedTx); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Replaced:", signedTx.TxHash())
+}
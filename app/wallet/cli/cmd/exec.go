@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/vm"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/spf13/cobra"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec",
+	Short: "Send a transaction that executes a program",
+	Run:   execRun,
+}
+
+var program string
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+	execCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Nonce for the transaction.")
+	execCmd.Flags().StringVarP(&to, "to", "t", "", "Account receiving value transferred by the program, defaults to the account.")
+	execCmd.Flags().Uint64VarP(&value, "value", "v", 0, "Value to send.")
+	execCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+	execCmd.Flags().StringVarP(&program, "program", "s", "", `Program to execute, "push 10 push 1 store".`)
+}
+
+func execRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fromID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	toID := fromID
+	if to != "" {
+		if toID, err = database.ToAccountID(to); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	code, err := vm.Assemble(program)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	// The transaction must be signed for the chain the node is running.
+	gen, err := chainGenesis(ctx, cln)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tx, err := database.NewTx(gen.ChainID, nonce, fromID, toID, value, tip, code)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := cln.SubmitTransaction(ctx, signedTx); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Submitted:", signedTx.TxHash())
+}
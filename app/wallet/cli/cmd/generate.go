@@ -3,7 +3,7 @@ package cmd
 import (
 	"log"
 
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
 	"github.com/spf13/cobra"
 )
 
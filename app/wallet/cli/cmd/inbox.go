@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/envelope"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/spf13/cobra"
+)
+
+var inboxCmd = &cobra.Command{
+	Use:   "inbox",
+	Short: "Decrypt the messages sent to you",
+	Run:   inboxRun,
+}
+
+func init() {
+	rootCmd.AddCommand(inboxCmd)
+}
+
+func inboxRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	accountID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	blocks, err := cln.Blocks(ctx, accountID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			printMessage(privateKey, accountID, tx, fmt.Sprintf("block %d", block.Number))
+		}
+	}
+
+	txs, err := cln.Mempool(ctx, accountID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, tx := range txs {
+		printMessage(privateKey, accountID, tx, "pending")
+	}
+}
+
+// printMessage decrypts and displays the transaction data if it's an
+// envelope addressed to the specified account.
+func printMessage(privateKey *ecdsa.PrivateKey, accountID database.AccountID, tx client.Tx, where string) {
+	if tx.ToID != accountID || !envelope.IsEnvelope(tx.Data) {
+		return
+	}
+
+	fmt.Printf("From: %s (%s)\n", tx.FromID, where)
+	fmt.Printf("Hash: %s\n", tx.Hash)
+
+	msg, err := envelope.Open(privateKey, tx.Data)
+	if err != nil {
+		fmt.Printf("ERROR: %s\n\n", err)
+		return
+	}
+
+	fmt.Printf("%s\n\n", msg)
+}
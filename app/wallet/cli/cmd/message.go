@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/envelope"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/ardanlabs/blockchain/foundation/hexutil"
+	"github.com/spf13/cobra"
+)
+
+var messageCmd = &cobra.Command{
+	Use:   "message",
+	Short: "Send an encrypted message",
+	Run:   messageRun,
+}
+
+var (
+	message   string
+	publicKey string
+)
+
+func init() {
+	rootCmd.AddCommand(messageCmd)
+	messageCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Nonce for the transaction.")
+	messageCmd.Flags().StringVarP(&to, "to", "t", "", "Account to send the message to.")
+	messageCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+	messageCmd.Flags().StringVarP(&message, "message", "m", "", "Message to send.")
+	messageCmd.Flags().StringVarP(&publicKey, "public-key", "k", "", "Public key of the recipient, if not found on the chain.")
+}
+
+func messageRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fromID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	toID, err := database.ToAccountID(to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	toKey, err := recipientPublicKey(ctx, cln, toID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data, err := envelope.Seal(toID, toKey, []byte(message))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The transaction must be signed for the chain the node is running.
+	gen, err := chainGenesis(ctx, cln)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tx, err := database.NewTx(gen.ChainID, nonce, fromID, toID, 0, tip, data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := cln.SubmitTransaction(ctx, signedTx); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Submitted:", signedTx.TxHash())
+}
+
+// recipientPublicKey returns the public key for the specified account. When
+// a key isn't provided, it's recovered from the signature of a transaction
+// the account has sent. Any account that has signed a transaction has made
+// its public key known to everyone.
+func recipientPublicKey(ctx context.Context, cln *client.Client, accountID database.AccountID) (*ecdsa.PublicKey, error) {
+	if publicKey != "" {
+		pubKey, err := hexutil.Decode(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.UnmarshalPubkey(pubKey)
+	}
+
+	blocks, err := cln.Blocks(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			if tx.FromID != accountID {
+				continue
+			}
+
+			blockTx, err := tx.BlockTx()
+			if err != nil {
+				return nil, err
+			}
+
+			return signature.FromPublicKey(blockTx.Tx, blockTx.V, blockTx.R, blockTx.S)
+		}
+	}
+
+	return nil, errors.New("recipient has not signed any transactions, provide their public key")
+}
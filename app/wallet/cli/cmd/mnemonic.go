@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/hdwallet"
+	"github.com/spf13/cobra"
+)
+
+var mnemonicCmd = &cobra.Command{
+	Use:   "mnemonic",
+	Short: "Manage mnemonic phrases",
+}
+
+var mnemonicNewCmd = &cobra.Command{
+	Use:   "new",
+	Short: "Generate a new 12 word mnemonic phrase",
+	Run:   mnemonicNewRun,
+}
+
+var deriveCmd = &cobra.Command{
+	Use:   "derive",
+	Short: "Derive an account from a mnemonic phrase",
+	Run:   deriveRun,
+}
+
+var (
+	mnemonic   string
+	passphrase string
+	index      uint32
+	save       bool
+)
+
+func init() {
+	rootCmd.AddCommand(mnemonicCmd)
+	mnemonicCmd.AddCommand(mnemonicNewCmd)
+
+	rootCmd.AddCommand(deriveCmd)
+	deriveCmd.Flags().StringVarP(&mnemonic, "mnemonic", "m", envDefault("WALLET_MNEMONIC", ""), "Mnemonic phrase to derive the account from. ($WALLET_MNEMONIC)")
+	deriveCmd.Flags().StringVar(&passphrase, "passphrase", "", "Optional passphrase protecting the mnemonic.")
+	deriveCmd.Flags().Uint32VarP(&index, "index", "i", 0, "Index of the account in the derivation path.")
+	deriveCmd.Flags().BoolVarP(&save, "save", "s", false, "Save the private key as the account.")
+}
+
+func mnemonicNewRun(cmd *cobra.Command, args []string) {
+	phrase, err := hdwallet.NewMnemonic()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(phrase)
+}
+
+func deriveRun(cmd *cobra.Command, args []string) {
+	if mnemonic == "" {
+		log.Fatal(errors.New("mnemonic must be provided with --mnemonic or $WALLET_MNEMONIC"))
+	}
+
+	privateKey, err := hdwallet.DeriveAccount(mnemonic, passphrase, index)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Path:   ", hdwallet.AccountPath(index))
+	fmt.Println("Account:", database.PublicKeyToAccountID(privateKey.PublicKey))
+
+	if save {
+		if err := crypto.SaveECDSA(getPrivateKeyPath(), privateKey); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Saved:  ", getPrivateKeyPath())
+	}
+}
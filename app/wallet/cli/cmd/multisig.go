@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/spf13/cobra"
+)
+
+var multisigCmd = &cobra.Command{
+	Use:   "multisig",
+	Short: "Manage multisig accounts",
+}
+
+var multisigRegisterCmd = &cobra.Command{
+	Use:   "register",
+	Short: "Register a new multisig account",
+	Run:   multisigRegisterRun,
+}
+
+var multisigCoSignCmd = &cobra.Command{
+	Use:   "cosign",
+	Short: "Co-sign a transaction from a multisig account",
+	Run:   multisigCoSignRun,
+}
+
+var multisigSendCmd = &cobra.Command{
+	Use:   "send",
+	Short: "Send a co-signed transaction from a multisig account",
+	Run:   multisigSendRun,
+}
+
+var (
+	participants []string
+	threshold    int
+	multisigID   string
+	coSignatures []string
+)
+
+func init() {
+	rootCmd.AddCommand(multisigCmd)
+
+	multisigCmd.AddCommand(multisigRegisterCmd)
+	multisigRegisterCmd.Flags().StringSliceVar(&participants, "participants", nil, "Accounts that can sign for the multisig account.")
+	multisigRegisterCmd.Flags().IntVarP(&threshold, "threshold", "m", 1, "Number of participants that must sign a transaction.")
+	multisigRegisterCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Nonce for the transaction.")
+	multisigRegisterCmd.Flags().Uint64VarP(&value, "value", "v", 0, "Value to fund the multisig account with.")
+	multisigRegisterCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+
+	// The co-signers and the sender must describe the same transaction.
+	for _, c := range []*cobra.Command{multisigCoSignCmd, multisigSendCmd} {
+		multisigCmd.AddCommand(c)
+		c.Flags().StringVarP(&multisigID, "from", "f", "", "Multisig account to send value from.")
+		c.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Nonce for the transaction.")
+		c.Flags().StringVarP(&to, "to", "t", "", "Account to send value to.")
+		c.Flags().Uint64VarP(&value, "value", "v", 0, "Value to send.")
+		c.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+		c.Flags().BytesHexVarP(&data, "data", "d", nil, "Data to send.")
+	}
+	multisigSendCmd.Flags().StringSliceVarP(&coSignatures, "signatures", "s", nil, "Signatures of the other participants.")
+}
+
+func multisigRegisterRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fromID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	accountIDs := make([]database.AccountID, len(participants))
+	for i, participant := range participants {
+		if accountIDs[i], err = database.ToAccountID(participant); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	reg, err := database.NewMultisigRegistration(threshold, accountIDs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The multisig account is derived from the sender and nonce.
+	accountID := database.CreateAccountID(fromID, nonce)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	// The transaction must be signed for the chain the node is running.
+	gen, err := chainGenesis(ctx, cln)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tx, err := database.NewTx(gen.ChainID, nonce, fromID, accountID, value, tip, reg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := cln.SubmitTransaction(ctx, signedTx); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Submitted:", signedTx.TxHash())
+	fmt.Println("Multisig: ", accountID)
+}
+
+func multisigCoSignRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	tx, err := multisigTx(ctx, cln, data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sig, err := tx.CoSign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(sig)
+}
+
+func multisigSendRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	env, err := database.NewMultisigEnvelope(data, coSignatures)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	tx, err := multisigTx(ctx, cln, env)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := cln.SubmitTransaction(ctx, signedTx); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Submitted:", signedTx.TxHash())
+}
+
+// multisigTx constructs the transaction from the multisig account described
+// by the flags with the specified data.
+func multisigTx(ctx context.Context, cln *client.Client, data []byte) (database.Tx, error) {
+	fromID, err := database.ToAccountID(multisigID)
+	if err != nil {
+		return database.Tx{}, err
+	}
+
+	toID, err := database.ToAccountID(to)
+	if err != nil {
+		return database.Tx{}, err
+	}
+
+	// The transaction must be signed for the chain the node is running.
+	gen, err := chainGenesis(ctx, cln)
+	if err != nil {
+		return database.Tx{}, err
+	}
+
+	return database.NewTx(gen.ChainID, nonce, fromID, toID, value, tip, data)
+}
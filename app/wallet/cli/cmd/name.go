@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/spf13/cobra"
+)
+
+var nameCmd = &cobra.Command{
+	Use:   "name",
+	Short: "Manage names in the name registry",
+}
+
+var nameRegisterCmd = &cobra.Command{
+	Use:   "register <name>",
+	Short: "Register a name for the account",
+	Args:  cobra.ExactArgs(1),
+	Run:   nameRegisterRun,
+}
+
+var nameLookupCmd = &cobra.Command{
+	Use:   "lookup <name>",
+	Short: "Lookup the account a name is registered to",
+	Args:  cobra.ExactArgs(1),
+	Run:   nameLookupRun,
+}
+
+func init() {
+	rootCmd.AddCommand(nameCmd)
+
+	nameCmd.AddCommand(nameRegisterCmd)
+	nameRegisterCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Nonce for the transaction.")
+	nameRegisterCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+
+	nameCmd.AddCommand(nameLookupCmd)
+}
+
+func nameRegisterRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fromID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	data, err := database.NameRegistration(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	// The transaction must be signed for the chain the node is running.
+	gen, err := chainGenesis(ctx, cln)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The registration is sent to the account itself since no value moves.
+	tx, err := database.NewTx(gen.ChainID, nonce, fromID, fromID, 0, tip, data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := cln.SubmitTransaction(ctx, signedTx); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Submitted:", signedTx.TxHash())
+}
+
+func nameLookupRun(cmd *cobra.Command, args []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	accountID, err := cln.Name(ctx, args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(accountID)
+}
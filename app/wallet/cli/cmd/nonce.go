@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/client"
+)
+
+// pendingExtension is the extension of the file next to the key file that
+// tracks the transactions the wallet submitted that aren't mined yet.
+const pendingExtension = ".pending.json"
+
+// pendingTx represents a transaction the wallet submitted that isn't known
+// to be mined yet.
+type pendingTx struct {
+	ChainID   uint16    `json:"chain_id"`
+	Nonce     uint64    `json:"nonce"`
+	Hash      string    `json:"hash"`
+	Submitted time.Time `json:"submitted"`
+}
+
+// nextNonce picks the nonce for the next transaction of the account. It's
+// the lowest nonce after the confirmed nonce of the account that isn't used
+// by a pending transaction, either one this wallet submitted or one in the
+// mempool of the node. A transaction this wallet submitted that is no longer
+// pending and wasn't mined was rejected, so it's dropped and its nonce is
+// used again to fill the gap it left.
+func nextNonce(ctx context.Context, cln *client.Client, chainID uint16, accountID database.AccountID) (uint64, error) {
+
+	// The mempool is read before the account, so a transaction mined in
+	// between is counted by one or the other.
+	mempool, err := cln.Mempool(ctx, accountID)
+	if err != nil {
+		return 0, fmt.Errorf("reading mempool: %w", err)
+	}
+
+	account, err := cln.Account(ctx, accountID)
+	if err != nil {
+		return 0, fmt.Errorf("reading account: %w", err)
+	}
+
+	used := make(map[uint64]bool)
+	inMempool := make(map[string]bool)
+	for _, tx := range mempool {
+		used[tx.Nonce] = true
+		inMempool[tx.Hash] = true
+	}
+
+	local, err := loadPending()
+	if err != nil {
+		return 0, err
+	}
+
+	var keep []pendingTx
+	for _, ptx := range local {
+		switch {
+		case ptx.ChainID != chainID:
+			keep = append(keep, ptx)
+
+		case ptx.Nonce <= account.Nonce:
+			// Mined, or replaced by a transaction that was.
+
+		case !inMempool[ptx.Hash]:
+			fmt.Printf("Dropped rejected transaction: nonce %d, hash %s\n", ptx.Nonce, ptx.Hash)
+
+		default:
+			used[ptx.Nonce] = true
+			keep = append(keep, ptx)
+		}
+	}
+
+	if err := savePending(keep); err != nil {
+		return 0, err
+	}
+
+	next := account.Nonce + 1
+	for used[next] {
+		next++
+	}
+
+	return next, nil
+}
+
+// recordPending adds the submitted transaction to the pending transactions
+// of the account, replacing a transaction with the same nonce.
+func recordPending(chainID uint16, signedTx database.SignedTx) error {
+	local, err := loadPending()
+	if err != nil {
+		return err
+	}
+
+	ptx := pendingTx{
+		ChainID:   chainID,
+		Nonce:     signedTx.Nonce,
+		Hash:      signedTx.TxHash(),
+		Submitted: time.Now().UTC(),
+	}
+
+	keep := []pendingTx{ptx}
+	for _, p := range local {
+		if p.ChainID != chainID || p.Nonce != ptx.Nonce {
+			keep = append(keep, p)
+		}
+	}
+
+	return savePending(keep)
+}
+
+// =============================================================================
+
+// pendingPath returns the path of the file tracking the pending transactions
+// of the account.
+func pendingPath() string {
+	return strings.TrimSuffix(getPrivateKeyPath(), keyExtenstion) + pendingExtension
+}
+
+// loadPending reads the pending transactions of the account. An account
+// that never submitted a transaction has none.
+func loadPending() ([]pendingTx, error) {
+	data, err := os.ReadFile(pendingPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var local []pendingTx
+	if err := json.Unmarshal(data, &local); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", pendingPath(), err)
+	}
+
+	return local, nil
+}
+
+// savePending writes the pending transactions of the account, removing the
+// file once there are none.
+func savePending(local []pendingTx) error {
+	if len(local) == 0 {
+		if err := os.Remove(pendingPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(local, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(pendingPath(), data, 0600)
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/spf13/cobra"
+)
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Manage the accounts allowed to transact on a permissioned chain",
+}
+
+var policyAllowCmd = &cobra.Command{
+	Use:   "allow <account>",
+	Short: "Allow an account to transact, requires a policy admin",
+	Args:  cobra.ExactArgs(1),
+	Run:   policyRun(database.PolicyAllow),
+}
+
+var policyDenyCmd = &cobra.Command{
+	Use:   "deny <account>",
+	Short: "Deny an account from transacting, requires a policy admin",
+	Args:  cobra.ExactArgs(1),
+	Run:   policyRun(database.PolicyDeny),
+}
+
+var policyShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the admins and the accounts they allowed or denied",
+	Args:  cobra.NoArgs,
+	Run:   policyShowRun,
+}
+
+func init() {
+	rootCmd.AddCommand(policyCmd)
+
+	for _, cmd := range []*cobra.Command{policyAllowCmd, policyDenyCmd} {
+		policyCmd.AddCommand(cmd)
+		cmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Nonce for the transaction.")
+		cmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+	}
+
+	policyCmd.AddCommand(policyShowCmd)
+}
+
+func policyRun(action string) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		privateKey, err := loadPrivateKey()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fromID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+		accountID, err := database.ToAccountID(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		data, err := database.NewPolicyTx(action, accountID)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+		// The transaction must be signed for the chain the node is running.
+		gen, err := chainGenesis(ctx, cln)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// The policy change is sent to the admin itself since no value moves.
+		tx, err := database.NewTx(gen.ChainID, nonce, fromID, fromID, 0, tip, data)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		signedTx, err := tx.Sign(privateKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := cln.SubmitTransaction(ctx, signedTx); err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println("Submitted:", signedTx.TxHash())
+	}
+}
+
+func policyShowRun(cmd *cobra.Command, args []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	policy, err := cln.Policy(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !policy.Enabled {
+		fmt.Println("The chain is not permissioned, every account can transact.")
+		return
+	}
+
+	for _, admin := range policy.Admins {
+		fmt.Printf("%-42s admin\n", admin)
+	}
+	for _, entry := range policy.Entries {
+		status := "denied"
+		if entry.Allowed {
+			status = "allowed"
+		}
+		fmt.Printf("%-42s %s\n", entry.AccountID, status)
+	}
+}
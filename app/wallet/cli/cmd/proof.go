@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/hash"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/proof"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/spf13/cobra"
+)
+
+var proofCmd = &cobra.Command{
+	Use:   "proof",
+	Short: "Export a signed proof of payment for a mined transaction",
+	Run:   proofRun,
+}
+
+var verifyProofCmd = &cobra.Command{
+	Use:   "verify-proof",
+	Short: "Verify a proof of payment offline",
+	Run:   verifyProofRun,
+}
+
+var (
+	txHash    string
+	proofFile string
+	chainID   uint16
+	hashName  string
+)
+
+func init() {
+	rootCmd.AddCommand(proofCmd)
+	proofCmd.Flags().StringVarP(&txHash, "tx", "x", "", "Hash of the transaction to prove.")
+	proofCmd.Flags().StringVarP(&proofFile, "file", "f", "", "File to write the proof to, defaults to <hash>.proof.json.")
+
+	rootCmd.AddCommand(verifyProofCmd)
+	verifyProofCmd.Flags().StringVarP(&proofFile, "file", "f", "", "File containing the proof.")
+	verifyProofCmd.Flags().Uint16VarP(&chainID, "chain-id", "i", 1, "Chain id the transaction must be signed for.")
+	verifyProofCmd.Flags().StringVar(&hashName, "hash", "", "Hash function of the chain, sha256 when empty.")
+}
+
+func proofRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	accountID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	// The merkle proof is checked with the hash function of the chain.
+	if _, err := chainGenesis(ctx, cln); err != nil {
+		log.Fatal(err)
+	}
+
+	receipt, err := cln.Receipt(ctx, txHash)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	payment, err := findPayment(ctx, cln, accountID, receipt)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	signedPayment, err := payment.Sign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Make sure the node provided a proof that will verify.
+	if err := signedPayment.Verify(payment.Tx.ChainID); err != nil {
+		log.Fatal(err)
+	}
+
+	data, err := json.MarshalIndent(signedPayment, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if proofFile == "" {
+		proofFile = txHash + ".proof.json"
+	}
+
+	if err := os.WriteFile(proofFile, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Proof written:", proofFile)
+}
+
+func verifyProofRun(cmd *cobra.Command, args []string) {
+	data, err := os.ReadFile(proofFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var signedPayment proof.SignedPayment
+	if err := json.Unmarshal(data, &signedPayment); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := hash.Select(hashName); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := signedPayment.Verify(chainID); err != nil {
+		log.Fatal("INVALID: ", err)
+	}
+
+	fmt.Println("VALID")
+	fmt.Println("From:   ", signedPayment.Tx.FromID)
+	fmt.Println("To:     ", signedPayment.Tx.ToID)
+	fmt.Println("Value:  ", signedPayment.Tx.Value)
+	fmt.Println("Block:  ", signedPayment.Header.Number, signedPayment.Receipt.BlockHash)
+}
+
+// findPayment locates the transaction for the receipt in the blocks for the
+// account and packages it with the information needed to prove it.
+func findPayment(ctx context.Context, cln *client.Client, accountID database.AccountID, receipt database.Receipt) (proof.Payment, error) {
+	blocks, err := cln.Blocks(ctx, accountID)
+	if err != nil {
+		return proof.Payment{}, err
+	}
+
+	for _, block := range blocks {
+		if block.Number != receipt.BlockNumber {
+			continue
+		}
+
+		for _, tx := range block.Transactions {
+			if tx.Hash != receipt.TxHash {
+				continue
+			}
+
+			blockTx, err := tx.BlockTx()
+			if err != nil {
+				return proof.Payment{}, err
+			}
+
+			payment := proof.Payment{
+				Tx:         blockTx,
+				Receipt:    receipt,
+				Header:     block.Header(),
+				Proof:      tx.Proof,
+				ProofOrder: tx.ProofOrder,
+			}
+
+			return payment, nil
+		}
+	}
+
+	return proof.Payment{}, fmt.Errorf("transaction %s not found for account %s", receipt.TxHash, accountID)
+}
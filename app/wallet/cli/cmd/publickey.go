@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/hexutil"
+	"github.com/spf13/cobra"
+)
+
+var publicKeyCmd = &cobra.Command{
+	Use:   "public-key",
+	Short: "Print your public key so others can send you messages",
+	Run:   publicKeyRun,
+}
+
+func init() {
+	rootCmd.AddCommand(publicKeyCmd)
+}
+
+func publicKeyRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(hexutil.Encode(crypto.FromECDSAPub(&privateKey.PublicKey)))
+}
@@ -2,16 +2,27 @@
 package cmd
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/hash"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/ardanlabs/blockchain/foundation/keystore"
 	"github.com/spf13/cobra"
 )
 
 var (
 	accountName string
 	accountPath string
+	nodeURL     string
+	password    string
 )
 
 const (
@@ -20,8 +31,10 @@ const (
 
 func init() {
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
-	rootCmd.PersistentFlags().StringVarP(&accountName, "account", "a", "private.ecdsa", "The account to use.")
-	rootCmd.PersistentFlags().StringVarP(&accountPath, "account-path", "p", "zblock/accounts/", "Path to the directory with private keys.")
+	rootCmd.PersistentFlags().StringVarP(&accountName, "account", "a", envDefault("WALLET_ACCOUNT", "private.ecdsa"), "The account to use. ($WALLET_ACCOUNT)")
+	rootCmd.PersistentFlags().StringVarP(&accountPath, "account-path", "p", envDefault("WALLET_ACCOUNT_PATH", "zblock/accounts/"), "Path to the directory with private keys. ($WALLET_ACCOUNT_PATH)")
+	rootCmd.PersistentFlags().StringVarP(&nodeURL, "url", "u", envDefault("WALLET_URL", "http://localhost:8080"), "Url of the node's public API. ($WALLET_URL)")
+	rootCmd.PersistentFlags().StringVar(&password, "password", envDefault("WALLET_PASSWORD", ""), "Password for an account held in an encrypted key file. ($WALLET_PASSWORD)")
 }
 
 var rootCmd = &cobra.Command{
@@ -36,6 +49,15 @@ func Execute() {
 	}
 }
 
+// envDefault returns the value of the environment variable so it can be used
+// as the default for a flag, otherwise the specified default is returned.
+func envDefault(key string, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return def
+}
+
 func getPrivateKeyPath() string {
 	if !strings.HasSuffix(accountName, keyExtenstion) {
 		accountName += keyExtenstion
@@ -43,3 +65,35 @@ func getPrivateKeyPath() string {
 
 	return filepath.Join(accountPath, accountName)
 }
+
+// loadPrivateKey loads the private key for the account. When the account
+// has no key file, an encrypted key file for the account is decrypted with
+// the password instead.
+func loadPrivateKey() (*ecdsa.PrivateKey, error) {
+	path := getPrivateKeyPath()
+
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		encrypted := strings.TrimSuffix(path, keyExtenstion) + keystore.Extension
+		if _, err := os.Stat(encrypted); err == nil {
+			return keystore.Load(encrypted, password)
+		}
+	}
+
+	return crypto.LoadECDSA(path)
+}
+
+// chainGenesis returns the genesis of the chain the node is running and
+// selects the hash function of the chain, so the transaction hashes the
+// wallet reports match the ones the node reports.
+func chainGenesis(ctx context.Context, cln *client.Client) (genesis.Genesis, error) {
+	gen, err := cln.Genesis(ctx)
+	if err != nil {
+		return genesis.Genesis{}, err
+	}
+
+	if err := hash.Select(gen.Hash); err != nil {
+		return genesis.Genesis{}, err
+	}
+
+	return gen, nil
+}
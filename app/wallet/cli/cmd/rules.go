@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/spf13/cobra"
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Manage accounts governed by spending rules",
+}
+
+var rulesRegisterCmd = &cobra.Command{
+	Use:   "register",
+	Short: "Register a new account governed by spending rules",
+	Run:   rulesRegisterRun,
+}
+
+var rulesSendCmd = &cobra.Command{
+	Use:   "send",
+	Short: "Send a transaction from an account governed by spending rules",
+	Run:   rulesSendRun,
+}
+
+var (
+	dailyLimit uint64
+	recipients []string
+	rulesID    string
+)
+
+func init() {
+	rootCmd.AddCommand(rulesCmd)
+
+	rulesCmd.AddCommand(rulesRegisterCmd)
+	rulesRegisterCmd.Flags().Uint64Var(&dailyLimit, "daily-limit", 0, "Amount the account can send a day, no limit when zero.")
+	rulesRegisterCmd.Flags().StringSliceVar(&recipients, "recipients", nil, "Accounts the account can send to, any account when empty.")
+	rulesRegisterCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Nonce for the transaction.")
+	rulesRegisterCmd.Flags().Uint64VarP(&value, "value", "v", 0, "Value to fund the account with.")
+	rulesRegisterCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+
+	rulesCmd.AddCommand(rulesSendCmd)
+	rulesSendCmd.Flags().StringVarP(&rulesID, "from", "f", "", "Account governed by spending rules to send value from.")
+	rulesSendCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Nonce for the transaction.")
+	rulesSendCmd.Flags().StringVarP(&to, "to", "t", "", "Account to send value to.")
+	rulesSendCmd.Flags().Uint64VarP(&value, "value", "v", 0, "Value to send.")
+	rulesSendCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+	rulesSendCmd.Flags().BytesHexVarP(&data, "data", "d", nil, "Data to send.")
+}
+
+func rulesRegisterRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fromID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	accountIDs := make([]database.AccountID, len(recipients))
+	for i, recipient := range recipients {
+		if accountIDs[i], err = database.ToAccountID(recipient); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	reg, err := database.NewRulesRegistration(dailyLimit, accountIDs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The account is derived from the sender and nonce.
+	accountID := database.CreateAccountID(fromID, nonce)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	// The transaction must be signed for the chain the node is running.
+	gen, err := chainGenesis(ctx, cln)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tx, err := database.NewTx(gen.ChainID, nonce, fromID, accountID, value, tip, reg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := cln.SubmitTransaction(ctx, signedTx); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Submitted:", signedTx.TxHash())
+	fmt.Println("Account:  ", accountID)
+}
+
+func rulesSendRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fromID, err := database.ToAccountID(rulesID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	toID, err := database.ToAccountID(to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The envelope marks the owner as signing for the account.
+	env, err := database.NewRulesEnvelope(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	// The transaction must be signed for the chain the node is running.
+	gen, err := chainGenesis(ctx, cln)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tx, err := database.NewTx(gen.ChainID, nonce, fromID, toID, value, tip, env)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := cln.SubmitTransaction(ctx, signedTx); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Submitted:", signedTx.TxHash())
+}
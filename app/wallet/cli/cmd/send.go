@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/spf13/cobra"
+)
+
+var sendCmd = &cobra.Command{
+	Use:   "send",
+	Short: "Send transaction",
+	Run:   sendRun,
+}
+
+var (
+	nonce     uint64
+	to        string
+	value     uint64
+	tip       uint64
+	data      []byte
+	memo      string
+	reference string
+	autoNonce bool
+	notBefore uint64
+	notAfter  uint64
+)
+
+func init() {
+	rootCmd.AddCommand(sendCmd)
+	sendCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Nonce for the transaction.")
+	sendCmd.Flags().StringVarP(&to, "to", "t", "", "Account to send value to.")
+	sendCmd.Flags().Uint64VarP(&value, "value", "v", 0, "Value to send.")
+	sendCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+	sendCmd.Flags().BytesHexVarP(&data, "data", "d", nil, "Data to send.")
+	sendCmd.Flags().StringVarP(&memo, "memo", "m", "", "Note for the recipient, sent in place of the data.")
+	sendCmd.Flags().StringVarP(&reference, "reference", "r", "", "Payment reference, like an invoice number, sent with the memo.")
+	sendCmd.Flags().BoolVar(&autoNonce, "auto-nonce", false, "Pick the nonce from the node and the transactions this wallet has pending.")
+	sendCmd.Flags().Uint64Var(&notBefore, "not-before", 0, "First block number the transaction can be mined in.")
+	sendCmd.Flags().Uint64Var(&notAfter, "not-after", 0, "Last block number the transaction can be mined in.")
+}
+
+func sendRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fromID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	toID, err := database.ToAccountID(to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	// The transaction must be signed for the chain the node is running.
+	gen, err := chainGenesis(ctx, cln)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if (memo != "" || reference != "") && len(data) > 0 {
+		log.Fatal("a memo is sent in the data field, use either data or a memo")
+	}
+
+	sign := func(nonce uint64) (database.SignedTx, error) {
+		var tx database.Tx
+		var err error
+		switch {
+		case memo != "" || reference != "":
+			tx, err = database.NewTransferWithMemo(gen.ChainID, nonce, fromID, toID, value, tip, memo, reference)
+		default:
+			tx, err = database.NewTx(gen.ChainID, nonce, fromID, toID, value, tip, data)
+		}
+		if err != nil {
+			return database.SignedTx{}, err
+		}
+		tx.NotBefore = notBefore
+		tx.NotAfter = notAfter
+
+		return tx.Sign(privateKey)
+	}
+
+	if !autoNonce {
+		signedTx, err := sign(nonce)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := cln.SubmitTransaction(ctx, signedTx); err != nil {
+			log.Fatal(err)
+		}
+
+		if err := recordPending(gen.ChainID, signedTx); err != nil {
+			log.Println("WARNING: tracking pending transaction:", err)
+		}
+
+		fmt.Println("Submitted:", signedTx.TxHash())
+		return
+	}
+
+	if cmd.Flags().Changed("nonce") {
+		log.Fatal("use either a nonce or --auto-nonce")
+	}
+
+	signedTx, err := sendAutoNonce(ctx, cln, gen.ChainID, fromID, sign)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Submitted:", signedTx.TxHash(), "nonce:", signedTx.Nonce)
+}
+
+// sendAutoNonce signs the transaction with the next nonce of the account and
+// submits it. When the node rejects the transaction and the next nonce has
+// changed since it was picked, such as when another transaction took the
+// nonce, the transaction is signed with the new nonce and submitted again.
+// A rejected transaction doesn't use up its nonce.
+func sendAutoNonce(ctx context.Context, cln *client.Client, chainID uint16, fromID database.AccountID, sign func(nonce uint64) (database.SignedTx, error)) (database.SignedTx, error) {
+	next, err := nextNonce(ctx, cln, chainID, fromID)
+	if err != nil {
+		return database.SignedTx{}, err
+	}
+
+	for retried := false; ; retried = true {
+		signedTx, err := sign(next)
+		if err != nil {
+			return database.SignedTx{}, err
+		}
+
+		err = cln.SubmitTransaction(ctx, signedTx)
+		if err == nil {
+			if err := recordPending(chainID, signedTx); err != nil {
+				log.Println("WARNING: tracking pending transaction:", err)
+			}
+			return signedTx, nil
+		}
+
+		if retried || !client.IsBadRequest(err) {
+			return database.SignedTx{}, err
+		}
+
+		// Only a change in the next nonce can make a second attempt succeed.
+		again, nerr := nextNonce(ctx, cln, chainID, fromID)
+		if nerr != nil || again == next {
+			return database.SignedTx{}, err
+		}
+
+		fmt.Printf("Rejected with nonce %d, trying again with %d: %s\n", next, again, err)
+		next = again
+	}
+}
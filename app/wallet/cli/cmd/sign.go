@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+	"github.com/spf13/cobra"
+)
+
+var signMessageCmd = &cobra.Command{
+	Use:   "sign-message",
+	Short: "Sign a message to prove you own your account",
+	Run:   signMessageRun,
+}
+
+var verifyMessageCmd = &cobra.Command{
+	Use:   "verify-message",
+	Short: "Verify the signature of a message offline",
+	Run:   verifyMessageRun,
+}
+
+var (
+	messageSig string
+	signerID   string
+)
+
+func init() {
+	rootCmd.AddCommand(signMessageCmd)
+	signMessageCmd.Flags().StringVarP(&message, "message", "m", "", "Message to sign.")
+
+	rootCmd.AddCommand(verifyMessageCmd)
+	verifyMessageCmd.Flags().StringVarP(&message, "message", "m", "", "Message that was signed.")
+	verifyMessageCmd.Flags().StringVarP(&messageSig, "signature", "s", "", "Signature of the message.")
+	verifyMessageCmd.Flags().StringVarP(&signerID, "from", "f", "", "Account that must have signed the message, any account when empty.")
+}
+
+func signMessageRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sig, err := signature.SignMessage(message, privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Account:  ", database.PublicKeyToAccountID(privateKey.PublicKey))
+	fmt.Println("Signature:", sig)
+}
+
+func verifyMessageRun(cmd *cobra.Command, args []string) {
+	if signerID != "" {
+		if _, err := database.ToAccountID(signerID); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	from, err := signature.VerifyMessage(message, messageSig, signerID)
+	if err != nil {
+		log.Fatal("INVALID: ", err)
+	}
+
+	fmt.Println("VALID")
+	fmt.Println("Signed by:", from)
+}
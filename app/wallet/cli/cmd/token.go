@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/client"
+	"github.com/spf13/cobra"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage user defined tokens",
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create <symbol>",
+	Short: "Create a token holding its full supply",
+	Args:  cobra.ExactArgs(1),
+	Run:   tokenCreateRun,
+}
+
+var tokenSendCmd = &cobra.Command{
+	Use:   "send <symbol>",
+	Short: "Send tokens to another account",
+	Args:  cobra.ExactArgs(1),
+	Run:   tokenSendRun,
+}
+
+var tokenBalanceCmd = &cobra.Command{
+	Use:   "balance <symbol>",
+	Short: "Print the token balance for the account",
+	Args:  cobra.ExactArgs(1),
+	Run:   tokenBalanceRun,
+}
+
+var amount uint64
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCreateCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Nonce for the transaction.")
+	tokenCreateCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+	tokenCreateCmd.Flags().Uint64VarP(&amount, "supply", "s", 0, "Total supply of the token.")
+
+	tokenCmd.AddCommand(tokenSendCmd)
+	tokenSendCmd.Flags().Uint64VarP(&nonce, "nonce", "n", 0, "Nonce for the transaction.")
+	tokenSendCmd.Flags().Uint64VarP(&tip, "tip", "c", 0, "Tip to send.")
+	tokenSendCmd.Flags().StringVarP(&to, "to", "t", "", "Account to send the tokens to.")
+	tokenSendCmd.Flags().Uint64VarP(&amount, "amount", "m", 0, "Amount of tokens to send.")
+
+	tokenCmd.AddCommand(tokenBalanceCmd)
+}
+
+func tokenCreateRun(cmd *cobra.Command, args []string) {
+	data, err := database.NewTokenCreate(args[0], amount)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The token is created by sending the transaction to yourself.
+	tokenSubmit("", data)
+}
+
+func tokenSendRun(cmd *cobra.Command, args []string) {
+	data, err := database.NewTokenTransfer(args[0], amount)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tokenSubmit(to, data)
+}
+
+func tokenBalanceRun(cmd *cobra.Command, args []string) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	accountID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	balance, err := cln.TokenBalance(ctx, args[0], accountID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Account:", accountID)
+	fmt.Println("Balance:", balance, args[0])
+}
+
+// tokenSubmit signs and submits a token transaction to the specified
+// account, or to the account itself when no account is specified.
+func tokenSubmit(toStr string, data []byte) {
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fromID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	toID := fromID
+	if toStr != "" {
+		if toID, err = database.ToAccountID(toStr); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cln := client.New(client.Config{URL: nodeURL, Retries: 3})
+
+	// The transaction must be signed for the chain the node is running.
+	gen, err := chainGenesis(ctx, cln)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tx, err := database.NewTx(gen.ChainID, nonce, fromID, toID, 0, tip, data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := cln.SubmitTransaction(ctx, signedTx); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Submitted:", signedTx.TxHash())
+}
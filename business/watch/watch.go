@@ -0,0 +1,104 @@
+// Package watch provides support for notifying the node operator about
+// activity on the addresses the node has been configured to watch.
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ardanlabs/blockchain/business/web/metrics"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"go.uber.org/zap"
+)
+
+// subscriberID is the id used to subscribe to the state events.
+const subscriberID = "watch"
+
+// webhookTimeout is the amount of time a webhook call has to complete.
+const webhookTimeout = 5 * time.Second
+
+// Config represents the configuration required to run the watcher.
+type Config struct {
+	Log        *zap.SugaredLogger
+	State      *state.State
+	WebhookURL string
+}
+
+// Run starts a goroutine that listens for address watch events and for
+// reverted transactions involving a watched address, so the operator learns
+// when a payment they were told about is no longer in the chain. Every event
+// bumps the watched address metric and, when a webhook url is configured,
+// the event is posted to the url. The goroutine terminates when the state
+// is shutdown.
+func Run(cfg Config) {
+	ctx := metrics.Set(context.Background())
+	ch := cfg.State.Events().Subscribe(subscriberID)
+
+	go func() {
+		cfg.Log.Infow("watch: G started")
+		defer cfg.Log.Infow("watch: G completed")
+
+		for evt := range ch {
+			if !isWatchEvent(cfg.State, evt) {
+				continue
+			}
+
+			metrics.AddWatched(ctx)
+
+			if cfg.WebhookURL == "" {
+				continue
+			}
+
+			if err := postWebhook(ctx, cfg.WebhookURL, evt); err != nil {
+				cfg.Log.Errorw("watch: webhook", "url", cfg.WebhookURL, "ERROR", err)
+			}
+		}
+	}()
+}
+
+// isWatchEvent reports if the event is about a watched address.
+func isWatchEvent(st *state.State, evt state.Event) bool {
+	switch evt.Type {
+	case state.EventAddressWatch:
+		return true
+
+	case state.EventTxReverted:
+		reverted, ok := evt.Data.(state.RevertedTx)
+		return ok && (st.IsWatched(reverted.Tx.FromID) || st.IsWatched(reverted.Tx.ToID))
+	}
+
+	return false
+}
+
+// postWebhook sends the event as JSON to the specified url.
+func postWebhook(ctx context.Context, url string, evt state.Event) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -22,6 +22,7 @@ type metrics struct {
 	requests   *expvar.Int
 	errors     *expvar.Int
 	panics     *expvar.Int
+	watched    *expvar.Int
 }
 
 // init constructs the metrics value that will be used to capture metrics.
@@ -34,6 +35,7 @@ func init() {
 		requests:   expvar.NewInt("requests"),
 		errors:     expvar.NewInt("errors"),
 		panics:     expvar.NewInt("panics"),
+		watched:    expvar.NewInt("watched_address_hits"),
 	}
 }
 
@@ -87,3 +89,10 @@ func AddPanics(ctx context.Context) {
 		v.panics.Add(1)
 	}
 }
+
+// AddWatched increments the watched address hits metric by 1.
+func AddWatched(ctx context.Context) {
+	if v, ok := ctx.Value(key).(*metrics); ok {
+		v.watched.Add(1)
+	}
+}
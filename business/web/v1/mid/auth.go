@@ -0,0 +1,46 @@
+package mid
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	v1Web "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/foundation/web"
+)
+
+// Authenticate validates the request carries the specified token as a bearer
+// token in the Authorization header.
+func Authenticate(token string) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+			// Expecting: bearer <token>
+			authStr := r.Header.Get("authorization")
+			parts := strings.Split(authStr, " ")
+			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+				err := errors.New("expected authorization header format: bearer <token>")
+				return v1Web.NewRequestError(err, http.StatusUnauthorized)
+			}
+
+			// Use a constant time compare so the token can't be discovered
+			// by timing the responses.
+			if subtle.ConstantTimeCompare([]byte(parts[1]), []byte(token)) != 1 {
+				return v1Web.NewRequestError(errors.New("invalid token"), http.StatusUnauthorized)
+			}
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,55 @@
+package mid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v1Web "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/business/web/v1/mid"
+)
+
+func TestAuthenticate(t *testing.T) {
+	tt := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{"valid token", "Bearer secret", http.StatusOK},
+		{"lowercase scheme", "bearer secret", http.StatusOK},
+		{"missing header", "", http.StatusUnauthorized},
+		{"wrong scheme", "Basic secret", http.StatusUnauthorized},
+		{"wrong token", "Bearer secrets", http.StatusUnauthorized},
+		{"extra parts", "Bearer secret secret", http.StatusUnauthorized},
+	}
+
+	for _, tst := range tt {
+		var called bool
+		handler := mid.Authenticate("secret")(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			called = true
+			return nil
+		})
+
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tst.header != "" {
+			r.Header.Set("Authorization", tst.header)
+		}
+
+		status := http.StatusOK
+		if err := handler(context.Background(), httptest.NewRecorder(), r); err != nil {
+			reqErr := v1Web.GetRequestError(err)
+			if reqErr == nil {
+				t.Fatalf("%s: Should return a request error, got %v", tst.name, err)
+			}
+			status = reqErr.Status
+		}
+
+		if status != tst.status {
+			t.Errorf("%s: Should get status %d, got %d", tst.name, tst.status, status)
+		}
+		if called != (tst.status == http.StatusOK) {
+			t.Errorf("%s: Should only call the handler for a valid token, called %t", tst.name, called)
+		}
+	}
+}
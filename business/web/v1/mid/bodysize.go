@@ -0,0 +1,37 @@
+package mid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	v1Web "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/foundation/web"
+)
+
+// MaxBodySize rejects requests with a body larger than the specified number
+// of bytes. Bodies without a known length are cut off at the limit so they
+// fail to decode.
+func MaxBodySize(size int64) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.ContentLength > size {
+				err := fmt.Errorf("request body of %d bytes is larger than the max of %d bytes", r.ContentLength, size)
+				return v1Web.NewRequestError(err, http.StatusRequestEntityTooLarge)
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, size)
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
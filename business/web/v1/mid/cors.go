@@ -7,19 +7,42 @@ import (
 	"github.com/ardanlabs/blockchain/foundation/web"
 )
 
-// Cors sets the response headers needed for Cross-Origin Resource Sharing
-func Cors(origin string) web.Middleware {
+// Cors sets the response headers needed for Cross-Origin Resource Sharing.
+// Requests from an origin in the list are allowed, a "*" in the list allows
+// every origin. Requests from any other origin get no CORS headers, so the
+// browser blocks the response.
+func Cors(origins []string) web.Middleware {
+	allowAll := false
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[origin] = true
+	}
 
 	// This is the actual middleware function to be executed.
 	m := func(handler web.Handler) web.Handler {
 
 		// Create the handler that will be attached in the middleware chain.
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			origin := r.Header.Get("Origin")
 
 			// Set the CORS headers to the response.
-			w.Header().Set("Access-Control-Allow-Origin", origin)
+			switch {
+			case allowAll:
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			case allowed[origin]:
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Add("Vary", "Origin")
+			default:
+				return handler(ctx, w, r)
+			}
+
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, PUT, DELETE, OPTIONS")
 			w.Header().Set("Access-Control-Allow-Headers", "Origin, Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+			w.Header().Set("Access-Control-Expose-Headers", "API-Version")
+			w.Header().Set("Access-Control-Max-Age", "86400")
 
 			// Call the next handler.
 			return handler(ctx, w, r)
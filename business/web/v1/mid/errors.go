@@ -51,6 +51,7 @@ func Errors(log *zap.SugaredLogger) web.Middleware {
 					reqErr := v1Web.GetRequestError(err)
 					er = v1Web.ErrorResponse{
 						Error: reqErr.Error(),
+						Code:  reqErr.Code,
 					}
 					status = reqErr.Status
 
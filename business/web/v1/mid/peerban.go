@@ -0,0 +1,34 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	v1Web "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/web"
+)
+
+// PeerBan rejects the requests sent by peers that are banned so their data
+// doesn't cost this node any more validation.
+func PeerBan(isBanned func(host string) bool) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if isBanned(peer.FromRequest(r)) {
+				return v1Web.NewRequestError(errors.New("peer is banned"), http.StatusForbidden)
+			}
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,105 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	v1Web "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/foundation/web"
+)
+
+// RateLimit limits the number of requests each client IP address can make.
+// A client can make burst requests at once and is then limited to the rate
+// of requests per second. A rate of zero disables the limit.
+func RateLimit(rate float64, burst int) web.Middleware {
+	lim := limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		clients: make(map[string]*bucket),
+	}
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if rate <= 0 {
+				return handler(ctx, w, r)
+			}
+
+			ip, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				ip = r.RemoteAddr
+			}
+
+			if !lim.allow(ip, time.Now()) {
+				w.Header().Set("Retry-After", "1")
+				return v1Web.NewRequestError(errors.New("rate limit exceeded, try again later"), http.StatusTooManyRequests)
+			}
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// =============================================================================
+
+// bucket holds the tokens available to a single client.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter implements a token bucket per client. Each bucket refills at the
+// rate per second up to the burst size.
+type limiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	clients   map[string]*bucket
+	lastSweep time.Time
+}
+
+// allow reports if the client has a token available, taking it if so.
+func (l *limiter) allow(ip string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// A bucket that has been idle long enough to refill is the same as a new
+	// one, so remove those to keep the map from growing forever.
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	if now.Sub(l.lastSweep) > refill {
+		for client, b := range l.clients {
+			if now.Sub(b.last) > refill {
+				delete(l.clients, client)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, exists := l.clients[ip]
+	if !exists {
+		b = &bucket{tokens: l.burst, last: now}
+		l.clients[ip] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
@@ -0,0 +1,69 @@
+package mid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v1Web "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/business/web/v1/mid"
+)
+
+func TestRateLimit(t *testing.T) {
+	handler := mid.RateLimit(50, 2)(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+
+	call := func(remoteAddr string) (int, string) {
+		t.Helper()
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+
+		err := handler(context.Background(), w, r)
+		if err == nil {
+			return http.StatusOK, ""
+		}
+
+		reqErr := v1Web.GetRequestError(err)
+		if reqErr == nil {
+			t.Fatalf("Should return a request error, got %v", err)
+		}
+
+		return reqErr.Status, w.Header().Get("Retry-After")
+	}
+
+	// The burst is allowed and the next request is turned away.
+	for i := 0; i < 2; i++ {
+		if status, _ := call("10.0.0.1:1000"); status != http.StatusOK {
+			t.Fatalf("Should allow request %d of the burst, got %d", i, status)
+		}
+	}
+	if status, retry := call("10.0.0.1:1001"); status != http.StatusTooManyRequests || retry != "1" {
+		t.Fatalf("Should limit the client on any port, got %d, retry %q", status, retry)
+	}
+
+	// Every client has its own bucket.
+	if status, _ := call("10.0.0.2:1000"); status != http.StatusOK {
+		t.Fatalf("Should allow another client, got %d", status)
+	}
+
+	// The bucket refills at the rate, 1 request every 20ms.
+	time.Sleep(50 * time.Millisecond)
+	if status, _ := call("10.0.0.1:1000"); status != http.StatusOK {
+		t.Fatalf("Should allow the client once the bucket refills, got %d", status)
+	}
+
+	// A rate of zero disables the limit.
+	handler = mid.RateLimit(0, 0)(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+	for i := 0; i < 10; i++ {
+		if status, _ := call("10.0.0.1:1000"); status != http.StatusOK {
+			t.Fatalf("Should not limit the client, got %d", status)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package mid
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	v1Web "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/foundation/web"
+)
+
+// vendorPrefix is the start of the media type a client uses to ask for a
+// version of the API, such as application/vnd.blockchain.v1+json.
+const vendorPrefix = "application/vnd.blockchain."
+
+// Version negotiates the content of the response for the routes of the
+// specified version of the API. A request is accepted when the Accept header
+// is missing or allows JSON or the vendor media type for the version. A
+// request asking for the vendor media type of another version is rejected,
+// so a client written for a newer API learns it's talking to an older node.
+// The version that handled the request is returned in the API-Version header.
+// The routes can also serve the other media types specified, such as the
+// binary encodings peers exchange blocks with.
+func Version(version string, mediaTypes ...string) web.Middleware {
+	vendorType := vendorPrefix + version + "+json"
+	served := append([]string{"*/*", "application/*", "application/json", vendorType}, mediaTypes...)
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if accept := r.Header.Get("Accept"); accept != "" && r.Header.Get("Upgrade") == "" {
+				if !acceptable(accept, served) {
+					err := fmt.Errorf("this route serves %s or application/json, not %s", vendorType, accept)
+					return v1Web.NewRequestError(err, http.StatusNotAcceptable)
+				}
+			}
+
+			w.Header().Set("API-Version", version)
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// acceptable reports if any media type in the Accept header is one of the
+// media types served.
+func acceptable(accept string, served []string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		for _, mt := range served {
+			if mediaType == mt {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,128 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+)
+
+// AddressLength is the number of bytes in an address.
+const AddressLength = 20
+
+// Address represents the 20 byte address of an account.
+type Address [AddressLength]byte
+
+// HexToAddress converts the hex string into an address. When the string
+// holds more than 20 bytes, the address is taken from the last 20 bytes.
+func HexToAddress(s string) Address {
+	s = trimHexPrefix(s)
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+
+	// Invalid characters end the decoding like they do for Ethereum, the
+	// caller is expected to have checked the string with IsHexAddress.
+	b, _ := hex.DecodeString(s)
+
+	var a Address
+	if len(b) > AddressLength {
+		b = b[len(b)-AddressLength:]
+	}
+	copy(a[AddressLength-len(b):], b)
+
+	return a
+}
+
+// IsHexAddress reports if the string is a hex encoded address, with or
+// without the 0x prefix.
+func IsHexAddress(s string) bool {
+	s = trimHexPrefix(s)
+	if len(s) != 2*AddressLength {
+		return false
+	}
+
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// String returns the address in the EIP-55 mixed case checksum encoding.
+func (a Address) String() string {
+	return a.Hex()
+}
+
+// Hex returns the address in the EIP-55 mixed case checksum encoding. The
+// case of each letter is set by the keccak256 hash of the lower case
+// address, so a typing mistake is likely to produce an invalid checksum.
+func (a Address) Hex() string {
+	lower := hex.EncodeToString(a[:])
+	sum := Keccak256([]byte(lower))
+
+	result := []byte(lower)
+	for i := range result {
+		nibble := sum[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+
+		if result[i] > '9' && nibble&0xf > 7 {
+			result[i] -= 'a' - 'A'
+		}
+	}
+
+	return "0x" + string(result)
+}
+
+// PubkeyToAddress returns the address of the public key, which is the last
+// 20 bytes of the keccak256 hash of the public key.
+func PubkeyToAddress(publicKey ecdsa.PublicKey) Address {
+	pub := FromECDSAPub(&publicKey)
+
+	var a Address
+	copy(a[:], Keccak256(pub[1:])[12:])
+
+	return a
+}
+
+// CreateAddress returns the address of the contract created by the account
+// with the specified nonce. The address is the last 20 bytes of the
+// keccak256 hash of the RLP encoding of the account and nonce, the same as
+// Ethereum.
+func CreateAddress(creator Address, nonce uint64) Address {
+	var a Address
+	copy(a[:], Keccak256(rlpAddressNonce(creator, nonce))[12:])
+
+	return a
+}
+
+// =============================================================================
+
+// rlpAddressNonce returns the RLP encoding of the list holding the address
+// and nonce. The list is always short, so the single byte list header is
+// used.
+func rlpAddressNonce(a Address, nonce uint64) []byte {
+	var n []byte
+	switch {
+	case nonce == 0:
+		n = []byte{0x80}
+	case nonce < 0x80:
+		n = []byte{byte(nonce)}
+	default:
+		var b []byte
+		for v := nonce; v > 0; v >>= 8 {
+			b = append([]byte{byte(v)}, b...)
+		}
+		n = append([]byte{0x80 + byte(len(b))}, b...)
+	}
+
+	payload := append([]byte{0x80 + AddressLength}, a[:]...)
+	payload = append(payload, n...)
+
+	return append([]byte{0xc0 + byte(len(payload))}, payload...)
+}
+
+// trimHexPrefix removes the 0x prefix from the string.
+func trimHexPrefix(s string) string {
+	if len(s) >= 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		return s[2:]
+	}
+	return s
+}
@@ -0,0 +1,239 @@
+// Package crypto provides the secp256k1 key handling and signing the
+// blockchain needs: key generation, key files, signing a digest and
+// recovering the public key from a signature. It's implemented with the
+// decred secp256k1 package and the standard library, so the blockchain
+// doesn't depend on go-ethereum. Keys, signatures and addresses are
+// compatible with the ones Ethereum produces.
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/hash"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	decredecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// Set of lengths for the values handled by this package.
+const (
+	DigestLength     = 32 // Length of the digest that is signed.
+	SignatureLength  = 65 // Length of a signature in the [R || S || V] format.
+	RecoveryIDOffset = 64 // Offset of the recovery id V in a signature.
+	privateKeyLength = 32
+	publicKeyLength  = 65
+)
+
+// compactRecoveryCode is the value decred adds to the recovery id in a
+// compact signature for an uncompressed public key.
+const compactRecoveryCode = 27
+
+var (
+	secp256k1N     = secp256k1.S256().Params().N
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+// S256 returns the secp256k1 curve.
+func S256() elliptic.Curve {
+	return secp256k1.S256()
+}
+
+// Keccak256 returns the keccak256 hash of the data concatenated together.
+func Keccak256(data ...[]byte) []byte {
+	return hash.Keccak256.Sum(data...)
+}
+
+// =============================================================================
+
+// GenerateKey generates a new private key.
+func GenerateKey() (*ecdsa.PrivateKey, error) {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return key.ToECDSA(), nil
+}
+
+// ToECDSA constructs a private key from the 32 byte value of the key.
+func ToECDSA(d []byte) (*ecdsa.PrivateKey, error) {
+	if len(d) != privateKeyLength {
+		return nil, fmt.Errorf("invalid private key length %d, must be %d", len(d), privateKeyLength)
+	}
+
+	k := new(big.Int).SetBytes(d)
+	if k.Sign() <= 0 || k.Cmp(secp256k1N) >= 0 {
+		return nil, errors.New("invalid private key, must be in the range of the curve order")
+	}
+
+	return secp256k1.PrivKeyFromBytes(d).ToECDSA(), nil
+}
+
+// HexToECDSA constructs a private key from the hex encoded value of the key.
+func HexToECDSA(hexKey string) (*ecdsa.PrivateKey, error) {
+	d, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, errors.New("invalid hex data for private key")
+	}
+
+	return ToECDSA(d)
+}
+
+// FromECDSA returns the 32 byte value of the private key.
+func FromECDSA(privateKey *ecdsa.PrivateKey) []byte {
+	if privateKey == nil {
+		return nil
+	}
+
+	return privateKey.D.FillBytes(make([]byte, privateKeyLength))
+}
+
+// FromECDSAPub returns the 65 byte uncompressed encoding of the public key.
+func FromECDSAPub(publicKey *ecdsa.PublicKey) []byte {
+	if publicKey == nil || publicKey.X == nil || publicKey.Y == nil {
+		return nil
+	}
+
+	b := make([]byte, publicKeyLength)
+	b[0] = 4
+	publicKey.X.FillBytes(b[1:33])
+	publicKey.Y.FillBytes(b[33:])
+
+	return b
+}
+
+// UnmarshalPubkey constructs a public key from its 65 byte uncompressed
+// encoding.
+func UnmarshalPubkey(pub []byte) (*ecdsa.PublicKey, error) {
+	if len(pub) != publicKeyLength {
+		return nil, fmt.Errorf("invalid public key length %d, must be %d", len(pub), publicKeyLength)
+	}
+
+	key, err := secp256k1.ParsePubKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	return key.ToECDSA(), nil
+}
+
+// CompressPubkey returns the 33 byte compressed encoding of the public key.
+func CompressPubkey(publicKey *ecdsa.PublicKey) []byte {
+	var x, y secp256k1.FieldVal
+	x.SetByteSlice(publicKey.X.Bytes())
+	y.SetByteSlice(publicKey.Y.Bytes())
+
+	return secp256k1.NewPublicKey(&x, &y).SerializeCompressed()
+}
+
+// LoadECDSA reads the hex encoded private key from the specified file.
+func LoadECDSA(file string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return HexToECDSA(strings.TrimSpace(string(data)))
+}
+
+// SaveECDSA writes the hex encoded private key to the specified file. The
+// file is only readable by the owner.
+func SaveECDSA(file string, privateKey *ecdsa.PrivateKey) error {
+	k := hex.EncodeToString(FromECDSA(privateKey))
+	return os.WriteFile(file, []byte(k), 0600)
+}
+
+// =============================================================================
+
+// Sign signs the 32 byte digest with the private key and returns the
+// signature in the [R || S || V] format, where V is the recovery id of 0
+// or 1. The signature is deterministic and S is always in the lower half
+// of the curve order.
+func Sign(digest []byte, privateKey *ecdsa.PrivateKey) ([]byte, error) {
+	if len(digest) != DigestLength {
+		return nil, fmt.Errorf("digest is required to be exactly %d bytes (%d)", DigestLength, len(digest))
+	}
+
+	key := secp256k1.PrivKeyFromBytes(FromECDSA(privateKey))
+	defer key.Zero()
+
+	// The compact format is [V || R || S] with the recovery code added to V.
+	compact := decredecdsa.SignCompact(key, digest, false)
+
+	sig := make([]byte, SignatureLength)
+	copy(sig, compact[1:])
+	sig[RecoveryIDOffset] = compact[0] - compactRecoveryCode
+
+	return sig, nil
+}
+
+// SigToPub recovers the public key that produced the signature for the
+// 32 byte digest. The signature is in the [R || S || V] format.
+func SigToPub(digest []byte, sig []byte) (*ecdsa.PublicKey, error) {
+	if len(digest) != DigestLength {
+		return nil, fmt.Errorf("digest is required to be exactly %d bytes (%d)", DigestLength, len(digest))
+	}
+	if len(sig) != SignatureLength {
+		return nil, fmt.Errorf("invalid signature length %d, must be %d", len(sig), SignatureLength)
+	}
+	if sig[RecoveryIDOffset] > 3 {
+		return nil, fmt.Errorf("invalid signature recovery id %d", sig[RecoveryIDOffset])
+	}
+
+	compact := make([]byte, SignatureLength)
+	compact[0] = sig[RecoveryIDOffset] + compactRecoveryCode
+	copy(compact[1:], sig[:RecoveryIDOffset])
+
+	key, _, err := decredecdsa.RecoverCompact(compact, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return key.ToECDSA(), nil
+}
+
+// VerifySignature checks the 64 byte [R || S] signature of the digest was
+// produced by the public key, which can be compressed or uncompressed. A
+// signature with S in the upper half of the curve order is rejected so a
+// signature can't be changed into another valid signature.
+func VerifySignature(pub []byte, digest []byte, sig []byte) bool {
+	if len(sig) != RecoveryIDOffset || len(digest) != DigestLength {
+		return false
+	}
+
+	var r, s secp256k1.ModNScalar
+	if r.SetByteSlice(sig[:32]) || s.SetByteSlice(sig[32:]) {
+		return false
+	}
+	if r.IsZero() || s.IsZero() || s.IsOverHalfOrder() {
+		return false
+	}
+
+	key, err := secp256k1.ParsePubKey(pub)
+	if err != nil {
+		return false
+	}
+
+	return decredecdsa.NewSignature(&r, &s).Verify(digest, key)
+}
+
+// ValidateSignatureValues checks the signature values are in range for the
+// curve. When homestead is true, S must be in the lower half of the curve
+// order like Ethereum requires since the homestead release.
+func ValidateSignatureValues(v byte, r, s *big.Int, homestead bool) bool {
+	if r.Cmp(big.NewInt(1)) < 0 || s.Cmp(big.NewInt(1)) < 0 {
+		return false
+	}
+
+	if homestead && s.Cmp(secp256k1HalfN) > 0 {
+		return false
+	}
+
+	return r.Cmp(secp256k1N) < 0 && s.Cmp(secp256k1N) < 0 && (v == 0 || v == 1)
+}
@@ -0,0 +1,105 @@
+package crypto_test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+)
+
+// Key and address used by the go-ethereum crypto tests, so the address
+// derived here matches the one Ethereum derives.
+const (
+	testPrivHex = "289c2857d4598e37fb9647507e47a309d6133539bf21a8b9cb6df88fd5232032"
+	testAddrHex = "0x970E8128AB834E8EAC17Ab8E3812F010678CF791"
+)
+
+func TestSignRecover(t *testing.T) {
+	pk, err := crypto.HexToECDSA(testPrivHex)
+	if err != nil {
+		t.Fatalf("Should be able to load the key: %s", err)
+	}
+
+	if got := crypto.PubkeyToAddress(pk.PublicKey).Hex(); got != testAddrHex {
+		t.Fatalf("Should derive the Ethereum address, got %s, exp %s", got, testAddrHex)
+	}
+
+	digest := crypto.Keccak256([]byte("foo"))
+
+	sig, err := crypto.Sign(digest, pk)
+	if err != nil {
+		t.Fatalf("Should be able to sign the digest: %s", err)
+	}
+	if len(sig) != crypto.SignatureLength {
+		t.Fatalf("Should get a signature of %d bytes, got %d", crypto.SignatureLength, len(sig))
+	}
+
+	pub, err := crypto.SigToPub(digest, sig)
+	if err != nil {
+		t.Fatalf("Should be able to recover the public key: %s", err)
+	}
+	if got := crypto.PubkeyToAddress(*pub).Hex(); got != testAddrHex {
+		t.Fatalf("Should recover the public key of the signer, got %s, exp %s", got, testAddrHex)
+	}
+
+	pubBytes := crypto.FromECDSAPub(&pk.PublicKey)
+	if !crypto.VerifySignature(pubBytes, digest, sig[:crypto.RecoveryIDOffset]) {
+		t.Fatal("Should verify the signature with the uncompressed public key")
+	}
+	if !crypto.VerifySignature(crypto.CompressPubkey(&pk.PublicKey), digest, sig[:crypto.RecoveryIDOffset]) {
+		t.Fatal("Should verify the signature with the compressed public key")
+	}
+	if crypto.VerifySignature(pubBytes, crypto.Keccak256([]byte("bar")), sig[:crypto.RecoveryIDOffset]) {
+		t.Fatal("Should not verify the signature of another digest")
+	}
+}
+
+// TestMalleatedSignature checks a signature with S replaced by N - S, which
+// is just as valid for the curve, is rejected.
+func TestMalleatedSignature(t *testing.T) {
+	pk, err := crypto.HexToECDSA(testPrivHex)
+	if err != nil {
+		t.Fatalf("Should be able to load the key: %s", err)
+	}
+
+	digest := crypto.Keccak256([]byte("foo"))
+
+	sig, err := crypto.Sign(digest, pk)
+	if err != nil {
+		t.Fatalf("Should be able to sign the digest: %s", err)
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:64])
+	v := sig[crypto.RecoveryIDOffset]
+
+	if !crypto.ValidateSignatureValues(v, r, s, true) {
+		t.Fatal("Should accept the values of the signature")
+	}
+
+	n := crypto.S256().Params().N
+	highS := new(big.Int).Sub(n, s)
+
+	malleated := make([]byte, crypto.SignatureLength)
+	copy(malleated, sig[:32])
+	highS.FillBytes(malleated[32:64])
+	malleated[crypto.RecoveryIDOffset] = v ^ 1
+
+	if crypto.ValidateSignatureValues(malleated[crypto.RecoveryIDOffset], r, highS, true) {
+		t.Fatal("Should reject the values of the malleated signature")
+	}
+	if crypto.VerifySignature(crypto.FromECDSAPub(&pk.PublicKey), digest, malleated[:crypto.RecoveryIDOffset]) {
+		t.Fatal("Should not verify the malleated signature")
+	}
+
+	// The malleated signature still recovers the signer, which is why the
+	// value of S has to be checked.
+	pub, err := crypto.SigToPub(digest, malleated)
+	if err != nil {
+		t.Fatalf("Should be able to recover the public key: %s", err)
+	}
+	if !bytes.Equal(crypto.FromECDSAPub(pub), crypto.FromECDSAPub(&pk.PublicKey)) {
+		t.Fatal("Should recover the signer from the malleated signature")
+	}
+}
@@ -0,0 +1,141 @@
+// Package ecies encrypts messages to a secp256k1 public key with the
+// elliptic curve integrated encryption scheme. The message is encrypted
+// with AES-128-CTR and authenticated with HMAC-SHA256, using keys derived
+// from a shared secret between the public key and a new ephemeral key. The
+// format is the one go-ethereum uses, so messages encrypted before the
+// blockchain stopped depending on go-ethereum can still be decrypted.
+//
+//	ephemeral public key (65) || iv (16) || cipher text || tag (32)
+package ecies
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// Set of lengths for the parts of an encrypted message.
+const (
+	keyLength       = 16
+	ivLength        = aes.BlockSize
+	tagLength       = sha256.Size
+	publicKeyLength = 65
+	overhead        = publicKeyLength + ivLength + tagLength
+)
+
+// ErrInvalidMessage is returned when the message can't be decrypted, either
+// because it was changed or it was encrypted for a different key.
+var ErrInvalidMessage = errors.New("invalid message")
+
+// Encrypt encrypts the message so only the owner of the private key for the
+// public key can decrypt it.
+func Encrypt(publicKey *ecdsa.PublicKey, msg []byte) ([]byte, error) {
+	pub, err := toPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeral, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	defer ephemeral.Zero()
+
+	encKey, macKey := deriveKeys(secp256k1.GenerateSharedSecret(ephemeral, pub))
+
+	iv := make([]byte, ivLength)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+
+	em := make([]byte, ivLength+len(msg))
+	copy(em, iv)
+	cipher.NewCTR(block, iv).XORKeyStream(em[ivLength:], msg)
+
+	out := make([]byte, 0, overhead+len(msg))
+	out = append(out, ephemeral.PubKey().SerializeUncompressed()...)
+	out = append(out, em...)
+	out = append(out, messageTag(macKey, em)...)
+
+	return out, nil
+}
+
+// Decrypt decrypts the message with the private key it was encrypted for.
+func Decrypt(privateKey *ecdsa.PrivateKey, data []byte) ([]byte, error) {
+	if len(data) < overhead {
+		return nil, ErrInvalidMessage
+	}
+
+	ephemeral, err := secp256k1.ParsePubKey(data[:publicKeyLength])
+	if err != nil {
+		return nil, ErrInvalidMessage
+	}
+
+	key := secp256k1.PrivKeyFromBytes(crypto.FromECDSA(privateKey))
+	defer key.Zero()
+
+	encKey, macKey := deriveKeys(secp256k1.GenerateSharedSecret(key, ephemeral))
+
+	em := data[publicKeyLength : len(data)-tagLength]
+	if !hmac.Equal(messageTag(macKey, em), data[len(data)-tagLength:]) {
+		return nil, ErrInvalidMessage
+	}
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := make([]byte, len(em)-ivLength)
+	cipher.NewCTR(block, em[:ivLength]).XORKeyStream(msg, em[ivLength:])
+
+	return msg, nil
+}
+
+// =============================================================================
+
+// deriveKeys derives the encryption and authentication keys from the shared
+// secret with the NIST SP 800-56 concatenation key derivation function.
+func deriveKeys(secret []byte) (encKey []byte, macKey []byte) {
+	var k []byte
+	for counter := uint32(1); len(k) < 2*keyLength; counter++ {
+		h := sha256.New()
+		binary.Write(h, binary.BigEndian, counter)
+		h.Write(secret)
+		k = h.Sum(k)
+	}
+
+	mac := sha256.Sum256(k[keyLength : 2*keyLength])
+	return k[:keyLength], mac[:]
+}
+
+// messageTag authenticates the iv and cipher text.
+func messageTag(macKey []byte, em []byte) []byte {
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(em)
+	return mac.Sum(nil)
+}
+
+// toPublicKey converts the public key to a secp256k1 public key, making
+// sure it's on the curve.
+func toPublicKey(publicKey *ecdsa.PublicKey) (*secp256k1.PublicKey, error) {
+	b := crypto.FromECDSAPub(publicKey)
+	if b == nil {
+		return nil, errors.New("invalid public key")
+	}
+
+	return secp256k1.ParsePubKey(b)
+}
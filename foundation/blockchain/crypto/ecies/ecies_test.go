@@ -0,0 +1,90 @@
+package ecies_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto/ecies"
+)
+
+// privateKey is the key the go-ethereum message below was encrypted for.
+const privateKey = "fad9c8855b740a0b7ed4c221dbad0f33a83a49cad6b3fe8d5817ac83d38b6a19"
+
+// gethMessage is "Hello from go-ethereum" encrypted with the ecies package
+// of go-ethereum v1.10.26.
+const gethMessage = "0491e13225c5f6da20ac91b68177dfbc7f04d31540b96bec7cf59f2627501417c88c7ce11ae730eabf9d36c82db47c52ea76c3c8274326ddfb5ed6f41ab1f752d701b40f73ecc6dde8bcd20eeb34707cf5d82278abdd7084ea31e94f558c3f44923f2ef34790e81a5bf3cb5fb707d80d8b67e3afac9d632e3587da3ce6b10a1c6a99331e74cae3"
+
+func TestEncryptDecrypt(t *testing.T) {
+	pk, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Should be able to generate a key: %s", err)
+	}
+
+	msg := []byte("the test answers are in the usual place")
+
+	data, err := ecies.Encrypt(&pk.PublicKey, msg)
+	if err != nil {
+		t.Fatalf("Should be able to encrypt the message: %s", err)
+	}
+
+	got, err := ecies.Decrypt(pk, data)
+	if err != nil {
+		t.Fatalf("Should be able to decrypt the message: %s", err)
+	}
+	if string(got) != string(msg) {
+		t.Fatalf("Should get the message back, got %q, exp %q", got, msg)
+	}
+
+	// Changing any part of the message must be detected.
+	tt := []struct {
+		name  string
+		index int
+	}{
+		{"cipher text", len(data) - 33},
+		{"tag", len(data) - 1},
+		{"iv", 65},
+	}
+
+	for _, tst := range tt {
+		changed := append([]byte(nil), data...)
+		changed[tst.index] ^= 0x01
+
+		if _, err := ecies.Decrypt(pk, changed); !errors.Is(err, ecies.ErrInvalidMessage) {
+			t.Errorf("%s: Should reject the changed message, got %v", tst.name, err)
+		}
+	}
+
+	if _, err := ecies.Decrypt(pk, data[:64]); !errors.Is(err, ecies.ErrInvalidMessage) {
+		t.Errorf("Should reject the truncated message, got %v", err)
+	}
+
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Should be able to generate a key: %s", err)
+	}
+	if _, err := ecies.Decrypt(other, data); !errors.Is(err, ecies.ErrInvalidMessage) {
+		t.Fatalf("Should not decrypt the message with another key, got %v", err)
+	}
+}
+
+func TestDecryptGeth(t *testing.T) {
+	pk, err := crypto.HexToECDSA(privateKey)
+	if err != nil {
+		t.Fatalf("Should be able to load the key: %s", err)
+	}
+
+	data, err := hex.DecodeString(gethMessage)
+	if err != nil {
+		t.Fatalf("Should be able to decode the message: %s", err)
+	}
+
+	msg, err := ecies.Decrypt(pk, data)
+	if err != nil {
+		t.Fatalf("Should be able to decrypt the go-ethereum message: %s", err)
+	}
+	if string(msg) != "Hello from go-ethereum" {
+		t.Fatalf("Should get the message back, got %q, exp %q", msg, "Hello from go-ethereum")
+	}
+}
@@ -3,7 +3,10 @@ package database
 import (
 	"crypto/ecdsa"
 	"errors"
-	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+	"github.com/ardanlabs/blockchain/foundation/hexutil"
 )
 
 // Account represents information stored in the database for an individual account.
@@ -11,6 +14,8 @@ type Account struct {
 	AccountID AccountID
 	Nonce     uint64
 	Balance   uint64
+	Multisig  *Multisig      `json:",omitempty"`
+	Rules     *SpendingRules `json:",omitempty"`
 }
 
 // newAccount constructs a new account value for use.
@@ -21,6 +26,40 @@ func newAccount(accountID AccountID, balance uint64) Account {
 	}
 }
 
+// Hash implements the merkle Hashable interface for providing a hash of the
+// account. Everything recorded for the account is hashed, so a proof of the
+// account proves its balance and nonce.
+func (a Account) Hash() ([]byte, error) {
+	return hexutil.Decode(signature.Hash(a))
+}
+
+// Equals implements the merkle Hashable interface for providing an equality
+// check between two accounts. An account only appears once in the state.
+func (a Account) Equals(other Account) bool {
+	return a.AccountID == other.AccountID
+}
+
+// =============================================================================
+
+// byAccount provides sorting support by the account id value.
+type byAccount []Account
+
+// Len returns the number of transactions in the list.
+func (ba byAccount) Len() int {
+	return len(ba)
+}
+
+// Less helps to sort the list by account id in ascending order to keep the
+// accounts in the right order of processing.
+func (ba byAccount) Less(i, j int) bool {
+	return ba[i].AccountID < ba[j].AccountID
+}
+
+// Swap moves accounts in the order of the account id value.
+func (ba byAccount) Swap(i, j int) {
+	ba[i], ba[j] = ba[j], ba[i]
+}
+
 // =============================================================================
 
 // AccountID represents an account id that is used to sign transactions and is
@@ -44,6 +83,15 @@ func PublicKeyToAccountID(pk ecdsa.PublicKey) AccountID {
 	return AccountID(crypto.PubkeyToAddress(pk).String())
 }
 
+// CreateAccountID derives a new account id from the account creating it and
+// the nonce of the transaction performing the creation. This follows the
+// Ethereum CREATE rules so the same creator and nonce always produce the
+// same account id and there is no private key for the account.
+func CreateAccountID(creatorID AccountID, nonce uint64) AccountID {
+	creator := crypto.HexToAddress(string(creatorID))
+	return AccountID(crypto.CreateAddress(creator, nonce).String())
+}
+
 // IsAccountID verifies whether the underlying data represents a valid
 // hex-encoded account.
 func (a AccountID) IsAccountID() bool {
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/merkle"
+	"github.com/ardanlabs/blockchain/foundation/hexutil"
+)
+
+// ErrNoAccountsRoot is returned when an account can't be proven because the
+// latest block doesn't record an accounts root.
+var ErrNoAccountsRoot = errors.New("latest block does not record an accounts root")
+
+// AccountProof represents the information a light client needs to check the
+// balance and nonce of an account without trusting the node. The account is
+// proven against the accounts root in the header, so it's the account as it
+// was when the block was applied, before the transactions of the block. The
+// client checks the header is the one the network agrees on by comparing
+// its hash with the hash other peers report for the block number.
+type AccountProof struct {
+	Account    Account     `json:"account"`
+	Header     BlockHeader `json:"header"`
+	Proof      []string    `json:"proof"`
+	ProofOrder []int64     `json:"proof_order"`
+}
+
+// Verify checks the work was performed for the header and the merkle proof
+// ties the account to the accounts root recorded in the header.
+func (ap AccountProof) Verify() error {
+	if ap.Header.AccountsRoot == "" {
+		return ErrNoAccountsRoot
+	}
+
+	if block := (Block{Header: ap.Header}); !block.IsSolved() {
+		return fmt.Errorf("%s invalid block hash", block.Hash())
+	}
+
+	root, err := hexutil.Decode(ap.Header.AccountsRoot)
+	if err != nil {
+		return fmt.Errorf("decoding accounts root: %w", err)
+	}
+
+	leaf, err := ap.Account.Hash()
+	if err != nil {
+		return err
+	}
+
+	proof := make([][]byte, len(ap.Proof))
+	for i, p := range ap.Proof {
+		if proof[i], err = hexutil.Decode(p); err != nil {
+			return fmt.Errorf("decoding merkle proof: %w", err)
+		}
+	}
+
+	if !merkle.VerifyProof(root, leaf, proof, ap.ProofOrder) {
+		return errors.New("merkle proof does not match the accounts root")
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// AccountsRoot returns the merkle root of the accounts.
+func (db *Database) AccountsRoot() string {
+	return db.load().accountsTree().RootHex()
+}
+
+// StateRoots returns the roots of the current state a new block must match
+// and the median time past it must be mined after.
+func (db *Database) StateRoots() StateRoots {
+	return StateRoots{
+		State:      db.HashState(),
+		Accounts:   db.AccountsRoot(),
+		MedianTime: db.MedianTimePast(),
+	}
+}
+
+// ProveAccount returns the specified account along with the merkle proof
+// tying it to the accounts root in the header of the latest block.
+func (db *Database) ProveAccount(accountID AccountID) (AccountProof, error) {
+	db.mu.RLock()
+	header := db.latestBlock.Header
+	v := db.proofView
+	db.mu.RUnlock()
+
+	if header.AccountsRoot == "" || v == nil {
+		return AccountProof{}, ErrNoAccountsRoot
+	}
+
+	tree := v.accountsTree()
+	if root := tree.RootHex(); root != header.AccountsRoot {
+		return AccountProof{}, fmt.Errorf("%w, current %s, expected %s", ErrAccountsRoot, root, header.AccountsRoot)
+	}
+
+	account, exists := v.accounts[accountID]
+	if !exists {
+		return AccountProof{}, errors.New("account does not exist")
+	}
+
+	rawProof, order, err := tree.Proof(account)
+	if err != nil {
+		return AccountProof{}, err
+	}
+
+	proof := make([]string, len(rawProof))
+	for i, rp := range rawProof {
+		proof[i] = merkle.ToHex(rp)
+	}
+
+	ap := AccountProof{
+		Account:    account,
+		Header:     header,
+		Proof:      proof,
+		ProofOrder: order,
+	}
+
+	return ap, nil
+}
+
+// trackProofView records the view of the state the latest block was applied
+// to, which is the state its accounts root was calculated from, and the
+// view the block produced for the next block. It's called as the latest
+// block changes. The caller must hold the lock or be constructing the
+// database.
+func (db *Database) trackProofView() {
+	if atomic.LoadInt32(&db.dirty) == 1 {
+		db.publish()
+	}
+
+	db.proofView = db.headView
+	db.headView = db.view.Load().(*view)
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// maxBeneficiaries is the largest number of accounts a block can split the
+// mining reward and fees across.
+const maxBeneficiaries = 16
+
+// ErrBeneficiaries is returned when the accounts a block splits the mining
+// reward and fees across are not valid.
+var ErrBeneficiaries = errors.New("block beneficiaries are not valid")
+
+// Beneficiary represents an account receiving a share of the mining reward
+// and fees of a block, such as a member of a mining pool. Each account gets
+// its weight out of the total weight of the beneficiaries.
+type Beneficiary struct {
+	AccountID AccountID `json:"account"`
+	Weight    uint16    `json:"weight"`
+}
+
+// Payout represents the amount paid to a beneficiary.
+type Payout struct {
+	AccountID AccountID
+	Amount    uint64
+}
+
+// SplitReward divides the amount between the beneficiaries by weight. What
+// is left over from rounding down goes to the first beneficiary.
+func SplitReward(beneficiaries []Beneficiary, amount uint64) []Payout {
+	var totalWeight uint64
+	for _, ben := range beneficiaries {
+		totalWeight += uint64(ben.Weight)
+	}
+
+	payouts := make([]Payout, len(beneficiaries))
+
+	var paid uint64
+	for i, ben := range beneficiaries {
+
+		// The product of the amount and weight can be larger than 64 bits.
+		// The weight is never larger than the total, so the quotient fits.
+		hi, lo := bits.Mul64(amount, uint64(ben.Weight))
+		share, _ := bits.Div64(hi, lo, totalWeight)
+
+		payouts[i] = Payout{AccountID: ben.AccountID, Amount: share}
+		paid += share
+	}
+	payouts[0].Amount += amount - paid
+
+	return payouts
+}
+
+// checkBeneficiaries makes sure a block splitting the reward names a small
+// number of distinct, properly formatted accounts that each have a weight.
+func checkBeneficiaries(b Block, previousBlock Block, roots StateRoots) error {
+	bens := b.Header.Beneficiaries
+	if len(bens) > maxBeneficiaries {
+		return fmt.Errorf("%w, got %d accounts, max %d", ErrBeneficiaries, len(bens), maxBeneficiaries)
+	}
+
+	seen := make(map[AccountID]bool, len(bens))
+	for _, ben := range bens {
+		switch {
+		case !ben.AccountID.IsAccountID():
+			return fmt.Errorf("%w, account %q is not properly formatted", ErrBeneficiaries, ben.AccountID)
+		case ben.Weight == 0:
+			return fmt.Errorf("%w, account %s has no weight", ErrBeneficiaries, ben.AccountID)
+		case seen[ben.AccountID]:
+			return fmt.Errorf("%w, account %s is listed more than once", ErrBeneficiaries, ben.AccountID)
+		}
+		seen[ben.AccountID] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,542 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/merkle"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// ErrChainForked is returned from validateNextBlock if another node's chain
+// is two or more blocks ahead of ours.
+var ErrChainForked = errors.New("blockchain forked, start resync")
+
+// Set of errors returned when a block breaks one of the validation rules. The
+// error returned by ValidateBlock wraps one of these with the details.
+var (
+	ErrBlockNumber  = errors.New("block is not the next number")
+	ErrDifficulty   = errors.New("block difficulty is less than previous block difficulty")
+	ErrBlockHash    = errors.New("block hash does not solve the difficulty")
+	ErrParentHash   = errors.New("parent block hash doesn't match our known parent")
+	ErrTimestamp    = errors.New("block timestamp is outside the allowed window")
+	ErrMerkleRoot   = errors.New("merkle root does not match transactions")
+	ErrStateRoot    = errors.New("state of the accounts are wrong")
+	ErrAccountsRoot = errors.New("accounts root does not match the accounts")
+)
+
+// =============================================================================
+
+// BlockData represents what can be serialized to disk and over the network.
+type BlockData struct {
+	Hash   string      `json:"hash"`
+	Header BlockHeader `json:"block"`
+	Trans  []BlockTx   `json:"trans"`
+}
+
+// NewBlockData constructs block data from a block.
+func NewBlockData(block Block) BlockData {
+	blockData := BlockData{
+		Hash:   block.Hash(),
+		Header: block.Header,
+		Trans:  block.MerkleTree.Values(),
+	}
+
+	return blockData
+}
+
+// ToBlock converts a storage block into a database block.
+func ToBlock(blockData BlockData) (Block, error) {
+	tree, err := merkle.NewTree(blockData.Trans)
+	if err != nil {
+		return Block{}, err
+	}
+
+	block := Block{
+		Header:     blockData.Header,
+		MerkleTree: tree,
+	}
+
+	return block, nil
+}
+
+// =============================================================================
+
+// BlockHeader represents common information required for each block.
+type BlockHeader struct {
+	Number        uint64    `json:"number"`          // Ethereum: Block number in the chain.
+	PrevBlockHash string    `json:"prev_block_hash"` // Bitcoin: Hash of the previous block in the chain.
+	TimeStamp     uint64    `json:"timestamp"`       // Bitcoin: Time the block was mined.
+	BeneficiaryID AccountID `json:"beneficiary"`     // Ethereum: The account who is receiving fees and tips.
+	Difficulty    uint16    `json:"difficulty"`      // Ethereum: Number of 0's needed to solve the hash solution.
+	MiningReward  uint64    `json:"mining_reward"`   // Ethereum: The reward for mining this block.
+	StateRoot     string    `json:"state_root"`      // Ethereum: Represents a hash of the accounts and their balances.
+	TransRoot     string    `json:"trans_root"`      // Both: Represents the merkle tree root hash for the transactions in this block.
+	Nonce         uint64    `json:"nonce"`           // Both: Value identified to solve the hash solution.
+
+	// Pool: The accounts splitting the mining reward and fees by weight. When
+	// empty the beneficiary receives everything. Left out of the JSON when
+	// empty so the hash of blocks mined without a split doesn't change.
+	Beneficiaries []Beneficiary `json:"beneficiaries,omitempty"`
+
+	// Light clients: The merkle root of the accounts the block is applied
+	// to, in account id order, so the balance and nonce of an account can be
+	// proven against the header. Left out of the JSON when empty so the hash
+	// of blocks mined before the root was recorded doesn't change.
+	AccountsRoot string `json:"accounts_root,omitempty"`
+
+	// Upgrades: The format of the block, which decides the rules the header
+	// is checked with. Left out of the JSON for legacy blocks so their hash
+	// doesn't change.
+	Version uint16 `json:"version,omitempty"`
+}
+
+// StateRoots represents the roots of the state a block is applied to, which
+// the header of the block must match, and the median time past of the chain
+// the block must be mined after.
+type StateRoots struct {
+	State      string // Hash of the accounts, tokens, policy, names and contract storage.
+	Accounts   string // Merkle root of the accounts.
+	MedianTime uint64 // Median time past of the latest blocks, 0 to skip the check.
+}
+
+// Block represents a group of transactions batched together.
+type Block struct {
+	Header     BlockHeader
+	MerkleTree *merkle.Tree[BlockTx]
+}
+
+// POWArgs represents the set of arguments required to run POW.
+type POWArgs struct {
+	BeneficiaryID AccountID
+	Beneficiaries []Beneficiary // Optional: Accounts splitting the reward and fees.
+	Difficulty    uint16
+	MiningReward  uint64
+	PrevBlock     Block
+	MedianTime    uint64 // Optional: Median time past the block must be mined after.
+	StateRoot     string
+	AccountsRoot  string
+	Trans         []BlockTx
+	Workers       int         // Optional: Number of goroutines mining, defaults to the pool size or GOMAXPROCS.
+	Pool          *WorkerPool // Optional: Pool shared with the other chains hosted by the node.
+	Log           func(v ...any)
+	Hashes        func(n uint64) // Optional: Reports the number of hashes performed.
+}
+
+// POW constructs a new Block and performs the work to find a nonce that
+// solves the cryptographic POW puzzel.
+func POW(ctx context.Context, args POWArgs) (Block, error) {
+
+	// When mining the first block, the previous block's hash will be zero.
+	prevBlockHash := signature.ZeroHash
+	if args.PrevBlock.Header.Number > 0 {
+		prevBlockHash = args.PrevBlock.Hash()
+	}
+
+	// Construct a merkle tree from the transaction for this block. The root
+	// of this tree will be part of the block to be mined.
+	tree, err := merkle.NewTree(args.Trans)
+	if err != nil {
+		return Block{}, err
+	}
+
+	// Blocks mined within the same millisecond would share the median time
+	// past, so the timestamp is moved just past it like Bitcoin does. A
+	// parent mined by a node with a clock ahead of ours can be later than
+	// our clock, so the timestamp is never earlier than the parent's.
+	timeStamp := uint64(time.Now().UTC().UnixMilli())
+	if timeStamp < args.PrevBlock.Header.TimeStamp {
+		timeStamp = args.PrevBlock.Header.TimeStamp
+	}
+	if timeStamp <= args.MedianTime {
+		timeStamp = args.MedianTime + 1
+	}
+
+	// Construct the block to be mined.
+	nb := Block{
+		Header: BlockHeader{
+			Number:        args.PrevBlock.Header.Number + 1,
+			PrevBlockHash: prevBlockHash,
+			TimeStamp:     timeStamp,
+			BeneficiaryID: args.BeneficiaryID,
+			Difficulty:    args.Difficulty,
+			MiningReward:  args.MiningReward,
+			StateRoot:     args.StateRoot,
+			TransRoot:     tree.RootHex(),
+			Nonce:         0, // Will be identified by the POW algorithm.
+			Beneficiaries: args.Beneficiaries,
+			AccountsRoot:  args.AccountsRoot,
+			Version:       CurrentBlockVersion,
+		},
+		MerkleTree: tree,
+	}
+
+	// Peform the proof of work mining operation.
+	if err := nb.performPOW(ctx, args.Workers, args.Pool, args.Log, args.Hashes); err != nil {
+		return Block{}, err
+	}
+
+	return nb, nil
+}
+
+// performPOW does the work of mining to find a valid hash for a specified
+// block. Pointer semantics are being used since a nonce is being discovered.
+// The nonces are split into disjoint ranges searched by a goroutine each,
+// and all the workers stop as soon as one finds a solution or the context
+// is cancelled because another node found one. When a pool is provided, the
+// workers only hash while they hold a slot of the pool.
+func (b *Block) performPOW(ctx context.Context, workers int, pool *WorkerPool, log func(v ...any), hashes func(n uint64)) error {
+	log("database: PerformPOW: MINING: started")
+	defer log("database: PerformPOW: MINING: completed")
+
+	switch {
+	case workers > 0:
+	case pool != nil:
+		workers = pool.Size()
+	default:
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// Log the transactions that are a part of this potential block.
+	for _, tx := range b.MerkleTree.Values() {
+		log("database: PerformPOW: MINING", "tx", tx)
+	}
+
+	// Choose a random starting point for the nonce. Each worker is given an
+	// equal share of the nonces after this point, and increments its nonce
+	// by 1 until a solution is found by us or another node.
+	nBig, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+	if err != nil {
+		return err
+	}
+	start := nBig.Uint64()
+	share := math.MaxUint64 / uint64(workers)
+
+	log("database: PerformPOW: MINING: running", "workers", workers)
+
+	// The workers report the hashes they performed concurrently, so the
+	// reports are serialized for the caller.
+	var mu sync.Mutex
+	report := func(n uint64) {
+		if hashes != nil {
+			mu.Lock()
+			defer mu.Unlock()
+			hashes(n)
+		}
+	}
+
+	powCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	solved := make(chan BlockHeader, 1)
+	var attempts uint64
+	begin := time.Now()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		header := b.Header
+		header.Nonce = start + uint64(i)*share
+
+		go func(worker int) {
+			defer wg.Done()
+
+			n, ok := powWorker(powCtx, worker, &header, pool, log, report)
+			atomic.AddUint64(&attempts, n)
+
+			// The first worker to find a solution stops the others.
+			if ok {
+				select {
+				case solved <- header:
+				default:
+				}
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	hashRate := float64(attempts) / time.Since(begin).Seconds()
+
+	// Did we timeout trying to solve the problem.
+	if ctx.Err() != nil {
+		log("database: PerformPOW: MINING: CANCELLED", "attempts", attempts, "hashRate", hashRate)
+		return ctx.Err()
+	}
+
+	b.Header = <-solved
+
+	log("database: PerformPOW: MINING: SOLVED", "prevBlk", b.Header.PrevBlockHash, "newBlk", b.Hash())
+	log("database: PerformPOW: MINING: attempts", "attempts", attempts, "hashRate", hashRate)
+
+	return nil
+}
+
+// powWorker searches for a solution starting at the nonce in the header,
+// leaving the solution in the header. It returns the number of hashes
+// performed and whether a solution was found before the context was
+// cancelled.
+func powWorker(ctx context.Context, worker int, header *BlockHeader, pool *WorkerPool, log func(v ...any), report func(n uint64)) (uint64, bool) {
+
+	// The hashes performed are reported in batches to keep the cost of
+	// reporting out of the mining loop.
+	const hashReportBatch = 100_000
+	var attempts uint64
+	var reported uint64
+	defer func() { report(attempts - reported) }()
+
+	// The slot of the pool is given up after every batch, so the workers of
+	// another chain get their turn.
+	held := pool.acquire(ctx)
+	if !held {
+		return attempts, false
+	}
+	defer func() {
+		if held {
+			pool.release()
+		}
+	}()
+
+	b := Block{Header: *header}
+	for {
+		attempts++
+		if attempts%1_000_000 == 0 {
+			log("database: PerformPOW: MINING: running", "worker", worker, "attempts", attempts)
+		}
+		if attempts%hashReportBatch == 0 {
+			report(attempts - reported)
+			reported = attempts
+
+			pool.release()
+			if held = pool.acquire(ctx); !held {
+				return attempts, false
+			}
+		}
+
+		// Did we timeout or did another worker solve the problem.
+		if ctx.Err() != nil {
+			return attempts, false
+		}
+
+		// Hash the block and check if we have solved the puzzle.
+		if !isHashSolved(b.Header.Difficulty, b.Hash()) {
+			b.Header.Nonce++
+			continue
+		}
+
+		*header = b.Header
+		return attempts, true
+	}
+}
+
+// Hash returns the unique hash for the Block.
+func (b Block) Hash() string {
+	if b.Header.Number == 0 {
+		return signature.ZeroHash
+	}
+
+	// CORE NOTE: Hashing the block header and not the whole block so the blockchain
+	// can be cryptographically checked by only needing block headers and not full
+	// blocks with the transaction data. This will support the ability to have pruned
+	// nodes and light clients in the future.
+	// - A pruned node stores all the block headers, but only a small number of full
+	//   blocks (maybe the last 1000 blocks). This allows for full cryptographic
+	//   validation of blocks and transactions without all the extra storage.
+	// - A light client keeps block headers and just enough sufficient information
+	//   to follow the latest set of blocks being produced. The do not validate
+	//   blocks, but can prove a transaction is in a block.
+
+	return signature.Hash(b.Header)
+}
+
+// ValidateBlock takes a block and validates it to be included into the
+// blockchain. The rules are checked in order and the error for the first
+// rule the block breaks is returned. How far into the future the timestamp
+// can be depends on the clock of the node, which ValidateTimestamp checks.
+func (b Block) ValidateBlock(previousBlock Block, roots StateRoots, log func(v ...any)) error {
+	log("database: ValidateBlock: validate: check chain is not forked", "blk", b.Header.Number)
+
+	// The node who sent this block has a chain that is two or more blocks ahead
+	// of ours. This means there has been a fork and we are on the wrong side.
+	nextNumber := previousBlock.Header.Number + 1
+	if b.Header.Number >= (nextNumber + 2) {
+		return ErrChainForked
+	}
+
+	for _, rule := range blockRules {
+		log("database: ValidateBlock: validate: check "+rule.name, "blk", b.Header.Number)
+
+		if err := rule.check(b, previousBlock, roots); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Verify performs the checks that don't depend on the chain the block is
+// added to, so blocks can be verified in parallel before they are applied
+// in order. The hash must solve the difficulty and the merkle root must
+// match the transactions. The signer of each transaction is recovered so
+// applying the block finds it in the signature cache. A signature that
+// can't be recovered fails the transaction when the block is applied, not
+// the block.
+func (b Block) Verify() error {
+	for _, check := range []func(b Block, previousBlock Block, roots StateRoots) error{checkBlockHash, checkMerkleRoot} {
+		if err := check(b, Block{}, StateRoots{}); err != nil {
+			return fmt.Errorf("block %d: %w", b.Header.Number, err)
+		}
+	}
+
+	for _, tx := range b.MerkleTree.Values() {
+		tx.FromAddress()
+	}
+
+	return nil
+}
+
+// RuleResult represents the outcome of checking a block against one of the
+// validation rules. Err is nil when the block passed the rule.
+type RuleResult struct {
+	Rule string
+	Err  error
+}
+
+// Check runs every validation rule against the block and returns the result
+// of each rule instead of stopping at the first failure. Tools auditing a
+// chain use this to report everything wrong with a block.
+func (b Block) Check(previousBlock Block, roots StateRoots) []RuleResult {
+	results := make([]RuleResult, len(blockRules))
+	for i, rule := range blockRules {
+		results[i] = RuleResult{
+			Rule: rule.key,
+			Err:  rule.check(b, previousBlock, roots),
+		}
+	}
+
+	return results
+}
+
+// IsSolved reports if the hash of the block satisfies the difficulty recorded
+// in its header. Only the header is needed so a light client can check the
+// work that was performed without the rest of the chain.
+func (b Block) IsSolved() bool {
+	return isHashSolved(b.Header.Difficulty, b.Hash())
+}
+
+// =============================================================================
+
+// blockRule represents a single rule a block must pass to be accepted. The
+// key identifies the rule in the results returned by Check.
+type blockRule struct {
+	key   string
+	name  string
+	check func(b Block, previousBlock Block, roots StateRoots) error
+}
+
+// blockRules is the set of rules a block is validated against, in the order
+// they are checked. The cheap checks on the header are performed before the
+// merkle tree and state are compared.
+var blockRules = []blockRule{
+	{"version", "block version", checkVersion},
+	{"block_number", "block number is next number", checkBlockNumber},
+	{"difficulty", "block difficulty", checkDifficulty},
+	{"pow", "block hash has been solved", checkBlockHash},
+	{"hash_link", "parent hash does match parent", checkParentHash},
+	{"timestamp", "block timestamp follows the chain", checkTimestamp},
+	{"beneficiaries", "block beneficiaries", checkBeneficiaries},
+	{"merkle_root", "merkle root matches transactions", checkMerkleRoot},
+	{"tx_window", "transactions are inside their window", checkTxWindows},
+	{"state_root", "state root matches", checkStateRoot},
+	{"accounts_root", "accounts root matches", checkAccountsRoot},
+}
+
+func checkBlockNumber(b Block, previousBlock Block, roots StateRoots) error {
+	if nextNumber := previousBlock.Header.Number + 1; b.Header.Number != nextNumber {
+		return fmt.Errorf("%w, got %d, exp %d", ErrBlockNumber, b.Header.Number, nextNumber)
+	}
+	return nil
+}
+
+func checkDifficulty(b Block, previousBlock Block, roots StateRoots) error {
+	if b.Header.Difficulty < previousBlock.Header.Difficulty {
+		return fmt.Errorf("%w, parent %d, block %d", ErrDifficulty, previousBlock.Header.Difficulty, b.Header.Difficulty)
+	}
+	return nil
+}
+
+func checkBlockHash(b Block, previousBlock Block, roots StateRoots) error {
+	if hash := b.Hash(); !isHashSolved(b.Header.Difficulty, hash) {
+		return fmt.Errorf("%w, hash %s, difficulty %d", ErrBlockHash, hash, b.Header.Difficulty)
+	}
+	return nil
+}
+
+func checkParentHash(b Block, previousBlock Block, roots StateRoots) error {
+	if prevHash := previousBlock.Hash(); b.Header.PrevBlockHash != prevHash {
+		return fmt.Errorf("%w, got %s, exp %s", ErrParentHash, b.Header.PrevBlockHash, prevHash)
+	}
+	return nil
+}
+
+func checkMerkleRoot(b Block, previousBlock Block, roots StateRoots) error {
+	if root := b.MerkleTree.RootHex(); b.Header.TransRoot != root {
+		return fmt.Errorf("%w, got %s, exp %s", ErrMerkleRoot, root, b.Header.TransRoot)
+	}
+	return nil
+}
+
+func checkTxWindows(b Block, previousBlock Block, roots StateRoots) error {
+	for _, tx := range b.MerkleTree.Values() {
+		if err := tx.ValidAt(b.Header.Number); err != nil {
+			return fmt.Errorf("tx %s: %w", tx.TxHash(), err)
+		}
+	}
+	return nil
+}
+
+func checkStateRoot(b Block, previousBlock Block, roots StateRoots) error {
+	if b.Header.StateRoot != roots.State {
+		return fmt.Errorf("%w, current %s, expected %s", ErrStateRoot, roots.State, b.Header.StateRoot)
+	}
+	return nil
+}
+
+// checkAccountsRoot compares the accounts root in the header with the root
+// of the accounts the block is applied to. Blocks of BlockVersionRoots and
+// later are always checked, since the version requires the root, so a
+// missing root fails. Legacy blocks (BlockVersionLegacy) are only checked
+// when they record a root, because the legacy blocks mined before the root
+// was added to the header don't have one and must stay valid.
+func checkAccountsRoot(b Block, previousBlock Block, roots StateRoots) error {
+	if b.Header.Version < BlockVersionRoots && b.Header.AccountsRoot == "" {
+		return nil
+	}
+	if b.Header.AccountsRoot != roots.Accounts {
+		return fmt.Errorf("%w, current %s, expected %s", ErrAccountsRoot, roots.Accounts, b.Header.AccountsRoot)
+	}
+	return nil
+}
+
+// =============================================================================
+
+// isHashSolved checks the hash to make sure it complies with
+// the POW rules. We need to match a difficulty number of 0's.
+func isHashSolved(difficulty uint16, hash string) bool {
+	const match = "0x00000000000000000"
+
+	if len(hash) != 66 || int(difficulty) > len(match)-2 {
+		return false
+	}
+
+	difficulty += 2
+	return hash[:difficulty] == match[:difficulty]
+}
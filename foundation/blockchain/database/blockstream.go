@@ -0,0 +1,172 @@
+package database
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+)
+
+// Set of content types a range of blocks can be sent with between peers.
+const (
+	BlockContentJSON   = "application/json"     // A JSON array, what older nodes send.
+	BlockContentStream = "application/x-ndjson" // A JSON document per block, written as each block is encoded.
+	BlockContentGob    = "application/x-gob"    // A gob stream of blocks, the most compact.
+)
+
+// NegotiateBlockContent returns the content type for sending blocks to a
+// client based on its Accept header. The first streaming content type the
+// client lists is used, otherwise the blocks are sent as a JSON array.
+func NegotiateBlockContent(accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case BlockContentStream, BlockContentGob:
+			return mediaType
+		}
+	}
+
+	return BlockContentJSON
+}
+
+// =============================================================================
+
+// BlockEncoder writes blocks to a stream one at a time so a range of blocks
+// doesn't have to be held in memory as a single document.
+type BlockEncoder struct {
+	encode func(blockData BlockData) error
+}
+
+// NewBlockEncoder constructs an encoder writing the streaming content type
+// specified.
+func NewBlockEncoder(w io.Writer, contentType string) (*BlockEncoder, error) {
+	switch contentType {
+	case BlockContentStream:
+		enc := json.NewEncoder(w)
+		return &BlockEncoder{encode: func(blockData BlockData) error {
+			return enc.Encode(blockData)
+		}}, nil
+
+	case BlockContentGob:
+		enc := gob.NewEncoder(w)
+		return &BlockEncoder{encode: func(blockData BlockData) error {
+			return enc.Encode(newGobBlock(blockData))
+		}}, nil
+	}
+
+	return nil, fmt.Errorf("content type %q can't be streamed", contentType)
+}
+
+// Encode writes the block to the stream.
+func (e *BlockEncoder) Encode(blockData BlockData) error {
+	return e.encode(blockData)
+}
+
+// BlockDecoder reads blocks from a stream one at a time.
+type BlockDecoder struct {
+	decode func() (BlockData, error)
+}
+
+// NewBlockDecoder constructs a decoder reading the content type specified,
+// which can carry parameters like a Content-Type header does.
+func NewBlockDecoder(r io.Reader, contentType string) (*BlockDecoder, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("content type %q: %w", contentType, err)
+	}
+
+	switch mediaType {
+	case BlockContentJSON:
+		return newJSONArrayDecoder(r)
+
+	case BlockContentStream:
+		dec := json.NewDecoder(r)
+		return &BlockDecoder{decode: func() (BlockData, error) {
+			var blockData BlockData
+			err := dec.Decode(&blockData)
+			return blockData, err
+		}}, nil
+
+	case BlockContentGob:
+		dec := gob.NewDecoder(r)
+		return &BlockDecoder{decode: func() (BlockData, error) {
+			var gb gobBlock
+			if err := dec.Decode(&gb); err != nil {
+				return BlockData{}, err
+			}
+			return gb.blockData(), nil
+		}}, nil
+	}
+
+	return nil, fmt.Errorf("content type %q isn't supported for blocks", mediaType)
+}
+
+// Decode reads the next block from the stream. It returns io.EOF once all
+// the blocks have been read.
+func (d *BlockDecoder) Decode() (BlockData, error) {
+	return d.decode()
+}
+
+// newJSONArrayDecoder constructs a decoder that reads the elements of a JSON
+// array of blocks one at a time.
+func newJSONArrayDecoder(r io.Reader) (*BlockDecoder, error) {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("expected a JSON array of blocks")
+	}
+
+	return &BlockDecoder{decode: func() (BlockData, error) {
+		if !dec.More() {
+			return BlockData{}, io.EOF
+		}
+
+		var blockData BlockData
+		err := dec.Decode(&blockData)
+		return blockData, err
+	}}, nil
+}
+
+// =============================================================================
+
+// gobBlock is the gob encoding of a block. Gob doesn't tell an empty slice
+// from a nil slice, but the JSON a transaction is hashed with does, so the
+// transactions carrying empty data are recorded.
+type gobBlock struct {
+	Block     BlockData
+	EmptyData []int
+}
+
+// newGobBlock constructs the gob encoding of the block.
+func newGobBlock(blockData BlockData) gobBlock {
+	gb := gobBlock{Block: blockData}
+	for i, tx := range blockData.Trans {
+		if tx.Data != nil && len(tx.Data) == 0 {
+			gb.EmptyData = append(gb.EmptyData, i)
+		}
+	}
+
+	return gb
+}
+
+// blockData returns the block with the empty data restored.
+func (gb gobBlock) blockData() BlockData {
+	for _, i := range gb.EmptyData {
+		if i >= 0 && i < len(gb.Block.Trans) {
+			gb.Block.Trans[i].Data = []byte{}
+		}
+	}
+
+	return gb.Block
+}
@@ -0,0 +1,286 @@
+package database_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// Shape of the range of blocks used to measure the encodings, the size of a
+// range a syncing node asks a peer for.
+const (
+	rangeBlocks = 50
+	blockTrans  = 10
+)
+
+// encodings are the ways a range of blocks can be sent to a peer. The pretty
+// JSON array is what every node understood before the streams were added.
+var encodings = []struct {
+	name        string
+	contentType string
+	encode      func(w io.Writer, blocks []database.BlockData) error
+}{
+	{"json-indent", database.BlockContentJSON, func(w io.Writer, blocks []database.BlockData) error {
+		data, err := json.MarshalIndent(blocks, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}},
+	{"ndjson", database.BlockContentStream, streamEncode(database.BlockContentStream)},
+	{"gob", database.BlockContentGob, streamEncode(database.BlockContentGob)},
+}
+
+func TestBlockStreamRoundTrip(t *testing.T) {
+	blocks := newTestBlocks(t, 3, 4)
+
+	// Empty data must survive the trip, the transaction hash depends on it.
+	blocks[1].Trans[2].Data = []byte{}
+
+	exp, err := json.Marshal(blocks)
+	if err != nil {
+		t.Fatalf("Should be able to marshal the blocks: %s", err)
+	}
+
+	for _, e := range encodings {
+		t.Run(e.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := e.encode(&buf, blocks); err != nil {
+				t.Fatalf("Should be able to encode the blocks: %s", err)
+			}
+
+			got, err := decodeAll(&buf, e.contentType)
+			if err != nil {
+				t.Fatalf("Should be able to decode the blocks: %s", err)
+			}
+
+			data, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("Should be able to marshal the decoded blocks: %s", err)
+			}
+
+			if !bytes.Equal(data, exp) {
+				t.Fatalf("Should decode the same blocks, got %s, exp %s", data, exp)
+			}
+		})
+	}
+}
+
+func TestPeerMessageRoundTrip(t *testing.T) {
+	blocks := newTestBlocks(t, 1, 3)
+	txs := blocks[0].Trans
+
+	// Empty data must survive the trip, the transaction hash depends on it.
+	txs[1].Data = []byte{}
+
+	messages := []struct {
+		name string
+		send any
+		recv func() any
+	}{
+		{"block", blocks[0], func() any { return new(database.BlockData) }},
+		{"tx", txs[1], func() any { return new(database.BlockTx) }},
+		{"txs", txs, func() any { return new([]database.BlockTx) }},
+		{"hashes", []string{"0x01", "0x02"}, func() any { return new([]string) }},
+	}
+
+	for _, contentType := range []string{database.BlockContentJSON, database.BlockContentGob} {
+		for _, m := range messages {
+			t.Run(contentType+"/"+m.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := database.EncodePeerMessage(&buf, contentType, m.send); err != nil {
+					t.Fatalf("Should be able to encode the message: %s", err)
+				}
+
+				recv := m.recv()
+				if err := database.DecodePeerMessage(&buf, contentType+"; charset=utf-8", recv); err != nil {
+					t.Fatalf("Should be able to decode the message: %s", err)
+				}
+
+				exp, _ := json.Marshal(m.send)
+				got, _ := json.Marshal(recv)
+				if !bytes.Equal(got, exp) {
+					t.Fatalf("Should decode the same message, got %s, exp %s", got, exp)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkBlockEncode measures encoding a range of blocks in each of the
+// encodings, with and without compression. The size of a block on the wire
+// is reported.
+func BenchmarkBlockEncode(b *testing.B) {
+	blocks := newTestBlocks(b, rangeBlocks, blockTrans)
+
+	for _, e := range encodings {
+		for _, compress := range []bool{false, true} {
+			name := e.name
+			if compress {
+				name += "+gzip"
+			}
+
+			b.Run(name, func(b *testing.B) {
+				var buf bytes.Buffer
+				for i := 0; i < b.N; i++ {
+					buf.Reset()
+					if err := encode(&buf, e.encode, blocks, compress); err != nil {
+						b.Fatal(err)
+					}
+				}
+
+				b.ReportMetric(float64(buf.Len())/rangeBlocks, "bytes/block")
+			})
+		}
+	}
+}
+
+// BenchmarkBlockDecode measures decoding a range of blocks from each of the
+// encodings, with and without compression.
+func BenchmarkBlockDecode(b *testing.B) {
+	blocks := newTestBlocks(b, rangeBlocks, blockTrans)
+
+	for _, e := range encodings {
+		for _, compress := range []bool{false, true} {
+			name := e.name
+			if compress {
+				name += "+gzip"
+			}
+
+			var buf bytes.Buffer
+			if err := encode(&buf, e.encode, blocks, compress); err != nil {
+				b.Fatal(err)
+			}
+			data := buf.Bytes()
+
+			b.Run(name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					var r io.Reader = bytes.NewReader(data)
+					if compress {
+						gz, err := gzip.NewReader(r)
+						if err != nil {
+							b.Fatal(err)
+						}
+						r = gz
+					}
+
+					if _, err := decodeAll(r, e.contentType); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// =============================================================================
+
+// streamEncode returns a function encoding the blocks as the stream of the
+// content type specified.
+func streamEncode(contentType string) func(w io.Writer, blocks []database.BlockData) error {
+	return func(w io.Writer, blocks []database.BlockData) error {
+		enc, err := database.NewBlockEncoder(w, contentType)
+		if err != nil {
+			return err
+		}
+
+		for _, blockData := range blocks {
+			if err := enc.Encode(blockData); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// encode writes the blocks with the encoding, compressed with gzip when
+// asked for.
+func encode(w io.Writer, enc func(w io.Writer, blocks []database.BlockData) error, blocks []database.BlockData, compress bool) error {
+	if !compress {
+		return enc(w, blocks)
+	}
+
+	gz := gzip.NewWriter(w)
+	if err := enc(gz, blocks); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// decodeAll reads every block from the stream.
+func decodeAll(r io.Reader, contentType string) ([]database.BlockData, error) {
+	dec, err := database.NewBlockDecoder(r, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []database.BlockData
+	for {
+		blockData, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return blocks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, blockData)
+	}
+}
+
+// newTestBlocks constructs a range of blocks holding signed transactions.
+// The blocks aren't mined, only their size and shape matter.
+func newTestBlocks(tb testing.TB, numBlocks int, numTrans int) []database.BlockData {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	fromID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	blocks := make([]database.BlockData, numBlocks)
+	for i := range blocks {
+		trans := make([]database.BlockTx, numTrans)
+		for j := range trans {
+			nonce := uint64(i*numTrans + j + 1)
+
+			tx, err := database.NewTx(1, nonce, fromID, accountID(j), 100, 10, []byte(fmt.Sprintf("memo %d", nonce)))
+			if err != nil {
+				tb.Fatal(err)
+			}
+
+			signedTx, err := tx.Sign(privateKey)
+			if err != nil {
+				tb.Fatal(err)
+			}
+
+			trans[j] = database.NewBlockTx(signedTx, 15, 1)
+		}
+
+		blocks[i] = database.BlockData{
+			Hash: fmt.Sprintf("0x%064x", i+1),
+			Header: database.BlockHeader{
+				Number:        uint64(i + 1),
+				PrevBlockHash: fmt.Sprintf("0x%064x", i),
+				TimeStamp:     1_700_000_000_000 + uint64(i),
+				BeneficiaryID: fromID,
+				Difficulty:    6,
+				MiningReward:  700,
+				StateRoot:     fmt.Sprintf("0x%064x", i+2),
+				TransRoot:     fmt.Sprintf("0x%064x", i+3),
+				Nonce:         uint64(i) * 7919,
+			},
+			Trans: trans,
+		}
+	}
+
+	return blocks
+}
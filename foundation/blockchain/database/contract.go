@@ -0,0 +1,112 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/vm"
+)
+
+// slots represents the contract storage of a single account.
+type slots map[uint64]uint64
+
+// SlotEntry represents a value held in the contract storage of an account.
+type SlotEntry struct {
+	AccountID AccountID `json:"account"`
+	Key       uint64    `json:"key"`
+	Value     uint64    `json:"value"`
+}
+
+// Load implements the vm Storage interface.
+func (s slots) Load(key uint64) uint64 {
+	return s[key]
+}
+
+// QueryStorage returns the value stored under the key in the contract
+// storage of the specified account. A key that was never written holds 0.
+func (db *Database) QueryStorage(accountID AccountID, key uint64) uint64 {
+	return db.load().contracts[accountID][key]
+}
+
+// execute runs the bytecode carried in the transaction on behalf of the
+// sender. The program can only use the gas the sender can pay for after the
+// value and tip of the transaction. The fee for the gas used is charged even
+// when the program fails, and the changes it made are only applied when it
+// succeeds. The caller must hold the lock.
+func (db *Database) execute(block Block, tx BlockTx) (vm.Result, uint64, error) {
+	from := db.account(tx.FromID)
+	cost, ok := tx.cost()
+	if !ok || cost > from.Balance {
+		return vm.Result{}, 0, fmt.Errorf("execution failed: %w, bal %d, needed value %d and tip %d", ErrInsufficientFunds, from.Balance, tx.Value, tx.Tip)
+	}
+	available := from.Balance - cost
+
+	gasLimit := uint64(vm.MaxGas)
+	if tx.GasPrice > 0 {
+		gasLimit = available / tx.GasPrice
+	}
+
+	result, err := vm.Run(tx.Data, gasLimit, db.contracts[tx.FromID])
+
+	fee := result.GasUsed * tx.GasPrice
+	db.transfer(tx.FromID, block.Header.BeneficiaryID, fee)
+
+	if err != nil {
+		return result, fee, fmt.Errorf("execution failed: %w", err)
+	}
+
+	if result.Transferred > available-fee {
+		return result, fee, fmt.Errorf("execution failed: %w, bal %d, needed %d", ErrInsufficientFunds, available-fee, result.Transferred)
+	}
+
+	db.transfer(tx.FromID, tx.ToID, result.Transferred)
+
+	if len(result.Writes) > 0 {
+		storage, exists := db.contracts[tx.FromID]
+		if !exists {
+			storage = make(slots)
+			db.contracts[tx.FromID] = storage
+		}
+		for key, value := range result.Writes {
+			storage[key] = value
+		}
+	}
+
+	return result, fee, nil
+}
+
+// copyContracts makes a copy of the contract storage.
+func copyContracts(contracts map[AccountID]slots) map[AccountID]slots {
+	cpy := make(map[AccountID]slots, len(contracts))
+	for accountID, storage := range contracts {
+		values := make(slots, len(storage))
+		for key, value := range storage {
+			values[key] = value
+		}
+		cpy[accountID] = values
+	}
+	return cpy
+}
+
+// sortedSlots returns the values held in the contract storage in account id
+// and key order. A key holding 0 reads the same as a key that was never
+// written, so it's left out.
+func sortedSlots(contracts map[AccountID]slots) []SlotEntry {
+	var list []SlotEntry
+	for accountID, storage := range contracts {
+		for key, value := range storage {
+			if value != 0 {
+				list = append(list, SlotEntry{AccountID: accountID, Key: key, Value: value})
+			}
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].AccountID != list[j].AccountID {
+			return list[i].AccountID < list[j].AccountID
+		}
+		return list[i].Key < list[j].Key
+	})
+
+	return list
+}
@@ -0,0 +1,148 @@
+package database_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/vm"
+)
+
+func TestContractBalance(t *testing.T) {
+	owner := newKey(t)
+	ownerID := database.PublicKeyToAccountID(owner.PublicKey)
+
+	gen := genesis.Genesis{
+		ChainID:  1,
+		GasPrice: 1,
+		Balances: map[string]uint64{string(ownerID): 1_000},
+	}
+
+	db, err := database.New(gen, memStorage{}, func(...any) {})
+	if err != nil {
+		t.Fatalf("Should be able to construct the database: %s", err)
+	}
+
+	code, err := vm.Assemble("push 5 transfer")
+	if err != nil {
+		t.Fatalf("Should be able to assemble the program: %s", err)
+	}
+
+	// The value and tip add up past the largest amount, which must not
+	// leave the program running with a balance the owner doesn't hold.
+	tx, err := database.NewTx(gen.ChainID, 1, ownerID, accountID(1), math.MaxUint64, 2, code)
+	if err != nil {
+		t.Fatalf("Should be able to construct the transaction: %s", err)
+	}
+	signedTx, err := tx.Sign(owner)
+	if err != nil {
+		t.Fatalf("Should be able to sign the transaction: %s", err)
+	}
+
+	block := database.Block{Header: database.BlockHeader{Number: 1, BeneficiaryID: accountID(9)}}
+	if _, err := db.ApplyTransaction(block, database.NewBlockTx(signedTx, gen.GasPrice, 1)); !errors.Is(err, database.ErrInsufficientFunds) {
+		t.Fatalf("Should reject the transaction for insufficient funds, got %v", err)
+	}
+
+	if account, err := db.Query(accountID(1)); err == nil && account.Balance != 0 {
+		t.Fatalf("Should not transfer anything to the recipient, got %d", account.Balance)
+	}
+}
+
+func TestStateRootContracts(t *testing.T) {
+	owner := newKey(t)
+	ownerID := database.PublicKeyToAccountID(owner.PublicKey)
+
+	gen := genesis.Genesis{
+		ChainID:  1,
+		GasPrice: 1,
+		Balances: map[string]uint64{string(ownerID): 1_000},
+	}
+
+	// run constructs a database where the owner ran the program.
+	run := func(src string) *database.Database {
+		t.Helper()
+
+		db, err := database.New(gen, memStorage{}, func(...any) {})
+		if err != nil {
+			t.Fatalf("Should be able to construct the database: %s", err)
+		}
+
+		code, err := vm.Assemble(src)
+		if err != nil {
+			t.Fatalf("Should be able to assemble the program: %s", err)
+		}
+		tx, err := database.NewTx(gen.ChainID, 1, ownerID, accountID(1), 0, 0, code)
+		if err != nil {
+			t.Fatalf("Should be able to construct the transaction: %s", err)
+		}
+		signedTx, err := tx.Sign(owner)
+		if err != nil {
+			t.Fatalf("Should be able to sign the transaction: %s", err)
+		}
+
+		block := database.Block{Header: database.BlockHeader{Number: 1, BeneficiaryID: accountID(9)}}
+		if _, err := db.ApplyTransaction(block, database.NewBlockTx(signedTx, gen.GasPrice, 1)); err != nil {
+			t.Fatalf("Should be able to apply the transaction: %s", err)
+		}
+
+		return db
+	}
+
+	// The programs cost the same gas, only the value they store differs.
+	seven := run("push 7 push 1 store")
+	eight := run("push 8 push 1 store")
+
+	if seven.QueryStorage(ownerID, 1) != 7 || eight.QueryStorage(ownerID, 1) != 8 {
+		t.Fatalf("Should store the values written by the programs")
+	}
+	if seven.HashState() == eight.HashState() {
+		t.Fatalf("Should include the contract storage in the state root")
+	}
+}
+
+func TestPruneContracts(t *testing.T) {
+	creatorID := accountID(1)
+
+	gen := genesis.Genesis{
+		ChainID:       1,
+		PruneInterval: 1,
+		Balances:      map[string]uint64{string(creatorID): 1_000},
+	}
+
+	db, err := database.New(gen, memStorage{}, func(...any) {})
+	if err != nil {
+		t.Fatalf("Should be able to construct the database: %s", err)
+	}
+
+	// create adds an empty account the way a contract is created.
+	create := func(nonce uint64) database.AccountID {
+		t.Helper()
+
+		accountID, err := db.CreateAccount(creatorID, nonce)
+		if err != nil {
+			t.Fatalf("Should be able to create the account: %s", err)
+		}
+		return accountID
+	}
+
+	contractID, namedID, emptyID := create(1), create(2), create(3)
+	db.SetStorage(contractID, 1, 7)
+	db.SetName("contract", namedID)
+
+	pruned := db.Prune(database.Block{Header: database.BlockHeader{Number: 1}})
+	if len(pruned) != 1 || pruned[0] != emptyID {
+		t.Fatalf("Should only prune the empty account, got %v", pruned)
+	}
+
+	for _, accountID := range []database.AccountID{contractID, namedID} {
+		if _, err := db.Query(accountID); err != nil {
+			t.Fatalf("Should keep account %s: %s", accountID, err)
+		}
+	}
+	if got := db.QueryStorage(contractID, 1); got != 7 {
+		t.Fatalf("Should keep the contract storage, got %d, exp 7", got)
+	}
+}
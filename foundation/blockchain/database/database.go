@@ -1,14 +1,754 @@
+// Package database handles all the lower level support for maintaining the
+// blockchain in storage and maintaining an in-memory databse of account information.
 package database
 
 import (
-	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"errors"
+	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/vm"
 )
 
+// Storage interface represents the behavior required to be implemented by any
+// package providing support for reading and writing the blockchain.
+type Storage interface {
+	Write(blockData BlockData) error
+	GetBlock(num uint64) (BlockData, error)
+	ForEach() Iterator
+	ForEachFrom(num uint64) Iterator
+	WriteReceipts(num uint64, receipts []Receipt) error
+	GetReceipts(num uint64) ([]Receipt, error)
+	WriteStateDiff(num uint64, diff StateDiff) error
+	GetStateDiff(num uint64) (StateDiff, error)
+	WriteFees(num uint64, fees BlockFees) error
+	GetFees(num uint64) (BlockFees, error)
+	Close() error
+	Reset() error
+}
+
+// Iterator interface represents the behavior required to be implemented by any
+// package providing support to iterate over the blocks.
+type Iterator interface {
+	Next() (BlockData, error)
+	Done() bool
+}
+
+// =============================================================================
+
 // Database manages data related to accounts who have transacted on the blockchain.
+// Changes are made to a working copy of the state under the lock and then
+// published as an immutable view, which is what the query methods read.
 type Database struct {
-	mu      sync.RWMutex
-	genesis genesis.Genesis
-	// latestBlock Block
-	accounts map[AccountID]Account
+	mu          sync.RWMutex
+	genesis     genesis.Genesis
+	latestBlock Block
+	recentTimes []uint64
+	accounts    map[AccountID]Account
+	names       map[string]AccountID
+	contracts   map[AccountID]slots
+	tokens      map[string]Token
+	policy      map[AccountID]bool
+	shared      bool
+	dirty       int32
+	view        atomic.Value
+	headView    *view
+	proofView   *view
+	storage     Storage
+	base        *Snapshot
+}
+
+// New constructs a new database and applies account genesis information and
+// reads/writes the blockchain database on disk if a dbPath is provided.
+func New(genesis genesis.Genesis, storage Storage, log func(v ...any)) (*Database, error) {
+	db, err := newGenesisDatabase(genesis, storage, log)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read all the blocks from storage.
+	iter := DatabaseIterator{iterator: storage.ForEach()}
+	if err := db.replay(&iter, log); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// newGenesisDatabase constructs a database holding the account balances
+// from genesis.
+func newGenesisDatabase(genesis genesis.Genesis, storage Storage, log func(v ...any)) (*Database, error) {
+	db := Database{
+		genesis:   genesis,
+		accounts:  make(map[AccountID]Account),
+		names:     make(map[string]AccountID),
+		contracts: make(map[AccountID]slots),
+		tokens:    make(map[string]Token),
+		policy:    make(map[AccountID]bool),
+		storage:   storage,
+	}
+
+	// Update the database with account balance information from genesis.
+	for accountStr, balance := range genesis.Balances {
+		accountID, err := ToAccountID(accountStr)
+		if err != nil {
+			return nil, err
+		}
+		db.accounts[accountID] = newAccount(accountID, balance)
+
+		log("database: New: Genesis", "account", accountID, "balance", balance)
+	}
+
+	db.publish()
+	db.headView = db.view.Load().(*view)
+
+	return &db, nil
+}
+
+// replay validates and applies the blocks from the iterator on top of the
+// current state of the database.
+func (db *Database) replay(iter *DatabaseIterator, log func(v ...any)) error {
+	for block, err := iter.Next(); !iter.Done(); block, err = iter.Next() {
+		if err != nil {
+			return err
+		}
+
+		// Validate the block values and cryptographic audit trail.
+		if err := block.ValidateBlock(db.latestBlock, db.StateRoots(), log); err != nil {
+			return err
+		}
+
+		// Validate the block was mined after the blocks before it.
+		if err := block.ValidateTimestamp(db.latestBlock, medianTime(db.recentTimes), 0); err != nil {
+			return err
+		}
+
+		// Validate the block against the size limits of the chain.
+		if err := block.ValidateLimits(db.genesis.TransPerBlock, db.genesis.MaxTxDataBytes); err != nil {
+			return err
+		}
+
+		// Update the database with the transaction information.
+		receipts := db.applyBlock(block)
+
+		// Blocks written before receipts existed need them generated.
+		if _, err := db.storage.GetReceipts(block.Header.Number); err != nil {
+			if err := db.storage.WriteReceipts(block.Header.Number, receipts); err != nil {
+				return err
+			}
+		}
+
+		// Blocks written before fee statistics existed need them computed.
+		if _, err := db.storage.GetFees(block.Header.Number); err != nil {
+			if err := db.storage.WriteFees(block.Header.Number, NewBlockFees(block, receipts)); err != nil {
+				return err
+			}
+		}
+
+		// Blocks written before the transaction index existed need indexing.
+		if err := db.indexTxs(block); err != nil {
+			return err
+		}
+
+		// Update the current latest block.
+		db.setLatestBlock(block)
+	}
+
+	return nil
+}
+
+// ApplyBlock applies the transactions and mining reward of a block that was
+// validated and makes it the latest block. It returns the receipts for the
+// transactions. Tools replaying a chain outside of a node use this.
+func (db *Database) ApplyBlock(block Block) []Receipt {
+	receipts := db.applyBlock(block)
+	db.UpdateLatestBlock(block)
+
+	return receipts
+}
+
+// applyBlock applies the transactions and mining reward for a validated
+// block and returns the receipts for the transactions.
+func (db *Database) applyBlock(block Block) []Receipt {
+	values := block.MerkleTree.Values()
+	receipts := make([]Receipt, len(values))
+	for i, tx := range values {
+		receipts[i], _ = db.ApplyTransaction(block, tx)
+		receipts[i].Index = i
+	}
+	db.ApplyMiningReward(block, receipts)
+	db.Prune(block)
+
+	return receipts
+}
+
+// Close closes the open blocks database.
+func (db *Database) Close() {
+	db.storage.Close()
+}
+
+// Flush makes sure the latest block held in memory is in storage. This is
+// called on shutdown so a block that was applied to the accounts but not
+// written is not lost.
+func (db *Database) Flush() error {
+	latestBlock := db.LatestBlock()
+	if latestBlock.Header.Number == 0 {
+		return nil
+	}
+
+	if blockData, err := db.storage.GetBlock(latestBlock.Header.Number); err == nil {
+		if block, err := ToBlock(blockData); err == nil && block.Hash() == latestBlock.Hash() {
+			return nil
+		}
+	}
+
+	return db.storage.Write(NewBlockData(latestBlock))
+}
+
+// ResetChain re-initializes the database back to the state it started
+// from. A database constructed from a snapshot goes back to the block and
+// state of the snapshot, since the blocks before it were never replayed
+// and can't be validated. Otherwise it goes back to the genesis state.
+func (db *Database) ResetChain() error {
+
+	// Remove all the blocks from storage.
+	if err := db.storage.Reset(); err != nil {
+		return err
+	}
+
+	if db.base != nil {
+		return db.restore(*db.base)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	// Initializes the database back to the genesis information.
+	db.latestBlock = Block{}
+	db.recentTimes = nil
+	db.accounts = make(map[AccountID]Account)
+	db.names = make(map[string]AccountID)
+	db.contracts = make(map[AccountID]slots)
+	db.tokens = make(map[string]Token)
+	db.policy = make(map[AccountID]bool)
+	for accountStr, balance := range db.genesis.Balances {
+		accountID, err := ToAccountID(accountStr)
+		if err != nil {
+			return err
+		}
+		db.accounts[accountID] = newAccount(accountID, balance)
+	}
+	db.publish()
+	db.headView = db.view.Load().(*view)
+	db.proofView = nil
+
+	return nil
+}
+
+// Remove deletes an account from the database.
+func (db *Database) Remove(accountID AccountID) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.unshare()
+	delete(db.accounts, accountID)
+}
+
+// Query retrieves an account from the database.
+func (db *Database) Query(accountID AccountID) (Account, error) {
+	account, exists := db.load().accounts[accountID]
+	if !exists {
+		return Account{}, errors.New("account does not exist")
+	}
+
+	return account, nil
+}
+
+// CreateAccount derives the account id for an account created by the
+// specified account and nonce and adds it to the database. An error is
+// returned if the derived account already exists.
+func (db *Database) CreateAccount(creatorID AccountID, nonce uint64) (AccountID, error) {
+	accountID := CreateAccountID(creatorID, nonce)
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, exists := db.accounts[accountID]; exists {
+		return "", fmt.Errorf("account collision, account %s already exists", accountID)
+	}
+
+	db.unshare()
+	db.accounts[accountID] = newAccount(accountID, 0)
+
+	return accountID, nil
+}
+
+// AccountsSnapshot is a copy of the accounts, registered names, contract
+// storage, tokens and policy of the database at a point in time.
+type AccountsSnapshot struct {
+	view *view
+}
+
+// CopyAccounts returns a snapshot of the accounts and the state that goes
+// with them. The snapshot is the published view of the database, which is
+// never changed, so taking one doesn't copy anything. The database copies
+// its state the first time it's changed after the snapshot is taken.
+func (db *Database) CopyAccounts() AccountsSnapshot {
+	return AccountsSnapshot{view: db.load()}
+}
+
+// Reset replaces the accounts and the state that goes with them with the
+// snapshot. The snapshot is published as the current view, so restoring
+// it is a pointer swap and queries are never blocked.
+func (db *Database) Reset(snapshot AccountsSnapshot) {
+	v := snapshot.view
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.accounts = v.accounts
+	db.names = v.names
+	db.contracts = v.contracts
+	db.tokens = v.tokens
+	db.policy = v.policy
+	db.shared = true
+	atomic.StoreInt32(&db.dirty, 0)
+	db.view.Store(v)
+}
+
+// Commit replaces the accounts, registered names, contract storage and
+// tokens in the database with the ones held by the scratch database.
+func (db *Database) Commit(scratch *Database) {
+	db.Reset(scratch.CopyAccounts())
+}
+
+// Scratch returns a database holding a snapshot of the accounts, names,
+// contract storage and tokens.
+// Blocks can be applied to the scratch database without changing this
+// database, and the result committed with Commit. The scratch database
+// shares the current view and only copies the state when it's first
+// changed. The scratch database is not backed by storage so only the
+// account related methods can be used.
+func (db *Database) Scratch() *Database {
+	v := db.CopyAccounts().view
+
+	scratch := Database{
+		genesis:     db.genesis,
+		latestBlock: db.LatestBlock(),
+		recentTimes: db.recentTimesCopy(),
+		accounts:    v.accounts,
+		names:       v.names,
+		contracts:   v.contracts,
+		tokens:      v.tokens,
+		policy:      v.policy,
+		shared:      true,
+	}
+	scratch.view.Store(v)
+
+	return &scratch
+}
+
+// ApplyMiningReward gives the specified account the mining reward. When the
+// block splits the reward, the mining reward and the fees the beneficiary
+// collected from the transactions in the receipts are divided between the
+// beneficiaries of the block by weight.
+func (db *Database) ApplyMiningReward(block Block, receipts []Receipt) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.unshare()
+
+	account, exists := db.accounts[block.Header.BeneficiaryID]
+	if !exists {
+		account = newAccount(block.Header.BeneficiaryID, 0)
+	}
+
+	account.Balance += block.Header.MiningReward
+
+	db.accounts[block.Header.BeneficiaryID] = account
+
+	if len(block.Header.Beneficiaries) == 0 {
+		return
+	}
+
+	// The fees were paid to the beneficiary as the transactions were
+	// applied, so the shares are paid out of the beneficiary's account.
+	amount := block.Header.MiningReward
+	for _, receipt := range receipts {
+		amount += receipt.MinerFee
+	}
+
+	for _, payout := range SplitReward(block.Header.Beneficiaries, amount) {
+		db.transfer(block.Header.BeneficiaryID, payout.AccountID, payout.Amount)
+	}
+}
+
+// Prune removes the accounts that hold no balance, have never sent a
+// transaction and are not multisig accounts or governed by spending rules.
+// Accounts holding contract storage or owning a name are kept, so a contract
+// created with CreateAccount isn't left with storage and no account.
+// Pruning only happens on blocks whose number is a multiple of
+// the genesis prune interval, so every node prunes the same accounts at the
+// same point in the chain and the state roots continue to match. Accounts
+// with a nonce are kept so their transactions can't be replayed. The ids of
+// the pruned accounts are returned in sorted order.
+func (db *Database) Prune(block Block) []AccountID {
+	interval := db.genesis.PruneInterval
+	if interval == 0 || block.Header.Number%interval != 0 {
+		return nil
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.unshare()
+
+	owners := make(map[AccountID]bool, len(db.names))
+	for _, accountID := range db.names {
+		owners[accountID] = true
+	}
+
+	var pruned []AccountID
+	for accountID, account := range db.accounts {
+		if owners[accountID] || len(db.contracts[accountID]) > 0 {
+			continue
+		}
+
+		if account.Balance == 0 && account.Nonce == 0 && account.Multisig == nil && account.Rules == nil {
+			delete(db.accounts, accountID)
+			pruned = append(pruned, accountID)
+		}
+	}
+
+	sort.Slice(pruned, func(i, j int) bool { return pruned[i] < pruned[j] })
+
+	return pruned
+}
+
+// ApplyTransaction performs the business logic for applying a transaction
+// to the database. A receipt describing the outcome is always returned, even
+// when the transaction fails. The caller is responsible for setting the index
+// of the transaction in the block.
+func (db *Database) ApplyTransaction(block Block, tx BlockTx) (Receipt, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.unshare()
+
+	// A transaction the account didn't authorize could have been produced by
+	// anyone, so the account isn't charged for it.
+	if err := authorize(db.accounts, tx.SignedTx); err != nil {
+		return newReceipt(block, tx, 0, 0, err), err
+	}
+
+	// An account a permissioned chain doesn't allow can't transact, so it
+	// isn't charged either.
+	if err := db.permit(db.policy, tx.SignedTx); err != nil {
+		return newReceipt(block, tx, 0, 0, err), err
+	}
+
+	// The account needs to pay the gas fee regardless. Take the
+	// remaining balance if the account doesn't hold enough for the
+	// full amount of gas. This is the only way to stop bad actors.
+	from := db.account(tx.FromID)
+	gasFee := tx.GasPrice * tx.GasUnits
+	if gasFee > from.Balance {
+		gasFee = from.Balance
+	}
+	db.transfer(tx.FromID, block.Header.BeneficiaryID, gasFee)
+
+	// Perform basic accounting checks.
+	from = db.account(tx.FromID)
+	if tx.Nonce != (from.Nonce + 1) {
+		err := fmt.Errorf("transaction invalid, %w, got %d, exp %d", ErrBadNonce, tx.Nonce, from.Nonce+1)
+		return newReceipt(block, tx, gasFee, 0, err), err
+	}
+
+	// A value and tip adding up past the largest amount would wrap around
+	// to a small amount the account could cover.
+	cost, ok := tx.cost()
+	if !ok {
+		err := fmt.Errorf("transaction invalid, %w, bal %d, needed value %d and tip %d", ErrInsufficientFunds, from.Balance, tx.Value, tx.Tip)
+		return newReceipt(block, tx, gasFee, 0, err), err
+	}
+
+	if from.Balance == 0 || from.Balance < cost {
+		err := fmt.Errorf("transaction invalid, %w, bal %d, needed %d", ErrInsufficientFunds, from.Balance, cost)
+		return newReceipt(block, tx, gasFee, 0, err), err
+	}
+
+	// An account governed by spending rules can only send what the rules
+	// allow. The tip counts against the daily limit so the owner can't
+	// drain the account through the fees.
+	var rules *SpendingRules
+	if from.Rules != nil {
+		spent, err := from.Rules.Spend(block.Header.TimeStamp, tx.ToID, cost)
+		if err != nil {
+			return newReceipt(block, tx, gasFee, 0, err), err
+		}
+		rules = &spent
+	}
+
+	// A registration creates a multisig account at the account id derived
+	// from the sender and nonce of the transaction.
+	reg, register := ParseMultisigRegistration(tx.Data)
+	if register {
+		if err := db.validateRegistration(tx, reg); err != nil {
+			return newReceipt(block, tx, gasFee, 0, err), err
+		}
+	}
+
+	// A rules registration creates an account governed by spending rules
+	// the same way, with the sender as the owner of the rules.
+	rulesReg, registerRules := ParseRulesRegistration(tx.Data)
+	if registerRules {
+		if err := db.validateRulesRegistration(tx, rulesReg); err != nil {
+			return newReceipt(block, tx, gasFee, 0, err), err
+		}
+	}
+
+	// A name registration maps the name to the sender. The first account
+	// to register a name keeps it.
+	name, registerName := ParseNameRegistration(tx.Data)
+	if registerName {
+		if err := db.validateName(name); err != nil {
+			return newReceipt(block, tx, gasFee, 0, err), err
+		}
+	}
+
+	// A token transaction creates a token or moves tokens to the recipient.
+	ttx, tokenTx := ParseTokenTx(tx.Data)
+	if tokenTx {
+		if err := db.validateTokenTx(tx, ttx); err != nil {
+			return newReceipt(block, tx, gasFee, 0, err), err
+		}
+	}
+
+	// Bytecode carried in the data is executed on behalf of the sender.
+	var exec vm.Result
+	var execFee uint64
+	if vm.IsCode(tx.Data) {
+		var err error
+		if exec, execFee, err = db.execute(block, tx); err != nil {
+			receipt := newReceipt(block, tx, gasFee+execFee, 0, err)
+			receipt.GasUsed += exec.GasUsed
+			return receipt, err
+		}
+	}
+
+	// Update the balances between the two parties and give the
+	// beneficiary the tip.
+	db.transfer(tx.FromID, tx.ToID, tx.Value)
+	db.transfer(tx.FromID, block.Header.BeneficiaryID, tx.Tip)
+
+	// Update the nonce for the next transaction check.
+	from = db.account(tx.FromID)
+	from.Nonce = tx.Nonce
+	if rules != nil {
+		from.Rules = rules
+	}
+	db.accounts[tx.FromID] = from
+
+	if register {
+		to := db.account(tx.ToID)
+		to.Multisig = &reg.Multisig
+		db.accounts[tx.ToID] = to
+	}
+
+	if registerRules {
+		to := db.account(tx.ToID)
+		to.Rules = rulesReg.rules(tx.FromID)
+		db.accounts[tx.ToID] = to
+	}
+
+	if registerName {
+		db.names[name] = tx.FromID
+	}
+
+	if tokenTx {
+		db.applyTokenTx(tx, ttx)
+	}
+
+	if ptx, policyTx := ParsePolicyTx(tx.Data); policyTx {
+		db.applyPolicyTx(ptx)
+	}
+
+	receipt := newReceipt(block, tx, gasFee+execFee, tx.Tip, nil)
+	receipt.GasUsed += exec.GasUsed
+	receipt.Output = exec.Stack
+
+	return receipt, nil
+}
+
+// HashState returns a hash based on the contents of the accounts and
+// their balances, the token ledger, the policy, the name registry and the
+// contract storage. This is added to each block and checked by peers. The
+// hash is calculated once for each published view.
+func (db *Database) HashState() string {
+	return db.load().stateRoot()
+}
+
+// UpdateLatestBlock provides safe access to update the latest block.
+func (db *Database) UpdateLatestBlock(block Block) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.setLatestBlock(block)
+}
+
+// LatestBlock returns the latest block.
+func (db *Database) LatestBlock() Block {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.latestBlock
+}
+
+// Write adds a new block to the chain.
+func (db *Database) Write(block Block) error {
+	return db.storage.Write(NewBlockData(block))
+}
+
+// GetBlock searches the blockchain on disk to locate and return the
+// contents of the specified block by number.
+func (db *Database) GetBlock(num uint64) (Block, error) {
+	blockData, err := db.storage.GetBlock(num)
+	if err != nil {
+		return Block{}, err
+	}
+
+	return ToBlock(blockData)
+}
+
+// WriteReceipts stores the receipts for the transactions in the specified
+// block number.
+func (db *Database) WriteReceipts(num uint64, receipts []Receipt) error {
+	return db.storage.WriteReceipts(num, receipts)
+}
+
+// GetReceipts returns the receipts for the transactions in the specified
+// block number.
+func (db *Database) GetReceipts(num uint64) ([]Receipt, error) {
+	return db.storage.GetReceipts(num)
+}
+
+// ForEach returns an iterator to walk through all the blocks
+// starting with block number 1.
+func (db *Database) ForEach() DatabaseIterator {
+	return DatabaseIterator{iterator: db.storage.ForEach()}
+}
+
+// Authorize checks the transaction was signed by the account it's from, by
+// enough of the participants when the account is a multisig account, or by
+// the owner when the account is governed by spending rules.
+func (db *Database) Authorize(tx SignedTx) error {
+	return authorize(db.load().accounts, tx)
+}
+
+// =============================================================================
+
+// authorize checks the transaction was signed by the account it's from, by
+// enough of the participants of a multisig account held in the accounts, or
+// by the owner of an account governed by spending rules.
+func authorize(accounts map[AccountID]Account, tx SignedTx) error {
+	address, err := tx.FromAddress()
+	if err != nil {
+		return err
+	}
+
+	if AccountID(address) == tx.FromID {
+		return nil
+	}
+
+	from, exists := accounts[tx.FromID]
+
+	if _, ok := ParseRulesEnvelope(tx.Data); ok {
+		if !exists || from.Rules == nil {
+			return fmt.Errorf("%w, %s", ErrNoSpendingRules, tx.FromID)
+		}
+		return from.Rules.Authorize(AccountID(address))
+	}
+
+	if !exists || from.Multisig == nil {
+		return fmt.Errorf("%w, %s", ErrNotMultisig, tx.FromID)
+	}
+
+	return from.Multisig.Authorize(tx)
+}
+
+// validateRegistration checks the registration of a multisig account. The
+// caller must hold the lock.
+func (db *Database) validateRegistration(tx BlockTx, reg MultisigRegistration) error {
+	if err := reg.Validate(); err != nil {
+		return fmt.Errorf("multisig registration: %w", err)
+	}
+
+	if accountID := CreateAccountID(tx.FromID, tx.Nonce); tx.ToID != accountID {
+		return fmt.Errorf("multisig registration must be sent to %s", accountID)
+	}
+
+	if to := db.account(tx.ToID); to.Multisig != nil || to.Rules != nil {
+		return fmt.Errorf("account collision, account %s is already registered", tx.ToID)
+	}
+
+	return nil
+}
+
+// copyNames makes a copy of the registered names.
+func copyNames(names map[string]AccountID) map[string]AccountID {
+	cpy := make(map[string]AccountID, len(names))
+	for name, accountID := range names {
+		cpy[name] = accountID
+	}
+	return cpy
+}
+
+// account returns the account for the specified id, constructing an empty
+// account if one doesn't exist. The caller must hold the lock.
+func (db *Database) account(accountID AccountID) Account {
+	account, exists := db.accounts[accountID]
+	if !exists {
+		account = newAccount(accountID, 0)
+	}
+	return account
+}
+
+// transfer moves the specified amount between two accounts. The caller must
+// hold the lock and is responsible for checking the from account has enough
+// funds. Reading each account right before writing it back keeps the math
+// correct when both ids reference the same account.
+func (db *Database) transfer(fromID AccountID, toID AccountID, amount uint64) {
+	from := db.account(fromID)
+	from.Balance -= amount
+	db.accounts[fromID] = from
+
+	to := db.account(toID)
+	to.Balance += amount
+	db.accounts[toID] = to
+}
+
+// =============================================================================
+
+// DatabaseIterator provides support for iterating over the blocks in the
+// blockchain database using the configured storage option.
+type DatabaseIterator struct {
+	iterator Iterator
+}
+
+// Next retrieves the next block from disk.
+func (di *DatabaseIterator) Next() (Block, error) {
+	blockData, err := di.iterator.Next()
+	if err != nil {
+		return Block{}, err
+	}
+
+	return ToBlock(blockData)
+}
+
+// Done returns the end of chain value.
+func (di *DatabaseIterator) Done() bool {
+	return di.iterator.Done()
 }
@@ -0,0 +1,281 @@
+package database_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+)
+
+// numAccounts is the number of accounts held by the benchmark database.
+const numAccounts = 10_000
+
+func TestResetAccounts(t *testing.T) {
+	db := newTestDatabase(t)
+	root := db.HashState()
+
+	snapshot := db.CopyAccounts()
+	applyBlock(db, 0)
+
+	account, err := db.Query(accountID(0))
+	if err != nil {
+		t.Fatalf("Should be able to query the account: %s", err)
+	}
+	if account.Balance != 1_000_700 {
+		t.Fatalf("Should pay the mining reward, got %d, exp %d", account.Balance, 1_000_700)
+	}
+
+	db.Reset(snapshot)
+
+	account, err = db.Query(accountID(0))
+	if err != nil {
+		t.Fatalf("Should be able to query the account: %s", err)
+	}
+	if account.Balance != 1_000_000 {
+		t.Fatalf("Should restore the balance of the snapshot, got %d, exp %d", account.Balance, 1_000_000)
+	}
+	if got := db.HashState(); got != root {
+		t.Fatalf("Should restore the state root of the snapshot, got %s, exp %s", got, root)
+	}
+
+	// Changes after the reset must not reach the snapshot.
+	applyBlock(db, 0)
+	db.Reset(snapshot)
+	if got := db.HashState(); got != root {
+		t.Fatalf("Should leave the snapshot untouched, got %s, exp %s", got, root)
+	}
+}
+
+// BenchmarkQuery measures balance queries against a database that isn't
+// changing.
+func BenchmarkQuery(b *testing.B) {
+	db := newTestDatabase(b)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			if _, err := db.Query(accountID(i % numAccounts)); err != nil {
+				b.Error(err)
+				return
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkQueryDuringBlocks measures balance queries while blocks are
+// being applied and committed to the database, the way a mining node
+// serves the API. The slowest query is reported since a query that has to
+// wait for a block to be applied is what a client notices.
+func BenchmarkQueryDuringBlocks(b *testing.B) {
+	db := newTestDatabase(b)
+
+	stop := applyBlocks(db)
+
+	var mu sync.Mutex
+	var slowest time.Duration
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var max time.Duration
+		var i int
+		for pb.Next() {
+			start := time.Now()
+			if _, err := db.Query(accountID(i % numAccounts)); err != nil {
+				b.Error(err)
+				return
+			}
+			if d := time.Since(start); d > max {
+				max = d
+			}
+			i++
+		}
+
+		mu.Lock()
+		if max > slowest {
+			slowest = max
+		}
+		mu.Unlock()
+	})
+	b.StopTimer()
+
+	b.ReportMetric(float64(stop()), "blocks")
+	b.ReportMetric(float64(slowest.Microseconds()), "max-µs")
+}
+
+// BenchmarkHashState measures calculating the state root of a database that
+// isn't changing, which happens each time a block is validated or mined.
+func BenchmarkHashState(b *testing.B) {
+	db := newTestDatabase(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		db.HashState()
+	}
+}
+
+// BenchmarkApplyBlock measures applying a block to a scratch database,
+// committing it and calculating the new state root.
+func BenchmarkApplyBlock(b *testing.B) {
+	db := newTestDatabase(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		applyBlock(db, i)
+	}
+}
+
+// BenchmarkApplyBlockSize measures applying a block as the number of
+// accounts grows. The working state is copied the first time a block
+// changes it, so the cost grows with the size of the state and not with
+// the number of accounts the block changes.
+func BenchmarkApplyBlockSize(b *testing.B) {
+	for _, n := range []int{1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprintf("accounts-%d", n), func(b *testing.B) {
+			db := newSizedDatabase(b, n)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				scratch := db.Scratch()
+				scratch.ApplyMiningReward(database.Block{
+					Header: database.BlockHeader{
+						Number:        uint64(i + 1),
+						BeneficiaryID: accountID(i % n),
+						MiningReward:  700,
+					},
+				}, nil)
+				db.Commit(scratch)
+			}
+		})
+	}
+}
+
+// =============================================================================
+
+// applyBlocks applies blocks to the database until the returned function is
+// called, which returns the number of blocks applied. The state root is
+// calculated by another goroutine at the same time, the way the mining
+// goroutine does while blocks from peers are being applied.
+func applyBlocks(db *database.Database) func() int {
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	var blocks int
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			db.HashState()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			applyBlock(db, blocks)
+			blocks++
+		}
+	}()
+
+	return func() int {
+		close(done)
+		wg.Wait()
+		return blocks
+	}
+}
+
+// applyBlock applies a block paying the mining reward to one of the accounts
+// the same way the state package does for a mined block.
+func applyBlock(db *database.Database, i int) {
+	block := database.Block{
+		Header: database.BlockHeader{
+			Number:        uint64(i + 1),
+			BeneficiaryID: accountID(i % numAccounts),
+			MiningReward:  700,
+		},
+	}
+
+	scratch := db.Scratch()
+	scratch.ApplyMiningReward(block, nil)
+	db.Commit(scratch)
+	db.HashState()
+}
+
+// newTestDatabase constructs a database holding numAccounts accounts.
+func newTestDatabase(b testing.TB) *database.Database {
+	return newSizedDatabase(b, numAccounts)
+}
+
+// newSizedDatabase constructs a database holding n accounts.
+func newSizedDatabase(b testing.TB, n int) *database.Database {
+	gen := genesis.Genesis{
+		ChainID:       1,
+		TransPerBlock: 10,
+		MiningReward:  700,
+		GasPrice:      15,
+		Balances:      make(map[string]uint64, n),
+	}
+	for i := 0; i < n; i++ {
+		gen.Balances[string(accountID(i))] = uint64(1_000_000 + i)
+	}
+
+	db, err := database.New(gen, memStorage{}, func(...any) {})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return db
+}
+
+// accountID returns the account id for the specified account number.
+func accountID(i int) database.AccountID {
+	return database.AccountID(fmt.Sprintf("0x%040x", i+1))
+}
+
+// =============================================================================
+
+// memStorage is a storage holding no blocks.
+type memStorage struct{}
+
+func (memStorage) Write(blockData database.BlockData) error { return nil }
+func (memStorage) GetBlock(num uint64) (database.BlockData, error) {
+	return database.BlockData{}, errors.New("not found")
+}
+func (memStorage) ForEach() database.Iterator               { return memIterator{} }
+func (memStorage) ForEachFrom(num uint64) database.Iterator { return memIterator{} }
+func (memStorage) WriteReceipts(num uint64, receipts []database.Receipt) error {
+	return nil
+}
+func (memStorage) GetReceipts(num uint64) ([]database.Receipt, error) { return nil, nil }
+func (memStorage) WriteStateDiff(num uint64, diff database.StateDiff) error {
+	return nil
+}
+func (memStorage) GetStateDiff(num uint64) (database.StateDiff, error) {
+	return database.StateDiff{}, errors.New("not found")
+}
+func (memStorage) WriteFees(num uint64, fees database.BlockFees) error { return nil }
+func (memStorage) GetFees(num uint64) (database.BlockFees, error) {
+	return database.BlockFees{}, errors.New("not found")
+}
+func (memStorage) Close() error { return nil }
+func (memStorage) Reset() error { return nil }
+
+// memIterator is an iterator over no blocks.
+type memIterator struct{}
+
+func (memIterator) Next() (database.BlockData, error) { return database.BlockData{}, nil }
+func (memIterator) Done() bool                        { return true }
@@ -0,0 +1,26 @@
+package database
+
+// SetStorage writes a value to the contract storage of the account. Tests
+// use it to give storage to an account that can't run a program itself.
+func (db *Database) SetStorage(accountID AccountID, key uint64, value uint64) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.unshare()
+
+	storage, exists := db.contracts[accountID]
+	if !exists {
+		storage = make(slots)
+		db.contracts[accountID] = storage
+	}
+	storage[key] = value
+}
+
+// SetName registers the name to the account without a transaction.
+func (db *Database) SetName(name string, accountID AccountID) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.unshare()
+	db.names[name] = accountID
+}
@@ -0,0 +1,135 @@
+package database
+
+import "sort"
+
+// BlockFees represents the fees paid by the transactions in a block. The
+// totals are what the beneficiary collected according to the receipts,
+// while the tip statistics describe the tips the transactions offered, so
+// they show what it took to get into the block.
+type BlockFees struct {
+	BlockNumber uint64 `json:"block_number"`
+	BlockHash   string `json:"block_hash"`
+	TxCount     int    `json:"tx_count"`
+	TotalTips   uint64 `json:"total_tips"`
+	TotalGas    uint64 `json:"total_gas"`
+	MinTip      uint64 `json:"min_tip"`
+	MedianTip   uint64 `json:"median_tip"`
+	MaxTip      uint64 `json:"max_tip"`
+}
+
+// NewBlockFees computes the fee statistics for the block from the receipts
+// of its transactions.
+func NewBlockFees(block Block, receipts []Receipt) BlockFees {
+	fees := BlockFees{
+		BlockNumber: block.Header.Number,
+		BlockHash:   block.Hash(),
+	}
+
+	for _, receipt := range receipts {
+		fees.TotalTips += receipt.Tip
+		fees.TotalGas += receipt.GasFee
+	}
+
+	values := block.MerkleTree.Values()
+	tips := make([]uint64, len(values))
+	for i, tx := range values {
+		tips[i] = tx.Tip
+	}
+
+	fees.TxCount = len(tips)
+	fees.MinTip, fees.MedianTip, fees.MaxTip = tipStats(tips)
+
+	return fees
+}
+
+// FeeSummary represents the fees paid over a range of blocks.
+type FeeSummary struct {
+	FromBlock   uint64 `json:"from_block"`
+	ToBlock     uint64 `json:"to_block"`
+	Blocks      int    `json:"blocks"`
+	TxCount     int    `json:"tx_count"`
+	TotalTips   uint64 `json:"total_tips"`
+	TotalGas    uint64 `json:"total_gas"`
+	MinTip      uint64 `json:"min_tip"`
+	MedianTip   uint64 `json:"median_tip"`
+	MaxTip      uint64 `json:"max_tip"`
+	AvgTxsBlock uint64 `json:"avg_txs_per_block"`
+}
+
+// SummarizeFees combines the fees of the blocks. Only the statistics of
+// each block are kept, so the median tip is the median of the block
+// medians. Blocks without transactions count towards the totals but not
+// the tip statistics.
+func SummarizeFees(blocks []BlockFees) FeeSummary {
+	var sum FeeSummary
+	if len(blocks) == 0 {
+		return sum
+	}
+
+	sum.FromBlock = blocks[0].BlockNumber
+	sum.ToBlock = blocks[0].BlockNumber
+	sum.Blocks = len(blocks)
+
+	var medians []uint64
+	for _, fees := range blocks {
+		if fees.BlockNumber < sum.FromBlock {
+			sum.FromBlock = fees.BlockNumber
+		}
+		if fees.BlockNumber > sum.ToBlock {
+			sum.ToBlock = fees.BlockNumber
+		}
+
+		sum.TxCount += fees.TxCount
+		sum.TotalTips += fees.TotalTips
+		sum.TotalGas += fees.TotalGas
+
+		if fees.TxCount == 0 {
+			continue
+		}
+
+		if len(medians) == 0 || fees.MinTip < sum.MinTip {
+			sum.MinTip = fees.MinTip
+		}
+		if fees.MaxTip > sum.MaxTip {
+			sum.MaxTip = fees.MaxTip
+		}
+		medians = append(medians, fees.MedianTip)
+	}
+
+	_, sum.MedianTip, _ = tipStats(medians)
+	sum.AvgTxsBlock = uint64(sum.TxCount / sum.Blocks)
+
+	return sum
+}
+
+// WriteFees stores the fee statistics for the block they were computed for.
+func (db *Database) WriteFees(fees BlockFees) error {
+	return db.storage.WriteFees(fees.BlockNumber, fees)
+}
+
+// GetFees returns the fee statistics for the specified block number.
+func (db *Database) GetFees(num uint64) (BlockFees, error) {
+	return db.storage.GetFees(num)
+}
+
+// =============================================================================
+
+// tipStats returns the min, median and max of the tips. The median of an
+// even number of tips is the mean of the middle two.
+func tipStats(tips []uint64) (min uint64, median uint64, max uint64) {
+	if len(tips) == 0 {
+		return 0, 0, 0
+	}
+
+	sorted := make([]uint64, len(tips))
+	copy(sorted, tips)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	mid := len(sorted) / 2
+	median = sorted[mid]
+	if len(sorted)%2 == 0 {
+		median = sorted[mid-1] + (sorted[mid]-sorted[mid-1])/2
+	}
+
+	return sorted[0], median, sorted[len(sorted)-1]
+}
@@ -0,0 +1,169 @@
+package database_test
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// chainID is the chain the property and fuzz tests sign transactions for.
+const chainID = 1
+
+func TestTxSignRoundTrip(t *testing.T) {
+	f := func(nonce uint64, value uint64, tip uint64, data []byte) bool {
+		pk, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("Should be able to generate a private key: %s", err)
+		}
+		to, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("Should be able to generate a private key: %s", err)
+		}
+
+		// A value and tip that overflow are rejected, so the tip is kept to
+		// what the value leaves.
+		if tip > math.MaxUint64-value {
+			tip = math.MaxUint64 - value
+		}
+
+		fromID := database.PublicKeyToAccountID(pk.PublicKey)
+		toID := database.PublicKeyToAccountID(to.PublicKey)
+
+		tx, err := database.NewTx(chainID, nonce, fromID, toID, value, tip, data)
+		if err != nil {
+			t.Logf("Should be able to construct the transaction: %s", err)
+			return false
+		}
+
+		signedTx, err := tx.Sign(pk)
+		if err != nil {
+			t.Logf("Should be able to sign the transaction: %s", err)
+			return false
+		}
+
+		if err := signedTx.Validate(chainID); err != nil {
+			t.Logf("Should be able to validate the signed transaction: %s", err)
+			return false
+		}
+
+		// The transaction must survive the trip across the network.
+		b, err := json.Marshal(signedTx)
+		if err != nil {
+			t.Logf("Should be able to marshal the signed transaction: %s", err)
+			return false
+		}
+		var decoded database.SignedTx
+		if err := json.Unmarshal(b, &decoded); err != nil {
+			t.Logf("Should be able to unmarshal the signed transaction: %s", err)
+			return false
+		}
+		if err := decoded.Validate(chainID); err != nil {
+			t.Logf("Should be able to validate the decoded transaction: %s", err)
+			return false
+		}
+		if decoded.TxHash() != signedTx.TxHash() {
+			t.Logf("Should have the same hash after decoding, got %s, exp %s", decoded.TxHash(), signedTx.TxHash())
+			return false
+		}
+
+		// The signature must not validate for any other chain.
+		if err := signedTx.Validate(chainID + 1); err == nil {
+			t.Log("Should not validate for another chain")
+			return false
+		}
+
+		return true
+	}
+
+	if err := quick.Check(f, &quick.Config{MaxCount: 50}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// =============================================================================
+
+func FuzzToAccountID(f *testing.F) {
+	f.Add("0xF01813E4B85e178A83e29B8E7bF26BD830a25f32")
+	f.Add("F01813E4B85e178A83e29B8E7bF26BD830a25f32")
+	f.Add("0XF01813E4B85e178A83e29B8E7bF26BD830a25f32")
+	f.Add("0xF01813E4B85e178A83e29B8E7bF26BD830a25f3")
+	f.Add("0xF01813E4B85e178A83e29B8E7bF26BD830a25f3g")
+	f.Add("0x")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, hex string) {
+		accountID, err := database.ToAccountID(hex)
+
+		// The hand rolled validation must agree with the crypto package.
+		if valid := crypto.IsHexAddress(hex); valid != (err == nil) {
+			t.Fatalf("Should agree with the crypto package on %q, got valid %t, exp %t", hex, err == nil, valid)
+		}
+
+		if err != nil {
+			return
+		}
+
+		if string(accountID) != hex {
+			t.Fatalf("Should keep the account as provided, got %s, exp %s", accountID, hex)
+		}
+
+		if digits := strings.TrimPrefix(strings.TrimPrefix(hex, "0x"), "0X"); len(digits) != 40 {
+			t.Fatalf("Should only accept 20 byte accounts, got %d digits", len(digits))
+		}
+	})
+}
+
+func FuzzSignedTxValidate(f *testing.F) {
+	pk, err := crypto.HexToECDSA("fae85851bdf5c9f49923722ce38f3c1defcfd3619ef5453230a58ad805499959")
+	if err != nil {
+		f.Fatalf("Should be able to load the private key: %s", err)
+	}
+
+	tx, err := database.NewTx(chainID, 1, "0xdd6B972ffcc631a62CAE1BB9d80b7ff429c8ebA4", "0xF01813E4B85e178A83e29B8E7bF26BD830a25f32", 100, 1, []byte("hello"))
+	if err != nil {
+		f.Fatalf("Should be able to construct the transaction: %s", err)
+	}
+
+	signedTx, err := tx.Sign(pk)
+	if err != nil {
+		f.Fatalf("Should be able to sign the transaction: %s", err)
+	}
+
+	valid, err := json.Marshal(signedTx)
+	if err != nil {
+		f.Fatalf("Should be able to marshal the signed transaction: %s", err)
+	}
+
+	f.Add(valid)
+	f.Add([]byte(strings.Replace(string(valid), `"v":29`, `"v":18446744073709551645`, 1)))
+	f.Add([]byte(strings.Replace(string(valid), `"v":29`, `"v":-29`, 1)))
+	f.Add([]byte(strings.Replace(string(valid), `"value":100`, `"value":101`, 1)))
+	f.Add([]byte(`{"chain_id":1,"from":"0xdd6B972ffcc631a62CAE1BB9d80b7ff429c8ebA4","to":"0xF01813E4B85e178A83e29B8E7bF26BD830a25f32","v":29,"r":1,"s":1}`))
+	f.Add([]byte(`{"chain_id":1,"v":29,"r":1157920892373161954235709850086879078532699846656405640394575840079131296399360,"s":1}`))
+	f.Add([]byte(`{}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var tx database.SignedTx
+		if err := json.Unmarshal(data, &tx); err != nil {
+			return
+		}
+
+		if err := tx.Validate(chainID); err != nil {
+			return
+		}
+
+		// A transaction that validates must be signed by the from account.
+		// The signature was produced by the key of the seed transaction, so
+		// only that account can have signed anything that validates.
+		if tx.FromID != signedTx.FromID {
+			if _, err := tx.MultisigSigners(); err != nil {
+				t.Fatalf("Should only validate transactions signed by the from account, got %s", tx.FromID)
+			}
+		}
+	})
+}
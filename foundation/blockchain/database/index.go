@@ -0,0 +1,143 @@
+package database
+
+import "sort"
+
+// newBalanceIndex returns the accounts ordered by balance, largest first,
+// with accounts holding the same balance ordered by account id.
+func newBalanceIndex(accounts []Account) []Account {
+	index := make([]Account, len(accounts))
+	copy(index, accounts)
+
+	sort.Slice(index, func(i, j int) bool {
+		if index[i].Balance != index[j].Balance {
+			return index[i].Balance > index[j].Balance
+		}
+		return index[i].AccountID < index[j].AccountID
+	})
+
+	return index
+}
+
+// AccountsByBalance returns a page of the accounts selected by the filter
+// ordered by balance, largest first, along with the total number of accounts
+// selected. The index is built once for each published view so later
+// queries without a filter only copy the page.
+func (db *Database) AccountsByBalance(filter AccountFilter, offset int, limit int) ([]Account, int) {
+	index := db.load().balanceIndex()
+
+	if filter != (AccountFilter{}) {
+		return newAccountIterator(index, filter).page(offset, limit)
+	}
+
+	total := len(index)
+	if offset < 0 || offset >= total {
+		return []Account{}, total
+	}
+
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+
+	page := make([]Account, end-offset)
+	copy(page, index[offset:end])
+
+	return page, total
+}
+
+// QueryAccounts returns an iterator over the accounts selected by the filter
+// in account id order. The iterator walks the accounts as of the call, so
+// blocks applied during the walk aren't seen and the walk never holds the
+// database lock.
+func (db *Database) QueryAccounts(filter AccountFilter) *AccountIterator {
+	return newAccountIterator(db.load().accountList(), filter)
+}
+
+// AccountCount returns the number of accounts in the database.
+func (db *Database) AccountCount() int {
+	return len(db.load().accounts)
+}
+
+// =============================================================================
+
+// AccountFilter selects the accounts returned by a query. The zero value
+// selects every account.
+type AccountFilter struct {
+	MinBalance uint64 // Accounts holding less are skipped.
+	MinNonce   uint64 // Accounts with a lower nonce are skipped.
+	MaxNonce   uint64 // Accounts with a higher nonce are skipped, no limit when 0.
+}
+
+// match reports if the account is selected by the filter.
+func (f AccountFilter) match(account Account) bool {
+	switch {
+	case account.Balance < f.MinBalance:
+		return false
+	case account.Nonce < f.MinNonce:
+		return false
+	case f.MaxNonce > 0 && account.Nonce > f.MaxNonce:
+		return false
+	}
+	return true
+}
+
+// AccountIterator walks the accounts selected by a filter, the same way the
+// blocks in storage are walked.
+//
+//	for account := iter.Next(); !iter.Done(); account = iter.Next() {
+//	}
+//
+// The accounts are shared with the view of the database the iterator was
+// constructed from, so the iterator only hands out copies.
+type AccountIterator struct {
+	accounts []Account
+	filter   AccountFilter
+	next     int
+	done     bool
+}
+
+// newAccountIterator constructs an iterator over the accounts.
+func newAccountIterator(accounts []Account, filter AccountFilter) *AccountIterator {
+	return &AccountIterator{
+		accounts: accounts,
+		filter:   filter,
+	}
+}
+
+// Next returns the next account selected by the filter. Once there are no
+// more accounts, a zero account is returned and Done reports true.
+func (it *AccountIterator) Next() Account {
+	for it.next < len(it.accounts) {
+		account := it.accounts[it.next]
+		it.next++
+
+		if it.filter.match(account) {
+			return account
+		}
+	}
+
+	it.done = true
+	return Account{}
+}
+
+// Done reports if the last call to Next found no more accounts.
+func (it *AccountIterator) Done() bool {
+	return it.done
+}
+
+// page skips the offset of the selected accounts and returns up to the
+// limit of the ones that follow, along with the total number selected. A
+// limit of 0 returns all the accounts from the offset.
+func (it *AccountIterator) page(offset int, limit int) ([]Account, int) {
+	page := []Account{}
+
+	var total int
+	for account := it.Next(); !it.Done(); account = it.Next() {
+		if total >= offset && (limit == 0 || len(page) < limit) {
+			page = append(page, account)
+		}
+		total++
+	}
+
+	return page, total
+}
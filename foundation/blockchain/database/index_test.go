@@ -0,0 +1,93 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+)
+
+func TestQueryAccounts(t *testing.T) {
+	owner := newKey(t)
+	ownerID := database.PublicKeyToAccountID(owner.PublicKey)
+
+	gen := genesis.Genesis{
+		ChainID:  1,
+		GasPrice: 1,
+		Balances: map[string]uint64{
+			string(ownerID):      1_000_000,
+			string(accountID(1)): 100,
+			string(accountID(2)): 200,
+			string(accountID(3)): 300,
+		},
+	}
+
+	db, err := database.New(gen, memStorage{}, func(...any) {})
+	if err != nil {
+		t.Fatalf("Should be able to construct the database: %s", err)
+	}
+
+	// send applies a transaction from the owner, moving its nonce forward.
+	send := func(nonce uint64, toID database.AccountID, value uint64) {
+		t.Helper()
+
+		tx, err := database.NewTx(gen.ChainID, nonce, ownerID, toID, value, 0, nil)
+		if err != nil {
+			t.Fatalf("Should be able to construct the transaction: %s", err)
+		}
+		signedTx, err := tx.Sign(owner)
+		if err != nil {
+			t.Fatalf("Should be able to sign the transaction: %s", err)
+		}
+
+		block := database.Block{Header: database.BlockHeader{Number: nonce, BeneficiaryID: accountID(9)}}
+		if _, err := db.ApplyTransaction(block, database.NewBlockTx(signedTx, gen.GasPrice, 1)); err != nil {
+			t.Fatalf("Should be able to apply the transaction: %s", err)
+		}
+	}
+
+	// collect walks the iterator and returns the accounts.
+	collect := func(iter *database.AccountIterator) []database.Account {
+		var accounts []database.Account
+		for account := iter.Next(); !iter.Done(); account = iter.Next() {
+			accounts = append(accounts, account)
+		}
+		return accounts
+	}
+
+	send(1, accountID(1), 50)
+
+	// The iterator captures the accounts when it's constructed.
+	iter := db.QueryAccounts(database.AccountFilter{MinBalance: 150})
+	send(2, accountID(2), 1_000)
+
+	accounts := collect(iter)
+	if len(accounts) != 4 {
+		t.Fatalf("Should select the accounts holding the min balance, got %d, exp 4", len(accounts))
+	}
+	for i := 1; i < len(accounts); i++ {
+		if accounts[i-1].AccountID >= accounts[i].AccountID {
+			t.Fatalf("Should walk the accounts in account id order, got %s before %s", accounts[i-1].AccountID, accounts[i].AccountID)
+		}
+	}
+	for _, account := range accounts {
+		if account.AccountID == accountID(2) && account.Balance != 200 {
+			t.Fatalf("Should not see changes made during the walk, got %d, exp 200", account.Balance)
+		}
+	}
+
+	// Only the owner has sent transactions.
+	accounts = collect(db.QueryAccounts(database.AccountFilter{MinNonce: 2, MaxNonce: 2}))
+	if len(accounts) != 1 || accounts[0].AccountID != ownerID {
+		t.Fatalf("Should select the accounts in the nonce range, got %v", accounts)
+	}
+
+	// The rich list pages through the accounts selected by the filter.
+	page, total := db.AccountsByBalance(database.AccountFilter{MinBalance: 300}, 1, 1)
+	if total != 3 {
+		t.Fatalf("Should count the accounts selected, got %d, exp 3", total)
+	}
+	if len(page) != 1 || page[0].AccountID != accountID(2) {
+		t.Fatalf("Should return the second largest balance selected, got %v", page)
+	}
+}
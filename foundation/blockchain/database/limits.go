@@ -0,0 +1,41 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Set of errors returned when a transaction or block is larger than the
+// limits set in the genesis file.
+var (
+	ErrTxDataTooLarge = errors.New("transaction data is larger than allowed")
+	ErrTooManyTxs     = errors.New("block has more transactions than allowed")
+)
+
+// ValidateDataSize checks the data carried by the transaction is no larger
+// than the specified number of bytes. A limit of 0 means there is no limit.
+func (tx SignedTx) ValidateDataSize(maxBytes uint64) error {
+	if maxBytes > 0 && uint64(len(tx.Data)) > maxBytes {
+		return fmt.Errorf("%w, got %d bytes, max %d bytes", ErrTxDataTooLarge, len(tx.Data), maxBytes)
+	}
+	return nil
+}
+
+// ValidateLimits checks the block holds no more than the specified number of
+// transactions and none of them carry more data than allowed. A limit of 0
+// means there is no limit. Every node checks the blocks it's given against
+// the same limits the miner used to build them.
+func (b Block) ValidateLimits(transPerBlock uint16, maxTxDataBytes uint64) error {
+	values := b.MerkleTree.Values()
+	if transPerBlock > 0 && len(values) > int(transPerBlock) {
+		return fmt.Errorf("%w, got %d, max %d", ErrTooManyTxs, len(values), transPerBlock)
+	}
+
+	for _, tx := range values {
+		if err := tx.ValidateDataSize(maxTxDataBytes); err != nil {
+			return fmt.Errorf("tx %s: %w", tx.TxHash(), err)
+		}
+	}
+
+	return nil
+}
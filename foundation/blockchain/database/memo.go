@@ -0,0 +1,86 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// MemoType is the envelope type carried in the data field of a transaction
+// that transfers value with a memo.
+const MemoType = "memo"
+
+// Set of limits on the size of a memo so it stays a short note and not a
+// place to store data.
+const (
+	maxMemoText      = 256
+	maxMemoReference = 64
+)
+
+// Memo is the envelope carried in the data field of a transaction that
+// describes a payment. The text is a note for the recipient and the
+// reference identifies the payment, like an invoice number.
+type Memo struct {
+	Type      string `json:"type"`
+	Text      string `json:"text,omitempty"`
+	Reference string `json:"reference,omitempty"`
+}
+
+// NewMemo returns the data to place in a transaction that carries the memo
+// text and payment reference.
+func NewMemo(text string, reference string) ([]byte, error) {
+	memo := Memo{Type: MemoType, Text: text, Reference: reference}
+	if err := memo.validate(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(memo)
+}
+
+// NewTransferWithMemo constructs a transaction that transfers the value to
+// the recipient with the memo text and payment reference in the data field.
+func NewTransferWithMemo(chainID uint16, nonce uint64, fromID AccountID, toID AccountID, value uint64, tip uint64, text string, reference string) (Tx, error) {
+	data, err := NewMemo(text, reference)
+	if err != nil {
+		return Tx{}, err
+	}
+
+	return NewTx(chainID, nonce, fromID, toID, value, tip, data)
+}
+
+// ParseMemo returns the memo carried in the data field of a transaction if
+// there is one.
+func ParseMemo(data []byte) (Memo, bool) {
+	var memo Memo
+	if err := json.Unmarshal(data, &memo); err != nil || memo.Type != MemoType {
+		return Memo{}, false
+	}
+
+	if err := memo.validate(); err != nil {
+		return Memo{}, false
+	}
+
+	return memo, true
+}
+
+// validate checks the memo is readable text within the size limits.
+func (m Memo) validate() error {
+	if m.Text == "" && m.Reference == "" {
+		return errors.New("memo requires text or a reference")
+	}
+
+	if len(m.Text) > maxMemoText {
+		return fmt.Errorf("memo text is %d bytes, max is %d", len(m.Text), maxMemoText)
+	}
+
+	if len(m.Reference) > maxMemoReference {
+		return fmt.Errorf("memo reference is %d bytes, max is %d", len(m.Reference), maxMemoReference)
+	}
+
+	if !utf8.ValidString(m.Text) || !utf8.ValidString(m.Reference) {
+		return errors.New("memo must be valid utf-8")
+	}
+
+	return nil
+}
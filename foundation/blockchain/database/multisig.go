@@ -0,0 +1,195 @@
+package database
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// Set of envelope types carried in the data field of a transaction to
+// support multisig accounts.
+const (
+	MultisigRegisterType = "multisig_register"
+	MultisigSpendType    = "multisig"
+)
+
+// Set of errors returned when authorizing a multisig transaction.
+var (
+	ErrNotMultisig       = errors.New("account is not a multisig account")
+	ErrMultisigThreshold = errors.New("not enough participants signed the transaction")
+)
+
+// Multisig represents the participants of a multisig account and the number
+// of them that must sign a transaction spending from the account.
+type Multisig struct {
+	Threshold    int         `json:"threshold"`
+	Participants []AccountID `json:"participants"`
+}
+
+// Validate checks the threshold can be met by the participants and the
+// participants are unique and properly formatted.
+func (ms Multisig) Validate() error {
+	if ms.Threshold < 1 || ms.Threshold > len(ms.Participants) {
+		return fmt.Errorf("invalid threshold %d for %d participants", ms.Threshold, len(ms.Participants))
+	}
+
+	seen := make(map[AccountID]bool, len(ms.Participants))
+	for _, accountID := range ms.Participants {
+		if !accountID.IsAccountID() {
+			return fmt.Errorf("participant %s is not properly formatted", accountID)
+		}
+		if seen[accountID] {
+			return fmt.Errorf("participant %s is listed more than once", accountID)
+		}
+		seen[accountID] = true
+	}
+
+	return nil
+}
+
+// Authorize checks the transaction was signed by enough of the participants.
+// The account that signed the transaction counts as one of the signers.
+func (ms Multisig) Authorize(tx SignedTx) error {
+	signers, err := tx.MultisigSigners()
+	if err != nil {
+		return err
+	}
+
+	participants := make(map[AccountID]bool, len(ms.Participants))
+	for _, accountID := range ms.Participants {
+		participants[accountID] = true
+	}
+
+	approved := make(map[AccountID]bool, len(signers))
+	for _, accountID := range signers {
+		if !participants[accountID] {
+			return fmt.Errorf("signer %s is not a participant", accountID)
+		}
+		approved[accountID] = true
+	}
+
+	if len(approved) < ms.Threshold {
+		return fmt.Errorf("%w, got %d, exp %d", ErrMultisigThreshold, len(approved), ms.Threshold)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// MultisigRegistration is the envelope carried in the data field of the
+// transaction that creates a multisig account. The transaction must be sent
+// to the account id derived from the sender and nonce of the transaction.
+type MultisigRegistration struct {
+	Type string `json:"type"`
+	Multisig
+}
+
+// NewMultisigRegistration returns the data to place in the transaction that
+// registers a multisig account with the specified participants.
+func NewMultisigRegistration(threshold int, participants []AccountID) ([]byte, error) {
+	reg := MultisigRegistration{
+		Type: MultisigRegisterType,
+		Multisig: Multisig{
+			Threshold:    threshold,
+			Participants: participants,
+		},
+	}
+
+	if err := reg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(reg)
+}
+
+// ParseMultisigRegistration returns the registration carried in the data
+// field of a transaction if there is one.
+func ParseMultisigRegistration(data []byte) (MultisigRegistration, bool) {
+	var reg MultisigRegistration
+	if err := json.Unmarshal(data, &reg); err != nil || reg.Type != MultisigRegisterType {
+		return MultisigRegistration{}, false
+	}
+
+	return reg, true
+}
+
+// =============================================================================
+
+// MultisigEnvelope is carried in the data field of a transaction spending
+// from a multisig account. It holds the original data of the transaction and
+// the signatures of the participants co-signing it. Each co-signature is over
+// the transaction with the original data in place of the envelope.
+type MultisigEnvelope struct {
+	Type       string   `json:"type"`
+	Data       []byte   `json:"data"`
+	Signatures []string `json:"signatures"`
+}
+
+// NewMultisigEnvelope returns the data to place in the transaction that
+// spends from a multisig account.
+func NewMultisigEnvelope(data []byte, signatures []string) ([]byte, error) {
+	env := MultisigEnvelope{
+		Type:       MultisigSpendType,
+		Data:       data,
+		Signatures: signatures,
+	}
+
+	return json.Marshal(env)
+}
+
+// ParseMultisigEnvelope returns the envelope carried in the data field of a
+// transaction if there is one.
+func ParseMultisigEnvelope(data []byte) (MultisigEnvelope, bool) {
+	var env MultisigEnvelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Type != MultisigSpendType {
+		return MultisigEnvelope{}, false
+	}
+
+	return env, true
+}
+
+// CoSign uses the specified private key to co-sign the transaction for a
+// multisig account. The transaction must hold the original data, not the
+// envelope. The signature is returned in the [R|S|V] hex format.
+func (tx Tx) CoSign(privateKey *ecdsa.PrivateKey) (string, error) {
+	v, r, s, err := signature.Sign(tx, privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return signature.SignatureString(v, r, s), nil
+}
+
+// MultisigSigners returns the accounts that signed a transaction carrying a
+// multisig envelope. The first account is the one that signed the
+// transaction itself, followed by the co-signers.
+func (tx SignedTx) MultisigSigners() ([]AccountID, error) {
+	env, ok := ParseMultisigEnvelope(tx.Data)
+	if !ok {
+		return nil, errors.New("transaction does not carry a multisig envelope")
+	}
+
+	address, err := tx.FromAddress()
+	if err != nil {
+		return nil, err
+	}
+	signers := []AccountID{AccountID(address)}
+
+	// The co-signers signed the transaction before it was wrapped.
+	payload := tx.Tx
+	payload.Data = env.Data
+
+	for _, sig := range env.Signatures {
+		address, err := signature.FromAddressSignature(payload, sig)
+		if err != nil {
+			return nil, fmt.Errorf("co-signature: %w", err)
+		}
+		signers = append(signers, AccountID(address))
+	}
+
+	return signers, nil
+}
@@ -0,0 +1,140 @@
+package database_test
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+)
+
+func TestMultisig(t *testing.T) {
+	owner := newKey(t)
+	ownerID := database.PublicKeyToAccountID(owner.PublicKey)
+
+	keys := []*ecdsa.PrivateKey{newKey(t), newKey(t), newKey(t)}
+	participants := make([]database.AccountID, len(keys))
+	for i, key := range keys {
+		participants[i] = database.PublicKeyToAccountID(key.PublicKey)
+	}
+
+	gen := genesis.Genesis{
+		ChainID:  1,
+		GasPrice: 1,
+		Balances: map[string]uint64{string(ownerID): 1_000_000},
+	}
+
+	db, err := database.New(gen, memStorage{}, func(...any) {})
+	if err != nil {
+		t.Fatalf("Should be able to construct the database: %s", err)
+	}
+
+	block := database.Block{Header: database.BlockHeader{Number: 1, BeneficiaryID: accountID(99)}}
+
+	// Register an account 2 of the 3 participants must sign for.
+	reg, err := database.NewMultisigRegistration(2, participants)
+	if err != nil {
+		t.Fatalf("Should be able to construct the registration: %s", err)
+	}
+
+	multisigID := database.CreateAccountID(ownerID, 1)
+	tx, err := database.NewTx(gen.ChainID, 1, ownerID, multisigID, 10_000, 0, reg)
+	if err != nil {
+		t.Fatalf("Should be able to construct the registration transaction: %s", err)
+	}
+	signedTx, err := tx.Sign(owner)
+	if err != nil {
+		t.Fatalf("Should be able to sign the transaction: %s", err)
+	}
+	if _, err := db.ApplyTransaction(block, database.NewBlockTx(signedTx, gen.GasPrice, 1)); err != nil {
+		t.Fatalf("Should be able to register the account: %s", err)
+	}
+
+	// spend sends 100 from the multisig account, signed by the first key
+	// and co-signed by the others, with the co-signatures in the envelope.
+	spend := func(signer *ecdsa.PrivateKey, cosigners ...*ecdsa.PrivateKey) database.SignedTx {
+		t.Helper()
+
+		tx, err := database.NewTx(gen.ChainID, 1, multisigID, accountID(1), 100, 0, nil)
+		if err != nil {
+			t.Fatalf("Should be able to construct the transaction: %s", err)
+		}
+
+		var sigs []string
+		for _, key := range cosigners {
+			sig, err := tx.CoSign(key)
+			if err != nil {
+				t.Fatalf("Should be able to co-sign the transaction: %s", err)
+			}
+			sigs = append(sigs, sig)
+		}
+
+		if tx.Data, err = database.NewMultisigEnvelope(tx.Data, sigs); err != nil {
+			t.Fatalf("Should be able to construct the envelope: %s", err)
+		}
+
+		signedTx, err := tx.Sign(signer)
+		if err != nil {
+			t.Fatalf("Should be able to sign the transaction: %s", err)
+		}
+
+		return signedTx
+	}
+
+	tt := []struct {
+		name string
+		tx   database.SignedTx
+		exp  string
+	}{
+		{"one signer", spend(keys[0]), database.ErrMultisigThreshold.Error()},
+		{"same signer twice", spend(keys[0], keys[0]), database.ErrMultisigThreshold.Error()},
+		{"not a participant", spend(keys[0], newKey(t)), "is not a participant"},
+	}
+
+	for _, tst := range tt {
+		if err := db.Authorize(tst.tx); err == nil || !strings.Contains(err.Error(), tst.exp) {
+			t.Errorf("%s: Should reject the transaction with %q, got %v", tst.name, tst.exp, err)
+		}
+	}
+
+	// A co-signature over different data doesn't count.
+	tampered := spend(keys[0], keys[1])
+	tampered.Value = 200
+	if err := db.Authorize(tampered); err == nil {
+		t.Fatal("Should reject a transaction changed after it was co-signed")
+	}
+
+	signedTx = spend(keys[0], keys[2])
+	if err := db.Authorize(signedTx); err != nil {
+		t.Fatalf("Should authorize the transaction signed by 2 participants: %s", err)
+	}
+	if _, err := db.ApplyTransaction(block, database.NewBlockTx(signedTx, gen.GasPrice, 1)); err != nil {
+		t.Fatalf("Should be able to spend from the account: %s", err)
+	}
+
+	account, err := db.Query(multisigID)
+	if err != nil {
+		t.Fatalf("Should be able to query the account: %s", err)
+	}
+	if account.Multisig == nil || account.Multisig.Threshold != 2 {
+		t.Fatalf("Should register the multisig account, got %+v", account.Multisig)
+	}
+	if exp := uint64(10_000 - 100 - 1); account.Balance != exp || account.Nonce != 1 {
+		t.Fatalf("Should spend from the account, got %d:%d, exp %d:%d", account.Balance, account.Nonce, exp, 1)
+	}
+
+	// A transaction for an ordinary account signed by someone else isn't
+	// authorized by an envelope.
+	tx, err = database.NewTx(gen.ChainID, 2, ownerID, accountID(1), 100, 0, nil)
+	if err != nil {
+		t.Fatalf("Should be able to construct the transaction: %s", err)
+	}
+	if signedTx, err = tx.Sign(keys[0]); err != nil {
+		t.Fatalf("Should be able to sign the transaction: %s", err)
+	}
+	if err := db.Authorize(signedTx); !errors.Is(err, database.ErrNotMultisig) {
+		t.Fatalf("Should reject the transaction, got %v, exp %v", err, database.ErrNotMultisig)
+	}
+}
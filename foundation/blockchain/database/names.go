@@ -0,0 +1,86 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// registerPrefix marks transaction data that registers a name for the
+// account sending the transaction.
+var registerPrefix = []byte("register:")
+
+// validName defines the names that can be registered.
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{2,31}$`)
+
+// Set of errors returned by the name registry.
+var (
+	ErrNameNotFound = errors.New("name not found")
+	ErrNameTaken    = errors.New("name is already registered")
+)
+
+// NameEntry represents a name and the account it's registered to.
+type NameEntry struct {
+	Name      string    `json:"name"`
+	AccountID AccountID `json:"account"`
+}
+
+// NameRegistration returns the data to place in a transaction that registers
+// the name for the account sending the transaction.
+func NameRegistration(name string) ([]byte, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("invalid name %q, must be 3 to 32 lowercase letters, digits or dashes", name)
+	}
+
+	return append(append([]byte{}, registerPrefix...), name...), nil
+}
+
+// ParseNameRegistration returns the name being registered if the data
+// is a name registration.
+func ParseNameRegistration(data []byte) (string, bool) {
+	if !bytes.HasPrefix(data, registerPrefix) {
+		return "", false
+	}
+
+	return string(data[len(registerPrefix):]), true
+}
+
+// QueryName returns the account the specified name is registered to.
+func (db *Database) QueryName(name string) (AccountID, error) {
+	accountID, exists := db.load().names[name]
+	if !exists {
+		return "", ErrNameNotFound
+	}
+
+	return accountID, nil
+}
+
+// validateName checks the name can be registered. The caller must hold
+// the lock.
+func (db *Database) validateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid name %q", name)
+	}
+
+	if accountID, exists := db.names[name]; exists {
+		return fmt.Errorf("%w, %q belongs to %s", ErrNameTaken, name, accountID)
+	}
+
+	return nil
+}
+
+// sortedNames returns the registered names in name order.
+func sortedNames(names map[string]AccountID) []NameEntry {
+	list := make([]NameEntry, 0, len(names))
+	for name, accountID := range names {
+		list = append(list, NameEntry{Name: name, AccountID: accountID})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
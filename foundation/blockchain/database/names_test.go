@@ -0,0 +1,60 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+)
+
+func TestStateRootNames(t *testing.T) {
+	owner := newKey(t)
+	ownerID := database.PublicKeyToAccountID(owner.PublicKey)
+
+	gen := genesis.Genesis{
+		ChainID:  1,
+		GasPrice: 1,
+		Balances: map[string]uint64{string(ownerID): 1_000_000},
+	}
+
+	// register constructs a database where the owner registered the name.
+	register := func(name string) *database.Database {
+		t.Helper()
+
+		db, err := database.New(gen, memStorage{}, func(...any) {})
+		if err != nil {
+			t.Fatalf("Should be able to construct the database: %s", err)
+		}
+
+		data, err := database.NameRegistration(name)
+		if err != nil {
+			t.Fatalf("Should be able to construct the registration: %s", err)
+		}
+		tx, err := database.NewTx(gen.ChainID, 1, ownerID, ownerID, 0, 0, data)
+		if err != nil {
+			t.Fatalf("Should be able to construct the transaction: %s", err)
+		}
+		signedTx, err := tx.Sign(owner)
+		if err != nil {
+			t.Fatalf("Should be able to sign the transaction: %s", err)
+		}
+
+		block := database.Block{Header: database.BlockHeader{Number: 1, BeneficiaryID: accountID(9)}}
+		if _, err := db.ApplyTransaction(block, database.NewBlockTx(signedTx, gen.GasPrice, 1)); err != nil {
+			t.Fatalf("Should be able to apply the transaction: %s", err)
+		}
+		if got, err := db.QueryName(name); err != nil || got != ownerID {
+			t.Fatalf("Should register the name to the owner, got %s, %v", got, err)
+		}
+
+		return db
+	}
+
+	// The accounts are the same, only the owner of the names differs.
+	alice := register("alice")
+	bobby := register("bobby")
+
+	if alice.HashState() == bobby.HashState() {
+		t.Fatalf("Should include the registered names in the state root")
+	}
+}
@@ -0,0 +1,203 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"sort"
+	"strings"
+)
+
+// PolicyType is the envelope type carried in the data field of a transaction
+// that changes the policy of a permissioned chain.
+const PolicyType = "policy"
+
+// Set of actions a policy transaction can perform on an account.
+const (
+	PolicyAllow = "allow"
+	PolicyDeny  = "deny"
+)
+
+// Set of errors returned by the policy of a permissioned chain.
+var (
+	ErrNotPermitted   = errors.New("account is not permitted to transact")
+	ErrNotPolicyAdmin = errors.New("account is not a policy admin")
+)
+
+// PolicyTx is the envelope carried in the data field of a transaction that
+// allows or denies an account on a permissioned chain. Only a policy admin
+// listed in genesis can send it.
+type PolicyTx struct {
+	Type    string    `json:"type"`
+	Action  string    `json:"action"`
+	Account AccountID `json:"account"`
+}
+
+// PolicyEntry represents an account an admin allowed or denied.
+type PolicyEntry struct {
+	AccountID AccountID `json:"account"`
+	Allowed   bool      `json:"allowed"`
+}
+
+// Policy represents the rules of a permissioned chain.
+type Policy struct {
+	Enabled bool          `json:"enabled"`
+	Admins  []AccountID   `json:"admins"`
+	Entries []PolicyEntry `json:"entries"`
+}
+
+// NewPolicyTx returns the data to place in the transaction that performs the
+// action on the account.
+func NewPolicyTx(action string, accountID AccountID) ([]byte, error) {
+	ptx := PolicyTx{Type: PolicyType, Action: action, Account: accountID}
+	if err := ptx.validate(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(ptx)
+}
+
+// ParsePolicyTx returns the policy envelope carried in the data field of a
+// transaction if there is one.
+func ParsePolicyTx(data []byte) (PolicyTx, bool) {
+	var ptx PolicyTx
+	if err := json.Unmarshal(data, &ptx); err != nil || ptx.Type != PolicyType {
+		return PolicyTx{}, false
+	}
+
+	return ptx, true
+}
+
+// validate checks the action and account of the policy transaction.
+func (ptx PolicyTx) validate() error {
+	switch ptx.Action {
+	case PolicyAllow, PolicyDeny:
+	default:
+		return fmt.Errorf("invalid policy action %q, must be %s or %s", ptx.Action, PolicyAllow, PolicyDeny)
+	}
+
+	if !ptx.Account.IsAccountID() {
+		return fmt.Errorf("policy account %s is not properly formatted", ptx.Account)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// QueryPolicy returns the rules of the chain.
+func (db *Database) QueryPolicy() Policy {
+	if db.genesis.Policy == nil {
+		return Policy{Entries: []PolicyEntry{}}
+	}
+
+	admins := make([]AccountID, len(db.genesis.Policy.Admins))
+	for i, admin := range db.genesis.Policy.Admins {
+		admins[i] = AccountID(admin)
+	}
+
+	return Policy{
+		Enabled: true,
+		Admins:  admins,
+		Entries: sortedPolicy(db.load().policy),
+	}
+}
+
+// Permit checks the policy of a permissioned chain allows the transaction.
+// The sender must be permitted to transact and only an admin can change the
+// policy.
+func (db *Database) Permit(tx SignedTx) error {
+	return db.permit(db.load().policy, tx)
+}
+
+// permit checks the transaction against the policy decisions provided.
+func (db *Database) permit(policy map[AccountID]bool, tx SignedTx) error {
+	ptx, policyTx := ParsePolicyTx(tx.Data)
+
+	if db.genesis.Policy == nil {
+		if policyTx {
+			return errors.New("chain is not permissioned, policy transactions are not allowed")
+		}
+		return nil
+	}
+
+	if policyTx {
+		if !db.isPolicyAdmin(tx.FromID) {
+			return fmt.Errorf("%w, %s", ErrNotPolicyAdmin, tx.FromID)
+		}
+		return ptx.validate()
+	}
+
+	if !db.isPermitted(policy, tx.FromID) {
+		return fmt.Errorf("%w, %s", ErrNotPermitted, tx.FromID)
+	}
+
+	return nil
+}
+
+// isPermitted reports if the account can send transactions. An admin
+// decision about the account takes precedence over genesis.
+func (db *Database) isPermitted(policy map[AccountID]bool, accountID AccountID) bool {
+	if db.isPolicyAdmin(accountID) {
+		return true
+	}
+
+	if allowed, exists := policy[policyKey(accountID)]; exists {
+		return allowed
+	}
+
+	for account := range db.genesis.Balances {
+		if strings.EqualFold(account, string(accountID)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isPolicyAdmin reports if the account is a policy admin in genesis.
+func (db *Database) isPolicyAdmin(accountID AccountID) bool {
+	for _, admin := range db.genesis.Policy.Admins {
+		if strings.EqualFold(admin, string(accountID)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyPolicyTx records the admin decision of a validated policy
+// transaction. The caller must hold the lock.
+func (db *Database) applyPolicyTx(ptx PolicyTx) {
+	db.policy[policyKey(ptx.Account)] = ptx.Action == PolicyAllow
+}
+
+// policyKey returns the checksummed form of the account so a decision made
+// for an account in any letter case applies to it.
+func policyKey(accountID AccountID) AccountID {
+	return AccountID(crypto.HexToAddress(string(accountID)).String())
+}
+
+// copyPolicy makes a copy of the policy decisions.
+func copyPolicy(policy map[AccountID]bool) map[AccountID]bool {
+	cpy := make(map[AccountID]bool, len(policy))
+	for accountID, allowed := range policy {
+		cpy[accountID] = allowed
+	}
+	return cpy
+}
+
+// sortedPolicy returns the policy decisions in order of the account.
+func sortedPolicy(policy map[AccountID]bool) []PolicyEntry {
+	list := make([]PolicyEntry, 0, len(policy))
+	for accountID, allowed := range policy {
+		list = append(list, PolicyEntry{AccountID: accountID, Allowed: allowed})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].AccountID < list[j].AccountID
+	})
+
+	return list
+}
@@ -0,0 +1,46 @@
+package database
+
+// Set of status values for a receipt.
+const (
+	ReceiptStatusSuccess = "success"
+	ReceiptStatusFailed  = "failed"
+)
+
+// Receipt represents the outcome of applying a transaction that was recorded
+// in a block. A transaction that fails is still recorded in the block and the
+// account still pays the gas fee.
+type Receipt struct {
+	TxHash      string   `json:"tx_hash"`
+	Status      string   `json:"status"`
+	Error       string   `json:"error,omitempty"`
+	BlockNumber uint64   `json:"block_number"`
+	BlockHash   string   `json:"block_hash"`
+	Index       int      `json:"index"`
+	GasUsed     uint64   `json:"gas_used"`
+	GasFee      uint64   `json:"gas_fee"`
+	Tip         uint64   `json:"tip"`
+	MinerFee    uint64   `json:"miner_fee"`
+	Output      []uint64 `json:"output,omitempty"`
+}
+
+// newReceipt constructs a receipt for the transaction applied in the
+// specified block.
+func newReceipt(block Block, tx BlockTx, gasFee uint64, tip uint64, err error) Receipt {
+	receipt := Receipt{
+		TxHash:      tx.TxHash(),
+		Status:      ReceiptStatusSuccess,
+		BlockNumber: block.Header.Number,
+		BlockHash:   block.Hash(),
+		GasUsed:     tx.GasUnits,
+		GasFee:      gasFee,
+		Tip:         tip,
+		MinerFee:    gasFee + tip,
+	}
+
+	if err != nil {
+		receipt.Status = ReceiptStatusFailed
+		receipt.Error = err.Error()
+	}
+
+	return receipt
+}
@@ -0,0 +1,203 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Set of envelope types carried in the data field of a transaction to
+// support accounts governed by spending rules.
+const (
+	RulesRegisterType = "rules_register"
+	RulesSpendType    = "rules"
+)
+
+// msPerDay is the length of the window the daily limit of spending rules
+// applies to, in the milliseconds of a block timestamp.
+const msPerDay = uint64(24 * time.Hour / time.Millisecond)
+
+// Set of errors returned when authorizing or applying a transaction from an
+// account governed by spending rules.
+var (
+	ErrNoSpendingRules     = errors.New("account is not governed by spending rules")
+	ErrNotRulesOwner       = errors.New("account is not the owner of the spending rules")
+	ErrRecipientNotAllowed = errors.New("recipient is not allowed by the spending rules")
+	ErrDailyLimit          = errors.New("transaction exceeds the daily limit of the spending rules")
+)
+
+// SpendingRules represents the rules an account created by a registration
+// transaction is governed by. The account has no private key, so only the
+// owner that registered it can send from it, and only within the rules set
+// at creation. The day and amount spent that day are tracked so the daily
+// limit can be enforced.
+type SpendingRules struct {
+	Owner      AccountID   `json:"owner"`
+	DailyLimit uint64      `json:"daily_limit,omitempty"`
+	Recipients []AccountID `json:"recipients,omitempty"`
+	Day        uint64      `json:"day,omitempty"`
+	Spent      uint64      `json:"spent,omitempty"`
+}
+
+// Authorize checks the transaction was signed by the owner of the rules.
+func (sr SpendingRules) Authorize(signerID AccountID) error {
+	if signerID != sr.Owner {
+		return fmt.Errorf("%w, %s", ErrNotRulesOwner, signerID)
+	}
+
+	return nil
+}
+
+// Spend checks the amount can be sent to the recipient in a block with the
+// specified timestamp, and returns the rules with the amount recorded
+// against the daily limit. A zero daily limit places no limit on the amount
+// and no recipients allows any recipient.
+func (sr SpendingRules) Spend(timeStamp uint64, toID AccountID, amount uint64) (SpendingRules, error) {
+	if len(sr.Recipients) > 0 {
+		var allowed bool
+		for _, accountID := range sr.Recipients {
+			if accountID == toID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return SpendingRules{}, fmt.Errorf("%w, %s", ErrRecipientNotAllowed, toID)
+		}
+	}
+
+	// The amount spent starts over on the first spend of a new day.
+	if day := timeStamp / msPerDay; day != sr.Day {
+		sr.Day = day
+		sr.Spent = 0
+	}
+
+	if sr.DailyLimit > 0 && (amount > sr.DailyLimit || sr.Spent > sr.DailyLimit-amount) {
+		return SpendingRules{}, fmt.Errorf("%w, spent %d, amount %d, limit %d", ErrDailyLimit, sr.Spent, amount, sr.DailyLimit)
+	}
+	sr.Spent += amount
+
+	return sr, nil
+}
+
+// =============================================================================
+
+// RulesRegistration is the envelope carried in the data field of the
+// transaction that creates an account governed by spending rules. The
+// transaction must be sent to the account id derived from the sender and
+// nonce of the transaction, and the sender becomes the owner of the rules.
+type RulesRegistration struct {
+	Type       string      `json:"type"`
+	DailyLimit uint64      `json:"daily_limit,omitempty"`
+	Recipients []AccountID `json:"recipients,omitempty"`
+}
+
+// NewRulesRegistration returns the data to place in the transaction that
+// registers an account governed by the specified rules.
+func NewRulesRegistration(dailyLimit uint64, recipients []AccountID) ([]byte, error) {
+	reg := RulesRegistration{
+		Type:       RulesRegisterType,
+		DailyLimit: dailyLimit,
+		Recipients: recipients,
+	}
+
+	if err := reg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(reg)
+}
+
+// ParseRulesRegistration returns the registration carried in the data field
+// of a transaction if there is one.
+func ParseRulesRegistration(data []byte) (RulesRegistration, bool) {
+	var reg RulesRegistration
+	if err := json.Unmarshal(data, &reg); err != nil || reg.Type != RulesRegisterType {
+		return RulesRegistration{}, false
+	}
+
+	return reg, true
+}
+
+// Validate checks the registration sets at least one rule and the
+// recipients are unique and properly formatted.
+func (reg RulesRegistration) Validate() error {
+	if reg.DailyLimit == 0 && len(reg.Recipients) == 0 {
+		return errors.New("spending rules must set a daily limit or recipients")
+	}
+
+	seen := make(map[AccountID]bool, len(reg.Recipients))
+	for _, accountID := range reg.Recipients {
+		if !accountID.IsAccountID() {
+			return fmt.Errorf("recipient %s is not properly formatted", accountID)
+		}
+		if seen[accountID] {
+			return fmt.Errorf("recipient %s is listed more than once", accountID)
+		}
+		seen[accountID] = true
+	}
+
+	return nil
+}
+
+// rules returns the spending rules the registration creates for the owner.
+func (reg RulesRegistration) rules(ownerID AccountID) *SpendingRules {
+	return &SpendingRules{
+		Owner:      ownerID,
+		DailyLimit: reg.DailyLimit,
+		Recipients: reg.Recipients,
+	}
+}
+
+// =============================================================================
+
+// RulesEnvelope is carried in the data field of a transaction the owner
+// sends from an account governed by spending rules. It holds the original
+// data of the transaction and marks the signer as acting for the account.
+type RulesEnvelope struct {
+	Type string `json:"type"`
+	Data []byte `json:"data"`
+}
+
+// NewRulesEnvelope returns the data to place in the transaction that spends
+// from an account governed by spending rules.
+func NewRulesEnvelope(data []byte) ([]byte, error) {
+	env := RulesEnvelope{
+		Type: RulesSpendType,
+		Data: data,
+	}
+
+	return json.Marshal(env)
+}
+
+// ParseRulesEnvelope returns the envelope carried in the data field of a
+// transaction if there is one.
+func ParseRulesEnvelope(data []byte) (RulesEnvelope, bool) {
+	var env RulesEnvelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Type != RulesSpendType {
+		return RulesEnvelope{}, false
+	}
+
+	return env, true
+}
+
+// =============================================================================
+
+// validateRulesRegistration checks the registration of an account governed
+// by spending rules. The caller must hold the lock.
+func (db *Database) validateRulesRegistration(tx BlockTx, reg RulesRegistration) error {
+	if err := reg.Validate(); err != nil {
+		return fmt.Errorf("rules registration: %w", err)
+	}
+
+	if accountID := CreateAccountID(tx.FromID, tx.Nonce); tx.ToID != accountID {
+		return fmt.Errorf("rules registration must be sent to %s", accountID)
+	}
+
+	if to := db.account(tx.ToID); to.Multisig != nil || to.Rules != nil {
+		return fmt.Errorf("account collision, account %s is already registered", tx.ToID)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package database_test
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+)
+
+func TestSpendingRules(t *testing.T) {
+	const day = 24 * 60 * 60 * 1000
+
+	owner := newKey(t)
+	ownerID := database.PublicKeyToAccountID(owner.PublicKey)
+	allowedID := accountID(1)
+
+	gen := genesis.Genesis{
+		ChainID:  1,
+		GasPrice: 1,
+		Balances: map[string]uint64{string(ownerID): 1_000_000},
+	}
+
+	db, err := database.New(gen, memStorage{}, func(...any) {})
+	if err != nil {
+		t.Fatalf("Should be able to construct the database: %s", err)
+	}
+
+	apply := func(privateKey *ecdsa.PrivateKey, tx database.Tx, timeStamp uint64) error {
+		t.Helper()
+
+		signedTx, err := tx.Sign(privateKey)
+		if err != nil {
+			t.Fatalf("Should be able to sign the transaction: %s", err)
+		}
+
+		block := database.Block{Header: database.BlockHeader{TimeStamp: timeStamp, BeneficiaryID: accountID(99)}}
+		_, err = db.ApplyTransaction(block, database.NewBlockTx(signedTx, gen.GasPrice, 1))
+		return err
+	}
+
+	spend := func(fromID database.AccountID, nonce uint64, toID database.AccountID, value uint64) database.Tx {
+		t.Helper()
+
+		env, err := database.NewRulesEnvelope(nil)
+		if err != nil {
+			t.Fatalf("Should be able to construct the envelope: %s", err)
+		}
+
+		tx, err := database.NewTx(gen.ChainID, nonce, fromID, toID, value, 0, env)
+		if err != nil {
+			t.Fatalf("Should be able to construct the transaction: %s", err)
+		}
+
+		return tx
+	}
+
+	// Register an account that can send 1000 a day to one recipient.
+	reg, err := database.NewRulesRegistration(1000, []database.AccountID{allowedID})
+	if err != nil {
+		t.Fatalf("Should be able to construct the registration: %s", err)
+	}
+
+	rulesID := database.CreateAccountID(ownerID, 1)
+	tx, err := database.NewTx(gen.ChainID, 1, ownerID, rulesID, 10_000, 0, reg)
+	if err != nil {
+		t.Fatalf("Should be able to construct the registration transaction: %s", err)
+	}
+	if err := apply(owner, tx, day); err != nil {
+		t.Fatalf("Should be able to register the account: %s", err)
+	}
+
+	if err := apply(owner, spend(rulesID, 1, allowedID, 600), day); err != nil {
+		t.Fatalf("Should be able to spend within the rules: %s", err)
+	}
+
+	tests := []struct {
+		name   string
+		signer *ecdsa.PrivateKey
+		toID   database.AccountID
+		value  uint64
+		exp    error
+	}{
+		{"limit", owner, allowedID, 500, database.ErrDailyLimit},
+		{"recipient", owner, accountID(2), 10, database.ErrRecipientNotAllowed},
+		{"owner", newKey(t), allowedID, 10, database.ErrNotRulesOwner},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := apply(tt.signer, spend(rulesID, 2, tt.toID, tt.value), day+1)
+			if !errors.Is(err, tt.exp) {
+				t.Fatalf("Should reject the transaction, got %v, exp %v", err, tt.exp)
+			}
+		})
+	}
+
+	// A value and tip adding up past the largest amount can't pass for a
+	// small spend.
+	overflow := spend(rulesID, 2, allowedID, math.MaxUint64)
+	overflow.Tip = 2
+
+	signedTx, err := overflow.Sign(owner)
+	if err != nil {
+		t.Fatalf("Should be able to sign the transaction: %s", err)
+	}
+	if err := signedTx.Validate(gen.ChainID); err == nil {
+		t.Fatalf("Should not validate a transaction with a value and tip that overflow")
+	}
+	if err := apply(owner, overflow, day+1); !errors.Is(err, database.ErrInsufficientFunds) {
+		t.Fatalf("Should reject a value and tip that overflow, got %v", err)
+	}
+
+	// The limit starts over the next day.
+	if err := apply(owner, spend(rulesID, 2, allowedID, 500), 2*day); err != nil {
+		t.Fatalf("Should be able to spend the next day: %s", err)
+	}
+
+	account, err := db.Query(rulesID)
+	if err != nil {
+		t.Fatalf("Should be able to query the account: %s", err)
+	}
+	if account.Rules == nil || account.Rules.Spent != 500 || account.Rules.Owner != ownerID {
+		t.Fatalf("Should track the amount spent today, got %+v", account.Rules)
+	}
+
+	// The transactions the rules rejected still paid the gas fees, the one
+	// the owner didn't sign wasn't charged.
+	if exp := uint64(10_000 - 600 - 500 - 5); account.Balance != exp {
+		t.Fatalf("Should hold the remaining balance, got %d, exp %d", account.Balance, exp)
+	}
+}
+
+// newKey generates a private key for the test.
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Should be able to generate a key: %s", err)
+	}
+
+	return privateKey
+}
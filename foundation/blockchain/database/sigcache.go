@@ -0,0 +1,112 @@
+package database
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// sigCacheSize is the number of recovered addresses kept by the signature
+// cache. This covers several full mempools worth of transactions.
+const sigCacheSize = 20_000
+
+// sigCache holds the addresses recovered from the signatures of the
+// transactions the node has seen.
+var sigCache = newAddressCache(sigCacheSize)
+
+// FromAddress returns the address of the account that signed the
+// transaction. The same transaction is validated when it's submitted,
+// gossiped, mined and applied during a resync, so the recovered address is
+// cached by the hash of the signed transaction to only pay for the ECDSA
+// recovery once. The hash covers the signature, so a transaction carrying
+// a different signature is never given the address of another.
+func (tx SignedTx) FromAddress() (string, error) {
+	txHash := tx.TxHash()
+	if address, exists := sigCache.get(txHash); exists {
+		return address, nil
+	}
+
+	address, err := signature.FromAddress(tx.Tx, tx.V, tx.R, tx.S)
+	if err != nil {
+		return "", err
+	}
+
+	sigCache.add(txHash, address)
+
+	return address, nil
+}
+
+// SignatureCacheStats returns the number of times a recovered address was
+// found in the signature cache and the number of times it had to be
+// recovered.
+func SignatureCacheStats() (hits uint64, misses uint64) {
+	sigCache.mu.Lock()
+	defer sigCache.mu.Unlock()
+
+	return sigCache.hits, sigCache.misses
+}
+
+// =============================================================================
+
+// addressCache is a least recently used cache of addresses by tx hash.
+type addressCache struct {
+	mu     sync.Mutex
+	size   int
+	order  *list.List
+	items  map[string]*list.Element
+	hits   uint64
+	misses uint64
+}
+
+// cacheEntry is the value held by each element of the cache order.
+type cacheEntry struct {
+	txHash  string
+	address string
+}
+
+// newAddressCache constructs a cache holding up to size addresses.
+func newAddressCache(size int) *addressCache {
+	return &addressCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// get returns the address for the tx hash, marking it as recently used.
+func (c *addressCache) get(txHash string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, exists := c.items[txHash]
+	if !exists {
+		c.misses++
+		return "", false
+	}
+
+	c.hits++
+	c.order.MoveToFront(elem)
+
+	return elem.Value.(cacheEntry).address, true
+}
+
+// add stores the address for the tx hash, evicting the least recently used
+// address when the cache is full.
+func (c *addressCache) add(txHash string, address string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, exists := c.items[txHash]; exists {
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[txHash] = c.order.PushFront(cacheEntry{txHash: txHash, address: address})
+
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(cacheEntry).txHash)
+	}
+}
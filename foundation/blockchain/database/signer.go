@@ -0,0 +1,187 @@
+package database
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// Signer represents the ability to sign transactions for an account. The
+// private key may be held in process or by an external service, such as a
+// hardware wallet or key management system, so it never needs to live on
+// the host running the node. SignValue signs any other value the node needs
+// to vouch for, such as its status, and returns the signature in hex.
+type Signer interface {
+	AccountID() AccountID
+	Sign(tx Tx) (SignedTx, error)
+	SignValue(value any) (string, error)
+}
+
+// =============================================================================
+
+// ECDSASigner signs transactions with a private key held in process.
+type ECDSASigner struct {
+	privateKey *ecdsa.PrivateKey
+	accountID  AccountID
+}
+
+// NewECDSASigner constructs a signer for the specified private key.
+func NewECDSASigner(privateKey *ecdsa.PrivateKey) *ECDSASigner {
+	return &ECDSASigner{
+		privateKey: privateKey,
+		accountID:  PublicKeyToAccountID(privateKey.PublicKey),
+	}
+}
+
+// AccountID returns the account the signer signs for.
+func (s *ECDSASigner) AccountID() AccountID {
+	return s.accountID
+}
+
+// Sign signs the transaction with the private key.
+func (s *ECDSASigner) Sign(tx Tx) (SignedTx, error) {
+	return tx.Sign(s.privateKey)
+}
+
+// SignValue signs the value with the private key.
+func (s *ECDSASigner) SignValue(value any) (string, error) {
+	v, r, sig, err := signature.Sign(value, s.privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return signature.SignatureString(v, r, sig), nil
+}
+
+// =============================================================================
+
+// RemoteSigner signs transactions by calling an HTTP signing service. The
+// service provides three endpoints:
+//
+//	GET  /account     returns {"account": "0x..."}
+//	POST /sign        accepts a Tx and returns the SignedTx
+//	POST /sign/value  accepts any JSON value and returns {"sig": "0x..."}
+//
+// The signature returned by the service is checked against the transaction
+// that was sent and the account of the service.
+type RemoteSigner struct {
+	url       string
+	client    *http.Client
+	accountID AccountID
+}
+
+// NewRemoteSigner constructs a signer for the signing service at the
+// specified url and asks the service for the account it signs for.
+func NewRemoteSigner(url string, timeout time.Duration) (*RemoteSigner, error) {
+	s := RemoteSigner{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: timeout},
+	}
+
+	var resp struct {
+		Account AccountID `json:"account"`
+	}
+	if err := s.send(http.MethodGet, "/account", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	if !resp.Account.IsAccountID() {
+		return nil, fmt.Errorf("remote signer returned invalid account %q", resp.Account)
+	}
+	s.accountID = resp.Account
+
+	return &s, nil
+}
+
+// AccountID returns the account the signing service signs for.
+func (s *RemoteSigner) AccountID() AccountID {
+	return s.accountID
+}
+
+// Sign asks the signing service to sign the transaction.
+func (s *RemoteSigner) Sign(tx Tx) (SignedTx, error) {
+	var signedTx SignedTx
+	if err := s.send(http.MethodPost, "/sign", tx, &signedTx); err != nil {
+		return SignedTx{}, err
+	}
+
+	if err := signature.VerifySignature(signedTx.V, signedTx.R, signedTx.S); err != nil {
+		return SignedTx{}, fmt.Errorf("remote signer: %w", err)
+	}
+
+	// Recover the address from the transaction that was sent so a service
+	// can't change the transaction it signs.
+	address, err := signature.FromAddress(tx, signedTx.V, signedTx.R, signedTx.S)
+	if err != nil {
+		return SignedTx{}, fmt.Errorf("remote signer: %w", err)
+	}
+
+	if address != string(s.accountID) {
+		return SignedTx{}, fmt.Errorf("remote signer: signature address %s doesn't match account %s", address, s.accountID)
+	}
+
+	signedTx.Tx = tx
+
+	return signedTx, nil
+}
+
+// SignValue asks the signing service to sign the value.
+func (s *RemoteSigner) SignValue(value any) (string, error) {
+	var resp struct {
+		Sig string `json:"sig"`
+	}
+	if err := s.send(http.MethodPost, "/sign/value", value, &resp); err != nil {
+		return "", err
+	}
+
+	// Recover the address from the value that was sent so a service can't
+	// sign something else.
+	address, err := signature.FromAddressSignature(value, resp.Sig)
+	if err != nil {
+		return "", fmt.Errorf("remote signer: %w", err)
+	}
+
+	if address != string(s.accountID) {
+		return "", fmt.Errorf("remote signer: signature address %s doesn't match account %s", address, s.accountID)
+	}
+
+	return resp.Sig, nil
+}
+
+// send performs the call to the signing service.
+func (s *RemoteSigner) send(method string, path string, body any, v any) error {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, s.url+path, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("remote signer: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("remote signer: %s %s: status %d", method, path, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("remote signer: decoding response: %w", err)
+	}
+
+	return nil
+}
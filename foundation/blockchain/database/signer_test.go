@@ -0,0 +1,102 @@
+package database_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+func TestRemoteSigner(t *testing.T) {
+	signer := database.NewECDSASigner(newKey(t))
+
+	// tamper lets the signing service change the transaction it signs, the
+	// way a compromised service might.
+	var tamper bool
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/account", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]database.AccountID{"account": signer.AccountID()})
+	})
+	mux.HandleFunc("/sign", func(w http.ResponseWriter, r *http.Request) {
+		var tx database.Tx
+		if err := json.NewDecoder(r.Body).Decode(&tx); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if tamper {
+			tx.Value++
+		}
+		signedTx, err := signer.Sign(tx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(signedTx)
+	})
+	mux.HandleFunc("/sign/value", func(w http.ResponseWriter, r *http.Request) {
+		var value any
+		if err := json.NewDecoder(r.Body).Decode(&value); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if tamper {
+			value = "something else"
+		}
+		sig, err := signer.SignValue(value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"sig": sig})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	remote, err := database.NewRemoteSigner(srv.URL+"/", time.Second)
+	if err != nil {
+		t.Fatalf("Should be able to construct the remote signer: %s", err)
+	}
+	if remote.AccountID() != signer.AccountID() {
+		t.Fatalf("Should sign for the account of the service, got %s, exp %s", remote.AccountID(), signer.AccountID())
+	}
+
+	tx, err := database.NewTx(1, 1, signer.AccountID(), accountID(1), 100, 0, nil)
+	if err != nil {
+		t.Fatalf("Should be able to construct the transaction: %s", err)
+	}
+
+	signedTx, err := remote.Sign(tx)
+	if err != nil {
+		t.Fatalf("Should be able to sign the transaction: %s", err)
+	}
+	if err := signedTx.Validate(1); err != nil {
+		t.Fatalf("Should get a valid transaction: %s", err)
+	}
+	if signedTx.Value != tx.Value {
+		t.Fatalf("Should sign the transaction sent, got value %d, exp %d", signedTx.Value, tx.Value)
+	}
+
+	value := map[string]any{"status": "ok"}
+	sig, err := remote.SignValue(value)
+	if err != nil {
+		t.Fatalf("Should be able to sign the value: %s", err)
+	}
+	if address, err := signature.FromAddressSignature(value, sig); err != nil || address != string(signer.AccountID()) {
+		t.Fatalf("Should sign the value sent, got %s, %v", address, err)
+	}
+
+	tamper = true
+
+	if _, err := remote.Sign(tx); err == nil {
+		t.Fatal("Should reject a signature over a different transaction")
+	}
+	if _, err := remote.SignValue(value); err == nil {
+		t.Fatal("Should reject a signature over a different value")
+	}
+}
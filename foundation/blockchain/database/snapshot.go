@@ -0,0 +1,265 @@
+package database
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// Snapshot represents the state of the database at a block so a node can
+// start from that block instead of replaying the chain from genesis. The
+// state root in a block header is the hash of the state the block is
+// applied to, so the snapshot holds that state along with the block. The
+// timestamps of the blocks before the block are kept so the node computes
+// the same median time past as the nodes that replayed the whole chain.
+// The timestamps aren't part of the state root and are only covered by the
+// signature of the node that exported the snapshot.
+type Snapshot struct {
+	Block       BlockData            `json:"block"`
+	RecentTimes []uint64             `json:"recent_times,omitempty"`
+	Accounts    []Account            `json:"accounts"`
+	Tokens      []Token              `json:"tokens"`
+	Policy      []PolicyEntry        `json:"policy,omitempty"`
+	Names       map[string]AccountID `json:"names"`
+	Contracts   map[AccountID]slots  `json:"contracts"`
+}
+
+// NewSnapshot replays the chain in storage to build the snapshot for the
+// specified block number.
+func NewSnapshot(genesis genesis.Genesis, storage Storage, num uint64, log func(v ...any)) (Snapshot, error) {
+	if num == 0 {
+		return Snapshot{}, errors.New("snapshot must be for block 1 or later")
+	}
+
+	db, err := newGenesisDatabase(genesis, storage, log)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	// Replay the blocks leading up to the block of the snapshot. Receipts
+	// aren't written so the chain being exported isn't changed.
+	iter := DatabaseIterator{iterator: storage.ForEach()}
+	for block, err := iter.Next(); !iter.Done(); block, err = iter.Next() {
+		if err != nil {
+			return Snapshot{}, err
+		}
+
+		if err := block.ValidateBlock(db.latestBlock, db.StateRoots(), log); err != nil {
+			return Snapshot{}, err
+		}
+
+		if err := block.ValidateTimestamp(db.latestBlock, medianTime(db.recentTimes), 0); err != nil {
+			return Snapshot{}, err
+		}
+
+		if err := block.ValidateLimits(genesis.TransPerBlock, genesis.MaxTxDataBytes); err != nil {
+			return Snapshot{}, err
+		}
+
+		if block.Header.Number == num {
+			return db.snapshot(block), nil
+		}
+
+		db.applyBlock(block)
+		db.setLatestBlock(block)
+	}
+
+	return Snapshot{}, fmt.Errorf("block %d not found, latest block is %d", num, db.latestBlock.Header.Number)
+}
+
+// NewFromSnapshot constructs a database starting from the snapshot. The
+// state in the snapshot is checked against the state root of the block
+// before the block is applied. Blocks in storage after the block of the
+// snapshot are then replayed like New does from genesis. The database
+// keeps the snapshot, so resetting the chain goes back to the snapshot and
+// not to genesis.
+func NewFromSnapshot(genesis genesis.Genesis, storage Storage, snapshot Snapshot, log func(v ...any)) (*Database, error) {
+	db := Database{
+		genesis: genesis,
+		storage: storage,
+		base:    &snapshot,
+	}
+
+	if err := db.restore(snapshot); err != nil {
+		return nil, err
+	}
+
+	latestBlock := db.LatestBlock()
+	log("database: NewFromSnapshot", "blk", latestBlock.Header.Number, "hash", latestBlock.Hash(), "accounts", len(db.load().accounts))
+
+	iter := DatabaseIterator{iterator: storage.ForEachFrom(latestBlock.Header.Number + 1)}
+	if err := db.replay(&iter, log); err != nil {
+		return nil, err
+	}
+
+	return &db, nil
+}
+
+// restore replaces the state of the database with the state in the
+// snapshot and applies the block of the snapshot, which becomes the latest
+// block. The block is written to storage if it isn't there already.
+func (db *Database) restore(snapshot Snapshot) error {
+	accounts := make(map[AccountID]Account, len(snapshot.Accounts))
+	for _, account := range snapshot.Accounts {
+		accounts[account.AccountID] = account
+	}
+	tokens := make(map[string]Token, len(snapshot.Tokens))
+	for _, token := range snapshot.Tokens {
+		tokens[token.Symbol] = token.copy()
+	}
+	policy := make(map[AccountID]bool, len(snapshot.Policy))
+	for _, entry := range snapshot.Policy {
+		policy[entry.AccountID] = entry.Allowed
+	}
+
+	// The timestamps of the blocks before the block seed the median time
+	// past, which the block itself must be mined after.
+	recentTimes := append([]uint64(nil), snapshot.RecentTimes...)
+	if len(recentTimes) > medianTimeBlocks {
+		recentTimes = recentTimes[len(recentTimes)-medianTimeBlocks:]
+	}
+
+	db.mu.Lock()
+	db.latestBlock = Block{}
+	db.recentTimes = recentTimes
+	db.accounts = accounts
+	db.names = copyNames(snapshot.Names)
+	db.contracts = copyContracts(snapshot.Contracts)
+	db.tokens = tokens
+	db.policy = policy
+	db.publish()
+	db.headView = db.view.Load().(*view)
+	db.proofView = nil
+	db.mu.Unlock()
+
+	block, err := ToBlock(snapshot.Block)
+	if err != nil {
+		return err
+	}
+
+	// The block must be the one the node exported and the state must be
+	// the state the block was mined on top of.
+	if hash := block.Hash(); hash != snapshot.Block.Hash {
+		return fmt.Errorf("snapshot block hash mismatch, got %s, exp %s", snapshot.Block.Hash, hash)
+	}
+	for _, check := range []func(b Block, previousBlock Block, roots StateRoots) error{checkVersion, checkBlockHash, checkMerkleRoot, checkStateRoot, checkAccountsRoot} {
+		if err := check(block, Block{}, db.StateRoots()); err != nil {
+			return fmt.Errorf("snapshot block %d: %w", block.Header.Number, err)
+		}
+	}
+
+	if n := len(recentTimes); n > 0 {
+		parent := Block{Header: BlockHeader{TimeStamp: recentTimes[n-1]}}
+		if err := block.ValidateTimestamp(parent, medianTime(recentTimes), 0); err != nil {
+			return fmt.Errorf("snapshot block %d: %w", block.Header.Number, err)
+		}
+	}
+
+	receipts := db.applyBlock(block)
+	db.UpdateLatestBlock(block)
+
+	// Keep the block in storage so it can be provided to peers and the
+	// chain can continue from it.
+	if _, err := db.storage.GetBlock(block.Header.Number); err != nil {
+		if err := db.storage.Write(snapshot.Block); err != nil {
+			return err
+		}
+		if err := db.storage.WriteReceipts(block.Header.Number, receipts); err != nil {
+			return err
+		}
+		if err := db.storage.WriteFees(block.Header.Number, NewBlockFees(block, receipts)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Sign uses the specified private key to sign the snapshot.
+func (s Snapshot) Sign(privateKey *ecdsa.PrivateKey) (SignedSnapshot, error) {
+	v, r, sig, err := signature.Sign(s, privateKey)
+	if err != nil {
+		return SignedSnapshot{}, err
+	}
+
+	return SignedSnapshot{Snapshot: s, V: v, R: r, S: sig}, nil
+}
+
+// snapshot captures the current state along with the block to apply to
+// it.
+func (db *Database) snapshot(block Block) Snapshot {
+	v := db.load()
+
+	accounts := make([]Account, len(v.accountList()))
+	copy(accounts, v.accountList())
+
+	return Snapshot{
+		Block:       NewBlockData(block),
+		RecentTimes: append([]uint64(nil), db.recentTimes...),
+		Accounts:    accounts,
+		Tokens:      sortedTokens(v.tokens),
+		Policy:      sortedPolicy(v.policy),
+		Names:       copyNames(v.names),
+		Contracts:   copyContracts(v.contracts),
+	}
+}
+
+// =============================================================================
+
+// SignedSnapshot is a snapshot signed by the node that exported it. A node
+// only starts from a snapshot signed by an account it trusts.
+type SignedSnapshot struct {
+	Snapshot
+	V *big.Int `json:"v"`
+	R *big.Int `json:"r"`
+	S *big.Int `json:"s"`
+}
+
+// LoadSnapshot reads the signed snapshot from the specified file.
+func LoadSnapshot(path string) (SignedSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SignedSnapshot{}, err
+	}
+
+	var ss SignedSnapshot
+	if err := json.Unmarshal(data, &ss); err != nil {
+		return SignedSnapshot{}, fmt.Errorf("decoding snapshot: %w", err)
+	}
+
+	return ss, nil
+}
+
+// Save writes the signed snapshot to the specified file.
+func (ss SignedSnapshot) Save(path string) error {
+	data, err := json.MarshalIndent(ss, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// Verify checks the snapshot was signed by the trusted account.
+func (ss SignedSnapshot) Verify(trusted AccountID) error {
+	if err := signature.VerifySignature(ss.V, ss.R, ss.S); err != nil {
+		return err
+	}
+
+	address, err := signature.FromAddress(ss.Snapshot, ss.V, ss.R, ss.S)
+	if err != nil {
+		return err
+	}
+
+	if address != string(trusted) {
+		return fmt.Errorf("snapshot signed by %s, not the trusted account %s", address, trusted)
+	}
+
+	return nil
+}
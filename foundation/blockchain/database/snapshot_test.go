@@ -0,0 +1,107 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/memory"
+)
+
+func TestSnapshotMedianTime(t *testing.T) {
+	noLog := func(...any) {}
+
+	gen := genesis.Genesis{
+		ChainID:      1,
+		MiningReward: 10,
+		Balances:     map[string]uint64{string(accountID(1)): 1_000},
+	}
+
+	storage := memory.New()
+	db, err := database.New(gen, storage, noLog)
+	if err != nil {
+		t.Fatalf("Should be able to construct the database: %s", err)
+	}
+
+	// mine adds an empty block with the specified timestamp to the chain.
+	mine := func(timeStamp uint64) database.Block {
+		t.Helper()
+
+		roots := db.StateRoots()
+		block, err := database.POW(context.Background(), database.POWArgs{
+			BeneficiaryID: accountID(9),
+			Difficulty:    1,
+			MiningReward:  gen.MiningReward,
+			PrevBlock:     db.LatestBlock(),
+			StateRoot:     roots.State,
+			AccountsRoot:  roots.Accounts,
+			Log:           noLog,
+		})
+		if err != nil {
+			t.Fatalf("Should be able to mine the block: %s", err)
+		}
+
+		block.Header.TimeStamp = timeStamp
+		for block.Header.Nonce = 0; !block.IsSolved(); block.Header.Nonce++ {
+		}
+
+		db.ApplyBlock(block)
+		if err := db.Write(block); err != nil {
+			t.Fatalf("Should be able to write the block: %s", err)
+		}
+		return block
+	}
+
+	// The last block has the same timestamp as its parent, which is later
+	// than the median time past of the three blocks before it.
+	start := uint64(time.Now().Add(-time.Minute).UTC().UnixMilli())
+	mine(start + 1)
+	mine(start + 2)
+	mine(start + 3)
+	last := mine(start + 3)
+
+	if _, err := database.New(gen, storage, noLog); err != nil {
+		t.Fatalf("Should be able to replay the chain: %s", err)
+	}
+
+	snapshot, err := database.NewSnapshot(gen, storage, 3, noLog)
+	if err != nil {
+		t.Fatalf("Should be able to build the snapshot: %s", err)
+	}
+
+	// A node starting from the snapshot must accept the last block like
+	// the nodes that replayed the chain.
+	snapStorage := memory.New()
+	if err := snapStorage.Write(database.NewBlockData(last)); err != nil {
+		t.Fatalf("Should be able to write the block: %s", err)
+	}
+
+	snapDB, err := database.NewFromSnapshot(gen, snapStorage, snapshot, noLog)
+	if err != nil {
+		t.Fatalf("Should be able to start from the snapshot: %s", err)
+	}
+
+	if got := snapDB.LatestBlock().Header.Number; got != last.Header.Number {
+		t.Fatalf("Should replay the blocks after the snapshot, got %d, exp %d", got, last.Header.Number)
+	}
+	if got, exp := snapDB.MedianTimePast(), db.MedianTimePast(); got != exp {
+		t.Fatalf("Should compute the same median time past, got %d, exp %d", got, exp)
+	}
+
+	// Resetting the chain goes back to the block of the snapshot, since the
+	// blocks before it were never seen, and the chain can continue from it.
+	if err := snapDB.ResetChain(); err != nil {
+		t.Fatalf("Should be able to reset the chain: %s", err)
+	}
+	if got := snapDB.LatestBlock().Header.Number; got != 3 {
+		t.Fatalf("Should reset to the block of the snapshot, got %d, exp %d", got, 3)
+	}
+	if _, err := snapStorage.GetBlock(3); err != nil {
+		t.Fatalf("Should keep the block of the snapshot in storage: %s", err)
+	}
+	if err := last.ValidateBlock(snapDB.LatestBlock(), snapDB.StateRoots(), noLog); err != nil {
+		t.Fatalf("Should accept the next block after the reset: %s", err)
+	}
+}
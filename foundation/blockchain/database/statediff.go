@@ -0,0 +1,83 @@
+package database
+
+import "sort"
+
+// AccountChange represents the balance and nonce of an account before and
+// after a block was applied. An account created by the block has no before
+// values and an account pruned by the block has no after values.
+type AccountChange struct {
+	AccountID     AccountID `json:"account"`
+	Created       bool      `json:"created,omitempty"`
+	Pruned        bool      `json:"pruned,omitempty"`
+	BalanceBefore uint64    `json:"balance_before"`
+	BalanceAfter  uint64    `json:"balance_after"`
+	NonceBefore   uint64    `json:"nonce_before"`
+	NonceAfter    uint64    `json:"nonce_after"`
+}
+
+// StateDiff represents the accounts whose balance or nonce changed when a
+// block was applied.
+type StateDiff struct {
+	BlockNumber uint64          `json:"block_number"`
+	BlockHash   string          `json:"block_hash"`
+	Accounts    []AccountChange `json:"accounts"`
+}
+
+// StateDiff compares the accounts of this database with the accounts of the
+// scratch database the block was applied to, and returns the accounts whose
+// balance or nonce changed in order of the account.
+func (db *Database) StateDiff(block Block, scratch *Database) StateDiff {
+	before := db.load().accounts
+	after := scratch.load().accounts
+
+	diff := StateDiff{
+		BlockNumber: block.Header.Number,
+		BlockHash:   block.Hash(),
+		Accounts:    []AccountChange{},
+	}
+
+	for accountID, a := range after {
+		b, exists := before[accountID]
+		if exists && b.Balance == a.Balance && b.Nonce == a.Nonce {
+			continue
+		}
+
+		diff.Accounts = append(diff.Accounts, AccountChange{
+			AccountID:     accountID,
+			Created:       !exists,
+			BalanceBefore: b.Balance,
+			BalanceAfter:  a.Balance,
+			NonceBefore:   b.Nonce,
+			NonceAfter:    a.Nonce,
+		})
+	}
+
+	for accountID, b := range before {
+		if _, exists := after[accountID]; exists {
+			continue
+		}
+
+		diff.Accounts = append(diff.Accounts, AccountChange{
+			AccountID:     accountID,
+			Pruned:        true,
+			BalanceBefore: b.Balance,
+			NonceBefore:   b.Nonce,
+		})
+	}
+
+	sort.Slice(diff.Accounts, func(i, j int) bool {
+		return diff.Accounts[i].AccountID < diff.Accounts[j].AccountID
+	})
+
+	return diff
+}
+
+// WriteStateDiff stores the state diff for the block it was produced by.
+func (db *Database) WriteStateDiff(diff StateDiff) error {
+	return db.storage.WriteStateDiff(diff.BlockNumber, diff)
+}
+
+// GetStateDiff returns the state diff for the specified block number.
+func (db *Database) GetStateDiff(num uint64) (StateDiff, error) {
+	return db.storage.GetStateDiff(num)
+}
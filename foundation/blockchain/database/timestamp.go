@@ -0,0 +1,98 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultMaxBlockDrift is how far into the future a block timestamp can be
+// from the clock of the node validating it when no drift is configured.
+const DefaultMaxBlockDrift = 2 * time.Hour
+
+// medianTimeBlocks is the number of latest blocks whose timestamps make up
+// the median time past.
+const medianTimeBlocks = 11
+
+// ValidateTimestamp checks the block wasn't mined before its parent or the
+// median time past of the chain, and isn't further into the future than the
+// max drift allows. The median of the latest blocks can't be moved by a
+// single miner with a bad clock, so a block can't be backdated by building
+// on a parent with a timestamp far in the past. A max drift of 0 uses the
+// default drift.
+func (b Block) ValidateTimestamp(previousBlock Block, medianTimePast uint64, maxDrift time.Duration) error {
+	if err := checkTimestamp(b, previousBlock, StateRoots{MedianTime: medianTimePast}); err != nil {
+		return err
+	}
+
+	if maxDrift <= 0 {
+		maxDrift = DefaultMaxBlockDrift
+	}
+
+	maxTimeStamp := uint64(time.Now().Add(maxDrift).UTC().UnixMilli())
+	if b.Header.TimeStamp > maxTimeStamp {
+		return fmt.Errorf("%w, block %d is more than %v in the future", ErrTimestamp, b.Header.TimeStamp, maxDrift)
+	}
+
+	return nil
+}
+
+// MedianTimePast returns the median timestamp of the latest blocks. A new
+// block must be mined after this time.
+func (db *Database) MedianTimePast() uint64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return medianTime(db.recentTimes)
+}
+
+// checkTimestamp is the block rule for the timestamp. It checks the block
+// wasn't mined before its parent or the median time past of the chain.
+func checkTimestamp(b Block, previousBlock Block, roots StateRoots) error {
+	if b.Header.TimeStamp < previousBlock.Header.TimeStamp {
+		return fmt.Errorf("%w, block %d is earlier than parent %d", ErrTimestamp, b.Header.TimeStamp, previousBlock.Header.TimeStamp)
+	}
+
+	if roots.MedianTime > 0 && b.Header.TimeStamp <= roots.MedianTime {
+		return fmt.Errorf("%w, block %d is not later than median time past %d", ErrTimestamp, b.Header.TimeStamp, roots.MedianTime)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// setLatestBlock makes the block the latest block and records its timestamp
+// for the median time past. The caller must hold the lock or be
+// constructing the database.
+func (db *Database) setLatestBlock(block Block) {
+	db.latestBlock = block
+
+	db.recentTimes = append(db.recentTimes, block.Header.TimeStamp)
+	if len(db.recentTimes) > medianTimeBlocks {
+		db.recentTimes = db.recentTimes[len(db.recentTimes)-medianTimeBlocks:]
+	}
+
+	db.trackProofView()
+}
+
+// recentTimesCopy returns a copy of the timestamps of the latest blocks.
+func (db *Database) recentTimesCopy() []uint64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return append([]uint64(nil), db.recentTimes...)
+}
+
+// medianTime returns the median of the timestamps.
+func medianTime(times []uint64) uint64 {
+	if len(times) == 0 {
+		return 0
+	}
+
+	sorted := make([]uint64, len(times))
+	copy(sorted, times)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return sorted[len(sorted)/2]
+}
@@ -0,0 +1,107 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+func TestPOWTimestamp(t *testing.T) {
+	noLog := func(...any) {}
+
+	// The parent was mined by a node with a clock an hour ahead of ours,
+	// which is still inside the allowed drift.
+	parent := database.Block{
+		Header: database.BlockHeader{
+			Number:    1,
+			TimeStamp: uint64(time.Now().Add(time.Hour).UTC().UnixMilli()),
+		},
+	}
+
+	block, err := database.POW(context.Background(), database.POWArgs{
+		BeneficiaryID: accountID(1),
+		Difficulty:    1,
+		PrevBlock:     parent,
+		MedianTime:    parent.Header.TimeStamp - 1,
+		Log:           noLog,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to mine the block: %s", err)
+	}
+
+	if block.Header.TimeStamp < parent.Header.TimeStamp {
+		t.Fatalf("Should not mine a block earlier than its parent, got %d, exp %d", block.Header.TimeStamp, parent.Header.TimeStamp)
+	}
+	if err := block.ValidateTimestamp(parent, parent.Header.TimeStamp-1, 0); err != nil {
+		t.Fatalf("Should accept the block mined after a parent in the future: %s", err)
+	}
+
+	// The median time past still moves the timestamp past it.
+	block, err = database.POW(context.Background(), database.POWArgs{
+		BeneficiaryID: accountID(1),
+		Difficulty:    1,
+		PrevBlock:     parent,
+		MedianTime:    parent.Header.TimeStamp,
+		Log:           noLog,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to mine the block: %s", err)
+	}
+
+	if block.Header.TimeStamp != parent.Header.TimeStamp+1 {
+		t.Fatalf("Should mine the block after the median time past, got %d, exp %d", block.Header.TimeStamp, parent.Header.TimeStamp+1)
+	}
+}
+
+func TestValidateBlockTimestamp(t *testing.T) {
+	noLog := func(...any) {}
+
+	parent := database.Block{
+		Header: database.BlockHeader{
+			Number:    1,
+			TimeStamp: uint64(time.Now().Add(-time.Minute).UTC().UnixMilli()),
+		},
+	}
+
+	block, err := database.POW(context.Background(), database.POWArgs{
+		BeneficiaryID: accountID(1),
+		Difficulty:    1,
+		PrevBlock:     parent,
+		Log:           noLog,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to mine the block: %s", err)
+	}
+
+	// solve finds a nonce for the block with the timestamp changed.
+	solve := func(timeStamp uint64) database.Block {
+		b := block
+		b.Header.TimeStamp = timeStamp
+		for b.Header.Nonce = 0; !b.IsSolved(); b.Header.Nonce++ {
+		}
+		return b
+	}
+
+	tests := []struct {
+		name      string
+		timeStamp uint64
+		median    uint64
+		exp       error
+	}{
+		{"valid", parent.Header.TimeStamp + 1, parent.Header.TimeStamp - 1, nil},
+		{"parent", parent.Header.TimeStamp - 1, 0, database.ErrTimestamp},
+		{"median", parent.Header.TimeStamp, parent.Header.TimeStamp, database.ErrTimestamp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots := database.StateRoots{MedianTime: tt.median}
+			if err := solve(tt.timeStamp).ValidateBlock(parent, roots, noLog); !errors.Is(err, tt.exp) {
+				t.Fatalf("Should validate the timestamp, got %v, exp %v", err, tt.exp)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Set of envelope types carried in the data field of a transaction to
+// support the token ledger.
+const (
+	TokenCreateType   = "token_create"
+	TokenTransferType = "token_transfer"
+)
+
+// validSymbol defines the symbols a token can be created with.
+var validSymbol = regexp.MustCompile(`^[A-Z][A-Z0-9]{1,9}$`)
+
+// Set of errors returned by the token ledger.
+var (
+	ErrTokenNotFound = errors.New("token not found")
+	ErrTokenExists   = errors.New("token already exists")
+)
+
+// Token represents a user defined token and the balance each account holds.
+type Token struct {
+	Symbol   string               `json:"symbol"`
+	Owner    AccountID            `json:"owner"`
+	Supply   uint64               `json:"supply"`
+	Balances map[AccountID]uint64 `json:"balances"`
+}
+
+// copy returns a copy of the token that doesn't share the balances.
+func (t Token) copy() Token {
+	balances := make(map[AccountID]uint64, len(t.Balances))
+	for accountID, balance := range t.Balances {
+		balances[accountID] = balance
+	}
+	t.Balances = balances
+
+	return t
+}
+
+// TokenTx is the envelope carried in the data field of a transaction that
+// creates a token or transfers tokens. A create gives the full supply to
+// the sender. A transfer moves the amount from the sender to the recipient
+// of the transaction.
+type TokenTx struct {
+	Type   string `json:"type"`
+	Symbol string `json:"symbol"`
+	Amount uint64 `json:"amount"`
+}
+
+// NewTokenCreate returns the data to place in the transaction that creates
+// a token with the specified supply.
+func NewTokenCreate(symbol string, supply uint64) ([]byte, error) {
+	if !validSymbol.MatchString(symbol) {
+		return nil, fmt.Errorf("invalid symbol %q, must be 2 to 10 uppercase letters or digits", symbol)
+	}
+
+	return json.Marshal(TokenTx{Type: TokenCreateType, Symbol: symbol, Amount: supply})
+}
+
+// NewTokenTransfer returns the data to place in the transaction that
+// transfers the amount of the token to the recipient of the transaction.
+func NewTokenTransfer(symbol string, amount uint64) ([]byte, error) {
+	return json.Marshal(TokenTx{Type: TokenTransferType, Symbol: symbol, Amount: amount})
+}
+
+// ParseTokenTx returns the token envelope carried in the data field of a
+// transaction if there is one.
+func ParseTokenTx(data []byte) (TokenTx, bool) {
+	var ttx TokenTx
+	if err := json.Unmarshal(data, &ttx); err != nil {
+		return TokenTx{}, false
+	}
+
+	switch ttx.Type {
+	case TokenCreateType, TokenTransferType:
+		return ttx, true
+	}
+
+	return TokenTx{}, false
+}
+
+// =============================================================================
+
+// QueryToken returns a copy of the specified token.
+func (db *Database) QueryToken(symbol string) (Token, error) {
+	token, exists := db.load().tokens[symbol]
+	if !exists {
+		return Token{}, ErrTokenNotFound
+	}
+
+	return token.copy(), nil
+}
+
+// validateTokenTx checks the token transaction can be applied. The caller
+// must hold the lock.
+func (db *Database) validateTokenTx(tx BlockTx, ttx TokenTx) error {
+	if ttx.Amount == 0 {
+		return fmt.Errorf("%s amount must be greater than zero", ttx.Type)
+	}
+
+	token, exists := db.tokens[ttx.Symbol]
+
+	switch ttx.Type {
+	case TokenCreateType:
+		if !validSymbol.MatchString(ttx.Symbol) {
+			return fmt.Errorf("invalid symbol %q", ttx.Symbol)
+		}
+		if exists {
+			return fmt.Errorf("%w, %s", ErrTokenExists, ttx.Symbol)
+		}
+
+	case TokenTransferType:
+		if !exists {
+			return fmt.Errorf("%w, %s", ErrTokenNotFound, ttx.Symbol)
+		}
+		if balance := token.Balances[tx.FromID]; balance < ttx.Amount {
+			return fmt.Errorf("insufficient %s, bal %d, needed %d", ttx.Symbol, balance, ttx.Amount)
+		}
+	}
+
+	return nil
+}
+
+// applyTokenTx updates the token ledger for a validated token transaction.
+// Accounts left without a balance are removed so they don't change the
+// state root. The caller must hold the lock.
+func (db *Database) applyTokenTx(tx BlockTx, ttx TokenTx) {
+	switch ttx.Type {
+	case TokenCreateType:
+		db.tokens[ttx.Symbol] = Token{
+			Symbol:   ttx.Symbol,
+			Owner:    tx.FromID,
+			Supply:   ttx.Amount,
+			Balances: map[AccountID]uint64{tx.FromID: ttx.Amount},
+		}
+
+	case TokenTransferType:
+		token := db.tokens[ttx.Symbol]
+		token.Balances[tx.FromID] -= ttx.Amount
+		token.Balances[tx.ToID] += ttx.Amount
+		if token.Balances[tx.FromID] == 0 {
+			delete(token.Balances, tx.FromID)
+		}
+	}
+}
+
+// copyTokens makes a copy of the token ledger.
+func copyTokens(tokens map[string]Token) map[string]Token {
+	cpy := make(map[string]Token, len(tokens))
+	for symbol, token := range tokens {
+		cpy[symbol] = token.copy()
+	}
+	return cpy
+}
+
+// sortedTokens returns the tokens in order of their symbol.
+func sortedTokens(tokens map[string]Token) []Token {
+	list := make([]Token, 0, len(tokens))
+	for _, token := range tokens {
+		list = append(list, token)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Symbol < list[j].Symbol })
+
+	return list
+}
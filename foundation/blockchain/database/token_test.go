@@ -0,0 +1,121 @@
+package database_test
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+)
+
+func TestTokenLedger(t *testing.T) {
+	owner := newKey(t)
+	ownerID := database.PublicKeyToAccountID(owner.PublicKey)
+	other := newKey(t)
+	otherID := database.PublicKeyToAccountID(other.PublicKey)
+
+	gen := genesis.Genesis{
+		ChainID:  1,
+		GasPrice: 1,
+		Balances: map[string]uint64{string(ownerID): 1_000_000, string(otherID): 1_000_000},
+	}
+
+	db, err := database.New(gen, memStorage{}, func(...any) {})
+	if err != nil {
+		t.Fatalf("Should be able to construct the database: %s", err)
+	}
+
+	apply := func(privateKey *ecdsa.PrivateKey, nonce uint64, toID database.AccountID, data []byte) error {
+		t.Helper()
+
+		fromID := database.PublicKeyToAccountID(privateKey.PublicKey)
+		tx, err := database.NewTx(gen.ChainID, nonce, fromID, toID, 0, 0, data)
+		if err != nil {
+			t.Fatalf("Should be able to construct the transaction: %s", err)
+		}
+		signedTx, err := tx.Sign(privateKey)
+		if err != nil {
+			t.Fatalf("Should be able to sign the transaction: %s", err)
+		}
+
+		block := database.Block{Header: database.BlockHeader{Number: 1, BeneficiaryID: accountID(99)}}
+		_, err = db.ApplyTransaction(block, database.NewBlockTx(signedTx, gen.GasPrice, 1))
+		return err
+	}
+
+	create, err := database.NewTokenCreate("ARDAN", 1000)
+	if err != nil {
+		t.Fatalf("Should be able to construct the create: %s", err)
+	}
+	if err := apply(owner, 1, ownerID, create); err != nil {
+		t.Fatalf("Should be able to create the token: %s", err)
+	}
+	if err := apply(other, 1, otherID, create); !errors.Is(err, database.ErrTokenExists) {
+		t.Fatalf("Should not create the token twice, got %v, exp %v", err, database.ErrTokenExists)
+	}
+
+	stateRoot := db.HashState()
+
+	transfer, err := database.NewTokenTransfer("ARDAN", 400)
+	if err != nil {
+		t.Fatalf("Should be able to construct the transfer: %s", err)
+	}
+	if err := apply(owner, 2, otherID, transfer); err != nil {
+		t.Fatalf("Should be able to transfer the token: %s", err)
+	}
+
+	if db.HashState() == stateRoot {
+		t.Fatal("Should change the state root when tokens move")
+	}
+
+	token, err := db.QueryToken("ARDAN")
+	if err != nil {
+		t.Fatalf("Should be able to query the token: %s", err)
+	}
+	if token.Owner != ownerID || token.Supply != 1000 {
+		t.Fatalf("Should record the creator and supply, got %s:%d", token.Owner, token.Supply)
+	}
+	if token.Balances[ownerID] != 600 || token.Balances[otherID] != 400 {
+		t.Fatalf("Should move the tokens, got %d:%d, exp %d:%d", token.Balances[ownerID], token.Balances[otherID], 600, 400)
+	}
+
+	// The copy returned can't change the ledger.
+	token.Balances[otherID] = 1_000_000
+	if token, _ := db.QueryToken("ARDAN"); token.Balances[otherID] != 400 {
+		t.Fatalf("Should not share the balances with the ledger, got %d", token.Balances[otherID])
+	}
+
+	tooMuch, err := database.NewTokenTransfer("ARDAN", 401)
+	if err != nil {
+		t.Fatalf("Should be able to construct the transfer: %s", err)
+	}
+	if err := apply(other, 1, ownerID, tooMuch); err == nil {
+		t.Fatal("Should not transfer more tokens than the sender holds")
+	}
+
+	unknown, err := database.NewTokenTransfer("OTHER", 1)
+	if err != nil {
+		t.Fatalf("Should be able to construct the transfer: %s", err)
+	}
+	if err := apply(other, 1, ownerID, unknown); !errors.Is(err, database.ErrTokenNotFound) {
+		t.Fatalf("Should not transfer a token that doesn't exist, got %v, exp %v", err, database.ErrTokenNotFound)
+	}
+
+	// Sending the whole balance removes the account from the token.
+	all, err := database.NewTokenTransfer("ARDAN", 400)
+	if err != nil {
+		t.Fatalf("Should be able to construct the transfer: %s", err)
+	}
+	if err := apply(other, 1, ownerID, all); err != nil {
+		t.Fatalf("Should be able to transfer the token: %s", err)
+	}
+
+	token, err = db.QueryToken("ARDAN")
+	if err != nil {
+		t.Fatalf("Should be able to query the token: %s", err)
+	}
+	if _, exists := token.Balances[otherID]; exists || token.Balances[ownerID] != 1000 {
+		t.Fatalf("Should remove the empty balance, got %v", token.Balances)
+	}
+}
@@ -1,24 +1,47 @@
 package database
 
 import (
+	"bytes"
 	"crypto/ecdsa"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
 	"math/big"
+	"math/bits"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// Set of errors returned when a transaction is mined outside the window of
+// blocks it can be mined in.
+var (
+	ErrTxNotYetValid = errors.New("transaction can't be mined before its not before block")
+	ErrTxExpired     = errors.New("transaction can't be mined after its not after block")
+)
+
+// Set of errors returned when a transaction is rejected or fails to apply.
+// Callers check for these with errors.Is instead of matching the message.
+var (
+	ErrInvalidSignature  = errors.New("invalid signature")
+	ErrChainIDMismatch   = errors.New("invalid chain id")
+	ErrBadNonce          = errors.New("wrong nonce")
+	ErrInsufficientFunds = errors.New("insufficient funds")
 )
 
 // =============================================================================
 
 // Tx is the transactional information between two parties.
 type Tx struct {
-	ChainID uint16    `json:"chain_id"` // Ethereum: The chain id that is listed in the genesis file.
-	Nonce   uint64    `json:"nonce"`    // Ethereum: Unique id for the transaction supplied by the user.
-	FromID  AccountID `json:"from"`     // Ethereum: Account sending the transaction. Will be checked against signature.
-	ToID    AccountID `json:"to"`       // Ethereum: Account receiving the benefit of the transaction.
-	Value   uint64    `json:"value"`    // Ethereum: Monetary value received from this transaction.
-	Tip     uint64    `json:"tip"`      // Ethereum: Tip offered by the sender as an incentive to mine this transaction.
-	Data    []byte    `json:"data"`     // Ethereum: Extra data related to the transaction.
+	ChainID   uint16    `json:"chain_id"`                 // Ethereum: The chain id that is listed in the genesis file.
+	Nonce     uint64    `json:"nonce"`                    // Ethereum: Unique id for the transaction supplied by the user.
+	FromID    AccountID `json:"from" validate:"required"` // Ethereum: Account sending the transaction. Will be checked against signature.
+	ToID      AccountID `json:"to" validate:"required"`   // Ethereum: Account receiving the benefit of the transaction.
+	Value     uint64    `json:"value"`                    // Ethereum: Monetary value received from this transaction.
+	Tip       uint64    `json:"tip"`                      // Ethereum: Tip offered by the sender as an incentive to mine this transaction.
+	Data      []byte    `json:"data"`                     // Ethereum: Extra data related to the transaction.
+	NotBefore uint64    `json:"not_before,omitempty"`     // First block number the transaction can be mined in, no limit when zero.
+	NotAfter  uint64    `json:"not_after,omitempty"`      // Last block number the transaction can be mined in, no limit when zero.
 }
 
 // NewTx constructs a new transaction.
@@ -43,6 +66,29 @@ func NewTx(chainID uint16, nonce uint64, fromID AccountID, toID AccountID, value
 	return tx, nil
 }
 
+// ValidAt checks the transaction can be mined into the block with the
+// specified number. A transaction pre-signed with a window of blocks can
+// only be mined inside that window, both ends included.
+func (tx Tx) ValidAt(number uint64) error {
+	if tx.NotBefore > 0 && number < tx.NotBefore {
+		return fmt.Errorf("%w, block %d, not before %d", ErrTxNotYetValid, number, tx.NotBefore)
+	}
+
+	if tx.NotAfter > 0 && number > tx.NotAfter {
+		return fmt.Errorf("%w, block %d, not after %d", ErrTxExpired, number, tx.NotAfter)
+	}
+
+	return nil
+}
+
+// cost returns the value and tip of the transaction added together. It
+// returns false when the sum doesn't fit in a uint64, since it would wrap
+// around to a small amount an account could cover.
+func (tx Tx) cost() (uint64, bool) {
+	sum, carry := bits.Add64(tx.Value, tx.Tip, 0)
+	return sum, carry == 0
+}
+
 // Sign uses the specified private key to sign the transaction.
 func (tx Tx) Sign(privateKey *ecdsa.PrivateKey) (SignedTx, error) {
 
@@ -70,17 +116,19 @@ func (tx Tx) Sign(privateKey *ecdsa.PrivateKey) (SignedTx, error) {
 // a wallet provide transactions for inclusion into the blockchain.
 type SignedTx struct {
 	Tx
-	V *big.Int `json:"v"` // Ethereum: Recovery identifier, either 29 or 30 with tahaID.
-	R *big.Int `json:"r"` // Ethereum: First coordinate of the ECDSA signature.
-	S *big.Int `json:"s"` // Ethereum: Second coordinate of the ECDSA signature.
+	V *big.Int `json:"v" validate:"required"` // Ethereum: Recovery identifier, either 29 or 30 with tahaID.
+	R *big.Int `json:"r" validate:"required"` // Ethereum: First coordinate of the ECDSA signature.
+	S *big.Int `json:"s" validate:"required"` // Ethereum: Second coordinate of the ECDSA signature.
 }
 
 // Validate verifies the transaction has a proper signature that conforms to our
 // standards. It also checks the from field matches the account that signed the
-// transaction. Last it checks the format of the from and to fields.
+// transaction, unless the transaction carries the co-signatures for a
+// multisig account or is sent by the owner of an account governed by
+// spending rules. Last it checks the format of the from and to fields.
 func (tx SignedTx) Validate(chainID uint16) error {
 	if tx.ChainID != chainID {
-		return fmt.Errorf("invalid chain id, got[%d] exp[%d]", tx.ChainID, chainID)
+		return fmt.Errorf("%w, got[%d] exp[%d]", ErrChainIDMismatch, tx.ChainID, chainID)
 	}
 
 	if !tx.FromID.IsAccountID() {
@@ -91,26 +139,54 @@ func (tx SignedTx) Validate(chainID uint16) error {
 		return errors.New("to account is not properly formatted")
 	}
 
-	if tx.FromID == tx.ToID {
+	// A transaction to yourself is only allowed without a value. This is how
+	// a pending transaction is cancelled, by replacing it with one that only
+	// pays the fees.
+	if tx.FromID == tx.ToID && tx.Value > 0 {
 		return fmt.Errorf("transaction invalid, sending money to yourself, from %s, to %s", tx.FromID, tx.ToID)
 	}
 
+	if _, ok := tx.cost(); !ok {
+		return fmt.Errorf("transaction invalid, value %d and tip %d add up to more than an account can hold", tx.Value, tx.Tip)
+	}
+
+	if tx.NotAfter > 0 && tx.NotBefore > tx.NotAfter {
+		return fmt.Errorf("transaction invalid, not before %d is after not after %d", tx.NotBefore, tx.NotAfter)
+	}
+
 	if err := signature.VerifySignature(tx.V, tx.R, tx.S); err != nil {
-		return err
+		return fmt.Errorf("%w, %s", ErrInvalidSignature, err)
 	}
 
-	address, err := signature.FromAddress(tx.Tx, tx.V, tx.R, tx.S)
+	address, err := tx.FromAddress()
 	if err != nil {
-		return err
+		return fmt.Errorf("%w, %s", ErrInvalidSignature, err)
 	}
 
+	// A transaction from a multisig account is signed by one participant and
+	// carries the co-signatures of the others in a multisig envelope. The
+	// signers are checked against the participants of the registered
+	// account by the database. A transaction from an account governed by
+	// spending rules is signed by the owner, which the database checks too.
 	if address != string(tx.FromID) {
-		return errors.New("signature address doesn't match from address")
+		if _, ok := ParseRulesEnvelope(tx.Data); ok {
+			return nil
+		}
+		if _, err := tx.MultisigSigners(); err != nil {
+			return fmt.Errorf("%w, signature address doesn't match from address", ErrInvalidSignature)
+		}
 	}
 
 	return nil
 }
 
+// TxHash returns the hash that uniquely identifies the signed transaction.
+// Values a node adds when recording the transaction in a block are not
+// included, so every node computes the same hash for the same transaction.
+func (tx SignedTx) TxHash() string {
+	return signature.Hash(tx)
+}
+
 // SignatureString returns the signature as a string.
 func (tx SignedTx) SignatureString() string {
 	return signature.SignatureString(tx.V, tx.R, tx.S)
@@ -120,3 +196,43 @@ func (tx SignedTx) SignatureString() string {
 func (tx SignedTx) String() string {
 	return fmt.Sprintf("%s:%d", tx.FromID, tx.Nonce)
 }
+
+// =============================================================================
+
+// BlockTx represents the transaction as it's recorded inside a block. This
+// includes a timestamp and gas fees.
+type BlockTx struct {
+	SignedTx
+	TimeStamp uint64 `json:"timestamp"` // Ethereum: The time the transaction was received.
+	GasPrice  uint64 `json:"gas_price"` // Ethereum: The price of one unit of gas to be paid for fees.
+	GasUnits  uint64 `json:"gas_units"` // Ethereum: The number of units of gas used for this transaction.
+}
+
+// NewBlockTx constructs a new block transaction.
+func NewBlockTx(signedTx SignedTx, gasPrice uint64, unitsOfGas uint64) BlockTx {
+	return BlockTx{
+		SignedTx:  signedTx,
+		TimeStamp: uint64(time.Now().UTC().UnixMilli()),
+		GasPrice:  gasPrice,
+		GasUnits:  unitsOfGas,
+	}
+}
+
+// Hash implements the merkle Hashable interface for providing a hash
+// of a block transaction.
+func (tx BlockTx) Hash() ([]byte, error) {
+	str := signature.Hash(tx)
+
+	// Need to remove the 0x prefix from the hash.
+	return hex.DecodeString(str[2:])
+}
+
+// Equals implements the merkle Hashable interface for providing an equality
+// check between two block transactions. If the nonce and signatures are the
+// same, the two blocks are the same.
+func (tx BlockTx) Equals(otherTx BlockTx) bool {
+	txSig := signature.ToSignatureBytes(tx.V, tx.R, tx.S)
+	otherTxSig := signature.ToSignatureBytes(otherTx.V, otherTx.R, otherTx.S)
+
+	return tx.Nonce == otherTx.Nonce && bytes.Equal(txSig, otherTxSig)
+}
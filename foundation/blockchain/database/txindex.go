@@ -0,0 +1,55 @@
+package database
+
+import "errors"
+
+// ErrTxIndexNotSupported is returned when the storage doesn't keep an index
+// of the transactions and the chain has to be searched instead.
+var ErrTxIndexNotSupported = errors.New("storage does not index transactions")
+
+// TxLocation represents where a transaction is recorded in the chain.
+type TxLocation struct {
+	BlockNumber uint64 `json:"block_number"`
+	Index       int    `json:"index"`
+}
+
+// TxIndexer interface represents the behavior of storage that keeps an
+// index of where each transaction is recorded, so a transaction can be
+// found by its hash without searching the chain. The index is updated
+// when a block is written.
+type TxIndexer interface {
+	IndexTxs(blockData BlockData) error
+	GetTxLocation(txHash string) (TxLocation, error)
+	ResetTxIndex() error
+}
+
+// GetTxLocation returns where the transaction with the specified hash is
+// recorded in the chain.
+func (db *Database) GetTxLocation(txHash string) (TxLocation, error) {
+	indexer, ok := db.storage.(TxIndexer)
+	if !ok {
+		return TxLocation{}, ErrTxIndexNotSupported
+	}
+
+	return indexer.GetTxLocation(txHash)
+}
+
+// indexTxs makes sure the transactions in the block are indexed when the
+// storage keeps an index. Blocks written before the index existed are
+// indexed as they are replayed.
+func (db *Database) indexTxs(block Block) error {
+	indexer, ok := db.storage.(TxIndexer)
+	if !ok {
+		return nil
+	}
+
+	values := block.MerkleTree.Values()
+	if len(values) == 0 {
+		return nil
+	}
+
+	if loc, err := indexer.GetTxLocation(values[0].TxHash()); err == nil && loc.BlockNumber == block.Header.Number {
+		return nil
+	}
+
+	return indexer.IndexTxs(NewBlockData(block))
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// Set of block format versions. A new version is added when the header
+// gains a field the rules depend on. Blocks of every earlier version stay
+// valid so existing chains aren't orphaned, but only the current version is
+// mined.
+const (
+	BlockVersionLegacy uint16 = 0 // Blocks mined before the header recorded a version.
+	BlockVersionRoots  uint16 = 1 // The header must record the accounts root.
+
+	CurrentBlockVersion = BlockVersionRoots
+)
+
+// ErrBlockVersion is returned when a block uses a format this node doesn't
+// support or goes back to an earlier format than its parent.
+var ErrBlockVersion = errors.New("block version is not supported")
+
+// checkVersion accepts the versions up to the current one, as long as the
+// chain never goes back to an earlier version once it has moved on.
+func checkVersion(b Block, previousBlock Block, roots StateRoots) error {
+	if b.Header.Version > CurrentBlockVersion {
+		return fmt.Errorf("%w, got %d, this node supports up to %d, upgrade the node", ErrBlockVersion, b.Header.Version, CurrentBlockVersion)
+	}
+	if b.Header.Version < previousBlock.Header.Version {
+		return fmt.Errorf("%w, got %d, parent is version %d", ErrBlockVersion, b.Header.Version, previousBlock.Header.Version)
+	}
+	return nil
+}
+
+// Upgrade returns the block in the current format mined on top of the
+// previous block, for the roots of the state the block is applied to. The
+// transactions, timestamp and beneficiaries are kept, so a chain upgraded
+// block by block arrives at the same state. The hash of the block changes,
+// so every block after the first one upgraded must be upgraded too.
+func (b Block) Upgrade(ctx context.Context, previousBlock Block, roots StateRoots, workers int, log func(v ...any)) (Block, error) {
+	prevBlockHash := signature.ZeroHash
+	if previousBlock.Header.Number > 0 {
+		prevBlockHash = previousBlock.Hash()
+	}
+
+	nb := Block{
+		Header:     b.Header,
+		MerkleTree: b.MerkleTree,
+	}
+	nb.Header.Version = CurrentBlockVersion
+	nb.Header.PrevBlockHash = prevBlockHash
+	nb.Header.StateRoot = roots.State
+	nb.Header.AccountsRoot = roots.Accounts
+	nb.Header.Nonce = 0
+
+	if err := nb.performPOW(ctx, workers, nil, log, nil); err != nil {
+		return Block{}, err
+	}
+
+	return nb, nil
+}
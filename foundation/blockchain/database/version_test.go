@@ -0,0 +1,119 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+)
+
+func TestBlockVersion(t *testing.T) {
+	noLog := func(...any) {}
+
+	owner := newKey(t)
+	ownerID := database.PublicKeyToAccountID(owner.PublicKey)
+
+	gen := genesis.Genesis{
+		ChainID:  1,
+		GasPrice: 1,
+		Balances: map[string]uint64{string(ownerID): 1_000_000},
+	}
+
+	db, err := database.New(gen, memStorage{}, noLog)
+	if err != nil {
+		t.Fatalf("Should be able to construct the database: %s", err)
+	}
+
+	tx, err := database.NewTx(gen.ChainID, 1, ownerID, accountID(1), 100, 0, nil)
+	if err != nil {
+		t.Fatalf("Should be able to construct the transaction: %s", err)
+	}
+	signedTx, err := tx.Sign(owner)
+	if err != nil {
+		t.Fatalf("Should be able to sign the transaction: %s", err)
+	}
+
+	roots := db.StateRoots()
+
+	block, err := database.POW(context.Background(), database.POWArgs{
+		BeneficiaryID: accountID(99),
+		Difficulty:    1,
+		StateRoot:     roots.State,
+		AccountsRoot:  roots.Accounts,
+		Trans:         []database.BlockTx{database.NewBlockTx(signedTx, gen.GasPrice, 1)},
+		Log:           noLog,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to mine the block: %s", err)
+	}
+
+	if block.Header.Version != database.CurrentBlockVersion {
+		t.Fatalf("Should mine the current version, got %d, exp %d", block.Header.Version, database.CurrentBlockVersion)
+	}
+	if err := block.ValidateBlock(database.Block{}, roots, noLog); err != nil {
+		t.Fatalf("Should accept the current version: %s", err)
+	}
+
+	// solve finds a nonce for a header changed by the test.
+	solve := func(b database.Block) database.Block {
+		for b.Header.Nonce = 0; !b.IsSolved(); b.Header.Nonce++ {
+		}
+		return b
+	}
+
+	// A legacy block without an accounts root is still accepted.
+	legacy := block
+	legacy.Header.Version = database.BlockVersionLegacy
+	legacy.Header.AccountsRoot = ""
+	legacy = solve(legacy)
+
+	if err := legacy.ValidateBlock(database.Block{}, roots, noLog); err != nil {
+		t.Fatalf("Should accept a legacy block: %s", err)
+	}
+
+	// The current version requires the accounts root.
+	noRoot := block
+	noRoot.Header.AccountsRoot = ""
+	noRoot = solve(noRoot)
+
+	if err := noRoot.ValidateBlock(database.Block{}, roots, noLog); !errors.Is(err, database.ErrAccountsRoot) {
+		t.Fatalf("Should reject a block without an accounts root, got %v", err)
+	}
+
+	// A version this node doesn't know is rejected.
+	future := block
+	future.Header.Version = database.CurrentBlockVersion + 1
+	future = solve(future)
+
+	if err := future.ValidateBlock(database.Block{}, roots, noLog); !errors.Is(err, database.ErrBlockVersion) {
+		t.Fatalf("Should reject a future version, got %v", err)
+	}
+
+	// The chain can't go back to an earlier version.
+	downgrade := legacy
+	downgrade.Header.Number = 2
+	downgrade.Header.PrevBlockHash = block.Hash()
+
+	if err := downgrade.ValidateBlock(block, roots, noLog); !errors.Is(err, database.ErrBlockVersion) {
+		t.Fatalf("Should reject going back to an earlier version, got %v", err)
+	}
+
+	// Upgrading the legacy block keeps the transactions and records the
+	// accounts root.
+	upgraded, err := legacy.Upgrade(context.Background(), database.Block{}, roots, 1, noLog)
+	if err != nil {
+		t.Fatalf("Should be able to upgrade the block: %s", err)
+	}
+
+	if upgraded.Header.Version != database.CurrentBlockVersion {
+		t.Fatalf("Should upgrade to the current version, got %d, exp %d", upgraded.Header.Version, database.CurrentBlockVersion)
+	}
+	if upgraded.Header.TransRoot != legacy.Header.TransRoot {
+		t.Fatalf("Should keep the transactions, got %s, exp %s", upgraded.Header.TransRoot, legacy.Header.TransRoot)
+	}
+	if err := upgraded.ValidateBlock(database.Block{}, roots, noLog); err != nil {
+		t.Fatalf("Should accept the upgraded block: %s", err)
+	}
+}
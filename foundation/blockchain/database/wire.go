@@ -0,0 +1,133 @@
+package database
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+)
+
+// NegotiatePeerContent returns the content type for sending a message to a
+// peer based on its Accept header. Gob is used when the peer lists it,
+// otherwise the message is sent as JSON, which every node reads.
+func NegotiatePeerContent(accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == BlockContentGob {
+			return BlockContentGob
+		}
+	}
+
+	return BlockContentJSON
+}
+
+// PeerContent returns the content type a message from a peer was sent with
+// based on its Content-Type header. Anything other than gob is read as JSON
+// since older nodes don't always name the content type.
+func PeerContent(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && mediaType == BlockContentGob {
+		return BlockContentGob
+	}
+
+	return BlockContentJSON
+}
+
+// EncodePeerMessage writes the message with the content type specified.
+// Blocks and transactions are written so the data they carry reads back
+// exactly as it was signed.
+func EncodePeerMessage(w io.Writer, contentType string, v any) error {
+	switch contentType {
+	case BlockContentJSON:
+		return json.NewEncoder(w).Encode(v)
+
+	case BlockContentGob:
+		enc := gob.NewEncoder(w)
+		switch v := v.(type) {
+		case BlockData:
+			return enc.Encode(newGobBlock(v))
+		case BlockTx:
+			return enc.Encode(newGobTxs([]BlockTx{v}))
+		case []BlockTx:
+			return enc.Encode(newGobTxs(v))
+		}
+		return enc.Encode(v)
+	}
+
+	return fmt.Errorf("content type %q isn't supported for peer messages", contentType)
+}
+
+// DecodePeerMessage reads a message sent with the content type specified,
+// which can carry parameters like a Content-Type header does.
+func DecodePeerMessage(r io.Reader, contentType string, v any) error {
+	if PeerContent(contentType) == BlockContentJSON {
+		return json.NewDecoder(r).Decode(v)
+	}
+
+	dec := gob.NewDecoder(r)
+	switch v := v.(type) {
+	case *BlockData:
+		var gb gobBlock
+		if err := dec.Decode(&gb); err != nil {
+			return err
+		}
+		*v = gb.blockData()
+		return nil
+
+	case *BlockTx:
+		var gt gobTxs
+		if err := dec.Decode(&gt); err != nil {
+			return err
+		}
+		txs := gt.txs()
+		if len(txs) != 1 {
+			return fmt.Errorf("expected 1 transaction, got %d", len(txs))
+		}
+		*v = txs[0]
+		return nil
+
+	case *[]BlockTx:
+		var gt gobTxs
+		if err := dec.Decode(&gt); err != nil {
+			return err
+		}
+		*v = gt.txs()
+		return nil
+	}
+
+	return dec.Decode(v)
+}
+
+// =============================================================================
+
+// gobTxs is the gob encoding of a set of transactions. Like a block, the
+// transactions carrying empty data are recorded so their hash is kept.
+type gobTxs struct {
+	Txs       []BlockTx
+	EmptyData []int
+}
+
+// newGobTxs constructs the gob encoding of the transactions.
+func newGobTxs(txs []BlockTx) gobTxs {
+	gt := gobTxs{Txs: txs}
+	for i, tx := range txs {
+		if tx.Data != nil && len(tx.Data) == 0 {
+			gt.EmptyData = append(gt.EmptyData, i)
+		}
+	}
+
+	return gt
+}
+
+// txs returns the transactions with the empty data restored.
+func (gt gobTxs) txs() []BlockTx {
+	for _, i := range gt.EmptyData {
+		if i >= 0 && i < len(gt.Txs) {
+			gt.Txs[i].Data = []byte{}
+		}
+	}
+
+	return gt.Txs
+}
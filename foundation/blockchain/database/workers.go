@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"runtime"
+)
+
+// WorkerPool bounds the goroutines mining at the same time across the chains
+// hosted by a node. A mining goroutine holds a slot of the pool while it
+// hashes and gives it up between batches of hashes, so chains mining at the
+// same time take turns on the CPUs instead of each trying to use all of them.
+type WorkerPool struct {
+	slots chan struct{}
+}
+
+// NewWorkerPool constructs a pool with the number of slots specified. A size
+// of 0 uses GOMAXPROCS.
+func NewWorkerPool(size int) *WorkerPool {
+	if size <= 0 {
+		size = runtime.GOMAXPROCS(0)
+	}
+
+	return &WorkerPool{
+		slots: make(chan struct{}, size),
+	}
+}
+
+// Size returns the number of slots in the pool.
+func (p *WorkerPool) Size() int {
+	return cap(p.slots)
+}
+
+// acquire waits for a slot and reports if one was taken before the context
+// was cancelled. A nil pool never waits.
+func (p *WorkerPool) acquire(ctx context.Context) bool {
+	if p == nil {
+		return ctx.Err() == nil
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release gives the slot back to the pool.
+func (p *WorkerPool) release() {
+	if p == nil {
+		return
+	}
+
+	<-p.slots
+}
@@ -0,0 +1,45 @@
+package database_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+func TestWorkerPool(t *testing.T) {
+	noLog := func(...any) {}
+
+	// Two chains mine at the same time with more workers each than the pool
+	// has slots, so the workers must take turns.
+	pool := database.NewWorkerPool(1)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			block, err := database.POW(context.Background(), database.POWArgs{
+				BeneficiaryID: accountID(i),
+				Difficulty:    4,
+				Workers:       4,
+				Pool:          pool,
+				Log:           noLog,
+			})
+			if err == nil && !block.IsSolved() {
+				t.Errorf("Should solve the block for chain %d", i)
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Should be able to mine the block for chain %d: %s", i, err)
+		}
+	}
+}
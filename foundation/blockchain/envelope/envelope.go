@@ -0,0 +1,59 @@
+// Package envelope provides support for end-to-end encrypted messages between
+// accounts. A message is encrypted with ECIES to the public key of the
+// recipient and carried in the data field of a transaction. Only the holder
+// of the recipient's private key can open it.
+package envelope
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto/ecies"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// header marks transaction data as an envelope. The last byte is the version
+// of the envelope format.
+var header = []byte{'T', 'E', 'N', 'V', 1}
+
+// Set of errors returned by the envelope package.
+var (
+	ErrNotEnvelope = errors.New("data is not an envelope")
+	ErrKeyMismatch = errors.New("public key does not belong to the recipient account")
+)
+
+// Seal encrypts the message to the public key of the specified recipient and
+// returns the data to place in a transaction. The public key must belong to
+// the recipient's account so a message can't be sealed to the wrong key.
+func Seal(toID database.AccountID, publicKey *ecdsa.PublicKey, msg []byte) ([]byte, error) {
+	if database.PublicKeyToAccountID(*publicKey) != toID {
+		return nil, ErrKeyMismatch
+	}
+
+	ct, err := ecies.Encrypt(publicKey, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, len(header)+len(ct))
+	data = append(data, header...)
+	data = append(data, ct...)
+
+	return data, nil
+}
+
+// Open decrypts the envelope carried in the transaction data using the
+// private key of the recipient.
+func Open(privateKey *ecdsa.PrivateKey, data []byte) ([]byte, error) {
+	if !IsEnvelope(data) {
+		return nil, ErrNotEnvelope
+	}
+
+	return ecies.Decrypt(privateKey, data[len(header):])
+}
+
+// IsEnvelope reports if the transaction data contains an envelope.
+func IsEnvelope(data []byte) bool {
+	return bytes.HasPrefix(data, header)
+}
@@ -9,20 +9,31 @@ import (
 
 // Genesis represents the genesis file.
 type Genesis struct {
-	Date          time.Time         `json:"date"`
-	ChainID       uint16            `json:"chain_id"`        // The chain id represents an unique id for this running instance.
-	TransPerBlock uint16            `json:"trans_per_block"` // The maximum number of transactions that can be in a block.
-	Difficulty    uint16            `json:"difficulty"`      // How difficult it needs to be to solve the work problem.
-	MiningReward  uint64            `json:"mining_reward"`   // Reward for mining a block.
-	GasPrice      uint64            `json:"gas_price"`       // Fee paid for each transaction mined into a block.
-	Balances      map[string]uint64 `json:"balances"`
+	Date           time.Time         `json:"date"`
+	ChainID        uint16            `json:"chain_id"`          // The chain id represents an unique id for this running instance.
+	TransPerBlock  uint16            `json:"trans_per_block"`   // The maximum number of transactions that can be in a block, 0 is no limit.
+	MaxTxDataBytes uint64            `json:"max_tx_data_bytes"` // The maximum size of the data a transaction can carry, 0 is no limit.
+	Difficulty     uint16            `json:"difficulty"`        // How difficult it needs to be to solve the work problem.
+	MiningReward   uint64            `json:"mining_reward"`     // Reward for mining a block.
+	GasPrice       uint64            `json:"gas_price"`         // Fee paid for each transaction mined into a block.
+	PruneInterval  uint64            `json:"prune_interval"`    // Number of blocks between pruning empty accounts, 0 disables pruning.
+	Hash           string            `json:"hash,omitempty"`    // Hash function for blocks, transactions and state, sha256 when empty.
+	Balances       map[string]uint64 `json:"balances"`
+	Policy         *Policy           `json:"policy,omitempty"` // Makes the chain permissioned when provided.
+}
+
+// Policy represents the accounts that govern a permissioned chain. Only the
+// accounts funded by genesis, the admins, and the accounts the admins allow
+// can send transactions. The admins allow and deny accounts by sending
+// policy transactions, so every node enforces the same rules.
+type Policy struct {
+	Admins []string `json:"admins"`
 }
 
 // =============================================================================
 
-// Load opens and consumes the genesis file.
-func Load() (Genesis, error) {
-	path := "zblock/genesis.json"
+// Load opens and consumes the genesis file at the specified path.
+func Load(path string) (Genesis, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return Genesis{}, err
@@ -36,3 +47,13 @@ func Load() (Genesis, error) {
 
 	return genesis, nil
 }
+
+// Save writes the genesis information to the file at the specified path.
+func Save(path string, genesis Genesis) error {
+	content, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,117 @@
+// Package hash provides the hash functions a chain can be configured to use
+// for the hashes of its blocks, transactions, merkle trees and state. The
+// hash function is selected by name in the genesis file so the same chain
+// can be run with different functions to see the impact of the choice.
+package hash
+
+import (
+	"crypto/sha256"
+	"fmt"
+	stdhash "hash"
+	"sync/atomic"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Size is the number of bytes produced by every hash function in this
+// package.
+const Size = 32
+
+// Set of names a hash function can be selected by.
+const (
+	NameSHA256    = "sha256"
+	NameKeccak256 = "keccak256"
+	NameSHA3256   = "sha3-256"
+)
+
+// Hasher represents the behavior of a hash function that produces a hash of
+// Size bytes.
+type Hasher interface {
+	Name() string
+	Sum(data ...[]byte) []byte
+}
+
+// Set of hash functions that can be selected. SHA256 is the default so
+// chains created before the hash function could be selected keep their
+// hashes.
+var (
+	SHA256    Hasher = hasher{name: NameSHA256, new: sha256.New}
+	Keccak256 Hasher = hasher{name: NameKeccak256, new: sha3.NewLegacyKeccak256}
+	SHA3256   Hasher = hasher{name: NameSHA3256, new: sha3.New256}
+)
+
+// New returns the hash function for the specified name. An empty name
+// returns the default hash function.
+func New(name string) (Hasher, error) {
+	switch name {
+	case "", NameSHA256:
+		return SHA256, nil
+	case NameKeccak256:
+		return Keccak256, nil
+	case NameSHA3256:
+		return SHA3256, nil
+	}
+
+	return nil, fmt.Errorf("unknown hash function %q, must be %s, %s or %s", name, NameSHA256, NameKeccak256, NameSHA3256)
+}
+
+// =============================================================================
+
+// active holds the hash function used by the chain the program is running.
+var active atomic.Value
+
+func init() {
+	active.Store(holder{SHA256})
+}
+
+// holder wraps the hash function so values of different concrete types can
+// be stored in the atomic value.
+type holder struct {
+	Hasher
+}
+
+// Use makes the hash function the one used by the chain. It's called once
+// the genesis file is known and before any hashes are produced.
+func Use(h Hasher) {
+	active.Store(holder{h})
+}
+
+// Select makes the hash function with the specified name the one used by
+// the chain.
+func Select(name string) error {
+	h, err := New(name)
+	if err != nil {
+		return err
+	}
+
+	Use(h)
+	return nil
+}
+
+// Active returns the hash function used by the chain.
+func Active() Hasher {
+	return active.Load().(holder).Hasher
+}
+
+// =============================================================================
+
+// hasher implements the Hasher interface for a hash function from the
+// standard library or the x/crypto module.
+type hasher struct {
+	name string
+	new  func() stdhash.Hash
+}
+
+// Name returns the name the hash function is selected by.
+func (h hasher) Name() string {
+	return h.name
+}
+
+// Sum returns the hash of the data concatenated together.
+func (h hasher) Sum(data ...[]byte) []byte {
+	d := h.new()
+	for _, b := range data {
+		d.Write(b)
+	}
+	return d.Sum(nil)
+}
@@ -0,0 +1,274 @@
+// Package mempool maintains the mempool for the blockchain.
+package mempool
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/mempool/selector"
+)
+
+// ErrReplaceUnderpriced is returned when a transaction is submitted for an
+// account and nonce that already has a pending transaction paying the same
+// tip or a higher one.
+var ErrReplaceUnderpriced = errors.New("replacement transaction must offer a higher tip than the pending transaction")
+
+// Mempool represents a cache of transactions organized by account:nonce.
+type Mempool struct {
+	mu       sync.RWMutex
+	pool     map[string]database.BlockTx
+	ages     map[string]uint64
+	selectFn selector.Func
+}
+
+// New constructs a new mempool using the default sort strategy.
+func New() (*Mempool, error) {
+	return NewWithStrategy(selector.StrategyTip)
+}
+
+// NewWithStrategy constructs a new mempool with specified sort strategy.
+func NewWithStrategy(strategy string) (*Mempool, error) {
+	selectFn, err := selector.Retrieve(strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	mp := Mempool{
+		pool:     make(map[string]database.BlockTx),
+		ages:     make(map[string]uint64),
+		selectFn: selectFn,
+	}
+
+	return &mp, nil
+}
+
+// Count returns the current number of transaction in the pool.
+func (mp *Mempool) Count() int {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	return len(mp.pool)
+}
+
+// Upsert adds a transaction to the mempool. If a transaction is pending for
+// the same account and nonce, it's only replaced by a transaction paying a
+// strictly higher tip. With the same tip the pending transaction is kept, so
+// a replacement always costs the sender more. This is the only replacement
+// rule, transactions from wallets and peers both use it.
+func (mp *Mempool) Upsert(tx database.BlockTx) error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	key, err := mapKey(tx)
+	if err != nil {
+		return err
+	}
+
+	if pending, exists := mp.pool[key]; exists {
+		switch {
+		case pending.TxHash() == tx.TxHash():
+			return nil
+		case tx.Tip <= pending.Tip:
+			return fmt.Errorf("%w, pending tip %d", ErrReplaceUnderpriced, pending.Tip)
+		}
+	}
+
+	mp.pool[key] = tx
+	mp.ages[key] = 0
+
+	return nil
+}
+
+// Pending returns the transaction in the pool for the specified account and
+// nonce if one exists.
+func (mp *Mempool) Pending(accountID database.AccountID, nonce uint64) (database.BlockTx, bool) {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	tx, exists := mp.pool[fmt.Sprintf("%s:%d", accountID, nonce)]
+	return tx, exists
+}
+
+// Delete removed a transaction from the mempool.
+func (mp *Mempool) Delete(tx database.BlockTx) error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	key, err := mapKey(tx)
+	if err != nil {
+		return err
+	}
+
+	delete(mp.pool, key)
+	delete(mp.ages, key)
+
+	return nil
+}
+
+// Hashes returns the hash of every transaction in the pool. This provides a
+// compact summary of the pool that can be shared with peers.
+func (mp *Mempool) Hashes() []string {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	hashes := make([]string, 0, len(mp.pool))
+	for _, tx := range mp.pool {
+		hashes = append(hashes, tx.TxHash())
+	}
+
+	return hashes
+}
+
+// FindByHashes returns the transactions in the pool that match the
+// specified hashes.
+func (mp *Mempool) FindByHashes(hashes []string) []database.BlockTx {
+	want := make(map[string]struct{}, len(hashes))
+	for _, hash := range hashes {
+		want[hash] = struct{}{}
+	}
+
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	var txs []database.BlockTx
+	for _, tx := range mp.pool {
+		if _, exists := want[tx.TxHash()]; exists {
+			txs = append(txs, tx)
+		}
+	}
+
+	return txs
+}
+
+// Truncate clears all the transactions from the pool.
+func (mp *Mempool) Truncate() {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	mp.pool = make(map[string]database.BlockTx)
+	mp.ages = make(map[string]uint64)
+}
+
+// AgeBlocks records that a block has been added to the chain. The age of a
+// transaction is the number of blocks added since it entered the pool.
+func (mp *Mempool) AgeBlocks() {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	for key := range mp.ages {
+		mp.ages[key]++
+	}
+}
+
+// Evict removes the transactions the specified function reports as expired
+// and returns them. The function is provided the age of each transaction in
+// blocks.
+func (mp *Mempool) Evict(expired func(tx database.BlockTx, blocks uint64) bool) []database.BlockTx {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	var evicted []database.BlockTx
+	for key, tx := range mp.pool {
+		if expired(tx, mp.ages[key]) {
+			delete(mp.pool, key)
+			delete(mp.ages, key)
+			evicted = append(evicted, tx)
+		}
+	}
+
+	return evicted
+}
+
+// PickBest uses the configured sort strategy to return a set of transactions.
+// If 0 is passed, all transactions in the mempool will be returned.
+func (mp *Mempool) PickBest(howMany ...uint16) []database.BlockTx {
+	number := 0
+	if len(howMany) > 0 {
+		number = int(howMany[0])
+	}
+
+	// CORE NOTE: Most blockchains do set a max block size limit and this size
+	// will determined which transactions are selected. When picking the best
+	// transactions for the next block, the Ardan blockchain is currently not
+	// focused on block size but a max number of transactions.
+	//
+	// When the selection algorithm does need to consider sizing, picking the
+	// right transactions that maximize profit gets really hard. On top of this,
+	// today a miner gets a mining reward for each mined block. In the future
+	// this could go away leaving just fees for the transactions that are
+	// selected as the only form of revenue. This will change how transactions
+	// need to be selected.
+
+	// Copy all the transactions for each account into separate slices.
+	m := make(map[database.AccountID][]database.BlockTx)
+	mp.mu.RLock()
+	{
+		if number == 0 {
+			number = len(mp.pool)
+		}
+
+		for key, tx := range mp.pool {
+			account := accountFromMapKey(key)
+			m[account] = append(m[account], tx)
+		}
+	}
+	mp.mu.RUnlock()
+
+	return mp.selectFn(m, number)
+}
+
+// PickBestAt returns the best transactions that can be mined into the block
+// with the specified number. A transaction outside its window of blocks is
+// left in the pool, along with the later transactions of the same account
+// since they can't be applied until its nonce is used.
+func (mp *Mempool) PickBestAt(blockNumber uint64, howMany uint16) []database.BlockTx {
+	number := int(howMany)
+
+	m := make(map[database.AccountID][]database.BlockTx)
+	blocked := make(map[database.AccountID]uint64)
+	mp.mu.RLock()
+	{
+		if number == 0 {
+			number = len(mp.pool)
+		}
+
+		for key, tx := range mp.pool {
+			account := accountFromMapKey(key)
+			if tx.ValidAt(blockNumber) != nil {
+				if nonce, exists := blocked[account]; !exists || tx.Nonce < nonce {
+					blocked[account] = tx.Nonce
+				}
+				continue
+			}
+			m[account] = append(m[account], tx)
+		}
+	}
+	mp.mu.RUnlock()
+
+	for account, nonce := range blocked {
+		var keep []database.BlockTx
+		for _, tx := range m[account] {
+			if tx.Nonce < nonce {
+				keep = append(keep, tx)
+			}
+		}
+		m[account] = keep
+	}
+
+	return mp.selectFn(m, number)
+}
+
+// =============================================================================
+
+// mapKey is used to generate the map key.
+func mapKey(tx database.BlockTx) (string, error) {
+	return fmt.Sprintf("%s:%d", tx.FromID, tx.Nonce), nil
+}
+
+// accountFromMapKey extracts the account information from the mapkey.
+func accountFromMapKey(key string) database.AccountID {
+	return database.AccountID(strings.Split(key, ":")[0])
+}
@@ -0,0 +1,63 @@
+package selector
+
+import (
+	"sort"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// tipSelect returns transactions with the best tip while respecting the nonce
+// for each account/transaction. At each step the next transaction for every
+// account is considered and the one offering the highest tip is selected.
+var tipSelect = func(m map[database.AccountID][]database.BlockTx, howMany int) []database.BlockTx {
+
+	// Sort the transactions per account by nonce so they are selected
+	// in the order they must be applied.
+	queues := make(map[database.AccountID][]database.BlockTx, len(m))
+	total := 0
+	for key, txs := range m {
+		queue := make([]database.BlockTx, len(txs))
+		copy(queue, txs)
+		sort.Sort(byNonce(queue))
+
+		queues[key] = queue
+		total += len(queue)
+	}
+
+	if howMany <= 0 || howMany > total {
+		howMany = total
+	}
+
+	final := make([]database.BlockTx, 0, howMany)
+	for len(final) < howMany {
+
+		// Find the account whose next transaction offers the best tip. Ties
+		// are broken by account id so every node makes the same choice.
+		var best database.AccountID
+		found := false
+		for key, queue := range queues {
+			if len(queue) == 0 {
+				continue
+			}
+
+			if !found {
+				best, found = key, true
+				continue
+			}
+
+			bestTx, tx := queues[best][0], queue[0]
+			if tx.Tip > bestTx.Tip || (tx.Tip == bestTx.Tip && key < best) {
+				best = key
+			}
+		}
+
+		if !found {
+			break
+		}
+
+		final = append(final, queues[best][0])
+		queues[best] = queues[best][1:]
+	}
+
+	return final
+}
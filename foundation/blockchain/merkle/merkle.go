@@ -0,0 +1,178 @@
+// Package merkle implements a merkle tree for hashable values. The tree is
+// built bottom up where each parent node is the hash of its two children
+// concatenated together, using the hash function the chain is configured
+// to use.
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/hash"
+)
+
+// Hashable represents the behavior concrete data must exhibit to be used in
+// the merkle tree.
+type Hashable[T any] interface {
+	Hash() ([]byte, error)
+	Equals(other T) bool
+}
+
+// Tree represents a merkle tree that uses data of some type T that exhibits
+// the behavior defined by the Hashable constraint.
+type Tree[T Hashable[T]] struct {
+	values []T
+	levels [][][]byte
+}
+
+// NewTree constructs a new merkle tree that uses data of some type T that
+// exhibits the behavior defined by the Hashable interface.
+func NewTree[T Hashable[T]](values []T) (*Tree[T], error) {
+	leafs := make([][]byte, len(values))
+	for i, value := range values {
+		leaf, err := value.Hash()
+		if err != nil {
+			return nil, err
+		}
+		leafs[i] = leaf
+	}
+
+	t := Tree[T]{
+		values: values,
+		levels: buildLevels(leafs),
+	}
+
+	return &t, nil
+}
+
+// Values returns a slice of unique values stored in the tree.
+func (t *Tree[T]) Values() []T {
+	return t.values
+}
+
+// Root returns the merkle root for the tree. An empty tree has a root of
+// 32 bytes of zeros.
+func (t *Tree[T]) Root() []byte {
+	if len(t.levels) == 0 {
+		return make([]byte, hash.Size)
+	}
+
+	return t.levels[len(t.levels)-1][0]
+}
+
+// RootHex converts the merkle root byte hash to a hex encoded string.
+func (t *Tree[T]) RootHex() string {
+	return ToHex(t.Root())
+}
+
+// Verify validates the hashes at each level of the tree and returns the
+// resulting merkle root.
+func (t *Tree[T]) Verify() ([]byte, error) {
+	rebuilt, err := NewTree(t.values)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(rebuilt.Root(), t.Root()) {
+		return nil, errors.New("merkle root is not equivalent to the merkle root calculated on the critical path")
+	}
+
+	return t.Root(), nil
+}
+
+// Proof returns the set of hashes and the order of concatenating those
+// hashes for proving the specified value exists in the tree. An order value
+// of 0 means the proof hash belongs on the left, 1 means on the right.
+func (t *Tree[T]) Proof(data T) ([][]byte, []int64, error) {
+	idx := -1
+	for i, value := range t.values {
+		if value.Equals(data) {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return nil, nil, errors.New("unable to find the provided data")
+	}
+
+	var proof [][]byte
+	var order []int64
+	for _, level := range t.levels[:len(t.levels)-1] {
+		sibling := idx ^ 1
+		if sibling >= len(level) {
+			sibling = idx
+		}
+
+		proof = append(proof, level[sibling])
+		switch {
+		case sibling < idx:
+			order = append(order, 0)
+		default:
+			order = append(order, 1)
+		}
+
+		idx /= 2
+	}
+
+	return proof, order, nil
+}
+
+// =============================================================================
+
+// VerifyProof checks that the leaf hash combined with the proof hashes, in
+// the specified order, produces the provided merkle root.
+func VerifyProof(root []byte, leaf []byte, proof [][]byte, order []int64) bool {
+	if len(proof) != len(order) {
+		return false
+	}
+
+	node := leaf
+	for i := range proof {
+		switch order[i] {
+		case 0:
+			node = hashPair(proof[i], node)
+		default:
+			node = hashPair(node, proof[i])
+		}
+	}
+
+	return bytes.Equal(node, root)
+}
+
+// ToHex converts a byte slice into a 0x prefixed hex string.
+func ToHex(data []byte) string {
+	return "0x" + hex.EncodeToString(data)
+}
+
+// =============================================================================
+
+// buildLevels constructs each level of the tree starting with the leafs. When
+// a level has an odd number of nodes, the last node is paired with itself.
+func buildLevels(leafs [][]byte) [][][]byte {
+	if len(leafs) == 0 {
+		return nil
+	}
+
+	levels := [][][]byte{leafs}
+	for current := leafs; len(current) > 1; {
+		var next [][]byte
+		for i := 0; i < len(current); i += 2 {
+			right := current[i]
+			if i+1 < len(current) {
+				right = current[i+1]
+			}
+			next = append(next, hashPair(current[i], right))
+		}
+
+		levels = append(levels, next)
+		current = next
+	}
+
+	return levels
+}
+
+// hashPair produces the hash of the two hashes concatenated.
+func hashPair(left []byte, right []byte) []byte {
+	return hash.Active().Sum(left, right)
+}
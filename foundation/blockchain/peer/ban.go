@@ -0,0 +1,216 @@
+package peer
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HeaderHost is the request header a node uses to tell a peer the host of
+// its private API. The peer uses it to score the node for the data it sends.
+const HeaderHost = "X-Peer-Host"
+
+// FromRequest returns the host of the peer that sent the request. When the
+// peer didn't identify itself, the remote address is used.
+func FromRequest(r *http.Request) string {
+	if host := r.Header.Get(HeaderHost); host != "" {
+		return host
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return ip
+}
+
+// =============================================================================
+
+// Ban represents a peer that is not allowed to talk to this node until the
+// ban expires.
+type Ban struct {
+	Host   string    `json:"host"`
+	Reason string    `json:"reason"`
+	Score  int       `json:"score"`
+	Until  time.Time `json:"until"`
+	Manual bool      `json:"manual"`
+}
+
+// maxScores is the number of scored peers held before the scores that have
+// decayed are removed.
+const maxScores = 1024
+
+// offense tracks the misbehavior score of a peer.
+type offense struct {
+	score int
+	last  time.Time
+}
+
+// decayed returns the score after taking off a point for every minute since
+// the last offense.
+func (off offense) decayed(now time.Time) int {
+	score := off.score - int(now.Sub(off.last)/time.Minute)
+	if score < 0 {
+		return 0
+	}
+	return score
+}
+
+// BanList scores the peers that send invalid data and bans the peers whose
+// score reaches the threshold. The score of a peer decays by one point for
+// every minute it behaves, so a peer that makes an occasional mistake is
+// never banned.
+type BanList struct {
+	mu        sync.Mutex
+	threshold int
+	duration  time.Duration
+	scores    map[string]offense
+	bans      map[string]Ban
+}
+
+// NewBanList constructs a ban list that bans a peer for the duration once
+// its score reaches the threshold. A threshold of 0 turns off the scoring,
+// leaving only the manual bans.
+func NewBanList(threshold int, duration time.Duration) *BanList {
+	return &BanList{
+		threshold: threshold,
+		duration:  duration,
+		scores:    make(map[string]offense),
+		bans:      make(map[string]Ban),
+	}
+}
+
+// Penalize adds the points to the score of the peer and bans the peer when
+// the score reaches the threshold. It returns true if the peer was banned.
+func (bl *BanList) Penalize(host string, points int, reason string) bool {
+	if bl.threshold <= 0 {
+		return false
+	}
+
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	now := time.Now()
+	if _, banned := bl.active(host, now); banned {
+		return false
+	}
+
+	bl.sweep(now)
+
+	off := bl.scores[host]
+	off.score = off.decayed(now) + points
+	off.last = now
+
+	if off.score < bl.threshold {
+		bl.scores[host] = off
+		return false
+	}
+
+	delete(bl.scores, host)
+	bl.bans[host] = Ban{
+		Host:   host,
+		Reason: reason,
+		Score:  off.score,
+		Until:  now.Add(bl.duration),
+	}
+
+	return true
+}
+
+// Ban bans the peer for the duration. A duration of 0 uses the duration of
+// the ban list.
+func (bl *BanList) Ban(host string, duration time.Duration, reason string) Ban {
+	if duration <= 0 {
+		duration = bl.duration
+	}
+
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	delete(bl.scores, host)
+
+	ban := Ban{
+		Host:   host,
+		Reason: reason,
+		Until:  time.Now().Add(duration),
+		Manual: true,
+	}
+	bl.bans[host] = ban
+
+	return ban
+}
+
+// Unban lifts the ban on the peer and clears its score. It returns false if
+// the peer wasn't banned.
+func (bl *BanList) Unban(host string) bool {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	delete(bl.scores, host)
+
+	_, banned := bl.active(host, time.Now())
+	delete(bl.bans, host)
+
+	return banned
+}
+
+// IsBanned reports if the peer is currently banned.
+func (bl *BanList) IsBanned(host string) bool {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	_, banned := bl.active(host, time.Now())
+	return banned
+}
+
+// Bans returns the peers that are currently banned ordered by host.
+func (bl *BanList) Bans() []Ban {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	now := time.Now()
+	bans := []Ban{}
+	for host := range bl.bans {
+		if ban, banned := bl.active(host, now); banned {
+			bans = append(bans, ban)
+		}
+	}
+
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Host < bans[j].Host })
+
+	return bans
+}
+
+// sweep removes the scores that have decayed to 0 once enough peers are
+// being scored, so hosts that stop sending data don't grow the map forever.
+// The caller must hold the lock.
+func (bl *BanList) sweep(now time.Time) {
+	if len(bl.scores) < maxScores {
+		return
+	}
+
+	for host, off := range bl.scores {
+		if off.decayed(now) == 0 {
+			delete(bl.scores, host)
+		}
+	}
+}
+
+// active returns the ban for the peer if it hasn't expired. An expired ban
+// is removed. The caller must hold the lock.
+func (bl *BanList) active(host string, now time.Time) (Ban, bool) {
+	ban, exists := bl.bans[host]
+	if !exists {
+		return Ban{}, false
+	}
+
+	if now.After(ban.Until) {
+		delete(bl.bans, host)
+		return Ban{}, false
+	}
+
+	return ban, true
+}
@@ -0,0 +1,134 @@
+// Package peer maintains the peer related information such as the set
+// of know peers and their status.
+package peer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// ErrStatusUnsigned is returned when a peer responds with a status that
+// isn't signed.
+var ErrStatusUnsigned = errors.New("peer status is not signed")
+
+// Peer represents information about a Node in the network.
+type Peer struct {
+	Host string `json:"host"`
+}
+
+// New constructs a new info value.
+func New(host string) Peer {
+	return Peer{
+		Host: host,
+	}
+}
+
+// Match validates if the specified host matches this node.
+func (p Peer) Match(host string) bool {
+	return p.Host == host
+}
+
+// =============================================================================
+
+// PeerStatus represents information about the status
+// of any given peer. The status is signed by the account of the node along
+// with its host and the nonce the caller asked for, so a status can't be
+// forged for another node or replayed.
+type PeerStatus struct {
+	LatestBlockHash   string `json:"latest_block_hash"`
+	LatestBlockNumber uint64 `json:"latest_block_number"`
+	KnownPeers        []Peer `json:"known_peers"`
+	Host              string `json:"host"`
+	AccountID         string `json:"account_id"`
+	Time              uint64 `json:"time"`
+	Nonce             string `json:"nonce"`
+	Sig               string `json:"sig"`
+}
+
+// SignData returns the part of the status that is signed.
+func (ps PeerStatus) SignData() PeerStatus {
+	ps.Sig = ""
+	return ps
+}
+
+// Verify checks the status was signed by the account it names and is the
+// answer to the request made to the host with the nonce.
+func (ps PeerStatus) Verify(host string, nonce string) error {
+	if ps.Sig == "" {
+		return ErrStatusUnsigned
+	}
+
+	if ps.Host != host {
+		return fmt.Errorf("status is for host %s, expected %s", ps.Host, host)
+	}
+
+	if ps.Nonce != nonce {
+		return errors.New("status nonce doesn't match the request")
+	}
+
+	address, err := signature.FromAddressSignature(ps.SignData(), ps.Sig)
+	if err != nil {
+		return fmt.Errorf("status signature: %w", err)
+	}
+
+	if address != ps.AccountID {
+		return fmt.Errorf("status signed by %s, expected %s", address, ps.AccountID)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// PeerSet represents the data representation to maintain a set of known peers.
+type PeerSet struct {
+	mu  sync.RWMutex
+	set map[Peer]struct{}
+}
+
+// NewPeerSet constructs a new info set to manage node peer information.
+func NewPeerSet() *PeerSet {
+	return &PeerSet{
+		set: make(map[Peer]struct{}),
+	}
+}
+
+// Add adds a new node to the set.
+func (ps *PeerSet) Add(peer Peer) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	_, exist := ps.set[peer]
+	if !exist {
+		ps.set[peer] = struct{}{}
+		return true
+	}
+
+	return false
+}
+
+// Remove removes a node from the set.
+func (ps *PeerSet) Remove(peer Peer) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	delete(ps.set, peer)
+}
+
+// Copy returns a list of the known peers.
+func (ps *PeerSet) Copy(host string) []Peer {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	var peers []Peer
+	for peer := range ps.set {
+		if !peer.Match(host) {
+			peers = append(peers, peer)
+		}
+	}
+
+	return peers
+}
@@ -0,0 +1,135 @@
+// Package proof provides support for packaging a mined transaction into a
+// proof of payment that can be verified offline. The proof carries the
+// transaction, its receipt, the merkle proof and the header of the block the
+// transaction was recorded in. It's signed by the account that paid.
+package proof
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/merkle"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+	"github.com/ardanlabs/blockchain/foundation/hexutil"
+)
+
+// Payment represents the information needed to prove a transaction was
+// recorded in a block.
+type Payment struct {
+	Tx         database.BlockTx     `json:"tx"`
+	Receipt    database.Receipt     `json:"receipt"`
+	Header     database.BlockHeader `json:"header"`
+	Proof      []string             `json:"proof"`
+	ProofOrder []int64              `json:"proof_order"`
+}
+
+// Sign uses the specified private key to sign the proof. Only the account
+// that sent the transaction can produce a valid proof.
+func (p Payment) Sign(privateKey *ecdsa.PrivateKey) (SignedPayment, error) {
+	if database.PublicKeyToAccountID(privateKey.PublicKey) != p.Tx.FromID {
+		return SignedPayment{}, errors.New("private key does not belong to the account that paid")
+	}
+
+	v, r, s, err := signature.Sign(p, privateKey)
+	if err != nil {
+		return SignedPayment{}, err
+	}
+
+	sp := SignedPayment{
+		Payment: p,
+		V:       v,
+		R:       r,
+		S:       s,
+	}
+
+	return sp, nil
+}
+
+// =============================================================================
+
+// SignedPayment is a signed version of the proof of payment. This is what is
+// stored in a proof file.
+type SignedPayment struct {
+	Payment
+	V *big.Int `json:"v"`
+	R *big.Int `json:"r"`
+	S *big.Int `json:"s"`
+}
+
+// Verify checks the proof without the need of a node. The transaction must
+// be signed for the specified chain, its receipt must show it succeeded in
+// the block described by the header, the merkle proof must tie it to the
+// transaction root of that header, the header must have its work performed
+// and the proof must be signed by the account that paid.
+func (sp SignedPayment) Verify(chainID uint16) error {
+	if err := sp.Tx.Validate(chainID); err != nil {
+		return fmt.Errorf("transaction: %w", err)
+	}
+
+	txHash := sp.Tx.TxHash()
+	if sp.Receipt.TxHash != txHash {
+		return fmt.Errorf("receipt is for transaction %s, exp %s", sp.Receipt.TxHash, txHash)
+	}
+
+	if sp.Receipt.Status != database.ReceiptStatusSuccess {
+		return fmt.Errorf("transaction was not successful: %s", sp.Receipt.Error)
+	}
+
+	block := database.Block{Header: sp.Header}
+	if sp.Receipt.BlockNumber != sp.Header.Number || sp.Receipt.BlockHash != block.Hash() {
+		return fmt.Errorf("receipt is for block %d %s, header is for block %d %s", sp.Receipt.BlockNumber, sp.Receipt.BlockHash, sp.Header.Number, block.Hash())
+	}
+
+	if !block.IsSolved() {
+		return fmt.Errorf("%s invalid block hash", block.Hash())
+	}
+
+	if err := sp.verifyMerkleProof(); err != nil {
+		return err
+	}
+
+	if err := signature.VerifySignature(sp.V, sp.R, sp.S); err != nil {
+		return err
+	}
+
+	address, err := signature.FromAddress(sp.Payment, sp.V, sp.R, sp.S)
+	if err != nil {
+		return err
+	}
+
+	if address != string(sp.Tx.FromID) {
+		return errors.New("proof was not signed by the account that paid")
+	}
+
+	return nil
+}
+
+// verifyMerkleProof checks the transaction is part of the transaction root
+// recorded in the header.
+func (sp SignedPayment) verifyMerkleProof() error {
+	root, err := hexutil.Decode(sp.Header.TransRoot)
+	if err != nil {
+		return fmt.Errorf("decoding trans root: %w", err)
+	}
+
+	leaf, err := sp.Tx.Hash()
+	if err != nil {
+		return err
+	}
+
+	proof := make([][]byte, len(sp.Proof))
+	for i, p := range sp.Proof {
+		if proof[i], err = hexutil.Decode(p); err != nil {
+			return fmt.Errorf("decoding merkle proof: %w", err)
+		}
+	}
+
+	if !merkle.VerifyProof(root, leaf, proof, sp.ProofOrder) {
+		return errors.New("merkle proof does not match the trans root")
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package signature
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Canonical returns the canonical encoding of the value. This is the encoding
+// used for hashing and signing so the bytes don't depend on the order fields
+// are declared in a struct or on the language producing them. The canonical
+// encoding is JSON with these rules:
+//   - Object keys are sorted in byte order.
+//   - There is no insignificant whitespace.
+//   - Numbers are written exactly as they were marshaled.
+//   - The characters <, > and & are not escaped.
+func Canonical(value any) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode the data into generic values. Objects become maps which are
+	// always encoded with their keys sorted. Numbers are kept as written so
+	// large integers don't lose precision.
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	// The encoder terminates each value with a newline.
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
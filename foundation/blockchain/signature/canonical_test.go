@@ -0,0 +1,120 @@
+package signature_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// update rewrites the golden files with the current output. Only use this
+// when the canonical format is meant to change, since every hash and
+// signature on an existing chain changes with it.
+var update = flag.Bool("update", false, "update the golden files")
+
+// privateKey is the key for the from account of the test transaction. A fixed
+// key keeps the signatures in the golden files stable.
+const privateKey = "fae85851bdf5c9f49923722ce38f3c1defcfd3619ef5453230a58ad805499959"
+
+func TestCanonical(t *testing.T) {
+	tx := testTx(t)
+
+	header := database.BlockHeader{
+		Number:        2,
+		PrevBlockHash: "0x00a326d65eac66666cbcaf551d2f6c5daf2d0fb6273e21c0a47c10d513a5b6f0",
+		TimeStamp:     1639699200000,
+		BeneficiaryID: "0xFef311483Cc040e1A89fb9bb469eeB8A70935EF8",
+		Difficulty:    6,
+		MiningReward:  700,
+		StateRoot:     signature.ZeroHash,
+		TransRoot:     signature.ZeroHash,
+		Nonce:         18446744073709551615,
+	}
+
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{"tx", tx},
+		{"header", header},
+		{"accounts", []database.Account{{AccountID: tx.FromID, Nonce: 1, Balance: 999984}}},
+		{"map", map[string]any{"z": 1, "a": "<&>", "m": []int{3, 2, 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := signature.Canonical(tt.value)
+			if err != nil {
+				t.Fatalf("Should be able to encode the value: %s", err)
+			}
+
+			golden(t, tt.name+".golden", data)
+		})
+	}
+}
+
+func TestHashAndSign(t *testing.T) {
+	pk, err := crypto.HexToECDSA(privateKey)
+	if err != nil {
+		t.Fatalf("Should be able to load the private key: %s", err)
+	}
+
+	tx := testTx(t)
+
+	signedTx, err := tx.Sign(pk)
+	if err != nil {
+		t.Fatalf("Should be able to sign the transaction: %s", err)
+	}
+
+	if err := signedTx.Validate(tx.ChainID); err != nil {
+		t.Fatalf("Should be able to validate the signed transaction: %s", err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "hash: %s\n", signature.Hash(tx))
+	fmt.Fprintf(&buf, "signature: %s\n", signedTx.SignatureString())
+	fmt.Fprintf(&buf, "txhash: %s\n", signedTx.TxHash())
+
+	golden(t, "hash.golden", buf.Bytes())
+}
+
+// =============================================================================
+
+func testTx(t *testing.T) database.Tx {
+	tx, err := database.NewTx(1, 1, "0xdd6B972ffcc631a62CAE1BB9d80b7ff429c8ebA4", "0xF01813E4B85e178A83e29B8E7bF26BD830a25f32", 100, 1, []byte("hello"))
+	if err != nil {
+		t.Fatalf("Should be able to construct the transaction: %s", err)
+	}
+
+	return tx
+}
+
+// golden compares the data with the contents of the golden file, writing the
+// file instead when the update flag is set.
+func golden(t *testing.T, name string, data []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+
+	if *update {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Should be able to write the golden file: %s", err)
+		}
+		return
+	}
+
+	exp, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Should be able to read the golden file: %s", err)
+	}
+
+	if !bytes.Equal(data, exp) {
+		t.Errorf("Should match the golden file %s.\ngot: %s\nexp: %s", path, data, exp)
+	}
+}
@@ -0,0 +1,152 @@
+package signature_test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// payload is the value signed by the property and fuzz tests.
+type payload struct {
+	Text  string `json:"text"`
+	Value uint64 `json:"value"`
+	Data  []byte `json:"data"`
+}
+
+func TestSignRoundTrip(t *testing.T) {
+	f := func(p payload) bool {
+		pk, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("Should be able to generate a private key: %s", err)
+		}
+
+		v, r, s, err := signature.Sign(p, pk)
+		if err != nil {
+			t.Logf("Should be able to sign %+v: %s", p, err)
+			return false
+		}
+
+		if err := signature.VerifySignature(v, r, s); err != nil {
+			t.Logf("Should be able to verify the signature of %+v: %s", p, err)
+			return false
+		}
+
+		exp := crypto.PubkeyToAddress(pk.PublicKey).String()
+
+		address, err := signature.FromAddress(p, v, r, s)
+		if err != nil || address != exp {
+			t.Logf("Should recover the signer of %+v, got %s, exp %s: %v", p, address, exp, err)
+			return false
+		}
+
+		address, err = signature.FromAddressSignature(p, signature.SignatureString(v, r, s))
+		if err != nil || address != exp {
+			t.Logf("Should recover the signer from the signature string of %+v, got %s, exp %s: %v", p, address, exp, err)
+			return false
+		}
+
+		// The same signature with the high S value must be rejected.
+		highS := new(big.Int).Sub(crypto.S256().Params().N, s)
+		if err := signature.VerifySignature(v, r, highS); err == nil {
+			t.Logf("Should not verify the high S signature of %+v", p)
+			return false
+		}
+
+		// Any change to the signed value must recover a different account.
+		changed := p
+		changed.Value++
+		if address, err := signature.FromAddress(changed, v, r, s); err == nil && address == exp {
+			t.Logf("Should not recover the signer for a changed value %+v", changed)
+			return false
+		}
+
+		return true
+	}
+
+	if err := quick.Check(f, &quick.Config{MaxCount: 50}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// =============================================================================
+
+func FuzzFromAddress(f *testing.F) {
+	pk, err := crypto.HexToECDSA(privateKey)
+	if err != nil {
+		f.Fatalf("Should be able to load the private key: %s", err)
+	}
+
+	v, r, s, err := signature.Sign(payload{Text: "hello", Value: 10}, pk)
+	if err != nil {
+		f.Fatalf("Should be able to sign the payload: %s", err)
+	}
+
+	f.Add("hello", uint64(10), v.Bytes(), r.Bytes(), s.Bytes())
+	f.Add("", uint64(0), []byte{}, []byte{}, []byte{})
+	f.Add("hello", uint64(10), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 29}, r.Bytes(), s.Bytes())
+	f.Add("hello", uint64(10), v.Bytes(), append(r.Bytes(), r.Bytes()...), s.Bytes())
+	f.Add("hello", uint64(10), []byte{1}, r.Bytes(), s.Bytes())
+
+	exp := crypto.PubkeyToAddress(pk.PublicKey).String()
+
+	f.Fuzz(func(t *testing.T, text string, value uint64, vb []byte, rb []byte, sb []byte) {
+		v := new(big.Int).SetBytes(vb)
+		r := new(big.Int).SetBytes(rb)
+		s := new(big.Int).SetBytes(sb)
+
+		address, err := signature.FromAddress(payload{Text: text, Value: value}, v, r, s)
+		if err != nil {
+			return
+		}
+
+		// Any signature that is accepted must conform to the chain's rules.
+		if err := signature.VerifySignature(v, r, s); err != nil {
+			t.Fatalf("Should only recover an address for a valid signature: %s", err)
+		}
+
+		if !strings.HasPrefix(address, "0x") || len(address) != 42 {
+			t.Fatalf("Should recover a properly formatted address, got %s", address)
+		}
+
+		// The signature values must round trip through the signature string.
+		v2, r2, s2, err := signature.ToVRSFromHexSignature(signature.SignatureString(v, r, s))
+		if err != nil {
+			t.Fatalf("Should be able to parse the signature string: %s", err)
+		}
+		if v2.Cmp(v) != 0 || r2.Cmp(r) != 0 || s2.Cmp(s) != 0 {
+			t.Fatalf("Should round trip the signature values, got %d %d %d, exp %d %d %d", v2, r2, s2, v, r, s)
+		}
+
+		// Only the original value and signature can recover the signing key.
+		if address == exp && (text != "hello" || value != 10) {
+			t.Fatalf("Should not recover the signer for a forged value %q %d", text, value)
+		}
+	})
+}
+
+func FuzzToVRSFromHexSignature(f *testing.F) {
+	f.Add("0x" + strings.Repeat("ab", 64) + "1d")
+	f.Add(strings.Repeat("ab", 64) + "1b")
+	f.Add(strings.Repeat("ab", 64) + "01")
+	f.Add("0x")
+	f.Add("zz")
+
+	f.Fuzz(func(t *testing.T, sigStr string) {
+		v, r, s, err := signature.ToVRSFromHexSignature(sigStr)
+		if err != nil {
+			return
+		}
+
+		if id := v.Uint64(); id != 29 && id != 30 {
+			t.Fatalf("Should always return the Taha recovery id, got %d", id)
+		}
+
+		if r.BitLen() > 256 || s.BitLen() > 256 {
+			t.Fatalf("Should return 32 byte r and s values, got %d and %d bits", r.BitLen(), s.BitLen())
+		}
+	})
+}
@@ -4,15 +4,15 @@ package signature
 
 import (
 	"crypto/ecdsa"
-	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 
-	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/hash"
+	"github.com/ardanlabs/blockchain/foundation/hexutil"
 )
 
 // ZeroHash represents a hash code of zeros.
@@ -23,17 +23,20 @@ const ZeroHash string = "0x00000000000000000000000000000000000000000000000000000
 // Ethereum and Bitcoin do this as well, but they use the value of 27.
 const tahaID = 29
 
+// ethereumID is the value Ethereum adds to the recovery id.
+const ethereumID = 27
+
 // =============================================================================
 
-// Hash returns a unique string for the value.
+// Hash returns a unique string for the value based on its canonical encoding.
+// The value is hashed with the hash function the chain is configured to use.
 func Hash(value any) string {
-	data, err := json.Marshal(value)
+	data, err := Canonical(value)
 	if err != nil {
 		return ZeroHash
 	}
 
-	hash := sha256.Sum256(data)
-	return hexutil.Encode(hash[:])
+	return hexutil.Encode(hash.Active().Sum(data))
 }
 
 // Sign uses the specified private key to sign the data.
@@ -73,15 +76,25 @@ func Sign(value any, privateKey *ecdsa.PrivateKey) (v, r, s *big.Int, err error)
 
 // VerifySignature verifies the signature conforms to our standards.
 func VerifySignature(v, r, s *big.Int) error {
+	if v == nil || r == nil || s == nil {
+		return errors.New("missing signature values")
+	}
 
-	// Check the recovery id is either 0 or 1.
+	// Check the recovery id is either 0 or 1. A value too large for a uint64
+	// would otherwise wrap around to a valid id.
+	if !v.IsUint64() {
+		return errors.New("invalid recovery id")
+	}
 	uintV := v.Uint64() - tahaID
 	if uintV != 0 && uintV != 1 {
 		return errors.New("invalid recovery id")
 	}
 
-	// Check the signature values are valid.
-	if !crypto.ValidateSignatureValues(byte(uintV), r, s, false) {
+	// Check the signature values are valid. The S value must be in the lower
+	// half of the curve order, otherwise N-S is a second valid signature for
+	// the same data and the hash of a signed transaction could be changed by
+	// anyone who sees it.
+	if !crypto.ValidateSignatureValues(byte(uintV), r, s, true) {
 		return errors.New("invalid signature values")
 	}
 
@@ -90,24 +103,75 @@ func VerifySignature(v, r, s *big.Int) error {
 
 // FromAddress extracts the address for the account that signed the data.
 func FromAddress(value any, v, r, s *big.Int) (string, error) {
+	publicKey, err := FromPublicKey(value, v, r, s)
+	if err != nil {
+		return "", err
+	}
+
+	// Extract the account address from the public key.
+	return crypto.PubkeyToAddress(*publicKey).String(), nil
+}
+
+// FromPublicKey extracts the public key for the account that signed the data.
+// This allows anyone to learn the public key of an account once it has signed
+// a transaction. The signature values are verified first since they often
+// come straight off the network.
+func FromPublicKey(value any, v, r, s *big.Int) (*ecdsa.PublicKey, error) {
+	if err := VerifySignature(v, r, s); err != nil {
+		return nil, err
+	}
 
 	// Prepare the data for public key extraction.
 	data, err := stamp(value)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Convert the [R|S|V] format into the original 65 bytes.
 	sig := ToSignatureBytes(v, r, s)
 
 	// Capture the public key associated with this data and signature.
-	publicKey, err := crypto.SigToPub(data, sig)
+	return crypto.SigToPub(data, sig)
+}
+
+// FromAddressSignature extracts the address for the account that signed the
+// data using a 65 byte [R|S|V] signature in hex. This supports signatures
+// produced by wallets written in other languages.
+func FromAddressSignature(value any, sigStr string) (string, error) {
+	v, r, s, err := ToVRSFromHexSignature(sigStr)
 	if err != nil {
 		return "", err
 	}
 
-	// Extract the account address from the public key.
-	return crypto.PubkeyToAddress(*publicKey).String(), nil
+	return FromAddress(value, v, r, s)
+}
+
+// SignMessage signs the message with the Taha stamp and returns the signature
+// in hex. A message is signed as a string, so the signature can't be mistaken
+// for the signature of a transaction.
+func SignMessage(message string, privateKey *ecdsa.PrivateKey) (string, error) {
+	v, r, s, err := Sign(message, privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return SignatureString(v, r, s), nil
+}
+
+// VerifyMessage checks the signature of the message and returns the address
+// of the account that signed it. When an address is specified, it must be
+// the account that signed the message.
+func VerifyMessage(message string, sigStr string, address string) (string, error) {
+	from, err := FromAddressSignature(message, sigStr)
+	if err != nil {
+		return "", err
+	}
+
+	if address != "" && !strings.EqualFold(from, address) {
+		return "", fmt.Errorf("message was signed by %s, not %s", from, address)
+	}
+
+	return from, nil
 }
 
 // SignatureString returns the signature as a string.
@@ -116,22 +180,45 @@ func SignatureString(v, r, s *big.Int) string {
 }
 
 // ToVRSFromHexSignature converts a hex representation of the signature into
-// its R, S and V parts.
+// its R, S and V parts. The 0x prefix is optional.
 func ToVRSFromHexSignature(sigStr string) (v, r, s *big.Int, err error) {
-	sig, err := hex.DecodeString(sigStr[2:])
+	sig, err := hex.DecodeString(strings.TrimPrefix(sigStr, "0x"))
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
+	return ToVRSFromBytes(sig)
+}
+
+// ToVRSFromBytes converts a 65 byte [R|S|V] signature into its R, S and V
+// parts. The recovery id can be provided in its raw form (0 or 1), the
+// Ethereum form (27 or 28) or the Taha form (29 or 30). The V value returned
+// always includes the Taha id.
+func ToVRSFromBytes(sig []byte) (v, r, s *big.Int, err error) {
+	if len(sig) != crypto.SignatureLength {
+		return nil, nil, nil, fmt.Errorf("invalid signature length, got %d, exp %d", len(sig), crypto.SignatureLength)
+	}
+
+	recoveryID := sig[64]
+	switch {
+	case recoveryID <= 1:
+	case recoveryID == ethereumID || recoveryID == ethereumID+1:
+		recoveryID -= ethereumID
+	case recoveryID == tahaID || recoveryID == tahaID+1:
+		recoveryID -= tahaID
+	default:
+		return nil, nil, nil, fmt.Errorf("invalid recovery id %d", sig[64])
+	}
+
 	r = new(big.Int).SetBytes(sig[:32])
 	s = new(big.Int).SetBytes(sig[32:64])
-	v = new(big.Int).SetBytes([]byte{sig[64]})
+	v = new(big.Int).SetUint64(uint64(recoveryID) + tahaID)
 
 	return v, r, s, nil
 }
 
 // ToSignatureBytes converts the r, s, v values into a slice of bytes
-// with the removal of the tahaID.
+// with the removal of the tahaID. The values must pass VerifySignature.
 func ToSignatureBytes(v, r, s *big.Int) []byte {
 	sig := make([]byte, crypto.SignatureLength)
 
@@ -163,8 +250,8 @@ func ToSignatureBytesWithTahaID(v, r, s *big.Int) []byte {
 // the Taha stamp embedded into the final hash.
 func stamp(value any) ([]byte, error) {
 
-	// Marshal the data.
-	v, err := json.Marshal(value)
+	// Marshal the data into its canonical form.
+	v, err := Canonical(value)
 	if err != nil {
 		return nil, err
 	}
@@ -174,8 +261,9 @@ func stamp(value any) ([]byte, error) {
 	stamp := []byte(fmt.Sprintf("\x19Taha Signed Message:\n%d", len(v)))
 
 	// Hash the stamp and txHash together in a final 32 byte array
-	// that represents the data.
-	data := crypto.Keccak256(stamp, v)
+	// that represents the data. This is always keccak256, whatever hash
+	// function the chain uses, since wallets sign without knowing the chain.
+	data := hash.Keccak256.Sum(stamp, v)
 
 	return data, nil
 }
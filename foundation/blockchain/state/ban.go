@@ -0,0 +1,84 @@
+package state
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+// Set of points added to the score of a peer for the data it sends. A peer
+// is banned once its score reaches the configured threshold.
+const (
+	PenaltyMalformed    = 20 // Data that can't be decoded.
+	PenaltyInvalidTx    = 10 // Transaction with a bad signature or format.
+	PenaltyInvalidBlock = 50 // Block that breaks a consensus rule.
+)
+
+// IsPeerFault reports if the error returned validating a block from a peer
+// means the peer sent a block no honest node would produce. A block that is
+// stale or competes with the block this node already has is an ordinary
+// race between miners and isn't held against the peer.
+func IsPeerFault(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, database.ErrChainForked),
+		errors.Is(err, database.ErrBlockNumber),
+		errors.Is(err, database.ErrParentHash):
+		return false
+	}
+
+	return true
+}
+
+// PenalizePeer adds the points to the score of the peer. A peer whose score
+// reaches the threshold is banned and removed from the known peers. It
+// returns true if the peer was banned.
+func (s *State) PenalizePeer(host string, points int, reason string) bool {
+	s.log("state: PenalizePeer: penalize", "peer", host, "points", points, "reason", reason)
+
+	if !s.bans.Penalize(host, points, reason) {
+		return false
+	}
+
+	s.log("state: PenalizePeer: peer banned", "peer", host, "reason", reason)
+	peersBanned.Inc()
+	s.RemoveKnownPeer(peer.New(host))
+
+	return true
+}
+
+// IsPeerBanned reports if the peer is currently banned.
+func (s *State) IsPeerBanned(host string) bool {
+	return s.bans.IsBanned(host)
+}
+
+// BanPeer bans the peer for the duration and removes it from the known
+// peers. A duration of 0 uses the configured ban duration.
+func (s *State) BanPeer(host string, duration time.Duration, reason string) peer.Ban {
+	ban := s.bans.Ban(host, duration, reason)
+
+	s.log("state: BanPeer: peer banned", "peer", host, "until", ban.Until, "reason", reason)
+	peersBanned.Inc()
+	s.RemoveKnownPeer(peer.New(host))
+
+	return ban
+}
+
+// UnbanPeer lifts the ban on the peer. It returns false if the peer wasn't
+// banned.
+func (s *State) UnbanPeer(host string) bool {
+	if !s.bans.Unban(host) {
+		return false
+	}
+
+	s.log("state: UnbanPeer: peer unbanned", "peer", host)
+
+	return true
+}
+
+// PeerBans returns the peers that are currently banned.
+func (s *State) PeerBans() []peer.Ban {
+	return s.bans.Bans()
+}
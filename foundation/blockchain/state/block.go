@@ -0,0 +1,182 @@
+package state
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/tracing"
+)
+
+// ProcessProposedBlock takes a block received from a peer, validates it and
+// if that passes, adds the block to the local blockchain.
+func (s *State) ProcessProposedBlock(ctx context.Context, block database.Block) (err error) {
+	ctx, span := s.tracer.Start(ctx, "state.ProcessProposedBlock", tracing.Int("blk", int64(block.Header.Number)))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	s.log("state: ProcessProposedBlock: started", "prevBlk", block.Header.PrevBlockHash, "newBlk", block.Hash(), "numTrans", len(block.MerkleTree.Values()))
+	defer s.log("state: ProcessProposedBlock: completed", "newBlk", block.Hash())
+
+	// If the runMiningOperation function is being executed it needs to stop
+	// immediately. Once this block has been processed, mining is signaled to
+	// start again so any remaining transactions in the mempool get mined.
+	s.Worker.SignalCancelMining()
+	defer s.Worker.SignalStartMining()
+
+	// Validate the block and then update the blockchain database.
+	if err := s.validateUpdateDatabase(ctx, block); err != nil {
+		if errors.Is(err, database.ErrChainForked) {
+			s.log("state: ProcessProposedBlock: chain forked, starting resync", "blk", block.Header.Number)
+			if err := s.Resync(); err != nil {
+				return err
+			}
+		}
+
+		return err
+	}
+
+	blocksReceived.Inc()
+	s.events.Publish(EventBlockReceived, newBlockEvent(block))
+
+	return nil
+}
+
+// =============================================================================
+
+// validateUpdateDatabase takes the block and validates the block against the
+// consensus rules. If the block passes, then the state of the node is updated
+// including adding the block to disk.
+func (s *State) validateUpdateDatabase(ctx context.Context, block database.Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.log("state: validateUpdateDatabase: validate block")
+
+	// CORE NOTE: I could add logic to determine if this block was mined by this
+	// node or a peer. If the block is mined by this node, even if a peer beat
+	// me to this function for the same block number, I could replace the peer
+	// block with my own and attempt to have other peers accept my block instead.
+
+	if err := s.validateBlock(ctx, block); err != nil {
+		return err
+	}
+
+	_, span := s.tracer.Start(ctx, "state.applyBlock", tracing.Int("blk", int64(block.Header.Number)))
+	defer span.End()
+
+	s.log("state: validateUpdateDatabase: apply block to scratch accounts")
+
+	// The block is applied to a scratch copy of the accounts. The live
+	// accounts are only replaced once the block is on disk, so a failure
+	// along the way leaves the state of the node untouched.
+	scratch := s.db.Scratch()
+
+	// Process the transactions against the scratch accounts.
+	values := block.MerkleTree.Values()
+	receipts := make([]database.Receipt, len(values))
+	for i, tx := range values {
+		s.log("state: validateUpdateDatabase: tx", "tx", tx)
+
+		// Apply the balance changes based on this transaction.
+		receipt, err := scratch.ApplyTransaction(block, tx)
+		receipt.Index = i
+		receipts[i] = receipt
+
+		if err != nil {
+			s.log("state: validateUpdateDatabase: apply tx", "tx", tx, "ERROR", err)
+			continue
+		}
+	}
+
+	// Apply the mining reward for this block.
+	scratch.ApplyMiningReward(block, receipts)
+
+	// Remove the empty accounts if this block is a pruning point.
+	if pruned := scratch.Prune(block); len(pruned) > 0 {
+		s.log("state: validateUpdateDatabase: prune accounts", "blk", block.Header.Number, "pruned", len(pruned))
+	}
+
+	// Record the accounts the block changed before the scratch accounts
+	// replace the live accounts.
+	diff := s.db.StateDiff(block, scratch)
+
+	s.log("state: validateUpdateDatabase: write to disk")
+
+	// Write the new block to the chain on disk.
+	if err := s.db.Write(block); err != nil {
+		return err
+	}
+
+	s.log("state: validateUpdateDatabase: commit accounts and remove from mempool")
+
+	// Commit the scratch accounts and the new latest block.
+	s.db.Commit(scratch)
+	s.db.UpdateLatestBlock(block)
+
+	// Record the winners of any double spends before the competing versions
+	// are removed from the mempool with the transactions in this block.
+	s.settleDoubleSpends(block)
+
+	// Remove the transactions in this block from the mempool and age the
+	// ones that remain, evicting any that have waited too long.
+	for _, tx := range values {
+		s.mempool.Delete(tx)
+	}
+	s.mempool.AgeBlocks()
+	s.EvictExpiredTxs()
+
+	s.log("state: validateUpdateDatabase: write receipts, state diff and fees")
+
+	// The block has been applied so a failure to store the receipts is
+	// logged and not treated as a failure to process the block.
+	if err := s.db.WriteReceipts(block.Header.Number, receipts); err != nil {
+		s.log("state: validateUpdateDatabase: write receipts", "blk", block.Header.Number, "ERROR", err)
+	}
+	if err := s.db.WriteStateDiff(diff); err != nil {
+		s.log("state: validateUpdateDatabase: write state diff", "blk", block.Header.Number, "ERROR", err)
+	}
+	if err := s.db.WriteFees(database.NewBlockFees(block, receipts)); err != nil {
+		s.log("state: validateUpdateDatabase: write fees", "blk", block.Header.Number, "ERROR", err)
+	}
+
+	// Notify about any watched addresses found in this block.
+	s.watchBlock(block)
+
+	return nil
+}
+
+// validateBlock checks the block against the consensus rules and recovers
+// the signers of the transactions, which are cached for applying the block.
+// The caller must hold the lock.
+func (s *State) validateBlock(ctx context.Context, block database.Block) (err error) {
+	ctx, span := s.tracer.Start(ctx, "state.validateBlock", tracing.Int("blk", int64(block.Header.Number)))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	if err := block.ValidateBlock(s.db.LatestBlock(), s.db.StateRoots(), s.log); err != nil {
+		return err
+	}
+
+	if err := block.ValidateTimestamp(s.db.LatestBlock(), s.db.MedianTimePast(), s.maxBlockDrift); err != nil {
+		return err
+	}
+
+	if err := block.ValidateLimits(s.genesis.TransPerBlock, s.genesis.MaxTxDataBytes); err != nil {
+		return err
+	}
+
+	// A signature that can't be recovered fails the transaction when the
+	// block is applied, not the block.
+	_, sigSpan := s.tracer.Start(ctx, "signature.Verify", tracing.Int("trans", int64(len(block.MerkleTree.Values()))))
+	for _, tx := range block.MerkleTree.Values() {
+		tx.FromAddress()
+	}
+	sigSpan.End()
+
+	return nil
+}
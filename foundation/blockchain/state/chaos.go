@@ -0,0 +1,73 @@
+package state
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Chaos represents the faults injected into the messages this node sends to
+// its peers. It's meant for test networks, so a class can watch forks and
+// reorgs happen on their own and check the node recovers from a partition.
+// The zero value injects no faults.
+type Chaos struct {
+	Latency     time.Duration            // Added before every request to a peer.
+	Jitter      time.Duration            // Random latency up to this is added on top.
+	DropRate    float64                  // Fraction of gossip messages dropped, 0 to 1.
+	BlockDelays map[string]time.Duration // Delay proposing blocks to the peer host.
+}
+
+// Enabled reports if any fault is injected.
+func (c Chaos) Enabled() bool {
+	return c.Latency > 0 || c.Jitter > 0 || c.DropRate > 0 || len(c.BlockDelays) > 0
+}
+
+// =============================================================================
+
+// chaos injects the configured faults. The random source is shared by the
+// goroutines sending to peers so it's protected by a mutex.
+type chaos struct {
+	cfg Chaos
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// newChaos constructs the fault injection for the configuration.
+func newChaos(cfg Chaos) *chaos {
+	return &chaos{
+		cfg: cfg,
+		rnd: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// delay waits the latency configured for a request to a peer.
+func (c *chaos) delay() {
+	latency := c.cfg.Latency
+	if c.cfg.Jitter > 0 {
+		c.mu.Lock()
+		latency += time.Duration(c.rnd.Int63n(int64(c.cfg.Jitter)))
+		c.mu.Unlock()
+	}
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+}
+
+// drop reports if the gossip message should be dropped.
+func (c *chaos) drop() bool {
+	if c.cfg.DropRate <= 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.rnd.Float64() < c.cfg.DropRate
+}
+
+// blockDelay returns how long to wait before proposing a block to the peer.
+func (c *chaos) blockDelay(host string) time.Duration {
+	return c.cfg.BlockDelays[host]
+}
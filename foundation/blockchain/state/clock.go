@@ -0,0 +1,60 @@
+package state
+
+import (
+	"sort"
+	"time"
+)
+
+// clockWarnOffset is how far the clock of this node can be from the median
+// clock of its peers before a warning is logged. Blocks mined by a node
+// with a clock that is off can be rejected by its peers.
+const clockWarnOffset = 30 * time.Second
+
+// ClockOffset returns the median difference between the clocks of the
+// peers and the clock of this node. A positive offset means the peers are
+// ahead of this node.
+func (s *State) ClockOffset() time.Duration {
+	s.clockMu.Lock()
+	defer s.clockMu.Unlock()
+
+	return s.medianClockOffset()
+}
+
+// =============================================================================
+
+// checkClock records the difference between the clock of the peer and the
+// clock of this node. A warning is logged when this node disagrees with the
+// median clock of its peers, and again once it agrees.
+func (s *State) checkClock(host string, offset time.Duration) {
+	s.clockMu.Lock()
+	defer s.clockMu.Unlock()
+
+	s.clockOffsets[host] = offset
+
+	median := s.medianClockOffset()
+	skewed := median > clockWarnOffset || median < -clockWarnOffset
+
+	switch {
+	case skewed && !s.clockWarned:
+		s.log("state: checkClock: WARNING: local clock disagrees with peers", "offset", median, "peers", len(s.clockOffsets))
+	case !skewed && s.clockWarned:
+		s.log("state: checkClock: local clock agrees with peers", "offset", median, "peers", len(s.clockOffsets))
+	}
+	s.clockWarned = skewed
+}
+
+// medianClockOffset returns the median of the clock offsets of the peers.
+// The caller must hold the clock lock.
+func (s *State) medianClockOffset() time.Duration {
+	if len(s.clockOffsets) == 0 {
+		return 0
+	}
+
+	offsets := make([]time.Duration, 0, len(s.clockOffsets))
+	for _, offset := range s.clockOffsets {
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	return offsets[len(offsets)/2]
+}
@@ -0,0 +1,235 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// maxDoubleSpends is the number of double spends remembered for the report.
+const maxDoubleSpends = 1000
+
+// Set of sources a version of a double spend can be seen from.
+const (
+	SourceWallet  = "wallet"  // Submitted to this node by a wallet.
+	SourcePeer    = "peer"    // Shared with this node by a peer.
+	SourceBlock   = "block"   // Found in a block.
+	SourceMempool = "mempool" // Waiting in the mempool when a competing version arrived.
+)
+
+// Set of outcomes of a double spend.
+const (
+	DoubleSpendPending    = "pending"    // A version is waiting in the mempool.
+	DoubleSpendMined      = "mined"      // A version was mined into the chain.
+	DoubleSpendUnresolved = "unresolved" // No version is pending or found in the recent blocks.
+)
+
+// SpendVersion represents one of the competing transactions of a double
+// spend as this node first saw it.
+type SpendVersion struct {
+	Hash   string             `json:"hash"`
+	ToID   database.AccountID `json:"to"`
+	Value  uint64             `json:"value"`
+	Tip    uint64             `json:"tip"`
+	Source string             `json:"source"`
+	SeenAt uint64             `json:"seen_at"`
+}
+
+// DoubleSpend represents transactions from the same account with the same
+// nonce but different hashes. Only one of them can ever be mined.
+type DoubleSpend struct {
+	FromID      database.AccountID `json:"from"`
+	Nonce       uint64             `json:"nonce"`
+	Versions    []SpendVersion     `json:"versions"`
+	Status      string             `json:"status"`
+	Winner      string             `json:"winner,omitempty"`
+	BlockNumber uint64             `json:"block_number,omitempty"`
+}
+
+// DoubleSpendReport returns the double spends this node has seen, oldest
+// first, with the versions that competed and the version that won. A
+// version mined into the chain won, otherwise the version waiting in the
+// mempool is winning. Double spends that aren't settled are looked for in
+// the specified number of recent blocks, which finds a version mined
+// before the competing one arrived.
+func (s *State) DoubleSpendReport(recentBlocks uint64) []DoubleSpend {
+	s.spendMu.Lock()
+	defer s.spendMu.Unlock()
+
+	open := make(map[string]*DoubleSpend)
+	for _, key := range s.spendOrder {
+		if ds := s.doubleSpends[key]; ds.Status != DoubleSpendMined {
+			open[key] = ds
+		}
+	}
+
+	// Search the recent blocks, newest first, for the unsettled double
+	// spends. The version found is recorded so it's only searched once.
+	latest := s.db.LatestBlock().Header.Number
+	for num := latest; num > 0 && latest-num < recentBlocks && len(open) > 0; num-- {
+		block, err := s.db.GetBlock(num)
+		if err != nil {
+			s.log("state: DoubleSpendReport: getblock", "blk", num, "ERROR", err)
+			break
+		}
+
+		for _, tx := range block.MerkleTree.Values() {
+			key := spendKey(tx.FromID, tx.Nonce)
+			if ds, exists := open[key]; exists {
+				ds.settle(tx, num, time.Now().UTC())
+				delete(open, key)
+			}
+		}
+	}
+
+	report := make([]DoubleSpend, 0, len(s.spendOrder))
+	for _, key := range s.spendOrder {
+		ds := *s.doubleSpends[key]
+
+		// A late copy of the transaction that was mined isn't a double spend.
+		if len(ds.Versions) < 2 {
+			continue
+		}
+		ds.Versions = append([]SpendVersion(nil), ds.Versions...)
+
+		if ds.Status != DoubleSpendMined {
+			ds.Status = DoubleSpendUnresolved
+			ds.Winner = ""
+			if pending, exists := s.mempool.Pending(ds.FromID, ds.Nonce); exists {
+				ds.Status = DoubleSpendPending
+				ds.Winner = pending.TxHash()
+			}
+		}
+
+		report = append(report, ds)
+	}
+
+	return report
+}
+
+// =============================================================================
+
+// trackDoubleSpend records the transaction as a double spend when the
+// mempool holds a different transaction from the same account with the
+// same nonce, or a transaction with the nonce was already mined. The mined
+// transaction is looked for in the recent blocks by the report.
+func (s *State) trackDoubleSpend(tx database.BlockTx, source string) {
+	var confirmed uint64
+	if account, err := s.db.Query(tx.FromID); err == nil {
+		confirmed = account.Nonce
+	}
+
+	pending, exists := s.mempool.Pending(tx.FromID, tx.Nonce)
+	switch {
+	case exists && pending.TxHash() != tx.TxHash():
+	case !exists && tx.Nonce <= confirmed:
+	default:
+		return
+	}
+
+	now := time.Now().UTC()
+
+	s.spendMu.Lock()
+	defer s.spendMu.Unlock()
+
+	ds := s.doubleSpend(tx.FromID, tx.Nonce)
+	if exists {
+		ds.add(pending, SourceMempool, time.UnixMilli(int64(pending.TimeStamp)))
+	}
+	ds.add(tx, source, now)
+
+	s.log("state: trackDoubleSpend: detected", "from", tx.FromID, "nonce", tx.Nonce, "versions", len(ds.Versions), "source", source)
+}
+
+// settleDoubleSpends records the transactions of the block as the winners
+// of the double spends they are part of. A transaction in the block that
+// replaces a different pending transaction is a double spend this node
+// learns about from the block.
+func (s *State) settleDoubleSpends(block database.Block) {
+	now := time.Now().UTC()
+
+	s.spendMu.Lock()
+	defer s.spendMu.Unlock()
+
+	for _, tx := range block.MerkleTree.Values() {
+		key := spendKey(tx.FromID, tx.Nonce)
+
+		ds, tracked := s.doubleSpends[key]
+		if !tracked {
+			pending, exists := s.mempool.Pending(tx.FromID, tx.Nonce)
+			if !exists || pending.TxHash() == tx.TxHash() {
+				continue
+			}
+
+			ds = s.doubleSpend(tx.FromID, tx.Nonce)
+			ds.add(pending, SourceMempool, time.UnixMilli(int64(pending.TimeStamp)))
+
+			s.log("state: settleDoubleSpends: detected", "from", tx.FromID, "nonce", tx.Nonce, "blk", block.Header.Number)
+		}
+
+		ds.settle(tx, block.Header.Number, now)
+	}
+}
+
+// doubleSpend returns the double spend for the account and nonce, starting
+// to track it if it's new. The oldest double spend is forgotten once there
+// are too many to remember. The caller must hold the lock.
+func (s *State) doubleSpend(fromID database.AccountID, nonce uint64) *DoubleSpend {
+	key := spendKey(fromID, nonce)
+	if ds, exists := s.doubleSpends[key]; exists {
+		return ds
+	}
+
+	ds := DoubleSpend{
+		FromID:   fromID,
+		Nonce:    nonce,
+		Versions: []SpendVersion{},
+	}
+	s.doubleSpends[key] = &ds
+	s.spendOrder = append(s.spendOrder, key)
+
+	for len(s.spendOrder) > maxDoubleSpends {
+		delete(s.doubleSpends, s.spendOrder[0])
+		s.spendOrder = s.spendOrder[1:]
+	}
+
+	return &ds
+}
+
+// add records the version of the double spend if it hasn't been seen.
+func (ds *DoubleSpend) add(tx database.BlockTx, source string, seenAt time.Time) {
+	hash := tx.TxHash()
+	for _, v := range ds.Versions {
+		if v.Hash == hash {
+			return
+		}
+	}
+
+	ds.Versions = append(ds.Versions, SpendVersion{
+		Hash:   hash,
+		ToID:   tx.ToID,
+		Value:  tx.Value,
+		Tip:    tx.Tip,
+		Source: source,
+		SeenAt: uint64(seenAt.UnixMilli()),
+	})
+
+	sort.SliceStable(ds.Versions, func(i, j int) bool {
+		return ds.Versions[i].SeenAt < ds.Versions[j].SeenAt
+	})
+}
+
+// settle records the version mined into the block as the winner.
+func (ds *DoubleSpend) settle(tx database.BlockTx, blockNumber uint64, seenAt time.Time) {
+	ds.add(tx, SourceBlock, seenAt)
+	ds.Status = DoubleSpendMined
+	ds.Winner = tx.TxHash()
+	ds.BlockNumber = blockNumber
+}
+
+// spendKey returns the key double spends are tracked by.
+func spendKey(fromID database.AccountID, nonce uint64) string {
+	return fmt.Sprintf("%s:%d", fromID, nonce)
+}
@@ -0,0 +1,97 @@
+package state
+
+import "sort"
+
+// Set of values used to estimate tips.
+const (
+	estimateBlocks = 20 // Number of recent blocks sampled for the tips paid.
+	standardBlocks = 3  // Number of blocks the standard tip aims to be mined within.
+)
+
+// TipEstimate represents the tips a wallet can offer depending on how
+// quickly the transaction needs to be included in a block.
+type TipEstimate struct {
+	Low           uint64 // Likely to be included once the mempool clears.
+	Standard      uint64 // Likely to be included in the next 3 blocks.
+	Fast          uint64 // Likely to be included in the next block.
+	GasPrice      uint64 // Price of one unit of gas from the genesis file.
+	GasUnits      uint64 // Units of gas charged for a transaction.
+	MempoolDepth  int    // Number of transactions waiting in the mempool.
+	TransPerBlock uint16 // Max number of transactions in a block.
+	BlocksSampled int    // Number of recent blocks the tips were taken from.
+	TxsSampled    int    // Number of transactions in the sampled blocks.
+}
+
+// EstimateTip suggests the tips to offer based on the 25th, 50th and 90th
+// percentile of the tips included in recent blocks. When the mempool is
+// deeper than a block, the fast tip is raised to beat the transactions
+// that would be left out of the next block and the standard tip to beat
+// those left out of the next 3 blocks.
+func (s *State) EstimateTip() TipEstimate {
+	est := TipEstimate{
+		GasPrice:      s.genesis.GasPrice,
+		GasUnits:      oneUnitOfGas,
+		TransPerBlock: s.genesis.TransPerBlock,
+	}
+
+	latest := s.db.LatestBlock().Header.Number
+	from := uint64(1)
+	if latest > estimateBlocks {
+		from = latest - estimateBlocks + 1
+	}
+
+	var tips []uint64
+	if latest > 0 {
+		blocks := s.QueryBlocksByNumber(from, latest)
+		for _, block := range blocks {
+			for _, tx := range block.MerkleTree.Values() {
+				tips = append(tips, tx.Tip)
+			}
+		}
+		est.BlocksSampled = len(blocks)
+		est.TxsSampled = len(tips)
+	}
+
+	sort.Slice(tips, func(i, j int) bool { return tips[i] < tips[j] })
+	est.Low = percentile(tips, 25)
+	est.Standard = percentile(tips, 50)
+	est.Fast = percentile(tips, 90)
+
+	pending := s.mempool.PickBest()
+	est.MempoolDepth = len(pending)
+
+	pendingTips := make([]uint64, len(pending))
+	for i, tx := range pending {
+		pendingTips[i] = tx.Tip
+	}
+	sort.Slice(pendingTips, func(i, j int) bool { return pendingTips[i] > pendingTips[j] })
+
+	// Beat the tip of the last transaction that fits in the blocks the
+	// estimate is aiming for.
+	n := int(s.genesis.TransPerBlock)
+	if n > 0 && len(pendingTips) >= n {
+		if cutoff := pendingTips[n-1] + 1; est.Fast < cutoff {
+			est.Fast = cutoff
+		}
+	}
+	if n > 0 && len(pendingTips) >= n*standardBlocks {
+		if cutoff := pendingTips[n*standardBlocks-1] + 1; est.Standard < cutoff {
+			est.Standard = cutoff
+		}
+	}
+
+	if est.Fast < est.Standard {
+		est.Fast = est.Standard
+	}
+
+	return est
+}
+
+// percentile returns the value at the percentile of the sorted values.
+func percentile(sorted []uint64, p int) uint64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	return sorted[(len(sorted)-1)*p/100]
+}
@@ -0,0 +1,147 @@
+package state
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// Set of event types emitted by the state package.
+const (
+	EventTxAccepted    = "tx_accepted"
+	EventMiningStarted = "mining_started"
+	EventBlockMined    = "block_mined"
+	EventBlockReceived = "block_received"
+	EventReorg         = "reorg"
+	EventAddressWatch  = "address_watch"
+	EventChainSplit    = "chain_split"
+	EventTxEvicted     = "tx_evicted"
+	EventTxReverted    = "tx_reverted"
+)
+
+// subscriberBuffer is the number of events that can be queued for a
+// subscriber before new events are dropped for that subscriber.
+const subscriberBuffer = 100
+
+// Event represents something that happened in the processing of
+// transactions and blocks that clients may want to know about.
+type Event struct {
+	Type      string `json:"type"`
+	TimeStamp uint64 `json:"timestamp"`
+	Data      any    `json:"data,omitempty"`
+}
+
+// =============================================================================
+
+// EventBus maintains the set of subscribers interested in state events.
+type EventBus struct {
+	mu   sync.RWMutex
+	subs map[string]chan Event
+}
+
+// NewEventBus constructs an event bus for use.
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subs: make(map[string]chan Event),
+	}
+}
+
+// Subscribe registers a subscriber by the specified id and returns the
+// channel events will be delivered on.
+func (eb *EventBus) Subscribe(id string) <-chan Event {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	if ch, exists := eb.subs[id]; exists {
+		return ch
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	eb.subs[id] = ch
+
+	return ch
+}
+
+// Unsubscribe removes the subscriber and closes its channel.
+func (eb *EventBus) Unsubscribe(id string) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	ch, exists := eb.subs[id]
+	if !exists {
+		return
+	}
+
+	delete(eb.subs, id)
+	close(ch)
+}
+
+// Publish sends the event to every subscriber. A subscriber that is not
+// keeping up will miss the event instead of blocking the blockchain.
+func (eb *EventBus) Publish(typ string, data any) {
+	evt := Event{
+		Type:      typ,
+		TimeStamp: uint64(time.Now().UTC().UnixMilli()),
+		Data:      data,
+	}
+
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+
+	for _, ch := range eb.subs {
+		select {
+		case ch <- evt:
+		default:
+		}
+	}
+}
+
+// Shutdown closes all the subscriber channels.
+func (eb *EventBus) Shutdown() {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	for id, ch := range eb.subs {
+		delete(eb.subs, id)
+		close(ch)
+	}
+}
+
+// =============================================================================
+
+// blockEvent is the data provided with block related events.
+type blockEvent struct {
+	Number   uint64 `json:"number"`
+	Hash     string `json:"hash,omitempty"`
+	NumTrans int    `json:"num_trans"`
+}
+
+// newBlockEvent constructs the event data for the specified block.
+func newBlockEvent(block database.Block) blockEvent {
+	return blockEvent{
+		Number:   block.Header.Number,
+		Hash:     block.Hash(),
+		NumTrans: len(block.MerkleTree.Values()),
+	}
+}
+
+// =============================================================================
+
+// Set of roles a watched address can play in an applied block.
+const (
+	RoleFrom        = "from"
+	RoleTo          = "to"
+	RoleBeneficiary = "beneficiary"
+)
+
+// AddressActivity is the data provided with the address watch event when a
+// watched address appears in an applied block.
+type AddressActivity struct {
+	AccountID   database.AccountID `json:"account"`
+	Role        string             `json:"role"`
+	BlockNumber uint64             `json:"block_number"`
+	BlockHash   string             `json:"block_hash"`
+	TxHash      string             `json:"tx_hash,omitempty"`
+	Value       uint64             `json:"value"`
+}
@@ -0,0 +1,114 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// maxEvictions is the number of evicted transactions remembered so senders
+// can find out what happened to their transaction.
+const maxEvictions = 1000
+
+// ErrTxNotEvicted is returned when no eviction is recorded for a transaction.
+var ErrTxNotEvicted = errors.New("transaction has not been evicted")
+
+// Eviction describes a transaction that was removed from the mempool
+// because it sat there unmined for too long or its window of blocks closed.
+type Eviction struct {
+	TxHash    string             `json:"tx_hash"`
+	FromID    database.AccountID `json:"from"`
+	Nonce     uint64             `json:"nonce"`
+	Tip       uint64             `json:"tip"`
+	Reason    string             `json:"reason"`
+	Blocks    uint64             `json:"blocks"`
+	EvictedAt uint64             `json:"evicted_at"`
+}
+
+// EvictExpiredTxs removes the transactions that have been in the mempool for
+// longer than the configured time to live, measured in time or in blocks,
+// and the transactions that can no longer be mined because the window of
+// blocks they were signed for has closed. An event is published for every
+// transaction that is evicted.
+func (s *State) EvictExpiredTxs() []Eviction {
+	now := time.Now().UTC()
+	nextNumber := s.db.LatestBlock().Header.Number + 1
+	var evictions []Eviction
+
+	expired := func(tx database.BlockTx, blocks uint64) bool {
+		age := now.Sub(time.UnixMilli(int64(tx.TimeStamp)))
+
+		var reason string
+		switch {
+		case errors.Is(tx.ValidAt(nextNumber), database.ErrTxExpired):
+			reason = fmt.Sprintf("not mined by block %d, the last block it can be mined in", tx.NotAfter)
+		case s.txTTL > 0 && age > s.txTTL:
+			reason = fmt.Sprintf("not mined within %v, the tip may be too low or a nonce before %d is missing", s.txTTL, tx.Nonce)
+		case s.txTTLBlocks > 0 && blocks >= s.txTTLBlocks:
+			reason = fmt.Sprintf("not mined within %d blocks, the tip may be too low or a nonce before %d is missing", s.txTTLBlocks, tx.Nonce)
+		default:
+			return false
+		}
+
+		ev := Eviction{
+			TxHash:    tx.TxHash(),
+			FromID:    tx.FromID,
+			Nonce:     tx.Nonce,
+			Tip:       tx.Tip,
+			Reason:    reason,
+			Blocks:    blocks,
+			EvictedAt: uint64(now.UnixMilli()),
+		}
+		evictions = append(evictions, ev)
+
+		return true
+	}
+
+	s.mempool.Evict(expired)
+	if len(evictions) == 0 {
+		return nil
+	}
+	sort.Slice(evictions, func(i, j int) bool { return evictions[i].TxHash < evictions[j].TxHash })
+
+	s.evictMu.Lock()
+	{
+		for _, ev := range evictions {
+			if _, exists := s.evictions[ev.TxHash]; !exists {
+				s.evictOrder = append(s.evictOrder, ev.TxHash)
+			}
+			s.evictions[ev.TxHash] = ev
+
+			s.log("state: EvictExpiredTxs: evicted", "tx", ev.TxHash, "from", ev.FromID, "nonce", ev.Nonce, "reason", ev.Reason)
+		}
+
+		// Forget the oldest evictions once there are too many to remember.
+		for len(s.evictOrder) > maxEvictions {
+			delete(s.evictions, s.evictOrder[0])
+			s.evictOrder = s.evictOrder[1:]
+		}
+	}
+	s.evictMu.Unlock()
+
+	for _, ev := range evictions {
+		s.events.Publish(EventTxEvicted, ev)
+	}
+
+	return evictions
+}
+
+// QueryEviction returns the eviction recorded for the transaction with the
+// specified hash.
+func (s *State) QueryEviction(txHash string) (Eviction, error) {
+	s.evictMu.RLock()
+	defer s.evictMu.RUnlock()
+
+	ev, exists := s.evictions[txHash]
+	if !exists {
+		return Eviction{}, ErrTxNotEvicted
+	}
+
+	return ev, nil
+}
@@ -0,0 +1,63 @@
+package state
+
+import "fmt"
+
+// Readiness represents the checks that decide if the node is ready to serve
+// requests. A node that is still catching up with its peers would answer
+// queries with stale state.
+type Readiness struct {
+	Storage string `json:"storage"`
+	Genesis string `json:"genesis"`
+	Sync    string `json:"sync"`
+}
+
+// Ready reports if every check passed.
+func (r Readiness) Ready() bool {
+	return r.Storage == "ok" && r.Genesis == "ok" && r.Sync == "ok"
+}
+
+// Readiness runs the checks that decide if the node is ready to serve
+// requests. The storage must be able to return the latest block, the
+// genesis settings must be loaded and the node must have finished syncing
+// with its peers.
+func (s *State) Readiness() Readiness {
+	r := Readiness{
+		Storage: "ok",
+		Genesis: "ok",
+		Sync:    "ok",
+	}
+
+	if latest := s.db.LatestBlock().Header.Number; latest > 0 {
+		if _, err := s.db.GetBlock(latest); err != nil {
+			r.Storage = fmt.Sprintf("unable to read block %d: %s", latest, err)
+		}
+	}
+
+	if s.genesis.ChainID == 0 {
+		r.Genesis = "genesis not loaded"
+	}
+
+	if !s.IsSynced() {
+		r.Sync = "syncing with peers"
+	}
+
+	return r
+}
+
+// IsSynced reports if the node has finished syncing with its peers, either
+// when it started or after a resync.
+func (s *State) IsSynced() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.synced
+}
+
+// MarkSynced records the node has finished its initial sync with its peers.
+func (s *State) MarkSynced() {
+	s.mu.Lock()
+	s.synced = true
+	s.mu.Unlock()
+
+	s.log("state: MarkSynced: node is synced with its peers")
+}
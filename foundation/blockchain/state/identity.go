@@ -0,0 +1,83 @@
+package state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+// PeerStatus returns the status of this node signed by its account. The
+// nonce provided by the peer asking is signed with the status.
+func (s *State) PeerStatus(nonce string) (peer.PeerStatus, error) {
+	latestBlock := s.db.LatestBlock()
+
+	status := peer.PeerStatus{
+		LatestBlockHash:   latestBlock.Hash(),
+		LatestBlockNumber: latestBlock.Header.Number,
+		KnownPeers:        s.KnownExternalPeers(),
+		Host:              s.host,
+		AccountID:         string(s.signer.AccountID()),
+		Time:              uint64(time.Now().UTC().UnixMilli()),
+		Nonce:             nonce,
+	}
+
+	sig, err := s.signer.SignValue(status.SignData())
+	if err != nil {
+		return peer.PeerStatus{}, fmt.Errorf("signing status: %w", err)
+	}
+	status.Sig = sig
+
+	return status, nil
+}
+
+// PeerIdentities returns the account bound to each peer host.
+func (s *State) PeerIdentities() map[string]database.AccountID {
+	s.identMu.RLock()
+	defer s.identMu.RUnlock()
+
+	identities := make(map[string]database.AccountID, len(s.identities))
+	for host, accountID := range s.identities {
+		identities[host] = accountID
+	}
+
+	return identities
+}
+
+// =============================================================================
+
+// bindPeerIdentity checks the account that signed a status from the host is
+// the account bound to the host. The account is bound the first time a host
+// is heard from unless the operator configured the account for the host, so
+// once a peer is known no other node can speak for its host.
+func (s *State) bindPeerIdentity(host string, accountID database.AccountID) error {
+	s.identMu.Lock()
+	defer s.identMu.Unlock()
+
+	bound, exists := s.identities[host]
+	if !exists {
+		s.log("state: bindPeerIdentity: bound", "peer", host, "account", accountID)
+		s.identities[host] = accountID
+		return nil
+	}
+
+	if !strings.EqualFold(string(bound), string(accountID)) {
+		return fmt.Errorf("peer %s is bound to account %s, status signed by %s", host, bound, accountID)
+	}
+
+	return nil
+}
+
+// newNonce returns a random value the peer must sign with its status.
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
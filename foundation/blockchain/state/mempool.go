@@ -0,0 +1,241 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/tracing"
+)
+
+// oneUnitOfGas is the gas charged for every wallet transaction.
+const oneUnitOfGas = 1
+
+// ErrDuplicateTx is returned when a wallet submits a transaction that is
+// already pending in the mempool.
+var ErrDuplicateTx = errors.New("transaction is already pending in the mempool")
+
+// MempoolLength returns the current length of the mempool.
+func (s *State) MempoolLength() int {
+	return s.mempool.Count()
+}
+
+// Mempool returns a copy of the mempool.
+func (s *State) Mempool() []database.BlockTx {
+	return s.mempool.PickBest()
+}
+
+// MempoolHashes returns the hashes of the transactions in the mempool.
+func (s *State) MempoolHashes() []string {
+	return s.mempool.Hashes()
+}
+
+// MempoolByHashes returns the transactions in the mempool that match the
+// specified hashes.
+func (s *State) MempoolByHashes(hashes []string) []database.BlockTx {
+	return s.mempool.FindByHashes(hashes)
+}
+
+// TruncateMempool removes all the transactions from the mempool.
+func (s *State) TruncateMempool() {
+	s.mempool.Truncate()
+	s.log("state: TruncateMempool: mempool truncated")
+}
+
+// UpsertMempool adds a new transaction to the mempool.
+func (s *State) UpsertMempool(tx database.BlockTx) error {
+	return s.mempool.Upsert(tx)
+}
+
+// UpsertWalletTransaction accepts a transaction from a wallet for inclusion.
+func (s *State) UpsertWalletTransaction(ctx context.Context, signedTx database.SignedTx) (err error) {
+	ctx, span := s.tracer.Start(ctx, "state.UpsertWalletTransaction", tracing.String("tx", signedTx.TxHash()))
+	defer func() {
+		countTx(err)
+		span.RecordError(err)
+		span.End()
+	}()
+
+	// CORE NOTE: Just check the signed transaction has a proper signature and
+	// valid account for the recipient. It's up to the wallet to make sure the
+	// account has a proper balance and nonce. Fees will be taken if this
+	// transaction is mined into a block it doesn't have enough money to pay
+	// or the nonce isn't the next expected nonce for the account.
+
+	// Check the signed transaction has a proper signature, the from matches the
+	// signature, and the from and to fields are properly formatted.
+	if err := s.validateSignedTx(ctx, signedTx); err != nil {
+		return err
+	}
+
+	// Reject data that could never be mined into a block.
+	if err := signedTx.ValidateDataSize(s.genesis.MaxTxDataBytes); err != nil {
+		return err
+	}
+
+	// Check the signers of a multisig transaction against the participants
+	// registered for the account.
+	if err := s.db.Authorize(signedTx); err != nil {
+		return err
+	}
+
+	// On a permissioned chain only accounts the policy allows can transact.
+	if err := s.db.Permit(signedTx); err != nil {
+		return err
+	}
+
+	// Don't allow the wallet to queue transactions too far into the future.
+	if err := s.validateNonceWindow(signedTx); err != nil {
+		return err
+	}
+
+	// A transaction whose window of blocks has closed can never be mined.
+	if err := s.validateTxWindow(signedTx); err != nil {
+		return err
+	}
+
+	tx := database.NewBlockTx(signedTx, s.genesis.GasPrice, oneUnitOfGas)
+
+	// A transaction competing with another for the same nonce is recorded
+	// even when it's rejected, so the double spend can be reported.
+	s.trackDoubleSpend(tx, SourceWallet)
+
+	// The same transaction submitted again isn't shared a second time. A
+	// transaction replacing a pending one must pay a higher tip, which the
+	// mempool decides with the same rule used for transactions gossiped by
+	// peers.
+	if pending, exists := s.mempool.Pending(signedTx.FromID, signedTx.Nonce); exists && pending.TxHash() == signedTx.TxHash() {
+		return ErrDuplicateTx
+	}
+
+	if err := s.upsertMempool(ctx, tx); err != nil {
+		return err
+	}
+
+	s.log("state: UpsertWalletTransaction: accepted", "tx", tx)
+	s.events.Publish(EventTxAccepted, tx)
+
+	s.Worker.SignalShareTx(ctx, tx)
+	s.Worker.SignalStartMining()
+
+	return nil
+}
+
+// UpsertNodeTransaction accepts a transaction from a node for inclusion.
+func (s *State) UpsertNodeTransaction(ctx context.Context, tx database.BlockTx) (err error) {
+	ctx, span := s.tracer.Start(ctx, "state.UpsertNodeTransaction", tracing.String("tx", tx.TxHash()))
+	defer func() {
+		countTx(err)
+		span.RecordError(err)
+		span.End()
+	}()
+
+	// Check the signed transaction has a proper signature, the from matches the
+	// signature, and the from and to fields are properly formatted.
+	if err := s.validateSignedTx(ctx, tx.SignedTx); err != nil {
+		return err
+	}
+
+	if err := tx.ValidateDataSize(s.genesis.MaxTxDataBytes); err != nil {
+		return err
+	}
+
+	if err := s.db.Authorize(tx.SignedTx); err != nil {
+		return err
+	}
+
+	if err := s.db.Permit(tx.SignedTx); err != nil {
+		return err
+	}
+
+	// Peers could be configured with a larger window so check it here too.
+	if err := s.validateNonceWindow(tx.SignedTx); err != nil {
+		return err
+	}
+
+	if err := s.validateTxWindow(tx.SignedTx); err != nil {
+		return err
+	}
+
+	s.trackDoubleSpend(tx, SourcePeer)
+
+	if err := s.upsertMempool(ctx, tx); err != nil {
+		return err
+	}
+
+	s.log("state: UpsertNodeTransaction: accepted", "tx", tx)
+	s.events.Publish(EventTxAccepted, tx)
+
+	s.Worker.SignalStartMining()
+
+	return nil
+}
+
+// =============================================================================
+
+// validateSignedTx checks the signature and fields of the transaction.
+func (s *State) validateSignedTx(ctx context.Context, signedTx database.SignedTx) error {
+	_, span := s.tracer.Start(ctx, "signature.Verify")
+	defer span.End()
+
+	err := signedTx.Validate(s.genesis.ChainID)
+	span.RecordError(err)
+
+	return err
+}
+
+// upsertMempool adds the transaction to the mempool.
+func (s *State) upsertMempool(ctx context.Context, tx database.BlockTx) error {
+	_, span := s.tracer.Start(ctx, "mempool.Upsert")
+	defer span.End()
+
+	err := s.mempool.Upsert(tx)
+	span.RecordError(err)
+
+	return err
+}
+
+// countTx records if a transaction was accepted or rejected.
+func countTx(err error) {
+	if err != nil {
+		txRejected.Inc()
+		return
+	}
+	txAccepted.Inc()
+}
+
+// validateNonceWindow checks the nonce of the transaction is not further
+// ahead of the account's current nonce than the configured window. These
+// transactions can't be mined until the gap is filled, so allowing them
+// would let an attacker consume mempool memory. A window of 0 turns off
+// the check.
+func (s *State) validateNonceWindow(signedTx database.SignedTx) error {
+	if s.nonceWindow == 0 {
+		return nil
+	}
+
+	// An account the database has never seen has a nonce of 0.
+	var current uint64
+	if account, err := s.db.Query(signedTx.FromID); err == nil {
+		current = account.Nonce
+	}
+
+	if signedTx.Nonce > current+s.nonceWindow {
+		return fmt.Errorf("%w, nonce %d is too far in the future, account nonce %d, window %d", database.ErrBadNonce, signedTx.Nonce, current, s.nonceWindow)
+	}
+
+	return nil
+}
+
+// validateTxWindow checks the window of blocks the transaction can be mined
+// in hasn't closed. A transaction whose window hasn't opened yet is accepted
+// and waits in the mempool until it can be mined.
+func (s *State) validateTxWindow(signedTx database.SignedTx) error {
+	nextNumber := s.db.LatestBlock().Header.Number + 1
+	if err := signedTx.ValidAt(nextNumber); errors.Is(err, database.ErrTxExpired) {
+		return err
+	}
+
+	return nil
+}
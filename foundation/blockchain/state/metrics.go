@@ -0,0 +1,54 @@
+package state
+
+import (
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/metrics"
+)
+
+// Set of metrics describing the behavior of the node. They are registered
+// with the default metrics registry which is exposed by the debug service.
+var (
+	txAccepted      = metrics.NewCounter("blockchain_tx_accepted_total", "Transactions accepted into the mempool.")
+	txRejected      = metrics.NewCounter("blockchain_tx_rejected_total", "Transactions rejected from the mempool.")
+	blocksMined     = metrics.NewCounter("blockchain_blocks_mined_total", "Blocks mined by this node.")
+	blocksReceived  = metrics.NewCounter("blockchain_blocks_received_total", "Blocks received from peers and applied.")
+	powHashes       = metrics.NewCounter("blockchain_pow_hashes_total", "Hashes performed while mining.")
+	powHashRate     = metrics.NewGauge("blockchain_pow_hash_rate", "Hashes per second of the last mining operation.")
+	miningDuration  = metrics.NewHistogram("blockchain_mining_duration_seconds", "Time taken to mine a block.", []float64{.1, .5, 1, 5, 10, 30, 60, 120, 300, 600})
+	peerRequestTime = metrics.NewHistogram("blockchain_peer_request_duration_seconds", "Latency of requests made to peers.", nil)
+	peersBanned     = metrics.NewCounter("blockchain_peers_banned_total", "Peers banned for misbehaving.")
+)
+
+// registerMetrics registers the metrics that are read from the state when
+// they are scraped.
+func (s *State) registerMetrics() {
+	metrics.NewGaugeFunc("blockchain_mempool_depth", "Transactions in the mempool.", func() float64 {
+		return float64(s.mempool.Count())
+	})
+
+	metrics.NewGaugeFunc("blockchain_latest_block", "Number of the latest block.", func() float64 {
+		return float64(s.db.LatestBlock().Header.Number)
+	})
+
+	metrics.NewGaugeFunc("blockchain_known_peers", "Peers known to this node.", func() float64 {
+		return float64(len(s.KnownExternalPeers()))
+	})
+
+	metrics.NewGaugeFunc("blockchain_sig_cache_hits", "Signatures found in the signature cache.", func() float64 {
+		hits, _ := database.SignatureCacheStats()
+		return float64(hits)
+	})
+
+	metrics.NewGaugeFunc("blockchain_sig_cache_misses", "Signatures recovered and added to the signature cache.", func() float64 {
+		_, misses := database.SignatureCacheStats()
+		return float64(misses)
+	})
+
+	metrics.NewGaugeFunc("blockchain_sig_cache_hit_rate", "Fraction of signature lookups found in the signature cache.", func() float64 {
+		hits, misses := database.SignatureCacheStats()
+		if hits+misses == 0 {
+			return 0
+		}
+		return float64(hits) / float64(hits+misses)
+	})
+}
@@ -0,0 +1,112 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/tracing"
+)
+
+// ErrNoTransactions is returned when a block is requested to be created
+// and there are not enough transactions that can be mined into it.
+var ErrNoTransactions = errors.New("no transactions in mempool")
+
+// MineNewBlock attempts to create a new block with a proper hash that can become
+// the next block in the chain.
+func (s *State) MineNewBlock(ctx context.Context) (block database.Block, err error) {
+	defer s.log("state: MineNewBlock: MINING: completed")
+
+	s.log("state: MineNewBlock: MINING: check mempool count")
+
+	// Are there enough transactions in the pool. A block without any is
+	// only mined when the chain has gone too long without a block.
+	emptyDue := s.EmptyBlockDue()
+	if s.mempool.Count() == 0 && !emptyDue {
+		return database.Block{}, ErrNoTransactions
+	}
+
+	s.log("state: MineNewBlock: MINING: create new block: pick", "trans", s.genesis.TransPerBlock)
+
+	// Pick the best transactions from the mempool that can be mined into
+	// the next block.
+	nextNumber := s.db.LatestBlock().Header.Number + 1
+	trans := s.mempool.PickBestAt(nextNumber, s.genesis.TransPerBlock)
+	if len(trans) == 0 {
+		if !emptyDue {
+			return database.Block{}, ErrNoTransactions
+		}
+		s.log("state: MineNewBlock: MINING: empty block due", "blk", nextNumber)
+	}
+
+	// The trace only starts once there is a block to mine, so the worker
+	// checking an empty mempool doesn't produce a trace.
+	ctx, span := s.tracer.Start(ctx, "state.MineNewBlock", tracing.Int("blk", int64(nextNumber)), tracing.Int("trans", int64(len(trans))))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	s.log("state: MineNewBlock: MINING: perform POW")
+	s.events.Publish(EventMiningStarted, blockEvent{
+		Number:   s.db.LatestBlock().Header.Number + 1,
+		NumTrans: len(trans),
+	})
+
+	// Keep track of the work performed to report the hash rate.
+	var hashes uint64
+	start := time.Now()
+
+	// Attempt to create a new block by solving the POW puzzle. This can be cancelled.
+	powCtx, powSpan := s.tracer.Start(ctx, "database.POW", tracing.Int("difficulty", int64(s.genesis.Difficulty)))
+	block, err = database.POW(powCtx, database.POWArgs{
+		BeneficiaryID: s.beneficiaryID,
+		Beneficiaries: s.beneficiaries,
+		Difficulty:    s.genesis.Difficulty,
+		MiningReward:  s.genesis.MiningReward,
+		PrevBlock:     s.db.LatestBlock(),
+		MedianTime:    s.db.MedianTimePast(),
+		StateRoot:     s.db.HashState(),
+		AccountsRoot:  s.db.AccountsRoot(),
+		Trans:         trans,
+		Workers:       s.miningWorkers,
+		Pool:          s.miningPool,
+		Log:           s.log,
+		Hashes: func(n uint64) {
+			hashes += n
+			powHashes.Add(n)
+		},
+	})
+
+	duration := time.Since(start)
+	if seconds := duration.Seconds(); seconds > 0 {
+		powHashRate.Set(float64(hashes) / seconds)
+	}
+
+	powSpan.SetAttributes(tracing.Int("hashes", int64(hashes)))
+	powSpan.RecordError(err)
+	powSpan.End()
+
+	if err != nil {
+		return database.Block{}, err
+	}
+
+	// Just check one more time we were not cancelled.
+	if ctx.Err() != nil {
+		return database.Block{}, ctx.Err()
+	}
+
+	s.log("state: MineNewBlock: MINING: validate and update database")
+
+	// Validate the block and then update the blockchain database.
+	if err := s.validateUpdateDatabase(ctx, block); err != nil {
+		return database.Block{}, err
+	}
+
+	blocksMined.Inc()
+	miningDuration.Observe(duration.Seconds())
+	s.events.Publish(EventBlockMined, newBlockEvent(block))
+
+	return block, nil
+}
@@ -0,0 +1,462 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/tracing"
+)
+
+// peerTimeout is the time allowed for a request made to a peer.
+const peerTimeout = 30 * time.Second
+
+// baseURL returns the base URL for the private node API of the peer. When
+// the peers talk over TLS, the URL uses https. A named chain is served under
+// its name by every node hosting it.
+func (s *State) baseURL(host string) string {
+	scheme := "http"
+	if s.peerTLS != nil {
+		scheme = "https"
+	}
+
+	if s.chainName != "" {
+		return fmt.Sprintf("%s://%s/%s/v1/node", scheme, host, s.chainName)
+	}
+
+	return fmt.Sprintf("%s://%s/v1/node", scheme, host)
+}
+
+// NetSendBlockToPeers takes the new mined block and sends it to all know peers.
+func (s *State) NetSendBlockToPeers(ctx context.Context, block database.Block) (err error) {
+	s.log("state: NetSendBlockToPeers: started")
+	defer s.log("state: NetSendBlockToPeers: completed")
+
+	ctx, span := s.tracer.Start(ctx, "state.NetSendBlockToPeers", tracing.Int("blk", int64(block.Header.Number)))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	for _, peer := range s.KnownExternalPeers() {
+		if s.chaos.drop() {
+			s.log("state: NetSendBlockToPeers: chaos: dropped", "blk", block.Header.Number, "peer", peer.Host)
+			continue
+		}
+
+		url := fmt.Sprintf("%s/block/propose", s.baseURL(peer.Host))
+
+		// A delayed peer is sent the block later so the other peers aren't
+		// held up waiting for it.
+		if delay := s.chaos.blockDelay(peer.Host); delay > 0 {
+			s.log("state: NetSendBlockToPeers: chaos: delayed", "blk", block.Header.Number, "peer", peer.Host, "delay", delay)
+
+			host := peer.Host
+			time.AfterFunc(delay, func() {
+				if err := s.proposeBlock(ctx, url, block); err != nil {
+					s.log("state: NetSendBlockToPeers: chaos: send", "peer", host, "ERROR", err)
+				}
+			})
+			continue
+		}
+
+		s.log("state: NetSendBlockToPeers: send: block to peer", "blk", block.Header.Number, "peer", peer.Host)
+
+		if err := s.proposeBlock(ctx, url, block); err != nil {
+			return fmt.Errorf("%s: %s", peer.Host, err)
+		}
+	}
+
+	return nil
+}
+
+// proposeBlock sends the block to the peer at the url.
+func (s *State) proposeBlock(ctx context.Context, url string, block database.Block) error {
+	var status struct {
+		Status string `json:"status"`
+	}
+
+	return s.send(ctx, http.MethodPost, url, database.NewBlockData(block), &status)
+}
+
+// NetSendTxToPeers shares a new block transaction with the known peers.
+func (s *State) NetSendTxToPeers(ctx context.Context, tx database.BlockTx) {
+	s.log("state: NetSendTxToPeers: started")
+	defer s.log("state: NetSendTxToPeers: completed")
+
+	ctx, span := s.tracer.Start(ctx, "state.NetSendTxToPeers", tracing.String("tx", tx.TxHash()))
+	defer span.End()
+
+	// CORE NOTE: Bitcoin does not send the full transaction immediately to save
+	// on bandwidth. A node will send the transaction's mempool key first so the
+	// receiving node can check if they already have the transaction or not. If
+	// the receiving node doesn't have it, then it will request the transaction
+	// based on the mempool key it received.
+
+	// For now, the Ardan blockchain just sends the full transaction.
+	for _, peer := range s.KnownExternalPeers() {
+		if s.chaos.drop() {
+			s.log("state: NetSendTxToPeers: chaos: dropped", "tx", tx, "peer", peer.Host)
+			continue
+		}
+
+		s.log("state: NetSendTxToPeers: send: tx to peer", "tx", tx, "peer", peer.Host)
+
+		url := fmt.Sprintf("%s/tx/submit", s.baseURL(peer.Host))
+
+		if err := s.send(ctx, http.MethodPost, url, tx, nil); err != nil {
+			s.log("state: NetSendTxToPeers: send", "peer", peer.Host, "ERROR", err)
+		}
+	}
+}
+
+// NetSendNodeAvailableToPeers shares this node is available to
+// participate in the network with the known peers.
+func (s *State) NetSendNodeAvailableToPeers() {
+	s.log("state: NetSendNodeAvailableToPeers: started")
+	defer s.log("state: NetSendNodeAvailableToPeers: completed")
+
+	host := peer.Peer{Host: s.Host()}
+
+	for _, peer := range s.KnownExternalPeers() {
+		if s.chaos.drop() {
+			s.log("state: NetSendNodeAvailableToPeers: chaos: dropped", "host", host, "peer", peer.Host)
+			continue
+		}
+
+		s.log("state: NetSendNodeAvailableToPeers: send", "host", host, "peer", peer.Host)
+
+		url := fmt.Sprintf("%s/peers", s.baseURL(peer.Host))
+
+		if err := s.send(context.Background(), http.MethodPost, url, host, nil); err != nil {
+			s.log("state: NetSendNodeAvailableToPeers: send", "peer", peer.Host, "ERROR", err)
+		}
+	}
+}
+
+// NetRequestPeerStatus looks for new nodes on the blockchain by asking
+// known nodes for their peer list. New nodes are added to the list. The
+// status must be signed by the account bound to the peer for the nonce sent
+// with the request, so another node can't answer for the peer.
+func (s *State) NetRequestPeerStatus(pr peer.Peer) (peer.PeerStatus, error) {
+	s.log("state: NetRequestPeerStatus: started", "peer", pr.Host)
+	defer s.log("state: NetRequestPeerStatus: completed", "peer", pr.Host)
+
+	nonce, err := newNonce()
+	if err != nil {
+		return peer.PeerStatus{}, err
+	}
+
+	url := fmt.Sprintf("%s/status?nonce=%s", s.baseURL(pr.Host), nonce)
+
+	start := time.Now()
+
+	var ps peer.PeerStatus
+	if err := s.send(context.Background(), http.MethodGet, url, nil, &ps); err != nil {
+		return peer.PeerStatus{}, err
+	}
+
+	// The peer read its clock somewhere during the round trip, so the
+	// middle of the round trip is compared to it.
+	sent := start.Add(time.Since(start) / 2)
+
+	if err := ps.Verify(pr.Host, nonce); err != nil {
+		return peer.PeerStatus{}, err
+	}
+
+	if err := s.bindPeerIdentity(pr.Host, database.AccountID(ps.AccountID)); err != nil {
+		return peer.PeerStatus{}, err
+	}
+
+	s.checkClock(pr.Host, time.UnixMilli(int64(ps.Time)).Sub(sent))
+
+	s.log("state: NetRequestPeerStatus: peer-node", "peer", pr.Host, "latestBlkNum", ps.LatestBlockNumber, "peerList", ps.KnownPeers)
+
+	return ps, nil
+}
+
+// NetRequestPeerMempool asks the peer for the transactions in their mempool.
+func (s *State) NetRequestPeerMempool(pr peer.Peer) ([]database.BlockTx, error) {
+	s.log("state: NetRequestPeerMempool: started", "peer", pr.Host)
+	defer s.log("state: NetRequestPeerMempool: completed", "peer", pr.Host)
+
+	url := fmt.Sprintf("%s/tx/list", s.baseURL(pr.Host))
+
+	var mempool []database.BlockTx
+	if err := s.send(context.Background(), http.MethodGet, url, nil, &mempool); err != nil {
+		return nil, err
+	}
+
+	s.log("state: NetRequestPeerMempool: len", "len", len(mempool))
+
+	return mempool, nil
+}
+
+// NetSyncPeerMempool asks the peer for the hashes of the transactions in
+// their mempool and then requests only the transactions this node is missing.
+func (s *State) NetSyncPeerMempool(pr peer.Peer) error {
+	s.log("state: NetSyncPeerMempool: started", "peer", pr.Host)
+	defer s.log("state: NetSyncPeerMempool: completed", "peer", pr.Host)
+
+	url := fmt.Sprintf("%s/tx/hashes", s.baseURL(pr.Host))
+
+	var hashes []string
+	if err := s.send(context.Background(), http.MethodGet, url, nil, &hashes); err != nil {
+		return err
+	}
+
+	// Identify the transactions this node doesn't have.
+	have := make(map[string]struct{})
+	for _, hash := range s.mempool.Hashes() {
+		have[hash] = struct{}{}
+	}
+
+	var missing []string
+	for _, hash := range hashes {
+		if _, exists := have[hash]; !exists {
+			missing = append(missing, hash)
+		}
+	}
+
+	s.log("state: NetSyncPeerMempool: summary", "peer", pr.Host, "peerTxs", len(hashes), "missing", len(missing))
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	url = fmt.Sprintf("%s/tx/fetch", s.baseURL(pr.Host))
+
+	var txs []database.BlockTx
+	if err := s.send(context.Background(), http.MethodPost, url, missing, &txs); err != nil {
+		return err
+	}
+
+	for _, tx := range txs {
+		if err := s.UpsertNodeTransaction(context.Background(), tx); err != nil {
+			s.log("state: NetSyncPeerMempool: upsert", "tx", tx, "ERROR", err)
+		}
+	}
+
+	return nil
+}
+
+// NetRequestPeerBlocks queries the specified node for the range of blocks.
+// The peer must return every block in the range, in order.
+func (s *State) NetRequestPeerBlocks(pr peer.Peer, from uint64, to uint64) ([]database.Block, error) {
+	s.log("state: NetRequestPeerBlocks: started", "peer", pr.Host, "from", from, "to", to)
+	defer s.log("state: NetRequestPeerBlocks: completed", "peer", pr.Host)
+
+	defer func(start time.Time) {
+		peerRequestTime.Observe(time.Since(start).Seconds())
+	}(time.Now())
+
+	url := fmt.Sprintf("%s/block/list/%d/%d", s.baseURL(pr.Host), from, to)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ask for the compact binary stream. A peer that doesn't support it
+	// sends a JSON array, which is read the same way. The http client asks
+	// for gzip and decompresses the response on its own.
+	req.Header.Set("Accept", database.BlockContentGob+", "+database.BlockContentJSON)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// No content means the peer doesn't have any of the blocks.
+	exp := to - from + 1
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, fmt.Errorf("peer returned 0 blocks, expected %d", exp)
+	}
+
+	dec, err := database.NewBlockDecoder(resp.Body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The blocks are converted as they're decoded, so a peer sending more
+	// blocks than asked for is caught before they're all read.
+	blocks := make([]database.Block, 0, exp)
+	for {
+		blockData, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding block %d: %w", from+uint64(len(blocks)), err)
+		}
+
+		if uint64(len(blocks)) == exp {
+			return nil, fmt.Errorf("peer returned more than %d blocks", exp)
+		}
+
+		if num := from + uint64(len(blocks)); blockData.Header.Number != num {
+			return nil, fmt.Errorf("peer returned block %d, expected %d", blockData.Header.Number, num)
+		}
+
+		block, err := database.ToBlock(blockData)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+
+	if uint64(len(blocks)) != exp {
+		return nil, fmt.Errorf("peer returned %d blocks, expected %d", len(blocks), exp)
+	}
+
+	return blocks, nil
+}
+
+// =============================================================================
+
+// isGobPeer reports if the peer has shown it reads messages sent as gob.
+func (s *State) isGobPeer(host string) bool {
+	s.gobMu.RLock()
+	defer s.gobMu.RUnlock()
+
+	_, exists := s.gobPeers[host]
+	return exists
+}
+
+// addGobPeer records the peer reads messages sent as gob.
+func (s *State) addGobPeer(host string) {
+	s.gobMu.Lock()
+	defer s.gobMu.Unlock()
+
+	if _, exists := s.gobPeers[host]; !exists {
+		s.gobPeers[host] = struct{}{}
+		s.log("state: addGobPeer: peer reads gob", "peer", host)
+	}
+}
+
+// newPeerClient constructs the client used to make requests to peers. When
+// a TLS configuration is provided, the client presents this node's
+// certificate and only trusts peers signed by the same authority.
+func newPeerClient(tlsConfig *tls.Config) *http.Client {
+	client := http.Client{
+		Timeout: peerTimeout,
+	}
+
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.Transport = transport
+	}
+
+	return &client
+}
+
+// send is a helper function to send an HTTP request to a node. The request
+// carries the host of this node so the peer knows who sent the data. The
+// data is sent as gob once the peer has shown it reads gob, and the peer is
+// asked to answer with gob, falling back to JSON for older nodes. The request
+// carries the trace in the context so the peer continues it.
+func (s *State) send(ctx context.Context, method string, url string, dataSend any, dataRecv any) (err error) {
+	defer func(start time.Time) {
+		peerRequestTime.Observe(time.Since(start).Seconds())
+	}(time.Now())
+
+	ctx, span := tracing.StartClient(ctx, "state.send", tracing.String("http.method", method), tracing.String("http.url", url))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	var req *http.Request
+
+	switch {
+	case dataSend != nil:
+		var err error
+		req, err = http.NewRequest(method, url, nil)
+		if err != nil {
+			return err
+		}
+
+		contentType := database.BlockContentJSON
+		if s.isGobPeer(req.URL.Host) {
+			contentType = database.BlockContentGob
+		}
+
+		var data bytes.Buffer
+		if err := database.EncodePeerMessage(&data, contentType, dataSend); err != nil {
+			return err
+		}
+		req.Body = io.NopCloser(&data)
+		req.ContentLength = int64(data.Len())
+		req.Header.Set("Content-Type", contentType)
+
+	default:
+		var err error
+		req, err = http.NewRequest(method, url, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	req.Header.Set("Accept", database.BlockContentGob+", "+database.BlockContentJSON)
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if dataRecv != nil {
+		if err := database.DecodePeerMessage(resp.Body, resp.Header.Get("Content-Type"), dataRecv); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// do sends the request to a node. The request carries the host of this node
+// so the peer knows who sent the data. A response with a status other than
+// OK or no content is returned as an error holding the body of the
+// response. The caller must close the body of the response returned.
+func (s *State) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set(peer.HeaderHost, s.host)
+
+	// Test networks can slow down the requests to peers.
+	s.chaos.delay()
+
+	resp, err := s.peerClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// A peer answering with gob reads gob, so it's sent gob from now on.
+	if database.PeerContent(resp.Header.Get("Content-Type")) == database.BlockContentGob {
+		s.addGobPeer(req.URL.Host)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		defer resp.Body.Close()
+
+		msg, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.New(string(msg))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,320 @@
+package state
+
+import (
+	"errors"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// QueryLatest represents to query the latest block in the chain.
+const QueryLatest = ^uint64(0) >> 1
+
+// Set of error variables for the query API.
+var (
+	ErrReceiptNotFound   = errors.New("receipt not found")
+	ErrStateDiffNotFound = errors.New("state diff not found")
+	ErrFeesNotFound      = errors.New("fees not found")
+	ErrTxPending         = errors.New("transaction is pending in the mempool")
+	ErrTxEvicted         = errors.New("transaction was evicted from the mempool")
+)
+
+// =============================================================================
+
+// QueryAccount returns a copy of the account from the database.
+func (s *State) QueryAccount(account database.AccountID) (database.Account, error) {
+	return s.db.Query(account)
+}
+
+// QueryAccountProof returns the specified account as of the latest block
+// along with the merkle proof tying it to the header of the block.
+func (s *State) QueryAccountProof(accountID database.AccountID) (database.AccountProof, error) {
+	return s.db.ProveAccount(accountID)
+}
+
+// QueryAccountsByBalance returns a page of the accounts selected by the
+// filter ordered by balance, largest first, along with the total number of
+// accounts selected. A limit of 0 returns all the accounts from the offset.
+func (s *State) QueryAccountsByBalance(filter database.AccountFilter, offset int, limit int) ([]database.Account, int) {
+	return s.db.AccountsByBalance(filter, offset, limit)
+}
+
+// QueryAccounts returns an iterator over the accounts selected by the filter
+// in account id order, as of the latest block.
+func (s *State) QueryAccounts(filter database.AccountFilter) *database.AccountIterator {
+	return s.db.QueryAccounts(filter)
+}
+
+// QueryAccountCount returns the number of accounts in the database.
+func (s *State) QueryAccountCount() int {
+	return s.db.AccountCount()
+}
+
+// QueryStorage returns the value stored under the key in the contract
+// storage of the specified account.
+func (s *State) QueryStorage(accountID database.AccountID, key uint64) uint64 {
+	return s.db.QueryStorage(accountID, key)
+}
+
+// QueryToken returns a copy of the specified token.
+func (s *State) QueryToken(symbol string) (database.Token, error) {
+	return s.db.QueryToken(symbol)
+}
+
+// QueryPolicy returns the rules of a permissioned chain.
+func (s *State) QueryPolicy() database.Policy {
+	return s.db.QueryPolicy()
+}
+
+// QueryName returns the account the specified name is registered to.
+func (s *State) QueryName(name string) (database.AccountID, error) {
+	return s.db.QueryName(name)
+}
+
+// QueryStateDiff returns the accounts whose balance or nonce changed in the
+// specified block.
+func (s *State) QueryStateDiff(num uint64) (database.StateDiff, error) {
+	if num == 0 || num > s.db.LatestBlock().Header.Number {
+		return database.StateDiff{}, ErrStateDiffNotFound
+	}
+
+	diff, err := s.db.GetStateDiff(num)
+	if err != nil {
+		s.log("state: QueryStateDiff: getstatediff", "blk", num, "ERROR", err)
+		return database.StateDiff{}, ErrStateDiffNotFound
+	}
+
+	return diff, nil
+}
+
+// QueryBlockFees returns the fee statistics for the specified block.
+func (s *State) QueryBlockFees(num uint64) (database.BlockFees, error) {
+	if num == 0 || num > s.db.LatestBlock().Header.Number {
+		return database.BlockFees{}, ErrFeesNotFound
+	}
+
+	fees, err := s.db.GetFees(num)
+	if err != nil {
+		s.log("state: QueryBlockFees: getfees", "blk", num, "ERROR", err)
+		return database.BlockFees{}, ErrFeesNotFound
+	}
+
+	return fees, nil
+}
+
+// QueryFeeSummary returns the fee statistics combined over the specified
+// number of most recent blocks. Blocks missing their statistics are left
+// out of the summary.
+func (s *State) QueryFeeSummary(blocks uint64) database.FeeSummary {
+	latest := s.db.LatestBlock().Header.Number
+
+	var fees []database.BlockFees
+	for num := latest; num > 0 && latest-num < blocks; num-- {
+		blockFees, err := s.db.GetFees(num)
+		if err != nil {
+			s.log("state: QueryFeeSummary: getfees", "blk", num, "ERROR", err)
+			continue
+		}
+		fees = append(fees, blockFees)
+	}
+
+	return database.SummarizeFees(fees)
+}
+
+// QueryBlocksByNumber returns the set of blocks based on block numbers. This
+// function reads the blockchain from disk first.
+func (s *State) QueryBlocksByNumber(from uint64, to uint64) []database.Block {
+	if from == QueryLatest {
+		from = s.db.LatestBlock().Header.Number
+		to = from
+	}
+	if from == 0 {
+		from = 1
+	}
+	if latest := s.db.LatestBlock().Header.Number; to > latest {
+		to = latest
+	}
+
+	var out []database.Block
+	for i := from; i <= to; i++ {
+		block, err := s.db.GetBlock(i)
+		if err != nil {
+			s.log("state: QueryBlocksByNumber: getblock", "blk", i, "ERROR", err)
+			return nil
+		}
+		out = append(out, block)
+	}
+
+	return out
+}
+
+// QueryBlocksByAccount returns the set of blocks by account. If the account
+// is empty, all blocks are returned. This function reads the blockchain
+// from disk first.
+func (s *State) QueryBlocksByAccount(accountID database.AccountID) ([]database.Block, error) {
+	var out []database.Block
+
+	iter := s.db.ForEach()
+	for block, err := iter.Next(); !iter.Done(); block, err = iter.Next() {
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tx := range block.MerkleTree.Values() {
+			if accountID == "" || tx.FromID == accountID || tx.ToID == accountID {
+				out = append(out, block)
+				break
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// QueryReceipt returns the receipt for the transaction with the specified
+// hash. The transaction index locates the block holding the transaction,
+// when the storage doesn't keep an index the chain is searched starting
+// with the latest block.
+func (s *State) QueryReceipt(txHash string) (database.Receipt, error) {
+	receipt, err := s.findReceipt(txHash)
+	if err == nil {
+		return receipt, nil
+	}
+
+	if len(s.mempool.FindByHashes([]string{txHash})) > 0 {
+		return database.Receipt{}, ErrTxPending
+	}
+
+	if _, err := s.QueryEviction(txHash); err == nil {
+		return database.Receipt{}, ErrTxEvicted
+	}
+
+	return database.Receipt{}, ErrReceiptNotFound
+}
+
+// QueryTx returns the transaction with the specified hash that was recorded
+// in the chain along with its receipt.
+func (s *State) QueryTx(txHash string) (database.BlockTx, database.Receipt, error) {
+	receipt, err := s.QueryReceipt(txHash)
+	if err != nil {
+		return database.BlockTx{}, database.Receipt{}, err
+	}
+
+	block, err := s.db.GetBlock(receipt.BlockNumber)
+	if err != nil {
+		return database.BlockTx{}, database.Receipt{}, err
+	}
+
+	txs := block.MerkleTree.Values()
+	if receipt.Index >= len(txs) || txs[receipt.Index].TxHash() != txHash {
+		return database.BlockTx{}, database.Receipt{}, ErrReceiptNotFound
+	}
+
+	return txs[receipt.Index], receipt, nil
+}
+
+// findReceipt locates the receipt for the transaction in the chain.
+func (s *State) findReceipt(txHash string) (database.Receipt, error) {
+	loc, err := s.db.GetTxLocation(txHash)
+	switch {
+	case errors.Is(err, database.ErrTxIndexNotSupported):
+		return s.searchReceipt(txHash)
+	case err != nil:
+		return database.Receipt{}, ErrReceiptNotFound
+	}
+
+	// The index can't be trusted beyond the latest block or when it points
+	// at a different transaction.
+	if loc.BlockNumber > s.db.LatestBlock().Header.Number {
+		return database.Receipt{}, ErrReceiptNotFound
+	}
+
+	receipts, err := s.db.GetReceipts(loc.BlockNumber)
+	if err != nil {
+		s.log("state: findReceipt: getreceipts", "blk", loc.BlockNumber, "ERROR", err)
+		return database.Receipt{}, ErrReceiptNotFound
+	}
+
+	if loc.Index >= len(receipts) || receipts[loc.Index].TxHash != txHash {
+		return database.Receipt{}, ErrReceiptNotFound
+	}
+
+	return receipts[loc.Index], nil
+}
+
+// searchReceipt searches the chain for the receipt starting with the latest
+// block.
+func (s *State) searchReceipt(txHash string) (database.Receipt, error) {
+	for num := s.db.LatestBlock().Header.Number; num > 0; num-- {
+		receipts, err := s.db.GetReceipts(num)
+		if err != nil {
+			s.log("state: searchReceipt: getreceipts", "blk", num, "ERROR", err)
+			continue
+		}
+
+		for _, receipt := range receipts {
+			if receipt.TxHash == txHash {
+				return receipt, nil
+			}
+		}
+	}
+
+	return database.Receipt{}, ErrReceiptNotFound
+}
+
+// =============================================================================
+
+// PendingBalance represents the balance of an account once the transactions
+// waiting in the mempool have been applied.
+type PendingBalance struct {
+	AccountID    database.AccountID
+	Balance      uint64 // Balance recorded on the chain.
+	Nonce        uint64 // Nonce recorded on the chain.
+	Debits       uint64 // Value, tip and gas fee of the pending outgoing transactions.
+	Credits      uint64 // Value of the pending incoming transactions.
+	Pending      uint64 // Balance after the pending transactions are applied.
+	PendingNonce uint64 // Nonce after the pending outgoing transactions are applied.
+	PendingTxs   int    // Number of pending transactions involving the account.
+}
+
+// QueryPendingBalance returns the balance of the account after applying the
+// transactions in the mempool that send funds from or to the account. The
+// gas fee is included in the debits since it's paid even if a transaction
+// fails. An account the database has never seen starts with a zero balance.
+func (s *State) QueryPendingBalance(accountID database.AccountID) PendingBalance {
+	account, err := s.db.Query(accountID)
+	if err != nil {
+		account = database.Account{AccountID: accountID}
+	}
+
+	pb := PendingBalance{
+		AccountID:    accountID,
+		Balance:      account.Balance,
+		Nonce:        account.Nonce,
+		PendingNonce: account.Nonce,
+	}
+
+	for _, tx := range s.mempool.PickBest() {
+		if tx.FromID != accountID && tx.ToID != accountID {
+			continue
+		}
+		pb.PendingTxs++
+
+		if tx.FromID == accountID {
+			pb.Debits += tx.Value + tx.Tip + tx.GasPrice*tx.GasUnits
+			if tx.Nonce > pb.PendingNonce {
+				pb.PendingNonce = tx.Nonce
+			}
+		}
+
+		if tx.ToID == accountID {
+			pb.Credits += tx.Value
+		}
+	}
+
+	// The debits can't take more than the account will hold.
+	if total := pb.Balance + pb.Credits; pb.Debits < total {
+		pb.Pending = total - pb.Debits
+	}
+
+	return pb
+}
@@ -0,0 +1,138 @@
+package state
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// ErrNonceUsed is returned when a reverted transaction can't be mined again
+// because the account used its nonce in the chain the node switched to.
+var ErrNonceUsed = errors.New("nonce was used by another transaction")
+
+// revertWindow is the number of latest blocks whose transactions are
+// checked against the chain a resync replaces them with. Forks are
+// resolved near the head of the chain, so older blocks aren't kept.
+const revertWindow = 1000
+
+// RevertedTx is the data provided with the tx reverted event when a
+// transaction that was in the chain is missing from the chain the node
+// switched to.
+type RevertedTx struct {
+	Tx          database.BlockTx `json:"tx"`
+	TxHash      string           `json:"tx_hash"`
+	BlockNumber uint64           `json:"block_number"`
+	BlockHash   string           `json:"block_hash"`
+	Requeued    bool             `json:"requeued"`
+	Reason      string           `json:"reason,omitempty"`
+}
+
+// minedTx is a transaction held in a block of the chain being replaced.
+type minedTx struct {
+	tx          database.BlockTx
+	blockNumber uint64
+	blockHash   string
+}
+
+// =============================================================================
+
+// recentTxs returns the transactions in the latest blocks of the chain. It
+// is called before a resync throws the chain away.
+func (s *State) recentTxs() []minedTx {
+	latest := s.db.LatestBlock().Header.Number
+
+	from := uint64(1)
+	if latest > revertWindow {
+		from = latest - revertWindow + 1
+	}
+
+	var txs []minedTx
+	for num := from; num <= latest; num++ {
+		block, err := s.db.GetBlock(num)
+		if err != nil {
+			s.log("state: recentTxs: getblock", "blk", num, "ERROR", err)
+			continue
+		}
+
+		hash := block.Hash()
+		for _, tx := range block.MerkleTree.Values() {
+			txs = append(txs, minedTx{tx: tx, blockNumber: num, blockHash: hash})
+		}
+	}
+
+	return txs
+}
+
+// revertTxs compares the transactions of the replaced chain with the chain
+// the node now holds. Every transaction that is no longer in the chain is
+// published as reverted and returned to the mempool to be mined again,
+// unless the account has since used the nonce.
+func (s *State) revertTxs(old []minedTx) {
+	if len(old) == 0 {
+		return
+	}
+
+	// Collect the transactions of the new chain over the same blocks.
+	inChain := make(map[string]struct{})
+	latest := s.db.LatestBlock().Header.Number
+	for num := old[0].blockNumber; num <= latest; num++ {
+		block, err := s.db.GetBlock(num)
+		if err != nil {
+			s.log("state: revertTxs: getblock", "blk", num, "ERROR", err)
+			continue
+		}
+
+		for _, tx := range block.MerkleTree.Values() {
+			inChain[tx.TxHash()] = struct{}{}
+		}
+	}
+
+	var requeued int
+	for _, mtx := range old {
+		txHash := mtx.tx.TxHash()
+		if _, exists := inChain[txHash]; exists {
+			continue
+		}
+
+		reverted := RevertedTx{
+			Tx:          mtx.tx,
+			TxHash:      txHash,
+			BlockNumber: mtx.blockNumber,
+			BlockHash:   mtx.blockHash,
+		}
+
+		err := s.requeueTx(mtx.tx)
+		switch {
+		case err != nil:
+			reverted.Reason = err.Error()
+		default:
+			reverted.Requeued = true
+			requeued++
+		}
+
+		s.log("state: revertTxs: tx reverted", "tx", txHash, "blk", mtx.blockNumber, "requeued", reverted.Requeued, "reason", reverted.Reason)
+		s.events.Publish(EventTxReverted, reverted)
+	}
+
+	if requeued > 0 {
+		s.Worker.SignalStartMining()
+	}
+}
+
+// requeueTx returns a reverted transaction to the mempool and shares it
+// with the peers. A transaction whose nonce was used by another transaction
+// in the new chain can never be mined and is dropped.
+func (s *State) requeueTx(tx database.BlockTx) error {
+	if account, err := s.db.Query(tx.FromID); err == nil && tx.Nonce <= account.Nonce {
+		return ErrNonceUsed
+	}
+
+	if err := s.mempool.Upsert(tx); err != nil {
+		return err
+	}
+
+	s.Worker.SignalShareTx(context.Background(), tx)
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package state
+
+import (
+	"sort"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+// ChainSplit represents a peer that holds a different block than this node
+// at the same height.
+type ChainSplit struct {
+	Host        string `json:"host"`
+	BlockNumber uint64 `json:"block_number"`
+	PeerHash    string `json:"peer_hash"`
+	LocalHash   string `json:"local_hash"`
+	Since       uint64 `json:"since"`
+	Alerted     bool   `json:"alerted"`
+}
+
+// CheckPeerStatus compares the head block advertised by the peer with the
+// block this node holds at the same height. A split that persists while this
+// node adds splitThreshold blocks is logged as critical and published as an
+// event. A peer that is ahead of this node can't be compared.
+func (s *State) CheckPeerStatus(pr peer.Peer, status peer.PeerStatus) {
+	latest := s.db.LatestBlock()
+
+	if status.LatestBlockNumber == 0 || status.LatestBlockNumber > latest.Header.Number {
+		return
+	}
+
+	localHash := latest.Hash()
+	if status.LatestBlockNumber != latest.Header.Number {
+		block, err := s.db.GetBlock(status.LatestBlockNumber)
+		if err != nil {
+			s.log("state: CheckPeerStatus: getblock", "blk", status.LatestBlockNumber, "ERROR", err)
+			return
+		}
+		localHash = block.Hash()
+	}
+
+	s.splitMu.Lock()
+	defer s.splitMu.Unlock()
+
+	// The chains agree so any split with this peer has been resolved.
+	if localHash == status.LatestBlockHash {
+		if _, exists := s.splits[pr.Host]; exists {
+			s.log("state: CheckPeerStatus: chain split resolved", "peer", pr.Host)
+			delete(s.splits, pr.Host)
+		}
+		return
+	}
+
+	split, exists := s.splits[pr.Host]
+	if !exists {
+		split = ChainSplit{
+			Host:  pr.Host,
+			Since: latest.Header.Number,
+		}
+		s.log("state: CheckPeerStatus: chain split detected", "peer", pr.Host, "blk", status.LatestBlockNumber, "peerHash", status.LatestBlockHash, "localHash", localHash)
+	}
+
+	split.BlockNumber = status.LatestBlockNumber
+	split.PeerHash = status.LatestBlockHash
+	split.LocalHash = localHash
+
+	if !split.Alerted && latest.Header.Number-split.Since >= s.splitThreshold {
+		split.Alerted = true
+		s.log("state: CheckPeerStatus: CRITICAL: persistent chain split", "peer", pr.Host, "blk", split.BlockNumber, "since", split.Since, "peerHash", split.PeerHash, "localHash", split.LocalHash)
+		s.events.Publish(EventChainSplit, split)
+	}
+
+	s.splits[pr.Host] = split
+}
+
+// ChainSplits returns the set of persistent chain splits that have been
+// detected with peers.
+func (s *State) ChainSplits() []ChainSplit {
+	s.splitMu.RLock()
+	defer s.splitMu.RUnlock()
+
+	var splits []ChainSplit
+	for _, split := range s.splits {
+		if split.Alerted {
+			splits = append(splits, split)
+		}
+	}
+
+	sort.Slice(splits, func(i, j int) bool {
+		return splits[i].Host < splits[j].Host
+	})
+
+	return splits
+}
+
+// removeChainSplit forgets any split with the peer once it's no longer known.
+func (s *State) removeChainSplit(pr peer.Peer) {
+	s.splitMu.Lock()
+	defer s.splitMu.Unlock()
+
+	delete(s.splits, pr.Host)
+}
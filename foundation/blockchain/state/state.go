@@ -0,0 +1,435 @@
+// Package state is the core API for the blockchain and implements all the
+// business rules and processing.
+package state
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/mempool"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/tracing"
+)
+
+// Logger defines a function that is called to write structured log output
+// for the events that occur in the processing of blocks and transactions.
+// The first value is the message and the values after it are key/value
+// pairs, so logger.Func can write them to a zap SugaredLogger.
+type Logger func(v ...any)
+
+// Worker interface represents the behavior required to be implemented by any
+// package providing support for mining, peer updates, and transaction sharing.
+type Worker interface {
+	Shutdown()
+	Sync()
+	SignalStartMining()
+	SignalCancelMining()
+	SignalShareTx(ctx context.Context, blockTx database.BlockTx)
+	SignalMempoolSync(pr peer.Peer)
+}
+
+// =============================================================================
+
+// Config represents the configuration required to start
+// the blockchain node.
+type Config struct {
+	BeneficiaryID  database.AccountID
+	Signer         database.Signer
+	PeerIdentities map[string]database.AccountID
+	Beneficiaries  []database.Beneficiary
+	Host           string
+	Storage        database.Storage
+	Genesis        genesis.Genesis
+	Snapshot       *database.Snapshot
+	SelectStrategy string
+	KnownPeers     *peer.PeerSet
+	PeerTLS        *tls.Config
+	WatchList      []database.AccountID
+	SplitThreshold uint64
+	NonceWindow    uint64
+	TxTTL          time.Duration
+	TxTTLBlocks    uint64
+	MiningWorkers  int
+	MiningPool     *database.WorkerPool
+	ChainName      string
+	EmptyBlocks    time.Duration
+	BanThreshold   int
+	BanDuration    time.Duration
+	MaxBlockDrift  time.Duration
+	Chaos          Chaos
+	Tracer         *tracing.Tracer
+	Log            Logger
+}
+
+// State manages the blockchain database.
+type State struct {
+	mu          sync.RWMutex
+	resyncWG    sync.WaitGroup
+	allowMining bool
+	pauseMining bool
+	emptyBlocks time.Duration
+	synced      bool
+
+	beneficiaryID database.AccountID
+	beneficiaries []database.Beneficiary
+	signer        database.Signer
+	host          string
+	nonceWindow   uint64
+	maxBlockDrift time.Duration
+	miningWorkers int
+	miningPool    *database.WorkerPool
+	chainName     string
+	log           Logger
+
+	knownPeers *peer.PeerSet
+	bans       *peer.BanList
+	peerTLS    *tls.Config
+	peerClient *http.Client
+	chaos      *chaos
+	tracer     *tracing.Tracer
+	storage    database.Storage
+	genesis    genesis.Genesis
+	mempool    *mempool.Mempool
+	db         *database.Database
+	events     *EventBus
+	watchList  map[database.AccountID]struct{}
+
+	identMu    sync.RWMutex
+	identities map[string]database.AccountID
+
+	clockMu      sync.Mutex
+	clockOffsets map[string]time.Duration
+	clockWarned  bool
+
+	gobMu    sync.RWMutex
+	gobPeers map[string]struct{}
+
+	splitMu        sync.RWMutex
+	splits         map[string]ChainSplit
+	splitThreshold uint64
+
+	evictMu     sync.RWMutex
+	evictions   map[string]Eviction
+	evictOrder  []string
+	txTTL       time.Duration
+	txTTLBlocks uint64
+
+	spendMu      sync.Mutex
+	doubleSpends map[string]*DoubleSpend
+	spendOrder   []string
+
+	Worker Worker
+}
+
+// New constructs a new blockchain for data management.
+func New(cfg Config) (*State, error) {
+
+	// Build a safe log function for use.
+	log := func(v ...any) {
+		if cfg.Log != nil {
+			cfg.Log(v...)
+		}
+	}
+
+	// Access the storage for the blockchain, starting from the snapshot
+	// when one is provided instead of replaying the chain from genesis.
+	var db *database.Database
+	var err error
+	switch cfg.Snapshot {
+	case nil:
+		db, err = database.New(cfg.Genesis, cfg.Storage, log)
+	default:
+		db, err = database.NewFromSnapshot(cfg.Genesis, cfg.Storage, *cfg.Snapshot, log)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Construct a mempool with the specified sort strategy.
+	mempool, err := mempool.NewWithStrategy(cfg.SelectStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Construct the set of addresses the node operator wants to watch.
+	watchList := make(map[database.AccountID]struct{}, len(cfg.WatchList))
+	for _, accountID := range cfg.WatchList {
+		watchList[accountID] = struct{}{}
+	}
+
+	// Construct the accounts bound to peer hosts, starting with the ones
+	// the node operator configured.
+	identities := make(map[string]database.AccountID, len(cfg.PeerIdentities))
+	for host, accountID := range cfg.PeerIdentities {
+		identities[host] = accountID
+	}
+
+	// Create the State to provide support for managing the blockchain.
+	state := State{
+		beneficiaryID: cfg.BeneficiaryID,
+		beneficiaries: cfg.Beneficiaries,
+		signer:        cfg.Signer,
+		host:          cfg.Host,
+		nonceWindow:   cfg.NonceWindow,
+		maxBlockDrift: cfg.MaxBlockDrift,
+		miningWorkers: cfg.MiningWorkers,
+		miningPool:    cfg.MiningPool,
+		chainName:     cfg.ChainName,
+		log:           log,
+		allowMining:   true,
+		emptyBlocks:   cfg.EmptyBlocks,
+
+		knownPeers: cfg.KnownPeers,
+		bans:       peer.NewBanList(cfg.BanThreshold, cfg.BanDuration),
+		peerTLS:    cfg.PeerTLS,
+		peerClient: newPeerClient(cfg.PeerTLS),
+		chaos:      newChaos(cfg.Chaos),
+		tracer:     cfg.Tracer,
+		storage:    cfg.Storage,
+		genesis:    cfg.Genesis,
+		mempool:    mempool,
+		db:         db,
+		events:     NewEventBus(),
+		watchList:  watchList,
+
+		identities: identities,
+
+		clockOffsets: make(map[string]time.Duration),
+
+		gobPeers: make(map[string]struct{}),
+
+		splits:         make(map[string]ChainSplit),
+		splitThreshold: cfg.SplitThreshold,
+
+		evictions:   make(map[string]Eviction),
+		txTTL:       cfg.TxTTL,
+		txTTLBlocks: cfg.TxTTLBlocks,
+
+		doubleSpends: make(map[string]*DoubleSpend),
+	}
+
+	// Expose the metrics that are read from the state. When the node hosts
+	// more than one chain, the metrics describe the chain served at the root
+	// of the APIs.
+	if cfg.ChainName == "" {
+		state.registerMetrics()
+	}
+
+	// The Worker is not set here. The call to worker.Run will assign itself
+	// and start everything up and running for the node.
+
+	return &state, nil
+}
+
+// Shutdown cleanly brings the node down. The worker is stopped and any resync
+// is allowed to finish before the latest block is flushed to storage. If the
+// context is cancelled first, the node is left as is and an error is returned.
+func (s *State) Shutdown(ctx context.Context) error {
+	s.log("state: shutdown: started")
+	defer s.log("state: shutdown: completed")
+
+	// Stop all blockchain writing activity and wait for any resync to finish.
+	done := make(chan struct{})
+	go func() {
+		s.Worker.Shutdown()
+		s.resyncWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for blockchain activity to stop: %w", ctx.Err())
+	}
+
+	// Make sure the database file is properly closed and any event
+	// subscribers are released.
+	defer func() {
+		s.db.Close()
+		s.events.Shutdown()
+	}()
+
+	// Make sure the latest block made it to storage.
+	s.log("state: shutdown: flush latest block", "blk", s.db.LatestBlock().Header.Number)
+	if err := s.db.Flush(); err != nil {
+		return fmt.Errorf("flushing latest block: %w", err)
+	}
+
+	return nil
+}
+
+// IsMiningAllowed identifies if we are allowed to mine blocks. This
+// might be turned off if the blockchain needs to be re-synced or the
+// node operator has paused mining.
+func (s *State) IsMiningAllowed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.allowMining && !s.pauseMining
+}
+
+// PauseMining stops any mining operation and keeps new ones from starting
+// until mining is resumed by the node operator.
+func (s *State) PauseMining() {
+	s.mu.Lock()
+	s.pauseMining = true
+	s.mu.Unlock()
+
+	s.log("state: PauseMining: mining paused by operator")
+	s.Worker.SignalCancelMining()
+}
+
+// ResumeMining allows mining to take place again after being paused by the
+// node operator.
+func (s *State) ResumeMining() {
+	s.mu.Lock()
+	s.pauseMining = false
+	s.mu.Unlock()
+
+	s.log("state: ResumeMining: mining resumed by operator")
+	s.Worker.SignalStartMining()
+}
+
+// EmptyBlockInterval returns how long the node goes without a block before
+// it mines an empty one. Zero means the node only mines when there are
+// transactions to mine.
+func (s *State) EmptyBlockInterval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.emptyBlocks
+}
+
+// SetEmptyBlockInterval changes how long the node goes without a block
+// before it mines an empty one. Zero stops empty blocks from being mined.
+func (s *State) SetEmptyBlockInterval(interval time.Duration) {
+	s.mu.Lock()
+	s.emptyBlocks = interval
+	s.mu.Unlock()
+
+	s.log("state: SetEmptyBlockInterval: changed by operator", "interval", interval)
+}
+
+// EmptyBlockDue reports if an empty block can be mined since no block has
+// been added to the chain for the empty block interval.
+func (s *State) EmptyBlockDue() bool {
+	interval := s.EmptyBlockInterval()
+	if interval <= 0 {
+		return false
+	}
+
+	latest := time.UnixMilli(int64(s.db.LatestBlock().Header.TimeStamp))
+	return time.Since(latest) >= interval
+}
+
+// Resync resets the chain both on disk and in memory, back to genesis or to
+// the snapshot the node was started from. This is used to correct an
+// identified fork. No mining is allowed to take place while this
+// process is running. New transactions can be placed into the mempool. The
+// transactions of the latest blocks that don't make it into the new chain
+// are reported as reverted and returned to the mempool.
+func (s *State) Resync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Don't allow mining to continue or the node to report it's ready.
+	s.allowMining = false
+	s.synced = false
+
+	// Hold on to the transactions of the chain being replaced.
+	old := s.recentTxs()
+
+	// Reset the state of the blockchain node.
+	if err := s.db.ResetChain(); err != nil {
+		s.allowMining = true
+		s.synced = true
+		return err
+	}
+
+	s.events.Publish(EventReorg, nil)
+
+	// Resync the state of the blockchain.
+	s.resyncWG.Add(1)
+	go func() {
+		s.log("state: Resync: started")
+		defer func() {
+			s.mu.Lock()
+			s.allowMining = true
+			s.synced = true
+			s.mu.Unlock()
+
+			s.revertTxs(old)
+
+			s.log("state: Resync: completed")
+			s.resyncWG.Done()
+		}()
+
+		s.Worker.Sync()
+	}()
+
+	return nil
+}
+
+// =============================================================================
+
+// Events returns the event bus so clients can subscribe to state events.
+func (s *State) Events() *EventBus {
+	return s.events
+}
+
+// Genesis returns a copy of the genesis information.
+func (s *State) Genesis() genesis.Genesis {
+	return s.genesis
+}
+
+// LatestBlock returns a copy the current latest block.
+func (s *State) LatestBlock() database.Block {
+	return s.db.LatestBlock()
+}
+
+// Host returns a copy of host information.
+func (s *State) Host() string {
+	return s.host
+}
+
+// KnownExternalPeers retrieves a copy of the known peer list without
+// including this node.
+func (s *State) KnownExternalPeers() []peer.Peer {
+	return s.knownPeers.Copy(s.host)
+}
+
+// KnownPeers retrieves a copy of the full known peer list which includes
+// this node as well.
+func (s *State) KnownPeers() []peer.Peer {
+	return s.knownPeers.Copy("")
+}
+
+// AddKnownPeer provides the ability to add a new peer to
+// the known peer list. A banned peer is not added.
+func (s *State) AddKnownPeer(peer peer.Peer) bool {
+	if s.bans.IsBanned(peer.Host) {
+		return false
+	}
+	return s.knownPeers.Add(peer)
+}
+
+// RemoveKnownPeer provides the ability to remove a peer from
+// the known peer list.
+func (s *State) RemoveKnownPeer(peer peer.Peer) {
+	s.knownPeers.Remove(peer)
+	s.removeChainSplit(peer)
+
+	s.clockMu.Lock()
+	delete(s.clockOffsets, peer.Host)
+	s.clockMu.Unlock()
+
+	s.gobMu.Lock()
+	delete(s.gobPeers, peer.Host)
+	s.gobMu.Unlock()
+}
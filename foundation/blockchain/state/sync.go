@@ -0,0 +1,212 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+// Set of values used to size the block sync pipeline.
+const (
+	syncRangeSize = 50 // Number of blocks requested from a peer at a time.
+	syncDownloads = 4  // Number of ranges downloaded at the same time.
+	syncWindow    = 16 // Number of ranges held in memory waiting to be applied.
+)
+
+// syncRange represents a range of blocks moving through the sync pipeline.
+type syncRange struct {
+	index  int
+	from   uint64
+	to     uint64
+	peer   peer.Peer
+	blocks []database.Block
+	err    error
+}
+
+// NetSyncBlocks retrieves the blocks this node is missing from the peers,
+// which map to the latest block number each peer reported. The blocks are
+// downloaded in ranges from several peers at the same time and verified by
+// a pool of workers, then applied in order. If a range fails, the blocks
+// applied before it are kept and the error is returned.
+func (s *State) NetSyncBlocks(peers map[peer.Peer]uint64) error {
+	from := s.LatestBlock().Header.Number + 1
+
+	var to uint64
+	for _, latest := range peers {
+		if latest > to {
+			to = latest
+		}
+	}
+
+	if from > to {
+		return nil
+	}
+
+	s.log("state: NetSyncBlocks: started", "from", from, "to", to, "peers", len(peers))
+	defer s.log("state: NetSyncBlocks: completed")
+
+	// Cancelling the context stops every stage of the pipeline once the
+	// blocks stop being applied.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Split the missing blocks into ranges. The window keeps the downloads
+	// from getting too far ahead of the blocks being applied.
+	window := make(chan struct{}, syncWindow)
+	ranges := make(chan syncRange)
+	go func() {
+		defer close(ranges)
+
+		for index, start := 0, from; start <= to; index, start = index+1, start+syncRangeSize {
+			end := start + syncRangeSize - 1
+			if end > to {
+				end = to
+			}
+
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case ranges <- syncRange{index: index, from: start, to: end}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Download the ranges from the peers that have them.
+	downloaded := make(chan syncRange)
+	stage(ctx, syncDownloads, ranges, downloaded, func(rng syncRange) syncRange {
+		rng.peer, rng.blocks, rng.err = s.downloadRange(peers, rng)
+		return rng
+	})
+
+	// Verify the blocks of each range with a pool of workers.
+	verified := make(chan syncRange)
+	stage(ctx, runtime.GOMAXPROCS(0), downloaded, verified, func(rng syncRange) syncRange {
+		if rng.err == nil {
+			if rng.err = verifyRange(rng.blocks); rng.err != nil {
+				s.PenalizePeer(rng.peer.Host, PenaltyInvalidBlock, rng.err.Error())
+			}
+		}
+		return rng
+	})
+
+	// Apply the ranges in order. Ranges that finish early wait until the
+	// ranges before them are applied.
+	pending := make(map[int]syncRange)
+	next := 0
+	for rng := range verified {
+		pending[rng.index] = rng
+
+		for {
+			rng, exists := pending[next]
+			if !exists {
+				break
+			}
+			delete(pending, next)
+
+			if rng.err != nil {
+				return rng.err
+			}
+
+			for _, block := range rng.blocks {
+				if err := s.ProcessProposedBlock(context.Background(), block); err != nil {
+					if IsPeerFault(err) {
+						s.PenalizePeer(rng.peer.Host, PenaltyInvalidBlock, err.Error())
+					}
+					return err
+				}
+			}
+
+			s.log("state: NetSyncBlocks: applied", "from", rng.from, "to", rng.to)
+
+			next++
+			<-window
+		}
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// stage starts the number of goroutines to perform the work on each range
+// received from in and send the result to out. The out channel is closed
+// once in is closed and the work is done, or the context is cancelled.
+func stage(ctx context.Context, goroutines int, in <-chan syncRange, out chan<- syncRange, work func(rng syncRange) syncRange) {
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+
+			for rng := range in {
+				select {
+				case out <- work(rng):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+}
+
+// downloadRange requests the range of blocks from a peer that has them.
+// The ranges are spread across the peers, and the next peer is tried when
+// a peer fails. The peer that provided the blocks is returned with them.
+func (s *State) downloadRange(peers map[peer.Peer]uint64, rng syncRange) (peer.Peer, []database.Block, error) {
+	var candidates []peer.Peer
+	for pr, latest := range peers {
+		if latest >= rng.to {
+			candidates = append(candidates, pr)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Host < candidates[j].Host })
+
+	var err error
+	for i := range candidates {
+		pr := candidates[(rng.index+i)%len(candidates)]
+
+		var blocks []database.Block
+		if blocks, err = s.NetRequestPeerBlocks(pr, rng.from, rng.to); err == nil {
+			return pr, blocks, nil
+		}
+
+		s.log("state: NetSyncBlocks: download", "peer", pr.Host, "from", rng.from, "to", rng.to, "ERROR", err)
+	}
+
+	return peer.Peer{}, nil, fmt.Errorf("downloading blocks %d to %d: %w", rng.from, rng.to, err)
+}
+
+// verifyRange performs the checks on a range of blocks that don't need the
+// state of the chain. Each block must link to the block before it.
+func verifyRange(blocks []database.Block) error {
+	for i, block := range blocks {
+		if err := block.Verify(); err != nil {
+			return err
+		}
+
+		if i > 0 {
+			if prevHash := blocks[i-1].Hash(); block.Header.PrevBlockHash != prevHash {
+				return fmt.Errorf("block %d: %w, got %s, exp %s", block.Header.Number, database.ErrParentHash, block.Header.PrevBlockHash, prevHash)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package state
+
+import (
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// IsWatched reports if the specified account is on the node's watch list.
+func (s *State) IsWatched(accountID database.AccountID) bool {
+	_, exists := s.watchList[accountID]
+	return exists
+}
+
+// =============================================================================
+
+// watchBlock looks for watched addresses in the applied block. For every
+// appearance, a log entry is written and an address watch event is published
+// so the application can perform any other notifications.
+func (s *State) watchBlock(block database.Block) {
+	if len(s.watchList) == 0 {
+		return
+	}
+
+	hash := block.Hash()
+
+	notify := func(accountID database.AccountID, role string, txHash string, value uint64) {
+		if !s.IsWatched(accountID) {
+			return
+		}
+
+		activity := AddressActivity{
+			AccountID:   accountID,
+			Role:        role,
+			BlockNumber: block.Header.Number,
+			BlockHash:   hash,
+			TxHash:      txHash,
+			Value:       value,
+		}
+
+		s.log("state: watch: address activity", "account", accountID, "role", role, "blk", block.Header.Number, "tx", txHash, "value", value)
+		s.events.Publish(EventAddressWatch, activity)
+	}
+
+	for _, tx := range block.MerkleTree.Values() {
+		txHash := tx.TxHash()
+		notify(tx.FromID, RoleFrom, txHash, tx.Value)
+		notify(tx.ToID, RoleTo, txHash, tx.Value)
+	}
+
+	if len(block.Header.Beneficiaries) == 0 {
+		notify(block.Header.BeneficiaryID, RoleBeneficiary, "", block.Header.MiningReward)
+		return
+	}
+
+	for _, payout := range database.SplitReward(block.Header.Beneficiaries, block.Header.MiningReward) {
+		notify(payout.AccountID, RoleBeneficiary, "", payout.Amount)
+	}
+}
@@ -0,0 +1,258 @@
+// Package disk implements the ability to read and write blocks to disk
+// writing each block to a separate block numbered file.
+package disk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// Disk represents the serialization implementation for reading and storing
+// blocks in their own separate files on disk. This implements the database.Storage
+// interface.
+type Disk struct {
+	dbPath string
+
+	mu      sync.RWMutex
+	txIndex map[string]database.TxLocation
+}
+
+// New constructs an Disk value for use.
+func New(dbPath string) (*Disk, error) {
+	if err := os.MkdirAll(dbPath, 0755); err != nil {
+		return nil, err
+	}
+
+	d := Disk{
+		dbPath:  dbPath,
+		txIndex: make(map[string]database.TxLocation),
+	}
+
+	if err := d.loadTxIndex(); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// Close in this implementation has nothing to do since a new file is
+// written to disk for each new block and then immediately closed.
+func (d *Disk) Close() error {
+	return nil
+}
+
+// Write takes the specified database blocks and stores it on disk in a
+// file labeled with the block number.
+func (d *Disk) Write(blockData database.BlockData) error {
+
+	// Marshal the block for writing to disk in a more human readable format.
+	data, err := json.MarshalIndent(blockData, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Create a new file for this block and name it based on the block number.
+	f, err := os.OpenFile(d.getPath(blockData.Header.Number), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Write the new block to disk.
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+
+	// Record where the transactions in the block can be found.
+	return d.IndexTxs(blockData)
+}
+
+// GetBlock searches the blockchain on disk to locate and return the
+// contents of the specified block by number.
+func (d *Disk) GetBlock(num uint64) (database.BlockData, error) {
+
+	// Open the block file for the specified number.
+	f, err := os.OpenFile(d.getPath(num), os.O_RDONLY, 0600)
+	if err != nil {
+		return database.BlockData{}, err
+	}
+	defer f.Close()
+
+	// Decode the contents of the block.
+	var blockData database.BlockData
+	if err := json.NewDecoder(f).Decode(&blockData); err != nil {
+		return database.BlockData{}, err
+	}
+
+	// Return the block as a database block.
+	return blockData, nil
+}
+
+// WriteReceipts stores the receipts for the specified block number in a
+// file next to the block.
+func (d *Disk) WriteReceipts(num uint64, receipts []database.Receipt) error {
+	data, err := json.MarshalIndent(receipts, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(d.getReceiptsPath(num), data, 0600)
+}
+
+// GetReceipts returns the receipts for the specified block number.
+func (d *Disk) GetReceipts(num uint64) ([]database.Receipt, error) {
+	data, err := os.ReadFile(d.getReceiptsPath(num))
+	if err != nil {
+		return nil, err
+	}
+
+	var receipts []database.Receipt
+	if err := json.Unmarshal(data, &receipts); err != nil {
+		return nil, err
+	}
+
+	return receipts, nil
+}
+
+// WriteStateDiff stores the state diff for the specified block number in a
+// file next to the block.
+func (d *Disk) WriteStateDiff(num uint64, diff database.StateDiff) error {
+	data, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(d.getStateDiffPath(num), data, 0600)
+}
+
+// GetStateDiff returns the state diff for the specified block number.
+func (d *Disk) GetStateDiff(num uint64) (database.StateDiff, error) {
+	data, err := os.ReadFile(d.getStateDiffPath(num))
+	if err != nil {
+		return database.StateDiff{}, err
+	}
+
+	var diff database.StateDiff
+	if err := json.Unmarshal(data, &diff); err != nil {
+		return database.StateDiff{}, err
+	}
+
+	return diff, nil
+}
+
+// WriteFees stores the fee statistics for the specified block number in a
+// file next to the block.
+func (d *Disk) WriteFees(num uint64, fees database.BlockFees) error {
+	data, err := json.MarshalIndent(fees, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(d.getFeesPath(num), data, 0600)
+}
+
+// GetFees returns the fee statistics for the specified block number.
+func (d *Disk) GetFees(num uint64) (database.BlockFees, error) {
+	data, err := os.ReadFile(d.getFeesPath(num))
+	if err != nil {
+		return database.BlockFees{}, err
+	}
+
+	var fees database.BlockFees
+	if err := json.Unmarshal(data, &fees); err != nil {
+		return database.BlockFees{}, err
+	}
+
+	return fees, nil
+}
+
+// ForEach returns an iterator to walk through all the blocks
+// starting with block number 1.
+func (d *Disk) ForEach() database.Iterator {
+	return d.ForEachFrom(1)
+}
+
+// ForEachFrom returns an iterator to walk through the blocks starting with
+// the specified block number.
+func (d *Disk) ForEachFrom(num uint64) database.Iterator {
+	if num == 0 {
+		num = 1
+	}
+
+	return &diskIterator{storage: d, current: num - 1}
+}
+
+// Reset will clear out the blockchain on disk.
+func (d *Disk) Reset() error {
+	d.mu.Lock()
+	d.txIndex = make(map[string]database.TxLocation)
+	d.mu.Unlock()
+
+	if err := os.RemoveAll(d.dbPath); err != nil {
+		return err
+	}
+
+	return os.MkdirAll(d.dbPath, 0755)
+}
+
+// getPath forms the path to the specified block.
+func (d *Disk) getPath(blockNum uint64) string {
+	name := fmt.Sprintf("%d.json", blockNum)
+	return path.Join(d.dbPath, name)
+}
+
+// getReceiptsPath forms the path to the receipts for the specified block.
+func (d *Disk) getReceiptsPath(blockNum uint64) string {
+	name := fmt.Sprintf("%d.receipts.json", blockNum)
+	return path.Join(d.dbPath, name)
+}
+
+// getStateDiffPath forms the path to the state diff for the specified block.
+func (d *Disk) getStateDiffPath(blockNum uint64) string {
+	name := fmt.Sprintf("%d.statediff.json", blockNum)
+	return path.Join(d.dbPath, name)
+}
+
+// getFeesPath forms the path to the fee statistics for the specified block.
+func (d *Disk) getFeesPath(blockNum uint64) string {
+	name := fmt.Sprintf("%d.fees.json", blockNum)
+	return path.Join(d.dbPath, name)
+}
+
+// =============================================================================
+
+// diskIterator represents the iteration implementation for walking
+// through and reading blocks on disk. This implements the database
+// Iterator interface.
+type diskIterator struct {
+	storage *Disk  // Access to the storage API.
+	current uint64 // Currenet block number being iterated over.
+	eoc     bool   // Represents the iterator is at the end of the chain.
+}
+
+// Next retrieves the next block from disk.
+func (di *diskIterator) Next() (database.BlockData, error) {
+	if di.eoc {
+		return database.BlockData{}, errors.New("end of chain")
+	}
+
+	di.current++
+	blockData, err := di.storage.GetBlock(di.current)
+	if errors.Is(err, fs.ErrNotExist) {
+		di.eoc = true
+	}
+
+	return blockData, err
+}
+
+// Done returns the end of chain value.
+func (di *diskIterator) Done() bool {
+	return di.eoc
+}
@@ -0,0 +1,120 @@
+package disk
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// txIndexName is the name of the file holding the transaction index. Each
+// line records the hash of a transaction followed by the number of the
+// block holding it and its index in the block. Lines are only appended, so
+// when a transaction is recorded more than once the last line wins.
+const txIndexName = "txindex.log"
+
+// IndexTxs records where each transaction in the block is recorded.
+func (d *Disk) IndexTxs(blockData database.BlockData) error {
+	if len(blockData.Trans) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	for i, tx := range blockData.Trans {
+		fmt.Fprintf(&b, "%s %d %d\n", tx.TxHash(), blockData.Header.Number, i)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := os.OpenFile(d.getTxIndexPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(b.String()); err != nil {
+		return err
+	}
+
+	for i, tx := range blockData.Trans {
+		d.txIndex[tx.TxHash()] = database.TxLocation{BlockNumber: blockData.Header.Number, Index: i}
+	}
+
+	return nil
+}
+
+// GetTxLocation returns where the transaction with the specified hash is
+// recorded in the chain.
+func (d *Disk) GetTxLocation(txHash string) (database.TxLocation, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	loc, exists := d.txIndex[txHash]
+	if !exists {
+		return database.TxLocation{}, fmt.Errorf("tx %s: %w", txHash, fs.ErrNotExist)
+	}
+
+	return loc, nil
+}
+
+// ResetTxIndex removes every entry from the transaction index.
+func (d *Disk) ResetTxIndex() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := os.Remove(d.getTxIndexPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	d.txIndex = make(map[string]database.TxLocation)
+
+	return nil
+}
+
+// loadTxIndex reads the transaction index file into memory. A line left
+// partly written by a crash is skipped, the replay of the chain indexes the
+// block again.
+func (d *Disk) loadTxIndex() error {
+	f, err := os.Open(d.getTxIndexPath())
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+
+		num, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		index, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+
+		d.txIndex[fields[0]] = database.TxLocation{BlockNumber: num, Index: index}
+	}
+
+	return scanner.Err()
+}
+
+// getTxIndexPath forms the path to the transaction index file.
+func (d *Disk) getTxIndexPath() string {
+	return path.Join(d.dbPath, txIndexName)
+}
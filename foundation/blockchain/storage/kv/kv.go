@@ -0,0 +1,278 @@
+// Package kv implements the ability to read and write blocks to an embedded
+// key value store held in a single file. Keys are grouped by a prefix the
+// way column families are, so blocks, receipts and the transaction index
+// live side by side and any block can be read without scanning the chain.
+package kv
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// fileName is the name of the file holding the store in the database
+// directory.
+const fileName = "chain.kv"
+
+// Set of prefixes that group the keys in the store.
+const (
+	prefixBlock    byte = 'b' // Block number to the block.
+	prefixReceipts byte = 'r' // Block number to the receipts for the block.
+	prefixDiff     byte = 's' // Block number to the state diff for the block.
+	prefixFees     byte = 'f' // Block number to the fee statistics for the block.
+	prefixTx       byte = 't' // Transaction hash to where it's recorded in the chain.
+)
+
+// KV represents the serialization implementation for reading and storing
+// blocks in a key value store. This implements the database.Storage
+// interface.
+type KV struct {
+	store *store
+}
+
+// New constructs a KV value for use, opening the store in the specified
+// database directory.
+func New(dbPath string) (*KV, error) {
+	if err := os.MkdirAll(dbPath, 0755); err != nil {
+		return nil, err
+	}
+
+	store, err := openStore(filepath.Join(dbPath, fileName))
+	if err != nil {
+		return nil, err
+	}
+
+	return &KV{store: store}, nil
+}
+
+// Close closes the file backing the store.
+func (kv *KV) Close() error {
+	return kv.store.close()
+}
+
+// Write stores the block along with an index entry for each of its
+// transactions. The block is written last so a block found in the store
+// always has its transactions indexed.
+func (kv *KV) Write(blockData database.BlockData) error {
+	data, err := json.Marshal(blockData)
+	if err != nil {
+		return err
+	}
+
+	entries := append(txEntries(blockData), entry{key: numberKey(prefixBlock, blockData.Header.Number), value: data})
+
+	return kv.store.put(entries...)
+}
+
+// GetBlock returns the block for the specified number.
+func (kv *KV) GetBlock(num uint64) (database.BlockData, error) {
+	data, err := kv.store.get(numberKey(prefixBlock, num))
+	if err != nil {
+		return database.BlockData{}, fmt.Errorf("block %d: %w", num, err)
+	}
+
+	var blockData database.BlockData
+	if err := json.Unmarshal(data, &blockData); err != nil {
+		return database.BlockData{}, err
+	}
+
+	return blockData, nil
+}
+
+// WriteReceipts stores the receipts for the specified block number.
+func (kv *KV) WriteReceipts(num uint64, receipts []database.Receipt) error {
+	data, err := json.Marshal(receipts)
+	if err != nil {
+		return err
+	}
+
+	return kv.store.put(entry{key: numberKey(prefixReceipts, num), value: data})
+}
+
+// GetReceipts returns the receipts for the specified block number.
+func (kv *KV) GetReceipts(num uint64) ([]database.Receipt, error) {
+	data, err := kv.store.get(numberKey(prefixReceipts, num))
+	if err != nil {
+		return nil, fmt.Errorf("receipts %d: %w", num, err)
+	}
+
+	var receipts []database.Receipt
+	if err := json.Unmarshal(data, &receipts); err != nil {
+		return nil, err
+	}
+
+	return receipts, nil
+}
+
+// WriteStateDiff stores the state diff for the specified block number.
+func (kv *KV) WriteStateDiff(num uint64, diff database.StateDiff) error {
+	data, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+
+	return kv.store.put(entry{key: numberKey(prefixDiff, num), value: data})
+}
+
+// GetStateDiff returns the state diff for the specified block number.
+func (kv *KV) GetStateDiff(num uint64) (database.StateDiff, error) {
+	data, err := kv.store.get(numberKey(prefixDiff, num))
+	if err != nil {
+		return database.StateDiff{}, fmt.Errorf("state diff %d: %w", num, err)
+	}
+
+	var diff database.StateDiff
+	if err := json.Unmarshal(data, &diff); err != nil {
+		return database.StateDiff{}, err
+	}
+
+	return diff, nil
+}
+
+// WriteFees stores the fee statistics for the specified block number.
+func (kv *KV) WriteFees(num uint64, fees database.BlockFees) error {
+	data, err := json.Marshal(fees)
+	if err != nil {
+		return err
+	}
+
+	return kv.store.put(entry{key: numberKey(prefixFees, num), value: data})
+}
+
+// GetFees returns the fee statistics for the specified block number.
+func (kv *KV) GetFees(num uint64) (database.BlockFees, error) {
+	data, err := kv.store.get(numberKey(prefixFees, num))
+	if err != nil {
+		return database.BlockFees{}, fmt.Errorf("fees %d: %w", num, err)
+	}
+
+	var fees database.BlockFees
+	if err := json.Unmarshal(data, &fees); err != nil {
+		return database.BlockFees{}, err
+	}
+
+	return fees, nil
+}
+
+// IndexTxs records where each transaction in the block is recorded.
+func (kv *KV) IndexTxs(blockData database.BlockData) error {
+	entries := txEntries(blockData)
+	if len(entries) == 0 {
+		return nil
+	}
+
+	return kv.store.put(entries...)
+}
+
+// GetTxLocation returns where the transaction with the specified hash is
+// recorded in the chain.
+func (kv *KV) GetTxLocation(txHash string) (database.TxLocation, error) {
+	data, err := kv.store.get(txKey(txHash))
+	if err != nil {
+		return database.TxLocation{}, fmt.Errorf("tx %s: %w", txHash, err)
+	}
+
+	loc := database.TxLocation{
+		BlockNumber: binary.BigEndian.Uint64(data[:8]),
+		Index:       int(binary.BigEndian.Uint32(data[8:])),
+	}
+
+	return loc, nil
+}
+
+// ResetTxIndex removes every entry from the transaction index.
+func (kv *KV) ResetTxIndex() error {
+	return kv.store.deletePrefix([]byte{prefixTx})
+}
+
+// ForEach returns an iterator to walk through all the blocks
+// starting with block number 1.
+func (kv *KV) ForEach() database.Iterator {
+	return kv.ForEachFrom(1)
+}
+
+// ForEachFrom returns an iterator to walk through the blocks starting with
+// the specified block number.
+func (kv *KV) ForEachFrom(num uint64) database.Iterator {
+	if num == 0 {
+		num = 1
+	}
+
+	return &kvIterator{storage: kv, current: num - 1}
+}
+
+// Reset will clear out the blockchain in the store.
+func (kv *KV) Reset() error {
+	return kv.store.reset()
+}
+
+// =============================================================================
+
+// numberKey forms the key for a block number under the prefix. The number
+// is big endian so the keys sort in block order.
+func numberKey(prefix byte, num uint64) []byte {
+	return append([]byte{prefix}, encodeNumber(num)...)
+}
+
+// txKey forms the key for a transaction hash.
+func txKey(txHash string) []byte {
+	return append([]byte{prefixTx}, txHash...)
+}
+
+// txEntries forms the index entries for the transactions in the block.
+// Each value is the block number followed by the index in the block.
+func txEntries(blockData database.BlockData) []entry {
+	entries := make([]entry, len(blockData.Trans))
+	for i, tx := range blockData.Trans {
+		value := make([]byte, 12)
+		binary.BigEndian.PutUint64(value[:8], blockData.Header.Number)
+		binary.BigEndian.PutUint32(value[8:], uint32(i))
+
+		entries[i] = entry{key: txKey(tx.TxHash()), value: value}
+	}
+
+	return entries
+}
+
+// encodeNumber encodes the block number as big endian bytes.
+func encodeNumber(num uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, num)
+	return b
+}
+
+// =============================================================================
+
+// kvIterator represents the iteration implementation for walking through
+// and reading blocks in the store. This implements the database Iterator
+// interface.
+type kvIterator struct {
+	storage *KV    // Access to the storage API.
+	current uint64 // Current block number being iterated over.
+	eoc     bool   // Represents the iterator is at the end of the chain.
+}
+
+// Next retrieves the next block from the store.
+func (ki *kvIterator) Next() (database.BlockData, error) {
+	if ki.eoc {
+		return database.BlockData{}, errors.New("end of chain")
+	}
+
+	ki.current++
+	blockData, err := ki.storage.GetBlock(ki.current)
+	if errors.Is(err, ErrNotFound) {
+		ki.eoc = true
+	}
+
+	return blockData, err
+}
+
+// Done returns the end of chain value.
+func (ki *kvIterator) Done() bool {
+	return ki.eoc
+}
@@ -0,0 +1,215 @@
+package kv_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/kv"
+)
+
+func TestWriteRead(t *testing.T) {
+	store := open(t, t.TempDir())
+	defer store.Close()
+
+	blocks := newBlocks(t, 3)
+	for _, blockData := range blocks {
+		if err := store.Write(blockData); err != nil {
+			t.Fatalf("Should be able to write block %d: %s", blockData.Header.Number, err)
+		}
+	}
+
+	got, err := store.GetBlock(2)
+	if err != nil {
+		t.Fatalf("Should be able to read the block: %s", err)
+	}
+	if got.Hash != blocks[1].Hash || got.Header.Number != 2 {
+		t.Fatalf("Should read the block written, got %d:%s, exp %d:%s", got.Header.Number, got.Hash, 2, blocks[1].Hash)
+	}
+
+	if _, err := store.GetBlock(4); !errors.Is(err, kv.ErrNotFound) {
+		t.Fatalf("Should not find a block that wasn't written, got %v", err)
+	}
+
+	receipts := []database.Receipt{{BlockNumber: 2, Index: 0, Status: database.ReceiptStatusSuccess}}
+	if err := store.WriteReceipts(2, receipts); err != nil {
+		t.Fatalf("Should be able to write the receipts: %s", err)
+	}
+	gotReceipts, err := store.GetReceipts(2)
+	if err != nil || len(gotReceipts) != 1 || gotReceipts[0].BlockNumber != 2 {
+		t.Fatalf("Should read the receipts written, got %+v, %v", gotReceipts, err)
+	}
+
+	txHash := blocks[2].Trans[0].TxHash()
+	loc, err := store.GetTxLocation(txHash)
+	if err != nil {
+		t.Fatalf("Should be able to find the transaction: %s", err)
+	}
+	if loc.BlockNumber != 3 || loc.Index != 0 {
+		t.Fatalf("Should index the transaction, got %+v", loc)
+	}
+
+	if err := store.ResetTxIndex(); err != nil {
+		t.Fatalf("Should be able to reset the index: %s", err)
+	}
+	if _, err := store.GetTxLocation(txHash); !errors.Is(err, kv.ErrNotFound) {
+		t.Fatalf("Should remove the index entries, got %v", err)
+	}
+
+	iterate(t, store.ForEach(), 1, 2, 3)
+	iterate(t, store.ForEachFrom(2), 2, 3)
+
+	if err := store.Reset(); err != nil {
+		t.Fatalf("Should be able to reset the store: %s", err)
+	}
+	iterate(t, store.ForEach())
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	store := open(t, dir)
+
+	blocks := newBlocks(t, 3)
+	for _, blockData := range blocks {
+		if err := store.Write(blockData); err != nil {
+			t.Fatalf("Should be able to write block %d: %s", blockData.Header.Number, err)
+		}
+	}
+
+	// The index is rebuilt from the file, including the deletes.
+	if err := store.ResetTxIndex(); err != nil {
+		t.Fatalf("Should be able to reset the index: %s", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Should be able to close the store: %s", err)
+	}
+
+	store = open(t, dir)
+	defer store.Close()
+
+	iterate(t, store.ForEach(), 1, 2, 3)
+
+	if _, err := store.GetTxLocation(blocks[0].Trans[0].TxHash()); !errors.Is(err, kv.ErrNotFound) {
+		t.Fatalf("Should keep the index entries removed, got %v", err)
+	}
+}
+
+func TestCorruptFile(t *testing.T) {
+	tt := []struct {
+		name   string
+		damage func(data []byte) []byte
+	}{
+		{"truncated", func(data []byte) []byte { return data[:len(data)-5] }},
+		{"corrupt", func(data []byte) []byte { data[len(data)-5] ^= 0xFF; return data }},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := open(t, dir)
+
+			blocks := newBlocks(t, 3)
+			for _, blockData := range blocks {
+				if err := store.Write(blockData); err != nil {
+					t.Fatalf("Should be able to write block %d: %s", blockData.Header.Number, err)
+				}
+			}
+			store.Close()
+
+			// Damage the last record, which holds block 3.
+			path := filepath.Join(dir, "chain.kv")
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Should be able to read the file: %s", err)
+			}
+			if err := os.WriteFile(path, tst.damage(data), 0600); err != nil {
+				t.Fatalf("Should be able to write the file: %s", err)
+			}
+
+			// The damaged record is cut off and the store keeps working.
+			store = open(t, dir)
+			iterate(t, store.ForEach(), 1, 2)
+
+			if err := store.Write(blocks[2]); err != nil {
+				t.Fatalf("Should be able to write the block again: %s", err)
+			}
+			store.Close()
+
+			store = open(t, dir)
+			defer store.Close()
+
+			iterate(t, store.ForEach(), 1, 2, 3)
+		})
+	}
+}
+
+// =============================================================================
+
+// open opens the store in the directory.
+func open(t *testing.T, dir string) *kv.KV {
+	t.Helper()
+
+	store, err := kv.New(dir)
+	if err != nil {
+		t.Fatalf("Should be able to open the store: %s", err)
+	}
+
+	return store
+}
+
+// newBlocks constructs the number of blocks, each holding a transaction.
+func newBlocks(t *testing.T, n int) []database.BlockData {
+	t.Helper()
+
+	pk, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Should be able to generate a key: %s", err)
+	}
+	from := database.PublicKeyToAccountID(pk.PublicKey)
+
+	blocks := make([]database.BlockData, n)
+	for i := range blocks {
+		tx, err := database.NewTx(1, uint64(i+1), from, from, 0, 1, nil)
+		if err != nil {
+			t.Fatalf("Should be able to construct the transaction: %s", err)
+		}
+		signedTx, err := tx.Sign(pk)
+		if err != nil {
+			t.Fatalf("Should be able to sign the transaction: %s", err)
+		}
+
+		blocks[i] = database.BlockData{
+			Hash:   fmt.Sprintf("0x%064x", i+1),
+			Header: database.BlockHeader{Number: uint64(i + 1)},
+			Trans:  []database.BlockTx{database.NewBlockTx(signedTx, 15, 1)},
+		}
+	}
+
+	return blocks
+}
+
+// iterate checks the iterator returns the blocks with the numbers.
+func iterate(t *testing.T, iter database.Iterator, numbers ...uint64) {
+	t.Helper()
+
+	var got []uint64
+	for blockData, err := iter.Next(); !iter.Done(); blockData, err = iter.Next() {
+		if err != nil {
+			t.Fatalf("Should be able to iterate the blocks: %s", err)
+		}
+		got = append(got, blockData.Header.Number)
+	}
+
+	if len(got) != len(numbers) {
+		t.Fatalf("Should iterate the blocks, got %v, exp %v", got, numbers)
+	}
+	for i := range got {
+		if got[i] != numbers[i] {
+			t.Fatalf("Should iterate the blocks, got %v, exp %v", got, numbers)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package kv
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned when a key doesn't exist in the store.
+var ErrNotFound = errors.New("key not found")
+
+// headerSize is the size of the header written before each record, holding
+// the checksum followed by the length of the key and value.
+const headerSize = 12
+
+// maxValueSize is the largest value a record can hold. Larger lengths in
+// a header mean the record is corrupt.
+const maxValueSize = 1 << 30
+
+// tombstone is the value length of a record that deletes the key.
+const tombstone = math.MaxUint32
+
+// entry represents a key and value written to the store. An entry marked
+// deleted removes the key.
+type entry struct {
+	key     []byte
+	value   []byte
+	deleted bool
+}
+
+// location is where the value for a key lives in the file.
+type location struct {
+	offset int64
+	size   uint32
+}
+
+// store is an append only key value store. Every write is appended to a
+// single file and an index of where the latest value for each key lives is
+// kept in memory and rebuilt from the file when the store is opened.
+type store struct {
+	mu    sync.RWMutex
+	file  *os.File
+	size  int64
+	index map[string]location
+}
+
+// openStore opens the store in the specified file, creating it if it
+// doesn't exist. A record left partly written by a crash is cut off.
+func openStore(path string) (*store, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	s := store{
+		file:  f,
+		index: make(map[string]location),
+	}
+
+	if err := s.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// load reads the records in the file to build the index. The file is
+// truncated after the last complete record.
+func (s *store) load() error {
+	r := bufio.NewReader(io.NewSectionReader(s.file, 0, math.MaxInt64))
+
+	var offset int64
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			break
+		}
+
+		sum := binary.BigEndian.Uint32(header[0:4])
+		keyLen := binary.BigEndian.Uint32(header[4:8])
+		valueLen := binary.BigEndian.Uint32(header[8:12])
+
+		deleted := valueLen == tombstone
+		if deleted {
+			valueLen = 0
+		}
+		if keyLen > maxValueSize || valueLen > maxValueSize {
+			break
+		}
+
+		data := make([]byte, keyLen+valueLen)
+		if _, err := io.ReadFull(r, data); err != nil {
+			break
+		}
+
+		crc := crc32.NewIEEE()
+		crc.Write(header[4:])
+		crc.Write(data)
+		if crc.Sum32() != sum {
+			break
+		}
+
+		key := string(data[:keyLen])
+		switch {
+		case deleted:
+			delete(s.index, key)
+		default:
+			s.index[key] = location{
+				offset: offset + headerSize + int64(keyLen),
+				size:   valueLen,
+			}
+		}
+		offset += headerSize + int64(keyLen) + int64(valueLen)
+	}
+
+	s.size = offset
+
+	return s.file.Truncate(offset)
+}
+
+// get returns the latest value stored for the key.
+func (s *store) get(key []byte) ([]byte, error) {
+	s.mu.RLock()
+	loc, exists := s.index[string(key)]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	value := make([]byte, loc.size)
+	if _, err := s.file.ReadAt(value, loc.offset); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// put appends the entries to the file with a single write and syncs the
+// file before the index is updated. If a crash cuts the write short, the
+// entries before the cut are kept, so callers put the entry that marks the
+// batch as complete last.
+func (s *store) put(entries ...entry) error {
+	var buf []byte
+	for _, e := range entries {
+		valueLen := uint32(len(e.value))
+		if e.deleted {
+			valueLen = tombstone
+		}
+
+		header := make([]byte, headerSize)
+		binary.BigEndian.PutUint32(header[4:8], uint32(len(e.key)))
+		binary.BigEndian.PutUint32(header[8:12], valueLen)
+
+		crc := crc32.NewIEEE()
+		crc.Write(header[4:])
+		crc.Write(e.key)
+		crc.Write(e.value)
+		binary.BigEndian.PutUint32(header[0:4], crc.Sum32())
+
+		buf = append(buf, header...)
+		buf = append(buf, e.key...)
+		buf = append(buf, e.value...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.WriteAt(buf, s.size); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+
+	offset := s.size
+	for _, e := range entries {
+		switch {
+		case e.deleted:
+			delete(s.index, string(e.key))
+		default:
+			s.index[string(e.key)] = location{
+				offset: offset + headerSize + int64(len(e.key)),
+				size:   uint32(len(e.value)),
+			}
+		}
+		offset += headerSize + int64(len(e.key)) + int64(len(e.value))
+	}
+	s.size = offset
+
+	return nil
+}
+
+// deletePrefix removes every key starting with the prefix.
+func (s *store) deletePrefix(prefix []byte) error {
+	s.mu.RLock()
+	var entries []entry
+	for key := range s.index {
+		if strings.HasPrefix(key, string(prefix)) {
+			entries = append(entries, entry{key: []byte(key), deleted: true})
+		}
+	}
+	s.mu.RUnlock()
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	return s.put(entries...)
+}
+
+// reset removes every key from the store.
+func (s *store) reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+
+	s.size = 0
+	s.index = make(map[string]location)
+
+	return nil
+}
+
+// close closes the file backing the store.
+func (s *store) close() error {
+	return s.file.Close()
+}
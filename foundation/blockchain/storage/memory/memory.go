@@ -0,0 +1,187 @@
+// Package memory implements the ability to hold blocks in memory. Nothing is
+// written to disk so it's used by tools that need a throw away database.
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// Memory represents the storage implementation for holding blocks in
+// memory. This implements the database.Storage interface.
+type Memory struct {
+	mu       sync.RWMutex
+	blocks   map[uint64]database.BlockData
+	receipts map[uint64][]database.Receipt
+	diffs    map[uint64]database.StateDiff
+	fees     map[uint64]database.BlockFees
+}
+
+// New constructs an empty Memory value for use.
+func New() *Memory {
+	return &Memory{
+		blocks:   make(map[uint64]database.BlockData),
+		receipts: make(map[uint64][]database.Receipt),
+		diffs:    make(map[uint64]database.StateDiff),
+		fees:     make(map[uint64]database.BlockFees),
+	}
+}
+
+// Close in this implementation has nothing to do.
+func (m *Memory) Close() error {
+	return nil
+}
+
+// Write stores the specified block by its block number.
+func (m *Memory) Write(blockData database.BlockData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.blocks[blockData.Header.Number] = blockData
+	return nil
+}
+
+// GetBlock returns the contents of the specified block by number.
+func (m *Memory) GetBlock(num uint64) (database.BlockData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	blockData, exists := m.blocks[num]
+	if !exists {
+		return database.BlockData{}, fmt.Errorf("block %d not found", num)
+	}
+
+	return blockData, nil
+}
+
+// WriteReceipts stores the receipts for the specified block number.
+func (m *Memory) WriteReceipts(num uint64, receipts []database.Receipt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.receipts[num] = receipts
+	return nil
+}
+
+// GetReceipts returns the receipts for the specified block number.
+func (m *Memory) GetReceipts(num uint64) ([]database.Receipt, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	receipts, exists := m.receipts[num]
+	if !exists {
+		return nil, fmt.Errorf("receipts for block %d not found", num)
+	}
+
+	return receipts, nil
+}
+
+// WriteStateDiff stores the state diff for the specified block number.
+func (m *Memory) WriteStateDiff(num uint64, diff database.StateDiff) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.diffs[num] = diff
+	return nil
+}
+
+// GetStateDiff returns the state diff for the specified block number.
+func (m *Memory) GetStateDiff(num uint64) (database.StateDiff, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	diff, exists := m.diffs[num]
+	if !exists {
+		return database.StateDiff{}, fmt.Errorf("state diff for block %d not found", num)
+	}
+
+	return diff, nil
+}
+
+// WriteFees stores the fee statistics for the specified block number.
+func (m *Memory) WriteFees(num uint64, fees database.BlockFees) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.fees[num] = fees
+	return nil
+}
+
+// GetFees returns the fee statistics for the specified block number.
+func (m *Memory) GetFees(num uint64) (database.BlockFees, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	fees, exists := m.fees[num]
+	if !exists {
+		return database.BlockFees{}, fmt.Errorf("fees for block %d not found", num)
+	}
+
+	return fees, nil
+}
+
+// ForEach returns an iterator to walk through all the blocks
+// starting with block number 1.
+func (m *Memory) ForEach() database.Iterator {
+	return m.ForEachFrom(1)
+}
+
+// ForEachFrom returns an iterator to walk through the blocks starting with
+// the specified block number.
+func (m *Memory) ForEachFrom(num uint64) database.Iterator {
+	if num == 0 {
+		num = 1
+	}
+
+	return &memoryIterator{storage: m, current: num - 1}
+}
+
+// Reset will clear out the blocks and receipts.
+func (m *Memory) Reset() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.blocks = make(map[uint64]database.BlockData)
+	m.receipts = make(map[uint64][]database.Receipt)
+	m.diffs = make(map[uint64]database.StateDiff)
+	m.fees = make(map[uint64]database.BlockFees)
+	return nil
+}
+
+// =============================================================================
+
+// memoryIterator represents the iteration implementation for walking
+// through the blocks held in memory. This implements the database
+// Iterator interface.
+type memoryIterator struct {
+	storage *Memory // Access to the storage API.
+	current uint64  // Current block number being iterated over.
+	eoc     bool    // Represents the iterator is at the end of the chain.
+}
+
+// Next retrieves the next block.
+func (mi *memoryIterator) Next() (database.BlockData, error) {
+	if mi.eoc {
+		return database.BlockData{}, errors.New("end of chain")
+	}
+
+	mi.current++
+
+	mi.storage.mu.RLock()
+	blockData, exists := mi.storage.blocks[mi.current]
+	mi.storage.mu.RUnlock()
+
+	if !exists {
+		mi.eoc = true
+	}
+
+	return blockData, nil
+}
+
+// Done returns the end of chain value.
+func (mi *memoryIterator) Done() bool {
+	return mi.eoc
+}
@@ -0,0 +1,30 @@
+// Package storage constructs the storage backends that implement the
+// database.Storage interface.
+package storage
+
+import (
+	"fmt"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/disk"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/kv"
+)
+
+// Set of storage engines that can be selected.
+const (
+	EngineDisk = "disk" // A JSON file per block, easy to read by hand.
+	EngineKV   = "kv"   // A key value store in a single file, fast random access.
+)
+
+// New constructs the storage for the specified engine in the database
+// directory.
+func New(engine string, dbPath string) (database.Storage, error) {
+	switch engine {
+	case EngineDisk:
+		return disk.New(dbPath)
+	case EngineKV:
+		return kv.New(dbPath)
+	}
+
+	return nil, fmt.Errorf("unknown storage engine %q, must be %s or %s", engine, EngineDisk, EngineKV)
+}
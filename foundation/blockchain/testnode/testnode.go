@@ -0,0 +1,387 @@
+// Package testnode starts blockchain nodes inside the test binary so the
+// workflows between nodes, such as transaction gossip, block propagation,
+// syncing and reorgs, can be tested end to end with go test. Each node gets
+// its own temporary database directory, miner account and httptest servers,
+// and the chain is funded with a set of accounts the test can sign with.
+package testnode
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/hash"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/mempool/selector"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/worker"
+	"github.com/ardanlabs/blockchain/foundation/tracing"
+)
+
+// Set of defaults used when the config leaves a value at zero.
+const (
+	defaultAccounts = 2
+	defaultBalance  = 1_000_000
+	syncTimeout     = 10 * time.Second
+	shutdownTimeout = 10 * time.Second
+	pollInterval    = 10 * time.Millisecond
+)
+
+// Handler constructs the http.Handler that serves an API for the node. The
+// handlers live in the application layer, so the test provides them.
+type Handler func(st *state.State) http.Handler
+
+// Config represents the settings for starting a node.
+type Config struct {
+	Genesis  genesis.Genesis // Chain settings, funded accounts are added to the balances.
+	Accounts int             // Number of funded accounts, 2 when zero.
+	Balance  uint64          // Balance of each funded account, 1,000,000 when zero.
+	AutoMine bool            // Mine as transactions arrive instead of waiting for Mine.
+	Storage  string          // Storage engine, disk when empty.
+	Public   Handler         // Serves the public API, not started when nil.
+	Private  Handler         // Serves the private API, required to take part in a network.
+	Peers    []*Node         // Nodes to join, the chain settings of the first are used.
+	Chaos    state.Chaos     // Faults injected into the messages sent to peers.
+	Tracer   *tracing.Tracer // Traces the node, the handlers must be given it too.
+	Log      state.Logger    // Receives the node logs, discarded when nil.
+}
+
+// Node represents a blockchain node running in process.
+type Node struct {
+	State    *state.State
+	Genesis  genesis.Genesis
+	Accounts []*ecdsa.PrivateKey
+	Miner    *ecdsa.PrivateKey
+	Public   *httptest.Server
+	Private  *httptest.Server
+	host     string
+	log      state.Logger
+}
+
+// New starts a node and registers its shutdown with the test. The node is
+// synced with its peers before it's returned. Unless AutoMine is set, blocks
+// are only mined when Mine is called so the test decides when blocks are
+// produced.
+func New(t testing.TB, cfg Config) *Node {
+	t.Helper()
+
+	n, err := start(t, cfg)
+	if err != nil {
+		t.Fatalf("testnode: %s", err)
+	}
+
+	return n
+}
+
+// NewNetwork starts the number of nodes specified, every node joining the
+// ones started before it so they share the same chain.
+func NewNetwork(t testing.TB, count int, cfg Config) []*Node {
+	t.Helper()
+
+	nodes := make([]*Node, count)
+	for i := range nodes {
+		cfg.Peers = nodes[:i]
+		nodes[i] = New(t, cfg)
+	}
+
+	return nodes
+}
+
+// =============================================================================
+
+// AccountID returns the account of the funded account by index.
+func (n *Node) AccountID(i int) database.AccountID {
+	return database.PublicKeyToAccountID(n.Accounts[i].PublicKey)
+}
+
+// MinerID returns the account credited with the rewards of the blocks this
+// node mines.
+func (n *Node) MinerID() database.AccountID {
+	return database.PublicKeyToAccountID(n.Miner.PublicKey)
+}
+
+// Host returns the host of the private API peers use to reach the node.
+func (n *Node) Host() string {
+	return n.host
+}
+
+// Submit signs a transaction from the funded account by index with its next
+// nonce and submits it to the node, which shares it with its peers.
+func (n *Node) Submit(from int, toID database.AccountID, value uint64, tip uint64, data []byte) (database.SignedTx, error) {
+	fromID := n.AccountID(from)
+	nonce := n.State.QueryPendingBalance(fromID).PendingNonce + 1
+
+	tx, err := database.NewTx(n.Genesis.ChainID, nonce, fromID, toID, value, tip, data)
+	if err != nil {
+		return database.SignedTx{}, err
+	}
+
+	signedTx, err := tx.Sign(n.Accounts[from])
+	if err != nil {
+		return database.SignedTx{}, err
+	}
+
+	if err := n.State.UpsertWalletTransaction(context.Background(), signedTx); err != nil {
+		return database.SignedTx{}, err
+	}
+
+	return signedTx, nil
+}
+
+// Mine mines a block with the transactions in the mempool and proposes it
+// to the peers, the same as the worker does after mining a block. A peer
+// rejecting the block is only logged, since the block is part of this
+// node's chain either way.
+func (n *Node) Mine(ctx context.Context) (database.Block, error) {
+	block, err := n.State.MineNewBlock(ctx)
+	if err != nil {
+		return database.Block{}, err
+	}
+
+	if err := n.State.NetSendBlockToPeers(ctx, block); err != nil {
+		n.log("testnode: Mine: proposeBlockToPeers", "ERROR", err)
+	}
+
+	return block, nil
+}
+
+// WaitForBlock waits for the latest block of the node to reach the number
+// specified.
+func (n *Node) WaitForBlock(ctx context.Context, number uint64) (database.Block, error) {
+	return n.waitFor(ctx, func() bool {
+		return n.State.LatestBlock().Header.Number >= number
+	})
+}
+
+// WaitForMempool waits for the mempool of the node to hold the number of
+// transactions specified, such as the transactions shared by a peer.
+func (n *Node) WaitForMempool(ctx context.Context, count int) error {
+	_, err := n.waitFor(ctx, func() bool {
+		return n.State.MempoolLength() >= count
+	})
+
+	return err
+}
+
+// Connect makes the nodes known to each other so transactions and blocks
+// are shared between them.
+func (n *Node) Connect(other *Node) {
+	n.State.AddKnownPeer(peer.New(other.host))
+	other.State.AddKnownPeer(peer.New(n.host))
+}
+
+// Disconnect makes the nodes forget each other, so each can build its own
+// fork of the chain until they are connected again.
+func (n *Node) Disconnect(other *Node) {
+	n.State.RemoveKnownPeer(peer.New(other.host))
+	other.State.RemoveKnownPeer(peer.New(n.host))
+}
+
+// =============================================================================
+
+// start constructs the node and registers its shutdown with the test.
+func start(t testing.TB, cfg Config) (*Node, error) {
+	gen, accounts, err := chain(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every hash the node produces must use the hash function of the chain.
+	if err := hash.Select(gen.Hash); err != nil {
+		return nil, fmt.Errorf("genesis hash: %w", err)
+	}
+
+	miner, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("generating miner key: %w", err)
+	}
+
+	engine := cfg.Storage
+	if engine == "" {
+		engine = storage.EngineDisk
+	}
+
+	strg, err := storage.New(engine, t.TempDir())
+	if err != nil {
+		return nil, err
+	}
+
+	// The listeners are created before the state so the node knows the host
+	// its peers reach it on. The servers start once the handlers exist.
+	var public, private *httptest.Server
+	if cfg.Public != nil {
+		public = httptest.NewUnstartedServer(nil)
+	}
+
+	var host string
+	if cfg.Private != nil {
+		private = httptest.NewUnstartedServer(nil)
+		host = private.Listener.Addr().String()
+	}
+
+	peerSet := peer.NewPeerSet()
+	peerSet.Add(peer.New(host))
+	for _, p := range cfg.Peers {
+		peerSet.Add(peer.New(p.host))
+	}
+
+	// The worker calls the logger without checking it was provided.
+	log := cfg.Log
+	if log == nil {
+		log = func(v ...any) {}
+	}
+
+	signer := database.NewECDSASigner(miner)
+
+	st, err := state.New(state.Config{
+		BeneficiaryID:  signer.AccountID(),
+		Signer:         signer,
+		Host:           host,
+		Storage:        strg,
+		Genesis:        gen,
+		SelectStrategy: selector.StrategyTip,
+		KnownPeers:     peerSet,
+		Chaos:          cfg.Chaos,
+		Tracer:         cfg.Tracer,
+		Log:            log,
+	})
+	if err != nil {
+		closeServers(public, private)
+		return nil, err
+	}
+
+	worker.Run(st, log)
+	if !cfg.AutoMine {
+		st.PauseMining()
+	}
+
+	n := Node{
+		State:    st,
+		Genesis:  gen,
+		Accounts: accounts,
+		Miner:    miner,
+		Public:   public,
+		Private:  private,
+		host:     host,
+		log:      log,
+	}
+
+	if public != nil {
+		public.Config.Handler = cfg.Public(st)
+		public.Start()
+	}
+	if private != nil {
+		private.Config.Handler = cfg.Private(st)
+		private.Start()
+	}
+
+	t.Cleanup(func() {
+		closeServers(public, private)
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := st.Shutdown(ctx); err != nil {
+			t.Errorf("testnode: shutdown: %s", err)
+		}
+	})
+
+	// The peers learn about the node so they share with it too.
+	for _, p := range cfg.Peers {
+		p.State.AddKnownPeer(peer.New(host))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+	defer cancel()
+
+	if _, err := n.waitFor(ctx, st.IsSynced); err != nil {
+		return nil, fmt.Errorf("waiting for sync: %w", err)
+	}
+
+	return &n, nil
+}
+
+// chain returns the genesis and funded accounts for the node. A node joining
+// peers uses the chain of the first peer.
+func chain(cfg Config) (genesis.Genesis, []*ecdsa.PrivateKey, error) {
+	if len(cfg.Peers) > 0 {
+		return cfg.Peers[0].Genesis, cfg.Peers[0].Accounts, nil
+	}
+
+	gen := cfg.Genesis
+	if gen.Date.IsZero() {
+		gen.Date = time.Now().UTC()
+	}
+	if gen.ChainID == 0 {
+		gen.ChainID = 1
+	}
+	if gen.Difficulty == 0 {
+		gen.Difficulty = 1
+	}
+
+	count := cfg.Accounts
+	if count == 0 {
+		count = defaultAccounts
+	}
+
+	balance := cfg.Balance
+	if balance == 0 {
+		balance = defaultBalance
+	}
+
+	// The balances are copied so the config provided isn't changed.
+	balances := make(map[string]uint64, len(gen.Balances)+count)
+	for accountID, amount := range gen.Balances {
+		balances[accountID] = amount
+	}
+
+	accounts := make([]*ecdsa.PrivateKey, count)
+	for i := range accounts {
+		privateKey, err := crypto.GenerateKey()
+		if err != nil {
+			return genesis.Genesis{}, nil, fmt.Errorf("generating account key: %w", err)
+		}
+
+		accounts[i] = privateKey
+		balances[string(database.PublicKeyToAccountID(privateKey.PublicKey))] = balance
+	}
+	gen.Balances = balances
+
+	return gen, accounts, nil
+}
+
+// waitFor polls the condition until it's met, returning the latest block of
+// the node at that time.
+func (n *Node) waitFor(ctx context.Context, cond func() bool) (database.Block, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if cond() {
+			return n.State.LatestBlock(), nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return database.Block{}, ctx.Err()
+		}
+	}
+}
+
+// closeServers closes the servers that were created.
+func closeServers(servers ...*httptest.Server) {
+	for _, srv := range servers {
+		if srv != nil {
+			srv.Close()
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package testnode_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/testnode"
+)
+
+func TestMine(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	n := testnode.New(t, testnode.Config{})
+
+	signedTx, err := n.Submit(0, n.AccountID(1), 100, 10, nil)
+	if err != nil {
+		t.Fatalf("Should be able to submit a transaction: %s", err)
+	}
+	if l := n.State.MempoolLength(); l != 1 {
+		t.Fatalf("Should hold the transaction in the mempool, got %d", l)
+	}
+
+	block, err := n.Mine(ctx)
+	if err != nil {
+		t.Fatalf("Should be able to mine a block: %s", err)
+	}
+	if block.Header.Number != 1 || n.State.LatestBlock().Hash() != block.Hash() {
+		t.Fatalf("Should add the block to the chain, got block %d", block.Header.Number)
+	}
+	if l := n.State.MempoolLength(); l != 0 {
+		t.Fatalf("Should remove the mined transaction from the mempool, got %d", l)
+	}
+
+	_, receipt, err := n.State.QueryTx(signedTx.TxHash())
+	if err != nil {
+		t.Fatalf("Should be able to find the transaction: %s", err)
+	}
+	if receipt.BlockNumber != 1 || receipt.Status != database.ReceiptStatusSuccess {
+		t.Fatalf("Should mine the transaction in block 1, got %d:%s", receipt.BlockNumber, receipt.Status)
+	}
+
+	account, err := n.State.QueryAccount(n.AccountID(1))
+	if err != nil {
+		t.Fatalf("Should be able to query the account: %s", err)
+	}
+	if exp := uint64(1_000_000 + 100); account.Balance != exp {
+		t.Fatalf("Should credit the recipient, got %d, exp %d", account.Balance, exp)
+	}
+
+	miner, err := n.State.QueryAccount(n.MinerID())
+	if err != nil {
+		t.Fatalf("Should be able to query the miner: %s", err)
+	}
+	if miner.Balance == 0 {
+		t.Fatal("Should reward the miner")
+	}
+}
+
+func TestAutoMine(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	n := testnode.New(t, testnode.Config{AutoMine: true})
+
+	signedTx, err := n.Submit(0, n.AccountID(1), 100, 10, nil)
+	if err != nil {
+		t.Fatalf("Should be able to submit a transaction: %s", err)
+	}
+
+	if _, err := n.WaitForBlock(ctx, 1); err != nil {
+		t.Fatalf("Should mine the transaction without being asked: %s", err)
+	}
+
+	_, receipt, err := n.State.QueryTx(signedTx.TxHash())
+	if err != nil {
+		t.Fatalf("Should be able to find the transaction: %s", err)
+	}
+	if receipt.BlockNumber != 1 {
+		t.Fatalf("Should mine the transaction in block 1, got %d", receipt.BlockNumber)
+	}
+}
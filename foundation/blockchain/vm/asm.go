@@ -0,0 +1,56 @@
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// opcodes maps the mnemonic for each opcode to its value.
+var opcodes = map[string]byte{
+	"push":     OpPush,
+	"add":      OpAdd,
+	"store":    OpStore,
+	"load":     OpLoad,
+	"transfer": OpTransfer,
+}
+
+// Assemble converts a program written with mnemonics into the transaction
+// data to execute it. Instructions are separated by whitespace and the push
+// instruction is followed by its value.
+//
+//	push 10 push 1 store push 1 load push 5 add
+func Assemble(src string) ([]byte, error) {
+	var program []byte
+
+	fields := strings.Fields(strings.ToLower(src))
+	for i := 0; i < len(fields); i++ {
+		op, exists := opcodes[fields[i]]
+		if !exists {
+			return nil, fmt.Errorf("unknown instruction %q", fields[i])
+		}
+		program = append(program, op)
+
+		if op != OpPush {
+			continue
+		}
+
+		if i+1 == len(fields) {
+			return nil, errors.New("push is missing its value")
+		}
+		i++
+
+		v, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("push value %q: %w", fields[i], err)
+		}
+
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], v)
+		program = append(program, b[:]...)
+	}
+
+	return Code(program), nil
+}
@@ -0,0 +1,253 @@
+// Package vm provides a tiny stack based virtual machine for executing the
+// bytecode carried in the data field of a transaction. Each opcode costs gas
+// which is paid for by the account sending the transaction. Without gas a
+// program could make every node in the network perform an unbounded amount
+// of work for free.
+//
+// A program runs on behalf of the account sending the transaction. It can
+// read and write the storage of that account and transfer value from that
+// account to the recipient of the transaction. The machine doesn't change
+// any state itself, it reports the changes so the database can apply them
+// only when the program succeeds.
+package vm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// header marks transaction data as bytecode. The last byte is the version
+// of the bytecode format.
+var header = []byte{'T', 'V', 'M', 1}
+
+// Set of opcodes supported by the machine.
+const (
+	OpPush     byte = 0x01 // Pushes the 8 byte big endian value that follows.
+	OpAdd      byte = 0x02 // Pops two values and pushes their sum.
+	OpStore    byte = 0x03 // Pops a key then a value and stores the value.
+	OpLoad     byte = 0x04 // Pops a key and pushes the stored value.
+	OpTransfer byte = 0x05 // Pops an amount and transfers it to the recipient.
+)
+
+// gas defines the units of gas each opcode costs. Touching storage costs
+// more since every node must keep the result.
+var gas = map[byte]uint64{
+	OpPush:     1,
+	OpAdd:      1,
+	OpStore:    5,
+	OpLoad:     2,
+	OpTransfer: 3,
+}
+
+// Limits on the resources a program can use.
+const (
+	MaxGas   = 10_000
+	maxStack = 1024
+)
+
+// Set of errors returned when executing a program.
+var (
+	ErrNotCode        = errors.New("data is not bytecode")
+	ErrOutOfGas       = errors.New("out of gas")
+	ErrStackUnderflow = errors.New("stack underflow")
+	ErrStackOverflow  = errors.New("stack overflow")
+	ErrOverflow       = errors.New("integer overflow")
+	ErrInvalidOpcode  = errors.New("invalid opcode")
+)
+
+// Storage represents the behavior required to read the storage of the
+// account running the program.
+type Storage interface {
+	Load(key uint64) uint64
+}
+
+// Result represents the outcome of executing a program.
+type Result struct {
+	GasUsed     uint64            // Units of gas consumed by the program.
+	Stack       []uint64          // Values left on the stack, the output of the program.
+	Writes      map[uint64]uint64 // Storage values written by the program.
+	Transferred uint64            // Total value transferred to the recipient.
+}
+
+// IsCode reports if the transaction data contains bytecode.
+func IsCode(data []byte) bool {
+	return bytes.HasPrefix(data, header)
+}
+
+// Code returns the transaction data for the specified program.
+func Code(program []byte) []byte {
+	data := make([]byte, 0, len(header)+len(program))
+	data = append(data, header...)
+	data = append(data, program...)
+
+	return data
+}
+
+// Run executes the bytecode carried in the transaction data. Execution stops
+// with an error if the program uses more gas than the limit. The gas used is
+// always reported so it can be charged when the program fails.
+func Run(data []byte, gasLimit uint64, storage Storage) (Result, error) {
+	if !IsCode(data) {
+		return Result{}, ErrNotCode
+	}
+
+	if gasLimit > MaxGas {
+		gasLimit = MaxGas
+	}
+
+	m := machine{
+		storage: storage,
+		result: Result{
+			Writes: make(map[uint64]uint64),
+		},
+	}
+
+	code := data[len(header):]
+	for pc := 0; pc < len(code); pc++ {
+		op := code[pc]
+
+		cost, exists := gas[op]
+		if !exists {
+			return m.result, fmt.Errorf("%w 0x%02x at %d", ErrInvalidOpcode, op, pc)
+		}
+
+		if m.result.GasUsed+cost > gasLimit {
+			m.result.GasUsed = gasLimit
+			return m.result, ErrOutOfGas
+		}
+		m.result.GasUsed += cost
+
+		var err error
+		switch op {
+		case OpPush:
+			if pc+9 > len(code) {
+				return m.result, fmt.Errorf("push at %d: missing value", pc)
+			}
+			err = m.push(binary.BigEndian.Uint64(code[pc+1 : pc+9]))
+			pc += 8
+
+		case OpAdd:
+			err = m.add()
+
+		case OpStore:
+			err = m.store()
+
+		case OpLoad:
+			err = m.load()
+
+		case OpTransfer:
+			err = m.transfer()
+		}
+
+		if err != nil {
+			return m.result, fmt.Errorf("0x%02x at %d: %w", op, pc, err)
+		}
+	}
+
+	m.result.Stack = m.stack
+
+	return m.result, nil
+}
+
+// Add returns the sum of the values, or ErrOverflow when the sum doesn't fit
+// in a uint64. The machine adds values with it.
+func Add(a, b uint64) (uint64, error) {
+	if a > math.MaxUint64-b {
+		return 0, ErrOverflow
+	}
+
+	return a + b, nil
+}
+
+// =============================================================================
+
+// machine holds the state of a program while it executes.
+type machine struct {
+	stack   []uint64
+	storage Storage
+	result  Result
+}
+
+func (m *machine) push(v uint64) error {
+	if len(m.stack) == maxStack {
+		return ErrStackOverflow
+	}
+
+	m.stack = append(m.stack, v)
+	return nil
+}
+
+func (m *machine) pop() (uint64, error) {
+	if len(m.stack) == 0 {
+		return 0, ErrStackUnderflow
+	}
+
+	v := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	return v, nil
+}
+
+func (m *machine) add() error {
+	a, err := m.pop()
+	if err != nil {
+		return err
+	}
+	b, err := m.pop()
+	if err != nil {
+		return err
+	}
+
+	sum, err := Add(a, b)
+	if err != nil {
+		return err
+	}
+
+	return m.push(sum)
+}
+
+func (m *machine) store() error {
+	key, err := m.pop()
+	if err != nil {
+		return err
+	}
+	value, err := m.pop()
+	if err != nil {
+		return err
+	}
+
+	m.result.Writes[key] = value
+	return nil
+}
+
+func (m *machine) load() error {
+	key, err := m.pop()
+	if err != nil {
+		return err
+	}
+
+	// Values written by the program are seen before they are applied.
+	value, exists := m.result.Writes[key]
+	if !exists {
+		value = m.storage.Load(key)
+	}
+
+	return m.push(value)
+}
+
+func (m *machine) transfer() error {
+	amount, err := m.pop()
+	if err != nil {
+		return err
+	}
+
+	transferred, err := Add(m.result.Transferred, amount)
+	if err != nil {
+		return err
+	}
+
+	m.result.Transferred = transferred
+	return nil
+}
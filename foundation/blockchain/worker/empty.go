@@ -0,0 +1,32 @@
+package worker
+
+import "time"
+
+// emptyBlockCheckInterval represents the interval of checking if the chain
+// has gone long enough without a block to mine an empty one.
+const emptyBlockCheckInterval = time.Second
+
+// emptyBlockOperations handles mining empty blocks. Mining is only signaled
+// when transactions arrive, so a node configured to keep the chain moving
+// has to be woken up when the mempool stays empty. The check is cheap and
+// does nothing when empty blocks are turned off, which is the default so a
+// development machine isn't mining empty blocks all night.
+func (w *Worker) emptyBlockOperations() {
+	w.log("worker: emptyBlockOperations: G started")
+	defer w.log("worker: emptyBlockOperations: G completed")
+
+	ticker := time.NewTicker(emptyBlockCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !w.isShutdown() && w.state.IsMiningAllowed() && w.state.EmptyBlockDue() {
+				w.SignalStartMining()
+			}
+		case <-w.ctx.Done():
+			w.log("worker: emptyBlockOperations: received shut signal")
+			return
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package worker
+
+import "time"
+
+// mempoolExpireInterval represents the interval of checking the mempool for
+// transactions that have been waiting longer than their time to live.
+const mempoolExpireInterval = 30 * time.Second
+
+// mempoolExpireOperations handles evicting expired transactions from the
+// mempool. Transactions are also checked when a block is added, but a node
+// that isn't producing blocks still needs its mempool cleaned up.
+func (w *Worker) mempoolExpireOperations() {
+	w.log("worker: mempoolExpireOperations: G started")
+	defer w.log("worker: mempoolExpireOperations: G completed")
+
+	ticker := time.NewTicker(mempoolExpireInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !w.isShutdown() {
+				w.state.EvictExpiredTxs()
+			}
+		case <-w.ctx.Done():
+			w.log("worker: mempoolExpireOperations: received shut signal")
+			return
+		}
+	}
+}
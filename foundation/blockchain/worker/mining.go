@@ -0,0 +1,141 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+)
+
+// CORE NOTE: The POW mining operation is managed by this function which runs on
+// it's own goroutine. When a startMining signal is received (mainly because a
+// wallet transaction was received) a block is created and then the POW operation
+// starts. This operation can be cancelled if a proposed block is received and
+// is validated.
+
+// miningOperations handles mining.
+func (w *Worker) miningOperations() {
+	w.log("worker: miningOperations: G started")
+	defer w.log("worker: miningOperations: G completed")
+
+	for {
+		select {
+		case <-w.startMining:
+			if !w.isShutdown() {
+				w.runMiningOperation()
+			}
+		case <-w.ctx.Done():
+			w.log("worker: miningOperations: received shut signal")
+			return
+		}
+	}
+}
+
+// runMiningOperation takes all the transactions from the mempool and writes a
+// new block to the database.
+func (w *Worker) runMiningOperation() {
+	w.log("worker: runMiningOperation: MINING: started")
+	defer w.log("worker: runMiningOperation: MINING: completed")
+
+	// Make sure we are allowed to perform mining.
+	if !w.state.IsMiningAllowed() {
+		w.log("worker: runMiningOperation: MINING: turned off")
+		return
+	}
+
+	// Make sure there are at least transactions in the mempool, unless the
+	// chain has gone long enough without a block to mine an empty one.
+	length := w.state.MempoolLength()
+	if length == 0 && !w.state.EmptyBlockDue() {
+		w.log("worker: runMiningOperation: MINING: no transactions to mine", "txs", length)
+		return
+	}
+
+	// Set when none of the transactions in the mempool can be mined into the
+	// next block yet, such as transactions waiting for their window to open.
+	// Mining is signaled again when the next block arrives from a peer.
+	var idle bool
+
+	// After running a mining operation, check if a new operation should
+	// be signaled again.
+	defer func() {
+		length := w.state.MempoolLength()
+		if length > 0 && !idle && !w.isShutdown() {
+			w.log("worker: runMiningOperation: MINING: signal new mining operation", "txs", length)
+			w.SignalStartMining()
+		}
+	}()
+
+	// Drain the cancel mining channel before starting.
+	select {
+	case <-w.cancelMining:
+		w.log("worker: runMiningOperation: MINING: drained cancel channel")
+	default:
+	}
+
+	// Create a context so mining can be cancelled. Since it's derived from the
+	// worker context, a shutdown cancels the mining operation as well.
+	ctx, cancel := context.WithCancel(w.ctx)
+	defer cancel()
+
+	// Can't return from this function until these G's are complete.
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	// This G exists to cancel the mining operation.
+	go func() {
+		defer func() {
+			cancel()
+			wg.Done()
+		}()
+
+		select {
+		case <-w.cancelMining:
+			w.log("worker: runMiningOperation: MINING: CANCEL: requested")
+		case <-ctx.Done():
+			if w.isShutdown() {
+				w.log("worker: runMiningOperation: MINING: CANCEL: shutdown")
+			}
+		}
+	}()
+
+	// This G is performing the mining.
+	go func() {
+		defer func() {
+			cancel()
+			wg.Done()
+		}()
+
+		t := time.Now()
+		block, err := w.state.MineNewBlock(ctx)
+		duration := time.Since(t)
+
+		w.log("worker: runMiningOperation: MINING: mining duration", "duration", duration)
+
+		if err != nil {
+			switch {
+			case errors.Is(err, state.ErrNoTransactions):
+				w.log("worker: runMiningOperation: MINING: WARNING: no transactions in mempool")
+				idle = true
+			case ctx.Err() != nil:
+				w.log("worker: runMiningOperation: MINING: CANCEL: complete")
+			default:
+				w.log("worker: runMiningOperation: MINING", "ERROR", err)
+			}
+			return
+		}
+
+		w.log("worker: runMiningOperation: MINING: block mined", "blk", block.Header.Number, "hash", block.Hash())
+
+		// The block is mined. Propose the new block to the network.
+		// Log the error, but that's it.
+		if err := w.state.NetSendBlockToPeers(ctx, block); err != nil {
+			w.log("worker: runMiningOperation: MINING: proposeBlockToPeers", "ERROR", err)
+		}
+	}()
+
+	// Wait for both G's to terminate.
+	wg.Wait()
+}
@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+// peerOperations handles finding new peers.
+func (w *Worker) peerOperations() {
+	w.log("worker: peerOperations: G started")
+	defer w.log("worker: peerOperations: G completed")
+
+	// On startup talk to the origin node and get an updated
+	// peers list. Then share with the network that this
+	// node is available for transaction and block submissions.
+	w.runPeersOperation()
+
+	for {
+		select {
+		case <-w.ticker.C:
+			if !w.isShutdown() {
+				w.runPeersOperation()
+			}
+		case <-w.ctx.Done():
+			w.log("worker: peerOperations: received shut signal")
+			return
+		}
+	}
+}
+
+// runPeersOperation updates the peer list.
+func (w *Worker) runPeersOperation() {
+	w.log("worker: runPeersOperation: started")
+	defer w.log("worker: runPeersOperation: completed")
+
+	for _, peer := range w.state.KnownExternalPeers() {
+
+		// Retrieve the status of this peer.
+		peerStatus, err := w.state.NetRequestPeerStatus(peer)
+		if err != nil {
+			w.log("worker: runPeersOperation: requestPeerStatus", "peer", peer.Host, "ERROR", err)
+
+			// Since this peer is unavailable, remove them from the list.
+			w.state.RemoveKnownPeer(peer)
+
+			continue
+		}
+
+		// Add peers from this nodes peer list that we are missing.
+		w.addNewPeers(peerStatus.KnownPeers)
+
+		// Check the peer is on the same chain as this node.
+		w.state.CheckPeerStatus(peer, peerStatus)
+	}
+
+	// Share with peers this node is available to participate in the network.
+	w.state.NetSendNodeAvailableToPeers()
+}
+
+// addNewPeers takes the list of known peers and makes sure they are included
+// in the nodes list of know peers.
+func (w *Worker) addNewPeers(knownPeers []peer.Peer) {
+	w.log("worker: runPeerUpdatesOperation: addNewPeers: started")
+	defer w.log("worker: runPeerUpdatesOperation: addNewPeers: completed")
+
+	for _, peer := range knownPeers {
+
+		// Don't add this running node to the known peer list.
+		if peer.Match(w.state.Host()) {
+			continue
+		}
+
+		// Only log and pull their mempool when the peer is new.
+		if w.state.AddKnownPeer(peer) {
+			w.log("worker: runPeerUpdatesOperation: addNewPeers: add peer nodes", "peer", peer.Host)
+			w.SignalMempoolSync(peer)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package worker
+
+// shareTxOperations handles sharing new block transactions.
+func (w *Worker) shareTxOperations() {
+	w.log("worker: shareTxOperations: G started")
+	defer w.log("worker: shareTxOperations: G completed")
+
+	for {
+		select {
+		case share := <-w.txSharing:
+			if !w.isShutdown() {
+				w.state.NetSendTxToPeers(share.ctx, share.tx)
+			}
+		case <-w.ctx.Done():
+			w.log("worker: shareTxOperations: received shut signal")
+			return
+		}
+	}
+}
+
+// mempoolSyncOperations handles retrieving the missing transactions from the
+// mempool of newly connected peers.
+func (w *Worker) mempoolSyncOperations() {
+	w.log("worker: mempoolSyncOperations: G started")
+	defer w.log("worker: mempoolSyncOperations: G completed")
+
+	for {
+		select {
+		case pr := <-w.mempoolSync:
+			if !w.isShutdown() {
+				if err := w.state.NetSyncPeerMempool(pr); err != nil {
+					w.log("worker: mempoolSyncOperations: syncPeerMempool", "peer", pr.Host, "ERROR", err)
+				}
+			}
+		case <-w.ctx.Done():
+			w.log("worker: mempoolSyncOperations: received shut signal")
+			return
+		}
+	}
+}
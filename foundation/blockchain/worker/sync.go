@@ -0,0 +1,54 @@
+package worker
+
+import "github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+
+// CORE NOTE: The p2p network is managed by this goroutine. There is
+// a single node that is considered the origin node. The defaults in
+// main.go represent the origin node. That node must be running first.
+// All new peer nodes connect to the origin node to identify all other
+// peers on the network. The topology is all nodes having a connection
+// to all other nodes. If a node does not respond to a network call,
+// they are removed from the peer list until the next peer operation.
+
+// Sync updates the peer list, mempool and blocks.
+func (w *Worker) Sync() {
+	w.log("worker: sync: started")
+	defer w.log("worker: sync: completed")
+
+	// The latest block of each peer that has blocks this node is missing.
+	ahead := make(map[peer.Peer]uint64)
+
+	for _, peer := range w.state.KnownExternalPeers() {
+
+		// Retrieve the status of this peer.
+		peerStatus, err := w.state.NetRequestPeerStatus(peer)
+		if err != nil {
+			w.log("worker: sync: queryPeerStatus", "peer", peer.Host, "ERROR", err)
+			continue
+		}
+
+		// Add new peers to this nodes list.
+		w.addNewPeers(peerStatus.KnownPeers)
+
+		// Retrieve the transactions from the peer's mempool we are missing.
+		if err := w.state.NetSyncPeerMempool(peer); err != nil {
+			w.log("worker: sync: syncPeerMempool", "peer", peer.Host, "ERROR", err)
+		}
+
+		// If this peer has blocks we don't have, we need to add them.
+		if peerStatus.LatestBlockNumber > w.state.LatestBlock().Header.Number {
+			w.log("worker: sync: peer has blocks", "peer", peer.Host, "latestBlockNumber", peerStatus.LatestBlockNumber)
+			ahead[peer] = peerStatus.LatestBlockNumber
+		}
+	}
+
+	// Retrieve the missing blocks from all the peers that have them.
+	if len(ahead) > 0 {
+		if err := w.state.NetSyncBlocks(ahead); err != nil {
+			w.log("worker: sync: retrievePeerBlocks", "ERROR", err)
+		}
+	}
+
+	// Share with peers this node is available to participate in the network.
+	w.state.NetSendNodeAvailableToPeers()
+}
@@ -0,0 +1,202 @@
+// Package worker implements mining, peer updates, and transaction sharing for
+// the blockchain.
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/tracing"
+)
+
+// peerUpdateInterval represents the interval of finding new peer nodes
+// and updating the blockchain on disk with missing blocks.
+const peerUpdateInterval = time.Minute
+
+// maxTxShareRequests represents the max number of pending tx network share
+// requests that can be outstanding before share requests are dropped. To keep
+// this simple, a buffered channel of this arbitrary number is being used. If
+// the channel does become full, requests for new transactions to be shared
+// will not be accepted.
+const maxTxShareRequests = 100
+
+// maxMempoolSyncRequests represents the max number of pending peer mempool
+// sync requests that can be outstanding before requests are dropped.
+const maxMempoolSyncRequests = 10
+
+// =============================================================================
+
+// shareTx represents a transaction waiting to be shared with the peers and
+// the trace it was accepted in.
+type shareTx struct {
+	ctx context.Context
+	tx  database.BlockTx
+}
+
+// Worker manages the POW workflows for the blockchain.
+type Worker struct {
+	state        *state.State
+	wg           sync.WaitGroup
+	ticker       *time.Ticker
+	ctx          context.Context
+	cancel       context.CancelFunc
+	startMining  chan bool
+	cancelMining chan bool
+	txSharing    chan shareTx
+	mempoolSync  chan peer.Peer
+	log          state.Logger
+}
+
+// Run creates a worker, registers the worker with the state package, and
+// starts up all the background processes.
+func Run(st *state.State, log state.Logger) {
+
+	// The worker context is cancelled on shutdown. Every operation watches
+	// this context and any mining operation in progress is derived from it.
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := Worker{
+		state:        st,
+		ticker:       time.NewTicker(peerUpdateInterval),
+		ctx:          ctx,
+		cancel:       cancel,
+		startMining:  make(chan bool, 1),
+		cancelMining: make(chan bool, 1),
+		txSharing:    make(chan shareTx, maxTxShareRequests),
+		mempoolSync:  make(chan peer.Peer, maxMempoolSyncRequests),
+		log:          log,
+	}
+
+	// Register this worker with the state package.
+	st.Worker = &w
+
+	// Update this node before starting any support G's. The sync runs in the
+	// background so the API can start and report the node isn't ready yet.
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		w.Sync()
+		if w.isShutdown() {
+			return
+		}
+
+		st.MarkSynced()
+		w.startOperations()
+	}()
+}
+
+// startOperations starts the support G's and waits for them to be running.
+func (w *Worker) startOperations() {
+
+	// Load the set of operations we need to run.
+	operations := []func(){
+		w.peerOperations,
+		w.miningOperations,
+		w.shareTxOperations,
+		w.mempoolSyncOperations,
+		w.mempoolExpireOperations,
+		w.emptyBlockOperations,
+	}
+
+	// Set waitgroup to match the number of G's we need for the set
+	// of operations we have.
+	g := len(operations)
+	w.wg.Add(g)
+
+	// We don't want to return until we know all the G's are up and running.
+	hasStarted := make(chan bool)
+
+	// Start all the operational G's.
+	for _, op := range operations {
+		go func(op func()) {
+			defer w.wg.Done()
+			hasStarted <- true
+			op()
+		}(op)
+	}
+
+	// Wait for the G's to report they are running.
+	for i := 0; i < g; i++ {
+		<-hasStarted
+	}
+}
+
+// =============================================================================
+// These methods implement the state.Worker interface.
+
+// Shutdown terminates the goroutine performing work.
+func (w *Worker) Shutdown() {
+	w.log("worker: shutdown: started")
+	defer w.log("worker: shutdown: completed")
+
+	w.log("worker: shutdown: stop ticker")
+	w.ticker.Stop()
+
+	w.log("worker: shutdown: cancel worker context")
+	w.cancel()
+
+	w.log("worker: shutdown: terminate goroutines")
+	w.wg.Wait()
+}
+
+// SignalStartMining starts a mining operation. If there is already a signal
+// pending in the channel, just return since a mining operation will start.
+func (w *Worker) SignalStartMining() {
+	if !w.state.IsMiningAllowed() {
+		w.log("worker: SignalStartMining: mining is turned off")
+		return
+	}
+
+	select {
+	case w.startMining <- true:
+	default:
+	}
+	w.log("worker: SignalStartMining: mining signaled")
+}
+
+// SignalCancelMining signals the G executing the runMiningOperation function
+// to stop immediately.
+func (w *Worker) SignalCancelMining() {
+	select {
+	case w.cancelMining <- true:
+	default:
+	}
+	w.log("worker: SignalCancelMining: cancel signaled")
+}
+
+// SignalShareTx signals a share transaction operation. If
+// maxTxShareRequests signals exist in the channel, we won't send these. The
+// trace in the context is carried to the peers, the request accepting the
+// transaction has completed by the time it's shared.
+func (w *Worker) SignalShareTx(ctx context.Context, blockTx database.BlockTx) {
+	select {
+	case w.txSharing <- shareTx{ctx: tracing.Detach(ctx), tx: blockTx}:
+		w.log("worker: SignalShareTx: share Tx signaled")
+	default:
+		w.log("worker: SignalShareTx: queue full, transactions won't be shared.")
+	}
+}
+
+// SignalMempoolSync signals that a peer has connected and the transactions
+// in its mempool this node is missing should be retrieved. If
+// maxMempoolSyncRequests signals exist in the channel, we won't send these.
+func (w *Worker) SignalMempoolSync(pr peer.Peer) {
+	select {
+	case w.mempoolSync <- pr:
+		w.log("worker: SignalMempoolSync: mempool sync signaled", "peer", pr.Host)
+	default:
+		w.log("worker: SignalMempoolSync: queue full, mempool won't be synced", "peer", pr.Host)
+	}
+}
+
+// =============================================================================
+
+// isShutdown is used to test if a shutdown has been signaled.
+func (w *Worker) isShutdown() bool {
+	return w.ctx.Err() != nil
+}
@@ -0,0 +1,379 @@
+// Package client provides a Go SDK for the node's public API so programs
+// don't need to construct HTTP calls by hand.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/tracing"
+)
+
+// Set of default values used when the configuration doesn't provide them.
+const (
+	defaultTimeout    = 10 * time.Second
+	defaultRetryDelay = 250 * time.Millisecond
+)
+
+// Config represents the configuration for the client.
+type Config struct {
+	URL        string        // Base url of the node's public API, ex. http://localhost:8080
+	Timeout    time.Duration // Timeout for a single request.
+	Retries    int           // Number of times a failed request is retried.
+	RetryDelay time.Duration // Delay before the first retry, doubled for each retry.
+}
+
+// Client provides access to the node's public API.
+type Client struct {
+	url        string
+	http       *http.Client
+	retries    int
+	retryDelay time.Duration
+}
+
+// New constructs a client for the node's public API.
+func New(cfg Config) *Client {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.RetryDelay == 0 {
+		cfg.RetryDelay = defaultRetryDelay
+	}
+
+	return &Client{
+		url:        strings.TrimSuffix(cfg.URL, "/"),
+		http:       &http.Client{Timeout: cfg.Timeout},
+		retries:    cfg.Retries,
+		retryDelay: cfg.RetryDelay,
+	}
+}
+
+// =============================================================================
+
+// SubmitTransaction sends a signed transaction to the node to be added to
+// the mempool. Submitting the same transaction more than once is safe, the
+// node reporting the transaction is already pending isn't an error.
+func (c *Client) SubmitTransaction(ctx context.Context, tx database.SignedTx) error {
+	err := c.send(ctx, http.MethodPost, "/v1/tx/submit", tx, nil)
+	if ErrorCode(err) == CodeDuplicateTx {
+		return nil
+	}
+	return err
+}
+
+// SubmitTransactions sends a batch of signed transactions to the node. Each
+// transaction is validated independently and the result for every
+// transaction is returned in the same order as provided.
+func (c *Client) SubmitTransactions(ctx context.Context, txs []database.SignedTx) ([]BatchResult, error) {
+	var results []BatchResult
+	if err := c.send(ctx, http.MethodPost, "/v1/tx/batch", txs, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// CancelTransaction replaces the pending transaction for the account and
+// nonce of the specified transaction. The transaction must send no value to
+// the account itself and offer a higher tip than the pending transaction.
+func (c *Client) CancelTransaction(ctx context.Context, tx database.SignedTx) error {
+	path := fmt.Sprintf("/v1/tx/%s/%d", url.PathEscape(string(tx.FromID)), tx.Nonce)
+	return c.send(ctx, http.MethodDelete, path, tx, nil)
+}
+
+// Genesis returns the genesis information the node is running with.
+func (c *Client) Genesis(ctx context.Context) (genesis.Genesis, error) {
+	var gen genesis.Genesis
+	if err := c.send(ctx, http.MethodGet, "/v1/genesis/list", nil, &gen); err != nil {
+		return genesis.Genesis{}, err
+	}
+
+	return gen, nil
+}
+
+// Accounts returns the balance information for all the accounts.
+func (c *Client) Accounts(ctx context.Context) (AccountInfo, error) {
+	var info AccountInfo
+	if err := c.send(ctx, http.MethodGet, "/v1/accounts/list", nil, &info); err != nil {
+		return AccountInfo{}, err
+	}
+
+	return info, nil
+}
+
+// AccountsByBalance returns a page of the accounts ordered by balance,
+// largest first. Pages start at 1.
+func (c *Client) AccountsByBalance(ctx context.Context, page int, rows int) (AccountInfo, error) {
+	path := fmt.Sprintf("/v1/accounts/list?page=%d&rows=%d", page, rows)
+
+	var info AccountInfo
+	if err := c.send(ctx, http.MethodGet, path, nil, &info); err != nil {
+		return AccountInfo{}, err
+	}
+
+	return info, nil
+}
+
+// Account returns the balance information for the specified account. An
+// account the node has never seen is returned with a zero balance.
+func (c *Client) Account(ctx context.Context, accountID database.AccountID) (Account, error) {
+	var info AccountInfo
+	if err := c.send(ctx, http.MethodGet, "/v1/accounts/list/"+url.PathEscape(string(accountID)), nil, &info); err != nil {
+		return Account{}, err
+	}
+
+	if len(info.Accounts) == 0 {
+		return Account{AccountID: accountID}, nil
+	}
+
+	return info.Accounts[0], nil
+}
+
+// PendingBalance returns the balance information for the specified account
+// once the transactions in the mempool are applied.
+func (c *Client) PendingBalance(ctx context.Context, accountID database.AccountID) (PendingBalance, error) {
+	var pb PendingBalance
+	if err := c.send(ctx, http.MethodGet, "/v1/accounts/pending/"+url.PathEscape(string(accountID)), nil, &pb); err != nil {
+		return PendingBalance{}, err
+	}
+
+	return pb, nil
+}
+
+// AccountProof returns the specified account along with the merkle proof
+// tying it to the header of the latest block. The proof is checked with
+// Verify and the header hash compared with the hash other peers report.
+func (c *Client) AccountProof(ctx context.Context, accountID database.AccountID) (database.AccountProof, error) {
+	var ap database.AccountProof
+	if err := c.send(ctx, http.MethodGet, "/v1/accounts/proof/"+url.PathEscape(string(accountID)), nil, &ap); err != nil {
+		return database.AccountProof{}, err
+	}
+
+	return ap, nil
+}
+
+// Storage returns the value stored under the key in the contract storage of
+// the specified account.
+func (c *Client) Storage(ctx context.Context, accountID database.AccountID, key uint64) (uint64, error) {
+	var s Slot
+	path := fmt.Sprintf("/v1/accounts/storage/%s/%d", url.PathEscape(string(accountID)), key)
+	if err := c.send(ctx, http.MethodGet, path, nil, &s); err != nil {
+		return 0, err
+	}
+
+	return s.Value, nil
+}
+
+// Token returns the supply and balances for the specified token.
+func (c *Client) Token(ctx context.Context, symbol string) (database.Token, error) {
+	var token database.Token
+	if err := c.send(ctx, http.MethodGet, "/v1/tokens/"+url.PathEscape(symbol), nil, &token); err != nil {
+		return database.Token{}, err
+	}
+
+	return token, nil
+}
+
+// TokenBalance returns the balance of the token held by the specified account.
+func (c *Client) TokenBalance(ctx context.Context, symbol string, accountID database.AccountID) (uint64, error) {
+	var tb TokenBalance
+	path := "/v1/tokens/" + url.PathEscape(symbol) + "/balances/" + url.PathEscape(string(accountID))
+	if err := c.send(ctx, http.MethodGet, path, nil, &tb); err != nil {
+		return 0, err
+	}
+
+	return tb.Balance, nil
+}
+
+// Policy returns the rules of a permissioned chain.
+func (c *Client) Policy(ctx context.Context) (database.Policy, error) {
+	var policy database.Policy
+	if err := c.send(ctx, http.MethodGet, "/v1/policy", nil, &policy); err != nil {
+		return database.Policy{}, err
+	}
+
+	return policy, nil
+}
+
+// Name returns the account the specified name is registered to.
+func (c *Client) Name(ctx context.Context, name string) (database.AccountID, error) {
+	var n Name
+	if err := c.send(ctx, http.MethodGet, "/v1/names/"+url.PathEscape(name), nil, &n); err != nil {
+		return "", err
+	}
+
+	return n.AccountID, nil
+}
+
+// Blocks returns the blocks that contain transactions for the specified
+// account. If the account is empty, all the blocks are returned.
+func (c *Client) Blocks(ctx context.Context, accountID database.AccountID) ([]Block, error) {
+	path := "/v1/blocks/list"
+	if accountID != "" {
+		path += "/" + url.PathEscape(string(accountID))
+	}
+
+	var blocks []Block
+	if err := c.send(ctx, http.MethodGet, path, nil, &blocks); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// BlocksByRange returns a summary of the blocks in the specified range. Either
+// value can be "latest" to specify the latest block.
+func (c *Client) BlocksByRange(ctx context.Context, from string, to string) ([]BlockSummary, error) {
+	path := "/v1/blocks/list/" + url.PathEscape(from) + "/" + url.PathEscape(to)
+
+	var blocks []BlockSummary
+	if err := c.send(ctx, http.MethodGet, path, nil, &blocks); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// Mempool returns the uncommitted transactions for the specified account. If
+// the account is empty, all the uncommitted transactions are returned.
+func (c *Client) Mempool(ctx context.Context, accountID database.AccountID) ([]Tx, error) {
+	path := "/v1/tx/uncommitted/list"
+	if accountID != "" {
+		path += "/" + url.PathEscape(string(accountID))
+	}
+
+	var txs []Tx
+	if err := c.send(ctx, http.MethodGet, path, nil, &txs); err != nil {
+		return nil, err
+	}
+
+	return txs, nil
+}
+
+// Receipt returns the receipt for the transaction with the specified hash.
+func (c *Client) Receipt(ctx context.Context, txHash string) (database.Receipt, error) {
+	var receipt database.Receipt
+	if err := c.send(ctx, http.MethodGet, "/v1/tx/"+url.PathEscape(txHash)+"/receipt", nil, &receipt); err != nil {
+		return database.Receipt{}, err
+	}
+
+	return receipt, nil
+}
+
+// Tx returns the transaction recorded in the chain with the specified hash
+// along with its receipt.
+func (c *Client) Tx(ctx context.Context, txHash string) (TxLookup, error) {
+	var lookup TxLookup
+	if err := c.send(ctx, http.MethodGet, "/v1/tx/"+url.PathEscape(txHash), nil, &lookup); err != nil {
+		return TxLookup{}, err
+	}
+
+	return lookup, nil
+}
+
+// GasEstimate returns the tips to offer for a transaction to be included
+// based on the state of the mempool and recent blocks.
+func (c *Client) GasEstimate(ctx context.Context) (GasEstimate, error) {
+	var est GasEstimate
+	if err := c.send(ctx, http.MethodGet, "/v1/tx/gas-estimate", nil, &est); err != nil {
+		return GasEstimate{}, err
+	}
+
+	return est, nil
+}
+
+// =============================================================================
+
+// send performs the HTTP call against the node, retrying when the node can't
+// be reached or reports a server side failure. A 204 response leaves the
+// response value untouched.
+func (c *Client) send(ctx context.Context, method string, path string, dataSend any, dataRecv any) error {
+	var body []byte
+	if dataSend != nil {
+		data, err := json.Marshal(dataSend)
+		if err != nil {
+			return err
+		}
+		body = data
+	}
+
+	delay := c.retryDelay
+	for attempt := 0; ; attempt++ {
+		err := c.do(ctx, method, path, body, dataRecv)
+		if err == nil || attempt >= c.retries || !retryable(err) {
+			return err
+		}
+
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// do performs a single HTTP call against the node.
+func (c *Client) do(ctx context.Context, method string, path string, body []byte, dataRecv any) error {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	// A caller being traced has the node continue the trace.
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newError(resp)
+	}
+
+	if dataRecv != nil {
+		if err := json.NewDecoder(resp.Body).Decode(dataRecv); err != nil {
+			return fmt.Errorf("decoding response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// retryable identifies if the error is worth trying the request again.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	return true
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// Set of codes the node returns with a rejected transaction, so the wallet
+// can react to the failure without matching the message.
+const (
+	CodeInsufficientFunds = "insufficient_funds"
+	CodeBadNonce          = "bad_nonce"
+	CodeInvalidSignature  = "invalid_signature"
+	CodeChainIDMismatch   = "chain_id_mismatch"
+	CodeDuplicateTx       = "duplicate_tx"
+)
+
+// Error represents a failure reported by the node's API.
+type Error struct {
+	StatusCode int               `json:"-"`
+	Message    string            `json:"error"`
+	Code       string            `json:"code,omitempty"`
+	Fields     map[string]string `json:"fields,omitempty"`
+}
+
+// newError constructs an Error from the failed response.
+func newError(resp *http.Response) *Error {
+	apiErr := Error{StatusCode: resp.StatusCode}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return &apiErr
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// IsNotFound checks if the error represents something the node couldn't find.
+func IsNotFound(err error) bool {
+	return statusCode(err) == http.StatusNotFound
+}
+
+// IsBadRequest checks if the error represents a request the node rejected,
+// such as an invalid transaction.
+func IsBadRequest(err error) bool {
+	return statusCode(err) == http.StatusBadRequest
+}
+
+// ErrorCode returns the code the node reported with the error or an empty
+// string if the error has no code.
+func ErrorCode(err error) string {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+	return apiErr.Code
+}
+
+// statusCode returns the status code of the API error or 0 if the error
+// isn't an API error.
+func statusCode(err error) int {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return 0
+	}
+	return apiErr.StatusCode
+}
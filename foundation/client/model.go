@@ -0,0 +1,202 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// Account represents the balance information for an account.
+type Account struct {
+	AccountID database.AccountID      `json:"account"`
+	Balance   uint64                  `json:"balance"`
+	Nonce     uint64                  `json:"nonce"`
+	Multisig  *database.Multisig      `json:"multisig,omitempty"`
+	Rules     *database.SpendingRules `json:"rules,omitempty"`
+}
+
+// Slot represents a value held in the contract storage of an account.
+type Slot struct {
+	AccountID database.AccountID `json:"account"`
+	Key       uint64             `json:"key"`
+	Value     uint64             `json:"value"`
+}
+
+// TokenBalance represents the balance of a token held by an account.
+type TokenBalance struct {
+	Symbol    string             `json:"symbol"`
+	AccountID database.AccountID `json:"account"`
+	Balance   uint64             `json:"balance"`
+}
+
+// Name represents a name registered to an account.
+type Name struct {
+	Name      string             `json:"name"`
+	AccountID database.AccountID `json:"account"`
+}
+
+// PendingBalance represents the balance of an account once the transactions
+// in the mempool are applied.
+type PendingBalance struct {
+	AccountID    database.AccountID `json:"account"`
+	Balance      uint64             `json:"balance"`
+	Nonce        uint64             `json:"nonce"`
+	Debits       uint64             `json:"debits"`
+	Credits      uint64             `json:"credits"`
+	Pending      uint64             `json:"pending_balance"`
+	PendingNonce uint64             `json:"pending_nonce"`
+	PendingTxs   int                `json:"pending_txs"`
+}
+
+// GasEstimate represents the tips suggested by the node for a transaction
+// to be included in a block.
+type GasEstimate struct {
+	Low           uint64 `json:"low"`
+	Standard      uint64 `json:"standard"`
+	Fast          uint64 `json:"fast"`
+	GasPrice      uint64 `json:"gas_price"`
+	GasUnits      uint64 `json:"gas_units"`
+	MempoolDepth  int    `json:"mempool_depth"`
+	TransPerBlock uint16 `json:"trans_per_block"`
+	BlocksSampled int    `json:"blocks_sampled"`
+	TxsSampled    int    `json:"txs_sampled"`
+}
+
+// AccountInfo represents the set of accounts and the state of the node
+// when they were retrieved.
+type AccountInfo struct {
+	LatestBlock string    `json:"latest_block"`
+	Uncommitted int       `json:"uncommitted"`
+	Total       int       `json:"total"`
+	Accounts    []Account `json:"accounts"`
+}
+
+// BatchResult represents the outcome for a transaction submitted in a batch.
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Hash   string `json:"hash"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Code   string `json:"code,omitempty"`
+}
+
+// Accepted reports if the transaction was added to the mempool.
+func (br BatchResult) Accepted() bool {
+	return br.Status == "accepted"
+}
+
+// Tx represents a transaction as provided by the public API.
+type Tx struct {
+	Hash       string             `json:"hash"`
+	FromID     database.AccountID `json:"from"`
+	ToID       database.AccountID `json:"to"`
+	ChainID    uint16             `json:"chain_id"`
+	Nonce      uint64             `json:"nonce"`
+	Value      uint64             `json:"value"`
+	Tip        uint64             `json:"tip"`
+	Data       []byte             `json:"data"`
+	Memo       *database.Memo     `json:"memo,omitempty"`
+	NotBefore  uint64             `json:"not_before,omitempty"`
+	NotAfter   uint64             `json:"not_after,omitempty"`
+	TimeStamp  uint64             `json:"timestamp"`
+	GasPrice   uint64             `json:"gas_price"`
+	GasUnits   uint64             `json:"gas_units"`
+	Sig        string             `json:"sig"`
+	Proof      []string           `json:"proof,omitempty"`
+	ProofOrder []int64            `json:"proof_order,omitempty"`
+}
+
+// TxLookup represents a transaction recorded in the chain along with its
+// receipt.
+type TxLookup struct {
+	Tx      Tx               `json:"tx"`
+	Receipt database.Receipt `json:"receipt"`
+}
+
+// BlockTx converts the transaction back into its database form. The
+// signature is decoded so the transaction can be verified.
+func (tx Tx) BlockTx() (database.BlockTx, error) {
+	v, r, s, err := signature.ToVRSFromHexSignature(tx.Sig)
+	if err != nil {
+		return database.BlockTx{}, err
+	}
+
+	blockTx := database.BlockTx{
+		SignedTx: database.SignedTx{
+			Tx: database.Tx{
+				ChainID:   tx.ChainID,
+				Nonce:     tx.Nonce,
+				FromID:    tx.FromID,
+				ToID:      tx.ToID,
+				Value:     tx.Value,
+				Tip:       tx.Tip,
+				Data:      tx.Data,
+				NotBefore: tx.NotBefore,
+				NotAfter:  tx.NotAfter,
+			},
+			V: v,
+			R: r,
+			S: s,
+		},
+		TimeStamp: tx.TimeStamp,
+		GasPrice:  tx.GasPrice,
+		GasUnits:  tx.GasUnits,
+	}
+
+	return blockTx, nil
+}
+
+// Block represents a block as provided by the public API.
+type Block struct {
+	Hash          string                 `json:"hash"`
+	Number        uint64                 `json:"number"`
+	PrevBlockHash string                 `json:"prev_block_hash"`
+	TimeStamp     uint64                 `json:"timestamp"`
+	BeneficiaryID database.AccountID     `json:"beneficiary"`
+	Difficulty    uint16                 `json:"difficulty"`
+	MiningReward  uint64                 `json:"mining_reward"`
+	StateRoot     string                 `json:"state_root"`
+	TransRoot     string                 `json:"trans_root"`
+	Nonce         uint64                 `json:"nonce"`
+	Beneficiaries []database.Beneficiary `json:"beneficiaries,omitempty"`
+	AccountsRoot  string                 `json:"accounts_root,omitempty"`
+	Version       uint16                 `json:"version,omitempty"`
+	Transactions  []Tx                   `json:"txs"`
+}
+
+// Header returns the database form of the block header.
+func (b Block) Header() database.BlockHeader {
+	return database.BlockHeader{
+		Number:        b.Number,
+		PrevBlockHash: b.PrevBlockHash,
+		TimeStamp:     b.TimeStamp,
+		BeneficiaryID: b.BeneficiaryID,
+		Difficulty:    b.Difficulty,
+		MiningReward:  b.MiningReward,
+		StateRoot:     b.StateRoot,
+		TransRoot:     b.TransRoot,
+		Nonce:         b.Nonce,
+		Beneficiaries: b.Beneficiaries,
+		AccountsRoot:  b.AccountsRoot,
+		Version:       b.Version,
+	}
+}
+
+// BlockSummary represents the compact form of a block provided by the public
+// API when listing a range of blocks.
+type BlockSummary struct {
+	Hash          string             `json:"hash"`
+	Number        uint64             `json:"number"`
+	TxCount       int                `json:"tx_count"`
+	BeneficiaryID database.AccountID `json:"beneficiary"`
+	TimeStamp     uint64             `json:"timestamp"`
+}
+
+// Event represents an event published by the node. The data is left in its
+// raw form since it depends on the type of event.
+type Event struct {
+	Type      string          `json:"type"`
+	TimeStamp uint64          `json:"timestamp"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
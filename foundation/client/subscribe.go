@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/ardanlabs/blockchain/foundation/websocket"
+)
+
+// eventBuffer is the number of events that can be queued before the
+// subscription stops reading from the node.
+const eventBuffer = 100
+
+// Subscribe opens a connection to the node's event stream. Events are
+// delivered on the returned channel until the context is cancelled or the
+// node closes the connection, at which point the channel is closed.
+func (c *Client) Subscribe(ctx context.Context) (<-chan Event, error) {
+	wsURL := "ws" + strings.TrimPrefix(c.url, "http") + "/v1/events"
+
+	conn, err := websocket.Dial(ctx, wsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Close the connection when the caller is done with the subscription or
+	// the node goes away.
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	ch := make(chan Event, eventBuffer)
+
+	go func() {
+		defer func() {
+			cancel()
+			close(ch)
+		}()
+
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var evt Event
+			if err := json.Unmarshal(msg, &evt); err != nil {
+				continue
+			}
+
+			select {
+			case ch <- evt:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,156 @@
+package hdwallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+)
+
+// HardenedOffset is added to an index to derive a hardened child key.
+const HardenedOffset uint32 = 0x80000000
+
+// BasePath is the BIP-44 path for Ethereum accounts. The account index is
+// appended to derive each account.
+const BasePath = "m/44'/60'/0'/0"
+
+// ErrInvalidKey is returned in the unlikely case a derived key is outside
+// the range of the curve and the next index must be used.
+var ErrInvalidKey = errors.New("derived key is invalid")
+
+// Key represents an extended private key.
+type Key struct {
+	PrivateKey *ecdsa.PrivateKey
+	ChainCode  []byte
+}
+
+// NewMasterKey returns the master key for the seed.
+func NewMasterKey(seed []byte) (Key, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	return newKey(sum[:32], sum[32:])
+}
+
+// Child returns the child key at the specified index. Indexes at or above
+// HardenedOffset derive a hardened child.
+func (k Key) Child(index uint32) (Key, error) {
+	var data []byte
+	switch {
+	case index >= HardenedOffset:
+		data = append([]byte{0}, crypto.FromECDSA(k.PrivateKey)...)
+	default:
+		data = crypto.CompressPubkey(&k.PrivateKey.PublicKey)
+	}
+
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], index)
+	data = append(data, buf[:]...)
+
+	mac := hmac.New(sha512.New, k.ChainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := crypto.S256().Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return Key{}, ErrInvalidKey
+	}
+
+	child := il.Add(il, k.PrivateKey.D)
+	child.Mod(child, n)
+
+	return newKey(child.FillBytes(make([]byte, 32)), sum[32:])
+}
+
+// Derive returns the key for the path, such as m/44'/60'/0'/0/0. Hardened
+// indexes are marked with ' or H.
+func (k Key) Derive(path string) (Key, error) {
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return Key{}, err
+	}
+
+	for _, index := range indexes {
+		if k, err = k.Child(index); err != nil {
+			return Key{}, err
+		}
+	}
+
+	return k, nil
+}
+
+// ParsePath converts the path into the list of indexes to derive.
+func ParsePath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("invalid path %q, must start with m", path)
+	}
+
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		var offset uint32
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "H") {
+			offset = HardenedOffset
+			part = part[:len(part)-1]
+		}
+
+		index, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || uint32(index) >= HardenedOffset {
+			return nil, fmt.Errorf("invalid path %q, bad index %q", path, part)
+		}
+
+		indexes = append(indexes, uint32(index)+offset)
+	}
+
+	return indexes, nil
+}
+
+// AccountPath returns the BIP-44 path for the account at the index.
+func AccountPath(index uint32) string {
+	return fmt.Sprintf("%s/%d", BasePath, index)
+}
+
+// DeriveAccount returns the private key for the account at the index using
+// the mnemonic and optional passphrase.
+func DeriveAccount(mnemonic string, passphrase string, index uint32) (*ecdsa.PrivateKey, error) {
+	seed, err := Seed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	master, err := NewMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := master.Derive(AccountPath(index))
+	if err != nil {
+		return nil, err
+	}
+
+	return key.PrivateKey, nil
+}
+
+// =============================================================================
+
+// newKey constructs a key, rejecting private keys outside the curve range.
+func newKey(privateKey []byte, chainCode []byte) (Key, error) {
+	pk, err := crypto.ToECDSA(privateKey)
+	if err != nil {
+		return Key{}, fmt.Errorf("%w: %s", ErrInvalidKey, err)
+	}
+
+	return Key{
+		PrivateKey: pk,
+		ChainCode:  append([]byte(nil), chainCode...),
+	}, nil
+}
@@ -0,0 +1,78 @@
+package hdwallet_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"github.com/ardanlabs/blockchain/foundation/hdwallet"
+)
+
+// mnemonic is the 12 word phrase of the BIP-39 test vectors for all zero
+// entropy.
+const mnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestSeed(t *testing.T) {
+	seed, err := hdwallet.Seed(mnemonic, "TREZOR")
+	if err != nil {
+		t.Fatalf("Should be able to compute the seed: %s", err)
+	}
+
+	exp := "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"
+	if got := hex.EncodeToString(seed); got != exp {
+		t.Fatalf("Should compute the seed of the test vector, got %s, exp %s", got, exp)
+	}
+}
+
+func TestDeriveAccount(t *testing.T) {
+	pk, err := hdwallet.DeriveAccount(mnemonic, "", 0)
+	if err != nil {
+		t.Fatalf("Should be able to derive the account: %s", err)
+	}
+
+	exp := "0x9858EfFD232B4033E47d90003D41EC34EcaEda94"
+	if got := crypto.PubkeyToAddress(pk.PublicKey).Hex(); got != exp {
+		t.Fatalf("Should derive the address of m/44'/60'/0'/0/0, got %s, exp %s", got, exp)
+	}
+}
+
+// TestDerive checks the private keys and chain codes of BIP-32 test
+// vector 1.
+func TestDerive(t *testing.T) {
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	if err != nil {
+		t.Fatalf("Should be able to decode the seed: %s", err)
+	}
+
+	master, err := hdwallet.NewMasterKey(seed)
+	if err != nil {
+		t.Fatalf("Should be able to construct the master key: %s", err)
+	}
+
+	tt := []struct {
+		path      string
+		key       string
+		chainCode string
+	}{
+		{"m", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35", "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508"},
+		{"m/0H", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea", "47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141"},
+		{"m/0H/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368", "2a7857631386ba23dacac34180dd1983734e444fdbf774041578e9b6adb37c19"},
+		{"m/0H/1/2H", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca", "04466b9cc8e161e966409ca52986c584f07e9dc81f735db683c3ff6ec7b1503f"},
+		{"m/0H/1/2H/2", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4", "cfb71883f01676f587d023cc53a35bc7f88f724b1f8c2892ac1275ac822a3edd"},
+		{"m/0H/1/2H/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8", "c783e67b921d2beb8f6b389cc646d7263b4145701dadd2161548a8b078e65e9e"},
+	}
+
+	for _, tst := range tt {
+		key, err := master.Derive(tst.path)
+		if err != nil {
+			t.Fatalf("%s: Should be able to derive the key: %s", tst.path, err)
+		}
+
+		if got := hex.EncodeToString(crypto.FromECDSA(key.PrivateKey)); got != tst.key {
+			t.Errorf("%s: Should derive the private key, got %s, exp %s", tst.path, got, tst.key)
+		}
+		if got := hex.EncodeToString(key.ChainCode); got != tst.chainCode {
+			t.Errorf("%s: Should derive the chain code, got %s, exp %s", tst.path, got, tst.chainCode)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Package hdwallet provides support for hierarchical deterministic wallets.
+// A BIP-39 mnemonic phrase is turned into a seed, and BIP-32 derivation
+// produces any number of private keys from that seed. Following the BIP-44
+// path used by Ethereum wallets, every account for a student can be
+// recovered from a single 12 word phrase.
+package hdwallet
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// english is the BIP-39 English wordlist.
+//
+//go:embed english.txt
+var english string
+
+var (
+	words     = strings.Fields(english)
+	wordIndex = func() map[string]int {
+		m := make(map[string]int, len(words))
+		for i, w := range words {
+			m[w] = i
+		}
+		return m
+	}()
+)
+
+// Set of errors returned when working with a mnemonic.
+var (
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+	ErrChecksum        = errors.New("mnemonic checksum does not match")
+)
+
+// NewMnemonic returns a new 12 word mnemonic phrase built from 128 bits
+// of entropy.
+func NewMnemonic() (string, error) {
+	entropy := make([]byte, 16)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", err
+	}
+
+	return EntropyToMnemonic(entropy)
+}
+
+// EntropyToMnemonic converts the entropy into its mnemonic phrase. The
+// entropy must be between 128 and 256 bits in a multiple of 32 bits.
+func EntropyToMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", fmt.Errorf("invalid entropy length %d bits", bits)
+	}
+
+	// The checksum is the first bits of the hash of the entropy, one bit
+	// for every 32 bits of entropy.
+	checksum := sha256.Sum256(entropy)
+	csBits := bits / 32
+
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, uint(csBits))
+	data.Or(data, big.NewInt(int64(checksum[0]>>(8-csBits))))
+
+	// Each word holds 11 bits, taken from the end.
+	n := (bits + csBits) / 11
+	phrase := make([]string, n)
+	mask := big.NewInt(2047)
+	for i := n - 1; i >= 0; i-- {
+		idx := new(big.Int).And(data, mask)
+		phrase[i] = words[idx.Int64()]
+		data.Rsh(data, 11)
+	}
+
+	return strings.Join(phrase, " "), nil
+}
+
+// ValidateMnemonic checks every word is in the wordlist and the checksum
+// carried in the phrase matches.
+func ValidateMnemonic(mnemonic string) error {
+	phrase := strings.Fields(mnemonic)
+
+	n := len(phrase)
+	if n < 12 || n > 24 || n%3 != 0 {
+		return fmt.Errorf("%w: got %d words", ErrInvalidMnemonic, n)
+	}
+
+	data := new(big.Int)
+	for _, w := range phrase {
+		idx, exists := wordIndex[w]
+		if !exists {
+			return fmt.Errorf("%w: unknown word %q", ErrInvalidMnemonic, w)
+		}
+		data.Lsh(data, 11)
+		data.Or(data, big.NewInt(int64(idx)))
+	}
+
+	csBits := n / 3
+	checksum := new(big.Int).And(data, big.NewInt(int64(1<<csBits-1)))
+	data.Rsh(data, uint(csBits))
+
+	entropy := make([]byte, (n*11-csBits)/8)
+	data.FillBytes(entropy)
+
+	expected := sha256.Sum256(entropy)
+	if checksum.Int64() != int64(expected[0]>>(8-csBits)) {
+		return ErrChecksum
+	}
+
+	return nil
+}
+
+// Seed returns the 64 byte seed for the mnemonic and optional passphrase.
+func Seed(mnemonic string, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+
+	return pbkdf2.Key([]byte(mnemonic), []byte("mnemonic"+passphrase), 2048, 64, sha512.New), nil
+}
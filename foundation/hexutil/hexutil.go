@@ -0,0 +1,132 @@
+// Package hexutil encodes and decodes the 0x prefixed hex strings used by
+// the blockchain and the Ethereum JSON-RPC API.
+package hexutil
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Set of errors returned when decoding a hex string.
+var (
+	ErrMissingPrefix = errors.New("hex string without 0x prefix")
+	ErrEmptyNumber   = errors.New("hex string \"0x\"")
+	ErrLeadingZero   = errors.New("hex number with leading zero digits")
+)
+
+// Encode encodes the bytes as a 0x prefixed hex string.
+func Encode(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
+
+// Decode decodes the 0x prefixed hex string into bytes.
+func Decode(s string) ([]byte, error) {
+	if !has0xPrefix(s) {
+		return nil, ErrMissingPrefix
+	}
+
+	b, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex string: %w", err)
+	}
+
+	return b, nil
+}
+
+// =============================================================================
+
+// Bytes marshals as a 0x prefixed hex string in JSON.
+type Bytes []byte
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (b Bytes) MarshalText() ([]byte, error) {
+	return []byte(Encode(b)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (b *Bytes) UnmarshalText(input []byte) error {
+	dec, err := Decode(string(input))
+	if err != nil {
+		return err
+	}
+
+	*b = dec
+	return nil
+}
+
+// Uint64 marshals as a 0x prefixed hex number in JSON.
+type Uint64 uint64
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (u Uint64) MarshalText() ([]byte, error) {
+	return []byte("0x" + strconv.FormatUint(uint64(u), 16)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (u *Uint64) UnmarshalText(input []byte) error {
+	digits, err := numberDigits(string(input))
+	if err != nil {
+		return err
+	}
+
+	v, err := strconv.ParseUint(digits, 16, 64)
+	if err != nil {
+		return fmt.Errorf("invalid hex number: %w", err)
+	}
+
+	*u = Uint64(v)
+	return nil
+}
+
+// Big marshals as a 0x prefixed hex number in JSON.
+type Big big.Int
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (b Big) MarshalText() ([]byte, error) {
+	bi := big.Int(b)
+	return []byte("0x" + bi.Text(16)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (b *Big) UnmarshalText(input []byte) error {
+	digits, err := numberDigits(string(input))
+	if err != nil {
+		return err
+	}
+
+	var bi big.Int
+	if _, ok := bi.SetString(digits, 16); !ok {
+		return fmt.Errorf("invalid hex number %q", input)
+	}
+
+	*b = Big(bi)
+	return nil
+}
+
+// =============================================================================
+
+// numberDigits returns the digits of a 0x prefixed hex number.
+func numberDigits(s string) (string, error) {
+	if !has0xPrefix(s) {
+		return "", ErrMissingPrefix
+	}
+
+	digits := s[2:]
+	switch {
+	case digits == "":
+		return "", ErrEmptyNumber
+	case len(digits) > 1 && digits[0] == '0':
+		return "", ErrLeadingZero
+	}
+
+	return digits, nil
+}
+
+// has0xPrefix reports if the string starts with 0x or 0X.
+func has0xPrefix(s string) bool {
+	return strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X")
+}
@@ -0,0 +1,181 @@
+// Package keystore encrypts private keys with a password so they can be
+// handed out and stored as files without exposing the key to anyone who
+// reads the file.
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/crypto"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Extension is the file extension used for encrypted key files.
+const Extension = ".json"
+
+// Set of values describing how a key file is encrypted.
+const (
+	version    = 1
+	kdf        = "pbkdf2-sha256"
+	algorithm  = "aes-256-gcm"
+	iterations = 262_144
+	saltLength = 32
+	keyLength  = 32
+)
+
+// ErrPassword is returned when the key file can't be decrypted with the
+// password provided.
+var ErrPassword = errors.New("could not decrypt key with the password provided")
+
+// KeyFile represents a private key encrypted with a password. The key used
+// for the encryption is derived from the password with PBKDF2 so guessing
+// the password is slow.
+type KeyFile struct {
+	Version    int    `json:"version"`
+	AccountID  string `json:"account_id"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       string `json:"salt"`
+	Cipher     string `json:"cipher"`
+	Nonce      string `json:"nonce"`
+	CipherText string `json:"cipher_text"`
+}
+
+// Encrypt encrypts the private key with the password.
+func Encrypt(privateKey *ecdsa.PrivateKey, password string) (KeyFile, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return KeyFile{}, err
+	}
+
+	aead, err := newAEAD(password, salt, iterations)
+	if err != nil {
+		return KeyFile{}, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return KeyFile{}, err
+	}
+
+	accountID := crypto.PubkeyToAddress(privateKey.PublicKey).String()
+
+	// The account is authenticated with the key so a key file can't be
+	// edited to claim a different account.
+	cipherText := aead.Seal(nil, nonce, crypto.FromECDSA(privateKey), []byte(accountID))
+
+	kf := KeyFile{
+		Version:    version,
+		AccountID:  accountID,
+		KDF:        kdf,
+		Iterations: iterations,
+		Salt:       hex.EncodeToString(salt),
+		Cipher:     algorithm,
+		Nonce:      hex.EncodeToString(nonce),
+		CipherText: hex.EncodeToString(cipherText),
+	}
+
+	return kf, nil
+}
+
+// Decrypt decrypts the private key in the key file with the password.
+func Decrypt(kf KeyFile, password string) (*ecdsa.PrivateKey, error) {
+	if kf.Version != version || kf.KDF != kdf || kf.Cipher != algorithm {
+		return nil, fmt.Errorf("unsupported key file version %d, kdf %q, cipher %q", kf.Version, kf.KDF, kf.Cipher)
+	}
+
+	if kf.Iterations <= 0 {
+		return nil, fmt.Errorf("invalid key file iterations %d", kf.Iterations)
+	}
+
+	salt, err := hex.DecodeString(kf.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("decoding salt: %w", err)
+	}
+
+	nonce, err := hex.DecodeString(kf.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("decoding nonce: %w", err)
+	}
+
+	cipherText, err := hex.DecodeString(kf.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("decoding cipher text: %w", err)
+	}
+
+	aead, err := newAEAD(password, salt, kf.Iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d", len(nonce))
+	}
+
+	key, err := aead.Open(nil, nonce, cipherText, []byte(kf.AccountID))
+	if err != nil {
+		return nil, ErrPassword
+	}
+
+	privateKey, err := crypto.ToECDSA(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return privateKey, nil
+}
+
+// Save encrypts the private key with the password and writes the key file
+// to the specified path. The file is only readable by the owner.
+func Save(path string, privateKey *ecdsa.PrivateKey, password string) error {
+	kf, err := Encrypt(privateKey, password)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(kf, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0600)
+}
+
+// Load reads the key file at the specified path and decrypts the private key
+// with the password.
+func Load(path string, password string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var kf KeyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("decoding key file: %w", err)
+	}
+
+	return Decrypt(kf, password)
+}
+
+// =============================================================================
+
+// newAEAD constructs the cipher for the key derived from the password.
+func newAEAD(password string, salt []byte, iter int) (cipher.AEAD, error) {
+	key := pbkdf2.Key([]byte(password), salt, iter, keyLength, sha256.New)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -3,6 +3,8 @@
 package logger
 
 import (
+	"fmt"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -25,3 +27,25 @@ func New(service string) (*zap.SugaredLogger, error) {
 
 	return log.Sugar(), nil
 }
+
+// Func returns a logger function for the blockchain packages that writes to
+// the Sugared Logger. The first value is the message and the values after it
+// are key/value pairs.
+func Func(log *zap.SugaredLogger) func(v ...any) {
+
+	// Report the caller of the function, not this function.
+	log = log.Desugar().WithOptions(zap.AddCallerSkip(1)).Sugar()
+
+	return func(v ...any) {
+		if len(v) == 0 {
+			return
+		}
+
+		msg, ok := v[0].(string)
+		if !ok {
+			msg = fmt.Sprint(v[0])
+		}
+
+		log.Infow(msg, v[1:]...)
+	}
+}
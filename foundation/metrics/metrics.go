@@ -0,0 +1,275 @@
+// Package metrics provides counters, gauges and histograms that are exposed
+// in the Prometheus text format so they can be scraped and graphed.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are the histogram buckets used when none are specified. They
+// are tuned to measure durations in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// Default is the registry used by the package level functions. Like the
+// expvar package, metrics are normally registered once as a singleton.
+var Default = NewRegistry()
+
+// NewCounter registers a counter with the default registry.
+func NewCounter(name string, help string) *Counter {
+	return Default.NewCounter(name, help)
+}
+
+// NewGauge registers a gauge with the default registry.
+func NewGauge(name string, help string) *Gauge {
+	return Default.NewGauge(name, help)
+}
+
+// NewGaugeFunc registers a gauge with the default registry whose value is
+// provided by the function.
+func NewGaugeFunc(name string, help string, fn func() float64) {
+	Default.NewGaugeFunc(name, help, fn)
+}
+
+// NewHistogram registers a histogram with the default registry.
+func NewHistogram(name string, help string, buckets []float64) *Histogram {
+	return Default.NewHistogram(name, help, buckets)
+}
+
+// Handler returns a handler for the default registry.
+func Handler() http.Handler {
+	return Default
+}
+
+// =============================================================================
+
+// collector represents a metric that can write itself in the text format.
+type collector interface {
+	write(w io.Writer, name string, help string)
+}
+
+// entry represents a registered metric.
+type entry struct {
+	name string
+	help string
+	collector
+}
+
+// Registry maintains a set of metrics by name.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]entry
+}
+
+// NewRegistry constructs an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		entries: make(map[string]entry),
+	}
+}
+
+// NewCounter registers a counter by the specified name. If a counter by that
+// name already exists, it's returned.
+func (r *Registry) NewCounter(name string, help string) *Counter {
+	return r.register(name, help, &Counter{}).(*Counter)
+}
+
+// NewGauge registers a gauge by the specified name. If a gauge by that name
+// already exists, it's returned.
+func (r *Registry) NewGauge(name string, help string) *Gauge {
+	return r.register(name, help, &Gauge{}).(*Gauge)
+}
+
+// NewGaugeFunc registers a gauge whose value is provided by the function when
+// the metrics are written. An existing gauge by that name is replaced.
+func (r *Registry) NewGaugeFunc(name string, help string, fn func() float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[name] = entry{name: name, help: help, collector: gaugeFunc(fn)}
+}
+
+// NewHistogram registers a histogram by the specified name using the buckets.
+// If a histogram by that name already exists, it's returned.
+func (r *Registry) NewHistogram(name string, help string, buckets []float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	bounds := make([]float64, len(buckets))
+	copy(bounds, buckets)
+	sort.Float64s(bounds)
+
+	h := Histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)),
+	}
+
+	return r.register(name, help, &h).(*Histogram)
+}
+
+// Write writes all the metrics in the Prometheus text format, sorted by name.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.RLock()
+	entries := make([]entry, 0, len(r.entries))
+	for _, e := range r.entries {
+		entries = append(entries, e)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+
+	for _, e := range entries {
+		e.write(w, e.name, e.help)
+	}
+}
+
+// ServeHTTP implements the http.Handler interface so the registry can be
+// scraped.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.Write(w)
+}
+
+// register adds the metric to the registry or returns the existing metric
+// if one by the same name and type is already registered.
+func (r *Registry) register(name string, help string, c collector) collector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, exists := r.entries[name]; exists {
+		if fmt.Sprintf("%T", e.collector) != fmt.Sprintf("%T", c) {
+			panic(fmt.Sprintf("metrics: %q is already registered as a different type", name))
+		}
+		return e.collector
+	}
+
+	r.entries[name] = entry{name: name, help: help, collector: c}
+
+	return c
+}
+
+// =============================================================================
+
+// Counter is a value that only goes up.
+type Counter struct {
+	value uint64
+}
+
+// Inc increments the counter by 1.
+func (c *Counter) Inc() {
+	atomic.AddUint64(&c.value, 1)
+}
+
+// Add increments the counter by the specified value.
+func (c *Counter) Add(n uint64) {
+	atomic.AddUint64(&c.value, n)
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() uint64 {
+	return atomic.LoadUint64(&c.value)
+}
+
+func (c *Counter) write(w io.Writer, name string, help string) {
+	writeHeader(w, name, help, "counter")
+	fmt.Fprintf(w, "%s %d\n", name, c.Value())
+}
+
+// =============================================================================
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	bits uint64
+}
+
+// Set sets the gauge to the specified value.
+func (g *Gauge) Set(v float64) {
+	atomic.StoreUint64(&g.bits, math.Float64bits(v))
+}
+
+// Value returns the current value of the gauge.
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.bits))
+}
+
+func (g *Gauge) write(w io.Writer, name string, help string) {
+	writeHeader(w, name, help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(g.Value()))
+}
+
+// gaugeFunc is a gauge whose value is read when the metrics are written.
+type gaugeFunc func() float64
+
+func (fn gaugeFunc) write(w io.Writer, name string, help string) {
+	writeHeader(w, name, help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(fn()))
+}
+
+// =============================================================================
+
+// Histogram counts observations into a set of buckets.
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe records the value in the histogram.
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *Histogram) write(w io.Writer, name string, help string) {
+	h.mu.Lock()
+	counts := make([]uint64, len(h.counts))
+	copy(counts, h.counts)
+	count := h.count
+	sum := h.sum
+	h.mu.Unlock()
+
+	writeHeader(w, name, help, "histogram")
+
+	// Prometheus buckets are cumulative.
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", name, formatFloat(bound), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, formatFloat(sum))
+	fmt.Fprintf(w, "%s_count %d\n", name, count)
+}
+
+// =============================================================================
+
+func writeHeader(w io.Writer, name string, help string, typ string) {
+	if help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
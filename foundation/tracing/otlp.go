@@ -0,0 +1,275 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Set of values bounding the export of spans. Spans ending while the queue
+// is full are dropped rather than slowing the node down.
+const (
+	maxQueuedSpans      = 2048
+	maxBatchSpans       = 512
+	defaultBatchTimeout = 5 * time.Second
+)
+
+// exporter batches the ended spans and sends them to the collector in the
+// background.
+type exporter struct {
+	serviceName  string
+	endpoint     string
+	batchTimeout time.Duration
+	log          func(msg string, keysAndValues ...any)
+	client       http.Client
+
+	spans chan *Span
+	shut  chan struct{}
+	done  chan struct{}
+}
+
+// newExporter constructs the exporter and starts sending batches.
+func newExporter(cfg Config) *exporter {
+	log := cfg.Log
+	if log == nil {
+		log = func(msg string, keysAndValues ...any) {}
+	}
+
+	batchTimeout := cfg.BatchTimeout
+	if batchTimeout <= 0 {
+		batchTimeout = defaultBatchTimeout
+	}
+
+	e := exporter{
+		serviceName:  cfg.ServiceName,
+		endpoint:     cfg.Endpoint,
+		batchTimeout: batchTimeout,
+		log:          log,
+		client:       http.Client{Timeout: 10 * time.Second},
+		spans:        make(chan *Span, maxQueuedSpans),
+		shut:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	go e.run()
+
+	return &e
+}
+
+// export queues the span to be sent.
+func (e *exporter) export(span *Span) {
+	select {
+	case e.spans <- span:
+	default:
+		e.log("tracing: export: queue full, span dropped", "name", span.name)
+	}
+}
+
+// shutdown sends the queued spans and waits for the exporter to stop.
+func (e *exporter) shutdown(ctx context.Context) error {
+	close(e.shut)
+
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run sends a batch once it's full or the batch timeout has passed.
+func (e *exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.batchTimeout)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, maxBatchSpans)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			e.log("tracing: export", "spans", len(batch), "ERROR", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-e.spans:
+			batch = append(batch, span)
+			if len(batch) == maxBatchSpans {
+				flush()
+			}
+
+		case <-ticker.C:
+			flush()
+
+		case <-e.shut:
+			for {
+				select {
+				case span := <-e.spans:
+					batch = append(batch, span)
+					if len(batch) == maxBatchSpans {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// send posts the batch to the collector.
+func (e *exporter) send(batch []*Span) error {
+	if e.endpoint == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(newExportRequest(e.serviceName, batch))
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// These types represent the OTLP/HTTP JSON encoding of the export request.
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanData `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type spanData struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            *status    `json:"status,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// statusError is the OTLP status code for a failed span.
+const statusError = 2
+
+// newExportRequest converts the batch into the export request.
+func newExportRequest(serviceName string, batch []*Span) exportRequest {
+	spans := make([]spanData, len(batch))
+	for i, span := range batch {
+		span.mu.Lock()
+		sd := spanData{
+			TraceID:           span.traceID,
+			SpanID:            span.spanID,
+			ParentSpanID:      span.parentID,
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        toKeyValues(span.attrs),
+		}
+		if span.errMsg != "" {
+			sd.Status = &status{Code: statusError, Message: span.errMsg}
+		}
+		span.mu.Unlock()
+
+		spans[i] = sd
+	}
+
+	req := exportRequest{
+		ResourceSpans: []resourceSpans{
+			{
+				Resource: resource{
+					Attributes: toKeyValues([]Attribute{String("service.name", serviceName)}),
+				},
+				ScopeSpans: []scopeSpans{
+					{
+						Scope: scope{Name: "github.com/ardanlabs/blockchain"},
+						Spans: spans,
+					},
+				},
+			},
+		},
+	}
+
+	return req
+}
+
+// toKeyValues converts the attributes into their OTLP form.
+func toKeyValues(attrs []Attribute) []keyValue {
+	kvs := make([]keyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		var v anyValue
+		switch value := attr.Value.(type) {
+		case int64:
+			s := strconv.FormatInt(value, 10)
+			v.IntValue = &s
+		case bool:
+			v.BoolValue = &value
+		case string:
+			v.StringValue = &value
+		default:
+			s := fmt.Sprint(value)
+			v.StringValue = &s
+		}
+		kvs = append(kvs, keyValue{Key: attr.Key, Value: v})
+	}
+
+	return kvs
+}
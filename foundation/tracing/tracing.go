@@ -0,0 +1,370 @@
+// Package tracing provides spans that follow a request from the public API
+// through the node and out to its peers. The trace is propagated between
+// nodes with the W3C traceparent header and the sampled spans are exported
+// to an OpenTelemetry collector using the OTLP/HTTP JSON encoding, so traces
+// can be viewed in Jaeger, Tempo or any other OTLP backend.
+// https://www.w3.org/TR/trace-context/
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceParentHeader is the header carrying the trace between services.
+const TraceParentHeader = "traceparent"
+
+// Set of span kinds, using the values of the OpenTelemetry protocol.
+const (
+	kindInternal = 1
+	kindServer   = 2
+	kindClient   = 3
+)
+
+// Config represents the configuration for tracing.
+type Config struct {
+	ServiceName  string
+	Endpoint     string        // OTLP/HTTP traces endpoint, http://localhost:4318/v1/traces.
+	Probability  float64       // Fraction of new traces sampled, 0 to 1.
+	BatchTimeout time.Duration // Optional: Longest a span waits to be exported, 5s when zero.
+	Log          func(msg string, keysAndValues ...any)
+}
+
+// Tracer starts spans and exports the sampled spans once they end. A nil
+// Tracer is valid and starts no spans, so tracing can be turned off without
+// checks at every call site.
+type Tracer struct {
+	probability float64
+	exporter    *exporter
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// New constructs a tracer exporting to the configured endpoint.
+func New(cfg Config) *Tracer {
+	return &Tracer{
+		probability: cfg.Probability,
+		exporter:    newExporter(cfg),
+		rnd:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Shutdown exports the spans that have ended and stops the exporter.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	return t.exporter.shutdown(ctx)
+}
+
+// Start starts a span as a child of the span in the context, or as the root
+// of a new trace when the context has no span.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	return t.start(ctx, name, kindInternal, attrs)
+}
+
+// StartRequest starts the server span for a request, continuing the trace
+// of the caller when the request carries a valid traceparent header.
+func (t *Tracer) StartRequest(r *http.Request, name string) (context.Context, *Span) {
+	ctx := r.Context()
+	if t == nil {
+		return ctx, nil
+	}
+
+	if parent, ok := parseTraceParent(r.Header.Get(TraceParentHeader)); ok {
+		parent.tracer = t
+		ctx = context.WithValue(ctx, key, parent)
+	}
+
+	attrs := []Attribute{
+		String("http.method", r.Method),
+		String("http.target", r.URL.Path),
+		String("net.peer.addr", r.RemoteAddr),
+	}
+
+	return t.start(ctx, name, kindServer, attrs)
+}
+
+// start constructs the span and stores it in the context.
+func (t *Tracer) start(ctx context.Context, name string, kind int, attrs []Attribute) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := Span{
+		tracer: t,
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+		attrs:  attrs,
+	}
+
+	if parent := fromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+		span.sampled = parent.sampled
+	} else {
+		span.traceID = t.newID(16)
+		span.sampled = t.sample()
+	}
+	span.spanID = t.newID(8)
+
+	return context.WithValue(ctx, key, &span), &span
+}
+
+// newID returns a random non-zero id of the specified size, hex encoded.
+func (t *Tracer) newID(size int) string {
+	b := make([]byte, size)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for {
+		t.rnd.Read(b)
+		for _, v := range b {
+			if v != 0 {
+				return hex.EncodeToString(b)
+			}
+		}
+	}
+}
+
+// sample decides if a new trace is exported.
+func (t *Tracer) sample() bool {
+	if t.probability <= 0 {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.rnd.Float64() < t.probability
+}
+
+// =============================================================================
+
+// ctxKey represents the type of value for the context key.
+type ctxKey int
+
+// key is how the span is stored/retrieved.
+const key ctxKey = 1
+
+// fromContext returns the span in the context or nil.
+func fromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(key).(*Span)
+	return span
+}
+
+// TraceID returns the id of the trace in the context, or an empty string
+// when the context isn't being traced.
+func TraceID(ctx context.Context) string {
+	if span := fromContext(ctx); span != nil {
+		return span.traceID
+	}
+	return ""
+}
+
+// Start starts a span as a child of the span in the context using the
+// span's tracer. Nothing is traced when the context has no span, so packages
+// without access to the tracer only add to traces started elsewhere.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	span := fromContext(ctx)
+	if span == nil {
+		return ctx, nil
+	}
+
+	return span.tracer.start(ctx, name, kindInternal, attrs)
+}
+
+// StartClient starts a span for a request to another service as a child of
+// the span in the context. The traceparent header injected for the request
+// makes it the parent of the span the service starts.
+func StartClient(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	span := fromContext(ctx)
+	if span == nil {
+		return ctx, nil
+	}
+
+	return span.tracer.start(ctx, name, kindClient, attrs)
+}
+
+// Detach returns a context holding the span in the context without its
+// deadline or cancellation. Work continuing the trace after a request
+// completes, like gossiping a transaction, uses this.
+func Detach(ctx context.Context) context.Context {
+	span := fromContext(ctx)
+	if span == nil {
+		return context.Background()
+	}
+
+	return context.WithValue(context.Background(), key, span)
+}
+
+// Inject adds the traceparent header for the span in the context to the
+// headers of an outgoing request.
+func Inject(ctx context.Context, header http.Header) {
+	span := fromContext(ctx)
+	if span == nil {
+		return
+	}
+
+	flags := "00"
+	if span.sampled {
+		flags = "01"
+	}
+
+	header.Set(TraceParentHeader, fmt.Sprintf("00-%s-%s-%s", span.traceID, span.spanID, flags))
+}
+
+// parseTraceParent parses the traceparent header into the remote parent
+// span. Version 00 is the only version defined, but later versions start
+// with the same fields so they are parsed the same way.
+func parseTraceParent(value string) (*Span, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return nil, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return nil, false
+	}
+
+	traceID, spanID, flags := parts[1], parts[2], parts[3]
+	if !isID(traceID, 16) || !isID(spanID, 8) || len(flags) != 2 {
+		return nil, false
+	}
+
+	f, err := hex.DecodeString(flags)
+	if err != nil {
+		return nil, false
+	}
+
+	span := Span{
+		traceID: traceID,
+		spanID:  spanID,
+		sampled: f[0]&1 == 1,
+	}
+
+	return &span, true
+}
+
+// isID reports if the value is a non-zero lowercase hex id of the size.
+func isID(value string, size int) bool {
+	if len(value) != size*2 || value != strings.ToLower(value) {
+		return false
+	}
+
+	b, err := hex.DecodeString(value)
+	if err != nil {
+		return false
+	}
+
+	for _, v := range b {
+		if v != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// =============================================================================
+
+// Attribute represents a key/value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String constructs a string attribute.
+func String(key string, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int constructs an integer attribute.
+func Int(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool constructs a boolean attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span represents a unit of work in a trace. A nil Span is valid and records
+// nothing.
+type Span struct {
+	tracer   *Tracer
+	traceID  string
+	spanID   string
+	parentID string
+	sampled  bool
+	name     string
+	kind     int
+	start    time.Time
+
+	mu     sync.Mutex
+	end    time.Time
+	attrs  []Attribute
+	errMsg string
+	ended  bool
+}
+
+// TraceID returns the id of the trace the span belongs to.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.traceID
+}
+
+// SetAttributes adds attributes describing the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// RecordError marks the span as failed when the error is not nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errMsg = err.Error()
+}
+
+// End completes the span and queues it for export when it's sampled. Calls
+// after the first are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if s.sampled {
+		s.tracer.exporter.export(s)
+	}
+}
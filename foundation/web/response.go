@@ -1,9 +1,12 @@
 package web
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
+	"strings"
 )
 
 // Respond converts a Go value to JSON and sends it to the client.
@@ -37,3 +40,49 @@ func Respond(ctx context.Context, w http.ResponseWriter, data any, statusCode in
 
 	return nil
 }
+
+// RespondStream sends the content written by the function to the client as
+// it's written, so a large response doesn't have to be held in memory and
+// is sent in chunks. The content is compressed with gzip when the client
+// accepts it. The headers are sent before the function is called, so an
+// error it returns can't change the response.
+func RespondStream(ctx context.Context, w http.ResponseWriter, r *http.Request, contentType string, statusCode int, write func(w io.Writer) error) error {
+
+	// Set the status code for the request logger middleware.
+	SetStatusCode(ctx, statusCode)
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	if !acceptsGzip(r) {
+		w.WriteHeader(statusCode)
+		return write(w)
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.WriteHeader(statusCode)
+
+	gz := gzip.NewWriter(w)
+	if err := write(gz); err != nil {
+		gz.Close()
+		return err
+	}
+
+	return gz.Close()
+}
+
+// acceptsGzip reports if the client accepts content compressed with gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+
+		// A quality of zero means the client doesn't accept the coding.
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+
+	return false
+}
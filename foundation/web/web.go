@@ -8,6 +8,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/ardanlabs/blockchain/foundation/tracing"
 	"github.com/dimfeld/httptreemux/v5"
 	"github.com/google/uuid"
 )
@@ -22,22 +23,20 @@ type Handler func(ctx context.Context, w http.ResponseWriter, r *http.Request) e
 type App struct {
 	*httptreemux.ContextMux
 	shutdown chan os.Signal
+	tracer   *tracing.Tracer
 	mw       []Middleware
 }
 
 // NewApp creates an App value that handle a set of routes for the application.
-func NewApp(shutdown chan os.Signal, mw ...Middleware) *App {
-
-	// Create an OpenTelemetry HTTP Handler which wraps our router. This will start
-	// the initial span and annotate it with information about the request/response.
-	//
-	// This is configured to use the W3C TraceContext standard to set the remote
-	// parent if a client request includes the appropriate headers.
-	// https://w3c.github.io/trace-context/
-
+// The tracer is optional, when provided every request starts a span which
+// continues the trace of the caller if the request carries the W3C
+// traceparent header.
+// https://w3c.github.io/trace-context/
+func NewApp(shutdown chan os.Signal, tracer *tracing.Tracer, mw ...Middleware) *App {
 	return &App{
 		ContextMux: httptreemux.NewContextMux(),
 		shutdown:   shutdown,
+		tracer:     tracer,
 		mw:         mw,
 	}
 }
@@ -58,12 +57,19 @@ func (a *App) Handle(method string, group string, path string, handler Handler,
 	// Add the application's general middleware to the handler chain.
 	handler = wrapMiddleware(a.mw, handler)
 
+	finalPath := path
+	if group != "" {
+		finalPath = "/" + group + path
+	}
+
 	// The function to execute for each request.
 	h := func(w http.ResponseWriter, r *http.Request) {
 
-		// Pull the context from the request and
-		// use it as a separate parameter.
-		ctx := r.Context()
+		// Pull the context from the request and use it as a separate
+		// parameter. The span for the request is stored in the context so
+		// the work performed for the request is traced.
+		ctx, span := a.tracer.StartRequest(r, method+" "+finalPath)
+		defer span.End()
 
 		// Set the context with the required values to
 		// process the request.
@@ -78,11 +84,38 @@ func (a *App) Handle(method string, group string, path string, handler Handler,
 			a.SignalShutdown()
 			return
 		}
-	}
 
-	finalPath := path
-	if group != "" {
-		finalPath = "/" + group + path
+		span.SetAttributes(tracing.Int("http.status_code", int64(v.StatusCode)))
 	}
+
 	a.ContextMux.Handle(method, finalPath, h)
 }
+
+// Group creates a set of routes sharing the specified path prefix and
+// middleware, such as the routes for a version of the API.
+func (a *App) Group(group string, mw ...Middleware) *Group {
+	return &Group{
+		app:   a,
+		group: group,
+		mw:    mw,
+	}
+}
+
+// =============================================================================
+
+// Group represents a set of routes sharing a path prefix and middleware.
+type Group struct {
+	app   *App
+	group string
+	mw    []Middleware
+}
+
+// Handle sets a handler function for a given HTTP method and path pair
+// under the group. The group's middleware runs before the route's middleware.
+func (g *Group) Handle(method string, path string, handler Handler, mw ...Middleware) {
+	routeMW := make([]Middleware, 0, len(g.mw)+len(mw))
+	routeMW = append(routeMW, g.mw...)
+	routeMW = append(routeMW, mw...)
+
+	g.app.Handle(method, g.group, path, handler, routeMW...)
+}
@@ -0,0 +1,318 @@
+package websocket_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/websocket"
+)
+
+// Set of frame opcodes written by the raw client.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+func TestMessages(t *testing.T) {
+	srv, conns := newServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"))
+	if err != nil {
+		t.Fatalf("Should be able to dial the server: %s", err)
+	}
+	defer client.Close()
+
+	server := <-conns
+
+	// The sizes cover the 7 bit length and the 16 and 64 bit extended
+	// lengths, up to the largest message accepted.
+	tt := []struct {
+		name string
+		size int
+	}{
+		{"7 bit length", 125},
+		{"16 bit length", 126},
+		{"16 bit length max", 0xFFFF},
+		{"64 bit length", 1 << 16},
+	}
+
+	for _, tst := range tt {
+		msg := bytes.Repeat([]byte{'a'}, tst.size)
+
+		// The client masks the frame, which the server requires.
+		if err := client.WriteText(msg); err != nil {
+			t.Fatalf("%s: Should be able to write to the server: %s", tst.name, err)
+		}
+		got, err := server.ReadMessage()
+		if err != nil {
+			t.Fatalf("%s: Should be able to read from the client: %s", tst.name, err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Fatalf("%s: Should receive the message, got %d bytes, exp %d", tst.name, len(got), len(msg))
+		}
+
+		// The server doesn't mask the frame, which the client requires.
+		if err := server.WriteText(msg); err != nil {
+			t.Fatalf("%s: Should be able to write to the client: %s", tst.name, err)
+		}
+		got, err = client.ReadMessage()
+		if err != nil {
+			t.Fatalf("%s: Should be able to read from the server: %s", tst.name, err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Fatalf("%s: Should receive the message, got %d bytes, exp %d", tst.name, len(got), len(msg))
+		}
+	}
+
+	// The server answers the ping while it reads the next message, and the
+	// pong is skipped by the client while it waits for the next message.
+	if err := client.WritePing(); err != nil {
+		t.Fatalf("Should be able to ping the server: %s", err)
+	}
+	if err := client.WriteText([]byte("after ping")); err != nil {
+		t.Fatalf("Should be able to write to the server: %s", err)
+	}
+	if got, err := server.ReadMessage(); err != nil || string(got) != "after ping" {
+		t.Fatalf("Should skip the ping, got %q, %v", got, err)
+	}
+	if err := server.WriteText([]byte("after pong")); err != nil {
+		t.Fatalf("Should be able to write to the client: %s", err)
+	}
+	got, err := client.ReadMessage()
+	if err != nil || string(got) != "after pong" {
+		t.Fatalf("Should skip the pong, got %q, %v", got, err)
+	}
+}
+
+func TestFragments(t *testing.T) {
+	srv, conns := newServer(t)
+	raw := dialRaw(t, srv)
+	server := <-conns
+
+	// A ping can arrive between the fragments of a message.
+	raw.write(t, false, opText, []byte("hel"), true)
+	raw.write(t, true, opPing, []byte("ping"), true)
+	raw.write(t, true, opContinuation, []byte("lo"), true)
+
+	got, err := server.ReadMessage()
+	if err != nil {
+		t.Fatalf("Should be able to read the message: %s", err)
+	}
+	if string(got) != "hello" {
+		t.Fatalf("Should join the fragments, got %q, exp %q", got, "hello")
+	}
+
+	opcode, payload := raw.read(t)
+	if opcode != opPong || string(payload) != "ping" {
+		t.Fatalf("Should answer the ping with its payload, got %d:%q", opcode, payload)
+	}
+}
+
+func TestInvalidFrames(t *testing.T) {
+	tt := []struct {
+		name  string
+		write func(t *testing.T, raw *rawConn)
+		exp   string
+	}{
+		{
+			name: "unmasked",
+			write: func(t *testing.T, raw *rawConn) {
+				raw.write(t, true, opText, []byte("hello"), false)
+			},
+			exp: "invalid masking",
+		},
+		{
+			name: "frame too large",
+			write: func(t *testing.T, raw *rawConn) {
+				raw.write(t, true, opText, make([]byte, 1<<16+1), true)
+			},
+			exp: "frame too large",
+		},
+		{
+			name: "message too large",
+			write: func(t *testing.T, raw *rawConn) {
+				raw.write(t, false, opText, make([]byte, 1<<15), true)
+				raw.write(t, false, opContinuation, make([]byte, 1<<15), true)
+				raw.write(t, true, opContinuation, []byte{0}, true)
+			},
+			exp: "message too large",
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			srv, conns := newServer(t)
+			raw := dialRaw(t, srv)
+			server := <-conns
+
+			go tst.write(t, raw)
+
+			_, err := server.ReadMessage()
+			if err == nil || !strings.Contains(err.Error(), tst.exp) {
+				t.Fatalf("Should reject the frame with %q, got %v", tst.exp, err)
+			}
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	srv, conns := newServer(t)
+	raw := dialRaw(t, srv)
+	server := <-conns
+
+	raw.write(t, true, opClose, nil, true)
+
+	if _, err := server.ReadMessage(); !errors.Is(err, websocket.ErrClosed) {
+		t.Fatalf("Should report the connection closed, got %v", err)
+	}
+
+	if opcode, _ := raw.read(t); opcode != opClose {
+		t.Fatalf("Should answer the close, got opcode %d", opcode)
+	}
+
+	// Closing the server side sends a close and ends the connection.
+	server.Close()
+	if opcode, _ := raw.read(t); opcode != opClose {
+		t.Fatalf("Should send a close, got opcode %d", opcode)
+	}
+	if _, err := raw.br.ReadByte(); err != io.EOF {
+		t.Fatalf("Should end the connection, got %v", err)
+	}
+}
+
+// =============================================================================
+
+// newServer starts a server upgrading every request. The server side of
+// each connection is sent on the channel.
+func newServer(t *testing.T) (*httptest.Server, chan *websocket.Conn) {
+	conns := make(chan *websocket.Conn, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			t.Errorf("Should be able to upgrade the connection: %s", err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, conns
+}
+
+// rawConn is a client connection that writes frames byte by byte, so
+// frames the websocket package would never write can be sent.
+type rawConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// dialRaw performs the handshake with the server over a plain connection.
+func dialRaw(t *testing.T, srv *httptest.Server) *rawConn {
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Should be able to dial the server: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req := "GET / HTTP/1.1\r\n" +
+		"Host: " + srv.Listener.Addr().String() + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatalf("Should be able to send the handshake: %s", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("Should be able to read the handshake: %s", err)
+	}
+	resp.Body.Close()
+
+	// The accept value for this key is the one given in RFC 6455.
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-Websocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Should complete the handshake, got %d %q", resp.StatusCode, resp.Header.Get("Sec-Websocket-Accept"))
+	}
+
+	return &rawConn{conn: conn, br: br}
+}
+
+// write sends a single frame with the length encoded in the smallest form.
+func (c *rawConn) write(t *testing.T, fin bool, opcode byte, payload []byte, masked bool) {
+	var head byte = opcode
+	if fin {
+		head |= 0x80
+	}
+
+	var maskBit byte
+	if masked {
+		maskBit = 0x80
+	}
+
+	frame := []byte{head}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(n))
+	default:
+		frame = append(frame, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(n))
+	}
+
+	data := payload
+	if masked {
+		var mask [4]byte
+		rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+
+		data = make([]byte, len(payload))
+		for i := range payload {
+			data[i] = payload[i] ^ mask[i%4]
+		}
+	}
+
+	if _, err := c.conn.Write(append(frame, data...)); err != nil {
+		t.Errorf("Should be able to write the frame: %s", err)
+	}
+}
+
+// read reads a single unmasked frame with a 7 bit length from the server.
+func (c *rawConn) read(t *testing.T) (byte, []byte) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		t.Fatalf("Should be able to read the frame: %s", err)
+	}
+	if head[1]&0x80 != 0 {
+		t.Fatal("Should not mask a frame written by the server")
+	}
+
+	payload := make([]byte, head[1]&0x7F)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		t.Fatalf("Should be able to read the frame: %s", err)
+	}
+
+	return head[0] & 0x0F, payload
+}